@@ -0,0 +1,176 @@
+package apperr
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Code 稳定的机器可读错误码，客户端应依据错误码而不是错误信息判断错误类型
+type Code string
+
+// 通用错误码
+const (
+	CodeBadRequest   Code = "BAD_REQUEST"
+	CodeInvalidID    Code = "INVALID_ID"
+	CodeUnauthorized Code = "UNAUTHORIZED"
+	CodeForbidden    Code = "FORBIDDEN"
+	CodeNotFound     Code = "NOT_FOUND"
+	CodeInternal     Code = "INTERNAL_ERROR"
+)
+
+// 认证相关错误码
+const (
+	CodeAuthHeaderMissing  Code = "AUTH_HEADER_MISSING"
+	CodeAuthHeaderInvalid  Code = "AUTH_HEADER_INVALID"
+	CodeTokenInvalid       Code = "TOKEN_INVALID"
+	CodeAdminRequired      Code = "ADMIN_REQUIRED"
+	CodeInvalidCredentials Code = "INVALID_CREDENTIALS"
+	CodeAccountDisabled    Code = "ACCOUNT_DISABLED"
+	CodeAccountLocked      Code = "ACCOUNT_LOCKED"
+	CodeLoginThrottled     Code = "LOGIN_THROTTLED"
+
+	CodeRefreshTokenInvalid Code = "REFRESH_TOKEN_INVALID"
+	CodeRefreshTokenReused  Code = "REFRESH_TOKEN_REUSED"
+
+	CodeMFAChallengeInvalid Code = "MFA_CHALLENGE_INVALID"
+	CodeMFACodeInvalid      Code = "MFA_CODE_INVALID"
+	CodeMFAAlreadyEnabled   Code = "MFA_ALREADY_ENABLED"
+	CodeMFANotEnrolled      Code = "MFA_NOT_ENROLLED"
+	CodeMFARequired         Code = "MFA_REQUIRED"
+
+	CodeSessionNotFound Code = "SESSION_NOT_FOUND"
+
+	CodeAPIKeyInvalid            Code = "API_KEY_INVALID"
+	CodeAPIKeyScopeDenied        Code = "API_KEY_SCOPE_DENIED"
+	CodeAPIKeyNotFound           Code = "API_KEY_NOT_FOUND"
+	CodeAPIKeyLimitReached       Code = "API_KEY_LIMIT_REACHED"
+	CodeServiceAccountNotFound   Code = "SERVICE_ACCOUNT_NOT_FOUND"
+	CodeServiceAccountNoPassword Code = "SERVICE_ACCOUNT_NO_PASSWORD"
+)
+
+// 单点登录相关错误码
+const (
+	CodeOIDCProviderNotFound    Code = "OIDC_PROVIDER_NOT_FOUND"
+	CodeOIDCProviderNameTaken   Code = "OIDC_PROVIDER_NAME_TAKEN"
+	CodeOIDCProviderUnavailable Code = "OIDC_PROVIDER_UNAVAILABLE"
+	CodeOIDCStateInvalid        Code = "OIDC_STATE_INVALID"
+	CodeOIDCLoginFailed         Code = "OIDC_LOGIN_FAILED"
+	CodeOIDCUserNotLinked       Code = "OIDC_USER_NOT_LINKED"
+)
+
+// 目录服务（LDAP）相关错误码
+const (
+	CodeDirectoryNotConfigured Code = "DIRECTORY_NOT_CONFIGURED"
+	CodeDirectoryUnavailable   Code = "DIRECTORY_UNAVAILABLE"
+	CodeDirectoryNoRole        Code = "DIRECTORY_NO_ROLE"
+)
+
+// 用户相关错误码
+const (
+	CodeUserNotFound         Code = "USER_NOT_FOUND"
+	CodeUsernameTaken        Code = "USERNAME_TAKEN"
+	CodeInvalidOldPassword   Code = "INVALID_OLD_PASSWORD"
+	CodePasswordResetInvalid Code = "PASSWORD_RESET_TOKEN_INVALID"
+	CodeUserHasNoOrg         Code = "USER_HAS_NO_ORG"
+	CodeUserAlreadyDisabled  Code = "USER_ALREADY_DISABLED"
+	CodeUserNotDisabled      Code = "USER_NOT_DISABLED"
+	CodeCannotDisableSelf    Code = "CANNOT_DISABLE_SELF"
+)
+
+// 邀请和注册相关错误码
+const (
+	CodeRegistrationClosed   Code = "REGISTRATION_CLOSED"
+	CodeInvitationNotFound   Code = "INVITATION_NOT_FOUND"
+	CodeInvitationInvalid    Code = "INVITATION_INVALID"
+	CodeInvitationNotPending Code = "INVITATION_NOT_PENDING"
+	CodeInvitationExists     Code = "INVITATION_EXISTS"
+)
+
+// 密码策略相关错误码
+const (
+	CodePasswordTooShort          Code = "PASSWORD_TOO_SHORT"
+	CodePasswordTooLong           Code = "PASSWORD_TOO_LONG"
+	CodePasswordNeedsUppercase    Code = "PASSWORD_NEEDS_UPPERCASE"
+	CodePasswordNeedsLowercase    Code = "PASSWORD_NEEDS_LOWERCASE"
+	CodePasswordNeedsDigit        Code = "PASSWORD_NEEDS_DIGIT"
+	CodePasswordNeedsSymbol       Code = "PASSWORD_NEEDS_SYMBOL"
+	CodePasswordBanned            Code = "PASSWORD_BANNED"
+	CodePasswordContainsUsername  Code = "PASSWORD_CONTAINS_USERNAME"
+	CodePasswordReused            Code = "PASSWORD_REUSED"
+	CodePasswordExpired           Code = "PASSWORD_EXPIRED"
+	CodePasswordManagedExternally Code = "PASSWORD_MANAGED_EXTERNALLY"
+)
+
+// 组织相关错误码
+const (
+	CodeOrgNotFound        Code = "ORG_NOT_FOUND"
+	CodeOrgHasUsers        Code = "ORG_HAS_USERS"
+	CodeAdminUsernameTaken Code = "ADMIN_USERNAME_TAKEN"
+)
+
+// 对象类相关错误码
+const (
+	CodeObjectClassNotFound       Code = "OBJECT_CLASS_NOT_FOUND"
+	CodeParentObjectClassNotFound Code = "PARENT_OBJECT_CLASS_NOT_FOUND"
+)
+
+// Error 带错误码和HTTP状态码的业务错误
+type Error struct {
+	Code    Code
+	Status  int
+	Details map[string]interface{} // 返回给客户端的附加信息，也用于填充错误信息模板
+	cause   error                  // 原始错误，只记录日志，不返回给客户端
+}
+
+// New 创建业务错误
+func New(code Code, status int) *Error {
+	return &Error{Code: code, Status: status}
+}
+
+// Internal 将未知错误包装为内部错误
+func Internal(cause error) *Error {
+	return &Error{Code: CodeInternal, Status: http.StatusInternalServerError, cause: cause}
+}
+
+// Error 实现error
+func (e *Error) Error() string {
+	if e.cause != nil {
+		return fmt.Sprintf("%s: %v", e.Code, e.cause)
+	}
+	return string(e.Code)
+}
+
+// Unwrap 返回原始错误
+func (e *Error) Unwrap() error {
+	return e.cause
+}
+
+// Is 错误码相同即视为同一错误，使 errors.Is(err.WithDetails(...), err) 成立
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+// WithDetails 返回附带详细信息的副本
+func (e *Error) WithDetails(details map[string]interface{}) *Error {
+	copied := *e
+	copied.Details = details
+	return &copied
+}
+
+// Wrap 返回记录了原始错误的副本
+func (e *Error) Wrap(cause error) *Error {
+	copied := *e
+	copied.cause = cause
+	return &copied
+}
+
+// From 将任意错误转换为*Error，无法识别的错误视为内部错误
+func From(err error) *Error {
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return appErr
+	}
+	return Internal(err)
+}
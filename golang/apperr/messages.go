@@ -0,0 +1,201 @@
+package apperr
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/text/language"
+)
+
+// 支持的语言，第一个为默认语言
+var (
+	supportedLanguages = []language.Tag{language.SimplifiedChinese, language.English}
+	languageMatcher    = language.NewMatcher(supportedLanguages)
+)
+
+// messages 错误信息目录，{name} 会被替换为Details中的同名字段
+var messages = map[language.Tag]map[Code]string{
+	language.SimplifiedChinese: {
+		CodeBadRequest:   "无效的请求数据",
+		CodeInvalidID:    "无效的ID",
+		CodeUnauthorized: "未授权的操作",
+		CodeForbidden:    "没有权限执行该操作",
+		CodeNotFound:     "资源不存在",
+		CodeInternal:     "服务器内部错误",
+
+		CodeAuthHeaderMissing:  "缺少Authorization请求头",
+		CodeAuthHeaderInvalid:  "Authorization格式错误",
+		CodeTokenInvalid:       "登录凭证无效或已过期",
+		CodeAdminRequired:      "需要管理员权限",
+		CodeInvalidCredentials: "用户名或密码错误",
+		CodeAccountDisabled:    "该账号已被禁用",
+		CodeAccountLocked:      "登录失败次数过多，账号已被锁定，请在 {retry_after} 秒后重试或联系管理员解锁",
+		CodeLoginThrottled:     "登录失败次数过多，请在 {retry_after} 秒后重试",
+
+		CodeRefreshTokenInvalid: "刷新令牌无效或已过期，请重新登录",
+		CodeRefreshTokenReused:  "刷新令牌已被使用，为安全起见请重新登录",
+
+		CodeMFAChallengeInvalid: "两步验证已超时，请重新登录",
+		CodeMFACodeInvalid:      "验证码错误",
+		CodeMFAAlreadyEnabled:   "已启用两步验证",
+		CodeMFANotEnrolled:      "尚未设置两步验证",
+		CodeMFARequired:         "组织要求管理员启用两步验证，不能关闭",
+
+		CodeSessionNotFound: "会话不存在或已过期",
+
+		CodeAPIKeyInvalid:            "API密钥无效、已过期或已被吊销",
+		CodeAPIKeyScopeDenied:        "API密钥没有该操作的权限",
+		CodeAPIKeyNotFound:           "API密钥不存在",
+		CodeAPIKeyLimitReached:       "最多同时拥有{max_per_user}个有效的API密钥，请先吊销不再使用的密钥",
+		CodeServiceAccountNotFound:   "服务账号不存在",
+		CodeServiceAccountNoPassword: "服务账号不能设置密码，请使用API密钥",
+
+		CodeOIDCProviderNotFound:    "身份提供方不存在或未启用",
+		CodeOIDCProviderNameTaken:   "身份提供方标识已被使用",
+		CodeOIDCProviderUnavailable: "无法连接身份提供方，请检查签发者地址或稍后重试",
+		CodeOIDCStateInvalid:        "单点登录请求无效或已超时，请重新登录",
+		CodeOIDCLoginFailed:         "单点登录失败，请重新登录",
+		CodeOIDCUserNotLinked:       "没有与该账号关联的用户，请联系管理员",
+
+		CodeDirectoryNotConfigured: "组织未配置目录服务",
+		CodeDirectoryUnavailable:   "无法连接目录服务，请检查配置或稍后重试",
+		CodeDirectoryNoRole:        "目录中的账号不属于任何允许登录的组，请联系管理员",
+
+		CodeUserNotFound:         "用户不存在",
+		CodeUsernameTaken:        "用户名已存在",
+		CodeInvalidOldPassword:   "原密码错误",
+		CodePasswordResetInvalid: "重置链接无效或已过期，请重新申请",
+		CodeUserHasNoOrg:         "当前用户不属于任何组织",
+		CodeUserAlreadyDisabled:  "该用户已被禁用",
+		CodeUserNotDisabled:      "该用户未被禁用",
+		CodeCannotDisableSelf:    "不能禁用自己的账号",
+
+		CodeRegistrationClosed:   "该组织未开放注册，请联系管理员邀请您加入",
+		CodeInvitationNotFound:   "邀请不存在",
+		CodeInvitationInvalid:    "邀请链接无效、已过期或已被使用，请联系管理员重新发送",
+		CodeInvitationNotPending: "邀请已被接受或已被撤销",
+		CodeInvitationExists:     "已向该邮箱发出邀请，如需再次发送请使用重新发送",
+
+		CodePasswordTooShort:          "密码长度不能少于{min_length}个字符",
+		CodePasswordTooLong:           "密码长度不能超过{max_length}个字节",
+		CodePasswordNeedsUppercase:    "密码必须包含大写字母",
+		CodePasswordNeedsLowercase:    "密码必须包含小写字母",
+		CodePasswordNeedsDigit:        "密码必须包含数字",
+		CodePasswordNeedsSymbol:       "密码必须包含符号",
+		CodePasswordBanned:            "密码过于常见，请换一个",
+		CodePasswordContainsUsername:  "密码不能包含用户名",
+		CodePasswordReused:            "不能使用最近{history}次使用过的密码",
+		CodePasswordExpired:           "密码已过期，请修改密码后再登录",
+		CodePasswordManagedExternally: "该账号的密码由目录服务管理，请在目录中修改",
+
+		CodeOrgNotFound:        "组织不存在",
+		CodeOrgHasUsers:        "无法删除组织[{organization}]，该组织下还有 {user_count} 个用户",
+		CodeAdminUsernameTaken: "管理员用户名已存在",
+
+		CodeObjectClassNotFound:       "对象类不存在",
+		CodeParentObjectClassNotFound: "父对象类不存在",
+	},
+	language.English: {
+		CodeBadRequest:   "Invalid request data",
+		CodeInvalidID:    "Invalid ID",
+		CodeUnauthorized: "Unauthorized",
+		CodeForbidden:    "You are not allowed to perform this operation",
+		CodeNotFound:     "Resource not found",
+		CodeInternal:     "Internal server error",
+
+		CodeAuthHeaderMissing:  "Authorization header is required",
+		CodeAuthHeaderInvalid:  "Invalid authorization format",
+		CodeTokenInvalid:       "Invalid or expired token",
+		CodeAdminRequired:      "Admin privileges required",
+		CodeInvalidCredentials: "Invalid username or password",
+		CodeAccountDisabled:    "This account has been disabled",
+		CodeAccountLocked:      "Too many failed sign-in attempts. The account is locked; try again in {retry_after} seconds or ask an administrator to unlock it",
+		CodeLoginThrottled:     "Too many failed sign-in attempts, please try again in {retry_after} seconds",
+
+		CodeRefreshTokenInvalid: "Invalid or expired refresh token, please sign in again",
+		CodeRefreshTokenReused:  "Refresh token has already been used, please sign in again",
+
+		CodeMFAChallengeInvalid: "Two-factor verification timed out, please sign in again",
+		CodeMFACodeInvalid:      "Invalid verification code",
+		CodeMFAAlreadyEnabled:   "Two-factor authentication is already enabled",
+		CodeMFANotEnrolled:      "Two-factor authentication has not been set up",
+		CodeMFARequired:         "Your organization requires administrators to use two-factor authentication",
+
+		CodeSessionNotFound: "Session not found or expired",
+
+		CodeAPIKeyInvalid:            "The API key is invalid, expired or revoked",
+		CodeAPIKeyScopeDenied:        "The API key is not allowed to perform this operation",
+		CodeAPIKeyNotFound:           "API key not found",
+		CodeAPIKeyLimitReached:       "You can have at most {max_per_user} active API keys, please revoke keys you no longer use",
+		CodeServiceAccountNotFound:   "Service account not found",
+		CodeServiceAccountNoPassword: "Service accounts cannot have a password, use an API key instead",
+
+		CodeOIDCProviderNotFound:    "Identity provider not found or disabled",
+		CodeOIDCProviderNameTaken:   "Identity provider name already exists",
+		CodeOIDCProviderUnavailable: "Cannot reach the identity provider, check the issuer URL or try again later",
+		CodeOIDCStateInvalid:        "The single sign-on request is invalid or has expired, please sign in again",
+		CodeOIDCLoginFailed:         "Single sign-on failed, please sign in again",
+		CodeOIDCUserNotLinked:       "No user is linked to this account, please contact your administrator",
+
+		CodeDirectoryNotConfigured: "The organization has no directory configured",
+		CodeDirectoryUnavailable:   "Cannot reach the directory server, check the configuration or try again later",
+		CodeDirectoryNoRole:        "Your directory account is not in any group allowed to sign in, please contact your administrator",
+
+		CodeUserNotFound:         "User not found",
+		CodeUsernameTaken:        "Username already exists",
+		CodeInvalidOldPassword:   "Invalid old password",
+		CodePasswordResetInvalid: "The password reset link is invalid or has expired, please request a new one",
+		CodeUserHasNoOrg:         "The current user does not belong to an organization",
+		CodeUserAlreadyDisabled:  "The user is already disabled",
+		CodeUserNotDisabled:      "The user is not disabled",
+		CodeCannotDisableSelf:    "You cannot disable your own account",
+
+		CodeRegistrationClosed:   "This organization does not allow sign-ups, please ask an administrator to invite you",
+		CodeInvitationNotFound:   "Invitation not found",
+		CodeInvitationInvalid:    "The invitation link is invalid, expired or already used, please ask an administrator to resend it",
+		CodeInvitationNotPending: "The invitation has already been accepted or revoked",
+		CodeInvitationExists:     "An invitation has already been sent to this email, resend it instead",
+
+		CodePasswordTooShort:          "Password must be at least {min_length} characters long",
+		CodePasswordTooLong:           "Password must be at most {max_length} bytes long",
+		CodePasswordNeedsUppercase:    "Password must contain an uppercase letter",
+		CodePasswordNeedsLowercase:    "Password must contain a lowercase letter",
+		CodePasswordNeedsDigit:        "Password must contain a digit",
+		CodePasswordNeedsSymbol:       "Password must contain a symbol",
+		CodePasswordBanned:            "This password is too common, please choose another one",
+		CodePasswordContainsUsername:  "Password must not contain the username",
+		CodePasswordReused:            "Password must not match any of the last {history} passwords",
+		CodePasswordExpired:           "Your password has expired, please change it before signing in",
+		CodePasswordManagedExternally: "This account's password is managed by the directory, please change it there",
+
+		CodeOrgNotFound:        "Organization not found",
+		CodeOrgHasUsers:        "Cannot delete organization [{organization}]: it still has {user_count} users",
+		CodeAdminUsernameTaken: "Organization admin username already exists",
+
+		CodeObjectClassNotFound:       "Object class not found",
+		CodeParentObjectClassNotFound: "Parent object class not found",
+	},
+}
+
+// MatchLanguage 根据Accept-Language选择语言
+func MatchLanguage(acceptLanguage string) language.Tag {
+	tags, _, _ := language.ParseAcceptLanguage(acceptLanguage)
+	_, index, _ := languageMatcher.Match(tags...)
+	return supportedLanguages[index]
+}
+
+// Message 返回错误在指定语言下的信息
+func Message(lang language.Tag, code Code, details map[string]interface{}) string {
+	msg, ok := messages[lang][code]
+	if !ok {
+		msg, ok = messages[supportedLanguages[0]][code]
+	}
+	if !ok {
+		return string(code)
+	}
+
+	for key, value := range details {
+		msg = strings.ReplaceAll(msg, "{"+key+"}", fmt.Sprint(value))
+	}
+	return msg
+}
@@ -0,0 +1,49 @@
+package apperr
+
+import (
+	"net/http"
+	"strconv"
+	"xzyq/logging"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Response 统一的错误响应
+type Response struct {
+	Code      Code                   `json:"code"`
+	Message   string                 `json:"message"`
+	Details   map[string]interface{} `json:"details,omitempty"`
+	RequestID string                 `json:"request_id,omitempty"`
+}
+
+// Respond 以统一格式返回错误并终止请求，错误信息的语言由Accept-Language决定
+func Respond(c *gin.Context, err error) {
+	appErr := From(err)
+
+	if appErr.Status >= http.StatusInternalServerError {
+		logging.FromContext(c.Request.Context()).Error("request failed",
+			"code", appErr.Code, "route", c.FullPath(), "error", err)
+	}
+
+	// 需要等待后重试的错误同时设置Retry-After响应头
+	if retryAfter, ok := appErr.Details["retry_after"].(int64); ok {
+		c.Header("Retry-After", strconv.FormatInt(retryAfter, 10))
+	}
+
+	lang := MatchLanguage(c.GetHeader("Accept-Language"))
+	c.AbortWithStatusJSON(appErr.Status, Response{
+		Code:      appErr.Code,
+		Message:   Message(lang, appErr.Code, appErr.Details),
+		Details:   appErr.Details,
+		RequestID: logging.RequestID(c.Request.Context()),
+	})
+}
+
+// 常用错误
+var (
+	ErrBadRequest   = New(CodeBadRequest, http.StatusBadRequest)
+	ErrInvalidID    = New(CodeInvalidID, http.StatusBadRequest)
+	ErrUnauthorized = New(CodeUnauthorized, http.StatusUnauthorized)
+	ErrForbidden    = New(CodeForbidden, http.StatusForbidden)
+	ErrNotFound     = New(CodeNotFound, http.StatusNotFound)
+)
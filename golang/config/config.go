@@ -0,0 +1,711 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config 应用配置
+//
+// 配置的优先级从低到高依次为：默认值 < 配置文件 < 环境变量 < 命令行参数
+type Config struct {
+	Server   ServerConfig   `yaml:"server"`
+	Database DatabaseConfig `yaml:"database"`
+	JWT      JWTConfig      `yaml:"jwt"`
+	Auth     AuthConfig     `yaml:"auth"`
+	Mail     MailConfig     `yaml:"mail"`
+	Log      LogConfig      `yaml:"log"`
+}
+
+// ServerConfig HTTP服务配置
+type ServerConfig struct {
+	Addr              string        `yaml:"addr"` // 监听地址，例如 :8080
+	Mode              string        `yaml:"mode"` // gin运行模式：debug、release或test
+	ReadTimeout       time.Duration `yaml:"read_timeout"`
+	ReadHeaderTimeout time.Duration `yaml:"read_header_timeout"`
+	WriteTimeout      time.Duration `yaml:"write_timeout"`
+	IdleTimeout       time.Duration `yaml:"idle_timeout"`
+	ShutdownTimeout   time.Duration `yaml:"shutdown_timeout"` // 停止服务时等待处理中请求的最长时间
+	TLS               TLSConfig     `yaml:"tls"`
+}
+
+// TLSConfig HTTPS配置，证书和私钥文件更新后会自动重新加载
+type TLSConfig struct {
+	CertFile string `yaml:"cert_file"`
+	KeyFile  string `yaml:"key_file"`
+}
+
+// Enabled 是否启用HTTPS
+func (c TLSConfig) Enabled() bool {
+	return c.CertFile != "" || c.KeyFile != ""
+}
+
+// DatabaseConfig 数据库配置
+type DatabaseConfig struct {
+	Driver          string        `yaml:"driver"` // postgres或sqlite
+	Path            string        `yaml:"path"`   // SQLite数据库文件路径，:memory: 表示内存数据库
+	Host            string        `yaml:"host"`
+	Port            int           `yaml:"port"`
+	Name            string        `yaml:"name"`
+	User            string        `yaml:"user"`
+	Password        string        `yaml:"password"`
+	SSLMode         string        `yaml:"sslmode"`
+	MaxIdleConns    int           `yaml:"max_idle_conns"`
+	MaxOpenConns    int           `yaml:"max_open_conns"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime"`
+	AutoMigrate     bool          `yaml:"auto_migrate"` // 启动服务时自动执行未执行的迁移
+	Seed            bool          `yaml:"seed"`         // 启动服务时写入初始数据
+}
+
+// JWTConfig JWT配置
+//
+// 配置了SigningKey时使用非对称签名（RSA私钥为RS256，Ed25519私钥为EdDSA），否则使用Secret进行HS256签名。
+// 轮换密钥时将新私钥设为SigningKey，旧密钥的公钥放入VerificationKeys，直到旧令牌全部过期。
+type JWTConfig struct {
+	Secret           string   `yaml:"secret"`
+	SigningKey       string   `yaml:"signing_key"`       // PEM格式私钥文件
+	VerificationKeys []string `yaml:"verification_keys"` // 仍然接受的其他PEM格式公钥文件
+
+	Expire         time.Duration `yaml:"expire"`          // 访问令牌有效期
+	RefreshExpire  time.Duration `yaml:"refresh_expire"`  // 刷新令牌有效期，每次刷新后重新计算
+	RevocationSync time.Duration `yaml:"revocation_sync"` // 从数据库同步令牌吊销记录的间隔
+}
+
+// AuthConfig 登录认证配置
+type AuthConfig struct {
+	Lockout LockoutConfig `yaml:"lockout"`
+	MFA     MFAConfig     `yaml:"mfa"`
+
+	PasswordReset PasswordResetConfig  `yaml:"password_reset"`
+	Invitation    InvitationConfig     `yaml:"invitation"`
+	Password      PasswordPolicyConfig `yaml:"password"`
+	PasswordHash  PasswordHashConfig   `yaml:"password_hash"`
+	OIDC          OIDCConfig           `yaml:"oidc"`
+	LDAP          LDAPConfig           `yaml:"ldap"`
+	APIKeys       APIKeyConfig         `yaml:"api_keys"`
+	Sessions      SessionConfig        `yaml:"sessions"`
+}
+
+// SessionConfig 登录会话配置
+type SessionConfig struct {
+	MaxPerUser int `yaml:"max_per_user"` // 每个用户同时登录的会话数上限，超过时吊销最久未活动的会话；0表示不限制
+}
+
+// APIKeyConfig API密钥配置
+type APIKeyConfig struct {
+	DefaultExpire time.Duration `yaml:"default_expire"` // 创建时未指定有效期时使用
+	MaxExpire     time.Duration `yaml:"max_expire"`     // 有效期上限
+	MaxPerUser    int           `yaml:"max_per_user"`   // 每个用户最多同时有效的密钥数量
+}
+
+// LDAPConfig LDAP/Active Directory认证配置，目录服务由各组织的管理员在系统中配置
+type LDAPConfig struct {
+	Timeout time.Duration `yaml:"timeout"` // 连接和每次请求目录服务的超时时间
+}
+
+// OIDCConfig OpenID Connect单点登录配置，身份提供方由各组织的管理员在系统中配置
+type OIDCConfig struct {
+	RedirectURL string        `yaml:"redirect_url"` // 前端回调页面地址，需要在身份提供方登记
+	StateExpire time.Duration `yaml:"state_expire"` // 跳转到身份提供方后完成登录的时限
+	HTTPTimeout time.Duration `yaml:"http_timeout"` // 请求身份提供方的超时时间
+}
+
+// PasswordPolicyConfig 全局密码策略，组织可以在此基础上设置更严格的要求
+type PasswordPolicyConfig struct {
+	MinLength        int           `yaml:"min_length"`        // 最少字符数
+	MaxLength        int           `yaml:"max_length"`        // 最多字节数，不能超过72
+	RequireUppercase bool          `yaml:"require_uppercase"` // 必须包含大写字母
+	RequireLowercase bool          `yaml:"require_lowercase"` // 必须包含小写字母
+	RequireDigit     bool          `yaml:"require_digit"`     // 必须包含数字
+	RequireSymbol    bool          `yaml:"require_symbol"`    // 必须包含符号
+	BannedFile       string        `yaml:"banned_file"`       // 禁用密码列表文件，每行一个，不区分大小写，为空时不检查
+	History          int           `yaml:"history"`           // 不能与最近几次使用过的密码相同，0表示不检查
+	MaxAge           time.Duration `yaml:"max_age"`           // 密码有效期，过期后登录时必须修改，0表示不过期
+}
+
+// PasswordHashConfig 密码哈希参数
+//
+// 新密码使用argon2id，bcrypt和早期直接保存的明文密码只用于校验，登录成功后自动升级；
+// 调高参数后，已有的argon2id哈希同样在下次登录时升级。
+type PasswordHashConfig struct {
+	Memory      int `yaml:"memory"`      // argon2id内存开销，单位KiB
+	Iterations  int `yaml:"iterations"`  // argon2id迭代次数
+	Parallelism int `yaml:"parallelism"` // argon2id并行度
+}
+
+// PasswordResetConfig 自助重置密码配置
+type PasswordResetConfig struct {
+	Expire time.Duration `yaml:"expire"` // 重置链接有效期
+	URL    string        `yaml:"url"`    // 前端重置密码页面地址，邮件中的链接为 URL?token=...
+}
+
+// InvitationConfig 邀请用户加入组织的配置
+type InvitationConfig struct {
+	Expire time.Duration `yaml:"expire"` // 邀请链接有效期，重新发送时重新计算
+	URL    string        `yaml:"url"`    // 前端接受邀请页面地址，邮件中的链接为 URL?token=...
+}
+
+// MFAConfig 两步验证配置
+type MFAConfig struct {
+	Issuer          string        `yaml:"issuer"`           // 验证器应用中显示的服务名称
+	ChallengeExpire time.Duration `yaml:"challenge_expire"` // 密码验证通过后完成第二步验证的时限
+}
+
+// LockoutConfig 登录失败限制
+//
+// 同一用户名连续失败DelayAfter次（同一IP为IPDelayAfter次）后，每次失败后需要等待的时间从BaseDelay起逐次翻倍；
+// 用户名连续失败MaxFailures次、或IP连续失败IPMaxFailures次后锁定Duration。
+// 同一IP后面可能有很多用户，因此IP的阈值应明显高于用户名。
+// 超过Window没有新的失败时计数清零。
+type LockoutConfig struct {
+	MaxFailures   int           `yaml:"max_failures"`
+	IPMaxFailures int           `yaml:"ip_max_failures"`
+	DelayAfter    int           `yaml:"delay_after"`
+	IPDelayAfter  int           `yaml:"ip_delay_after"`
+	BaseDelay     time.Duration `yaml:"base_delay"`
+	Duration      time.Duration `yaml:"duration"`
+	Window        time.Duration `yaml:"window"`
+}
+
+// MailConfig 邮件发送配置
+type MailConfig struct {
+	Driver string     `yaml:"driver"` // smtp、file或console，file和console只用于开发环境
+	From   string     `yaml:"from"`   // 发件人，例如 xzyq <noreply@example.com>
+	File   string     `yaml:"file"`   // driver为file时邮件追加写入的文件
+	SMTP   SMTPConfig `yaml:"smtp"`
+}
+
+// SMTPConfig SMTP服务器配置
+type SMTPConfig struct {
+	Host     string `yaml:"host"`
+	Port     int    `yaml:"port"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+	TLS      string `yaml:"tls"` // starttls、implicit或none
+}
+
+// LogConfig 日志配置
+type LogConfig struct {
+	Level  string `yaml:"level"`  // debug、info、warn或error，debug级别会输出SQL
+	Format string `yaml:"format"` // json或text
+}
+
+// Default 返回默认配置
+func Default() *Config {
+	return &Config{
+		Server: ServerConfig{
+			Addr:              ":8080",
+			Mode:              "debug",
+			ReadTimeout:       15 * time.Second,
+			ReadHeaderTimeout: 5 * time.Second,
+			WriteTimeout:      30 * time.Second,
+			IdleTimeout:       2 * time.Minute,
+			ShutdownTimeout:   30 * time.Second,
+		},
+		Database: DatabaseConfig{
+			Driver:          "postgres",
+			Path:            "xzyq.db",
+			Host:            "localhost",
+			Port:            5432,
+			Name:            "postgres",
+			User:            "postgres",
+			SSLMode:         "disable",
+			MaxIdleConns:    10,
+			MaxOpenConns:    100,
+			ConnMaxLifetime: time.Hour,
+		},
+		JWT: JWTConfig{
+			Expire:         15 * time.Minute,
+			RefreshExpire:  7 * 24 * time.Hour,
+			RevocationSync: 10 * time.Second,
+		},
+		Auth: AuthConfig{
+			Lockout: LockoutConfig{
+				MaxFailures:   5,
+				IPMaxFailures: 50,
+				DelayAfter:    3,
+				IPDelayAfter:  10,
+				BaseDelay:     time.Second,
+				Duration:      15 * time.Minute,
+				Window:        15 * time.Minute,
+			},
+			MFA: MFAConfig{
+				Issuer:          "xzyq",
+				ChallengeExpire: 5 * time.Minute,
+			},
+			PasswordReset: PasswordResetConfig{
+				Expire: 30 * time.Minute,
+				URL:    "http://localhost:8081/reset-password",
+			},
+			Invitation: InvitationConfig{
+				Expire: 7 * 24 * time.Hour,
+				URL:    "http://localhost:8081/accept-invitation",
+			},
+			Password: PasswordPolicyConfig{
+				MinLength: 8,
+				MaxLength: 72,
+				History:   5,
+			},
+			PasswordHash: PasswordHashConfig{
+				Memory:      64 * 1024,
+				Iterations:  3,
+				Parallelism: 2,
+			},
+			OIDC: OIDCConfig{
+				RedirectURL: "http://localhost:8081/oidc/callback",
+				StateExpire: 10 * time.Minute,
+				HTTPTimeout: 10 * time.Second,
+			},
+			LDAP: LDAPConfig{
+				Timeout: 10 * time.Second,
+			},
+			APIKeys: APIKeyConfig{
+				DefaultExpire: 90 * 24 * time.Hour,
+				MaxExpire:     365 * 24 * time.Hour,
+				MaxPerUser:    10,
+			},
+		},
+		Mail: MailConfig{
+			Driver: "console",
+			From:   "xzyq <noreply@localhost>",
+			File:   "mail.log",
+			SMTP: SMTPConfig{
+				Port: 587,
+				TLS:  "starttls",
+			},
+		},
+		Log: LogConfig{
+			Level:  "info",
+			Format: "json",
+		},
+	}
+}
+
+// Load 按优先级加载配置并校验，返回参数之后的子命令及其参数
+//
+// 配置文件路径可以通过 -config 参数或 XZYQ_CONFIG 环境变量指定，
+// 未指定时如果当前目录存在 config.yaml 则使用该文件。
+func Load(args []string) (*Config, []string, error) {
+	// 第一遍解析命令行参数，只为拿到配置文件路径
+	probe := newFlagSet(Default())
+	if err := probe.Parse(args); err != nil {
+		return nil, nil, err
+	}
+
+	cfg := Default()
+
+	path := probe.Lookup("config").Value.String()
+	if path == "" {
+		path = os.Getenv("XZYQ_CONFIG")
+	}
+	if path == "" {
+		if _, err := os.Stat("config.yaml"); err == nil {
+			path = "config.yaml"
+		}
+	}
+	if path != "" {
+		if err := loadFile(cfg, path); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if err := loadEnv(cfg); err != nil {
+		return nil, nil, err
+	}
+
+	// 第二遍解析命令行参数，覆盖配置文件和环境变量中的值
+	fs := newFlagSet(cfg)
+	if err := fs.Parse(args); err != nil {
+		return nil, nil, err
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, nil, err
+	}
+	return cfg, fs.Args(), nil
+}
+
+// Validate 校验配置是否完整有效
+func (c *Config) Validate() error {
+	var errs []error
+
+	if c.Server.Addr == "" {
+		errs = append(errs, errors.New("server.addr is required"))
+	}
+	switch c.Server.Mode {
+	case "debug", "release", "test":
+	default:
+		errs = append(errs, fmt.Errorf("server.mode must be one of debug, release, test, got %q", c.Server.Mode))
+	}
+	if c.Server.ReadTimeout < 0 || c.Server.ReadHeaderTimeout < 0 || c.Server.WriteTimeout < 0 || c.Server.IdleTimeout < 0 {
+		errs = append(errs, errors.New("server timeouts must not be negative"))
+	}
+	if c.Server.ShutdownTimeout <= 0 {
+		errs = append(errs, errors.New("server.shutdown_timeout must be positive"))
+	}
+	if c.Server.TLS.Enabled() && (c.Server.TLS.CertFile == "" || c.Server.TLS.KeyFile == "") {
+		errs = append(errs, errors.New("server.tls requires both cert_file and key_file"))
+	}
+
+	switch c.Database.Driver {
+	case "postgres":
+		if c.Database.Host == "" {
+			errs = append(errs, errors.New("database.host is required"))
+		}
+		if c.Database.Port <= 0 || c.Database.Port > 65535 {
+			errs = append(errs, fmt.Errorf("database.port %d is out of range", c.Database.Port))
+		}
+		if c.Database.Name == "" {
+			errs = append(errs, errors.New("database.name is required"))
+		}
+		if c.Database.User == "" {
+			errs = append(errs, errors.New("database.user is required"))
+		}
+	case "sqlite":
+		if c.Database.Path == "" {
+			errs = append(errs, errors.New("database.path is required for sqlite"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("database.driver must be postgres or sqlite, got %q", c.Database.Driver))
+	}
+	if c.Database.MaxOpenConns < 0 || c.Database.MaxIdleConns < 0 {
+		errs = append(errs, errors.New("database connection pool sizes must not be negative"))
+	}
+
+	if c.JWT.Secret == "" && c.JWT.SigningKey == "" {
+		errs = append(errs, errors.New("jwt.secret or jwt.signing_key is required"))
+	}
+	if c.JWT.Expire <= 0 {
+		errs = append(errs, errors.New("jwt.expire must be positive"))
+	}
+	if c.JWT.RefreshExpire <= c.JWT.Expire {
+		errs = append(errs, errors.New("jwt.refresh_expire must be longer than jwt.expire"))
+	}
+	if c.JWT.RevocationSync <= 0 {
+		errs = append(errs, errors.New("jwt.revocation_sync must be positive"))
+	}
+
+	lockout := c.Auth.Lockout
+	if lockout.MaxFailures <= 0 || lockout.IPMaxFailures <= 0 {
+		errs = append(errs, errors.New("auth.lockout.max_failures and ip_max_failures must be positive"))
+	}
+	if lockout.DelayAfter < 0 || lockout.IPDelayAfter < 0 || lockout.BaseDelay < 0 {
+		errs = append(errs, errors.New("auth.lockout.delay_after, ip_delay_after and base_delay must not be negative"))
+	}
+	if lockout.Duration <= 0 || lockout.Window <= 0 {
+		errs = append(errs, errors.New("auth.lockout.duration and window must be positive"))
+	}
+
+	if c.Auth.MFA.Issuer == "" {
+		errs = append(errs, errors.New("auth.mfa.issuer is required"))
+	}
+	if c.Auth.MFA.ChallengeExpire <= 0 {
+		errs = append(errs, errors.New("auth.mfa.challenge_expire must be positive"))
+	}
+
+	if c.Auth.PasswordReset.Expire <= 0 {
+		errs = append(errs, errors.New("auth.password_reset.expire must be positive"))
+	}
+	if c.Auth.PasswordReset.URL == "" {
+		errs = append(errs, errors.New("auth.password_reset.url is required"))
+	}
+	if c.Auth.Invitation.Expire <= 0 {
+		errs = append(errs, errors.New("auth.invitation.expire must be positive"))
+	}
+	if c.Auth.Invitation.URL == "" {
+		errs = append(errs, errors.New("auth.invitation.url is required"))
+	}
+
+	password := c.Auth.Password
+	if password.MinLength < 1 {
+		errs = append(errs, errors.New("auth.password.min_length must be positive"))
+	}
+	if password.MaxLength < password.MinLength || password.MaxLength > 72 {
+		errs = append(errs, fmt.Errorf("auth.password.max_length must be between min_length and 72, got %d", password.MaxLength))
+	}
+	if password.History < 0 || password.MaxAge < 0 {
+		errs = append(errs, errors.New("auth.password.history and max_age must not be negative"))
+	}
+	hash := c.Auth.PasswordHash
+	if hash.Parallelism < 1 || hash.Parallelism > 255 {
+		errs = append(errs, fmt.Errorf("auth.password_hash.parallelism must be between 1 and 255, got %d", hash.Parallelism))
+	}
+	// 上限与utils.Argon2MaxIterations和utils.Argon2MaxMemory一致，超过时生成的哈希无法校验
+	if hash.Iterations < 1 || hash.Iterations > 64 {
+		errs = append(errs, fmt.Errorf("auth.password_hash.iterations must be between 1 and 64, got %d", hash.Iterations))
+	}
+	if hash.Memory < 8*hash.Parallelism || hash.Memory > 1024*1024 {
+		errs = append(errs, fmt.Errorf("auth.password_hash.memory must be between 8*parallelism and 1048576 KiB, got %d", hash.Memory))
+	}
+
+	if c.Auth.OIDC.RedirectURL == "" {
+		errs = append(errs, errors.New("auth.oidc.redirect_url is required"))
+	}
+	if c.Auth.OIDC.StateExpire <= 0 || c.Auth.OIDC.HTTPTimeout <= 0 {
+		errs = append(errs, errors.New("auth.oidc.state_expire and http_timeout must be positive"))
+	}
+	if c.Auth.LDAP.Timeout <= 0 {
+		errs = append(errs, errors.New("auth.ldap.timeout must be positive"))
+	}
+	if keys := c.Auth.APIKeys; keys.DefaultExpire <= 0 || keys.MaxExpire < keys.DefaultExpire {
+		errs = append(errs, errors.New("auth.api_keys.default_expire must be positive and not exceed max_expire"))
+	}
+	if c.Auth.Sessions.MaxPerUser < 0 {
+		errs = append(errs, fmt.Errorf("auth.sessions.max_per_user must not be negative, got %d", c.Auth.Sessions.MaxPerUser))
+	}
+	if c.Auth.APIKeys.MaxPerUser < 1 {
+		errs = append(errs, fmt.Errorf("auth.api_keys.max_per_user must be at least 1, got %d", c.Auth.APIKeys.MaxPerUser))
+	}
+
+	if c.Mail.From == "" {
+		errs = append(errs, errors.New("mail.from is required"))
+	}
+	switch c.Mail.Driver {
+	case "smtp":
+		if c.Mail.SMTP.Host == "" {
+			errs = append(errs, errors.New("mail.smtp.host is required"))
+		}
+		if c.Mail.SMTP.Port <= 0 || c.Mail.SMTP.Port > 65535 {
+			errs = append(errs, fmt.Errorf("mail.smtp.port %d is out of range", c.Mail.SMTP.Port))
+		}
+		switch c.Mail.SMTP.TLS {
+		case "starttls", "implicit", "none":
+		default:
+			errs = append(errs, fmt.Errorf("mail.smtp.tls must be one of starttls, implicit, none, got %q", c.Mail.SMTP.TLS))
+		}
+	case "file":
+		if c.Mail.File == "" {
+			errs = append(errs, errors.New("mail.file is required for the file driver"))
+		}
+	case "console":
+	default:
+		errs = append(errs, fmt.Errorf("mail.driver must be smtp, file or console, got %q", c.Mail.Driver))
+	}
+
+	switch c.Log.Level {
+	case "debug", "info", "warn", "error":
+	default:
+		errs = append(errs, fmt.Errorf("log.level must be one of debug, info, warn, error, got %q", c.Log.Level))
+	}
+	if c.Log.Format != "json" && c.Log.Format != "text" {
+		errs = append(errs, fmt.Errorf("log.format must be json or text, got %q", c.Log.Format))
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid config: %w", errors.Join(errs...))
+	}
+	return nil
+}
+
+// loadFile 从YAML文件加载配置
+func loadFile(cfg *Config, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read config file %s: %w", path, err)
+	}
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		return fmt.Errorf("parse config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// loadEnv 从环境变量加载配置
+func loadEnv(cfg *Config) error {
+	vars := []struct {
+		name   string
+		target interface{}
+	}{
+		{"XZYQ_SERVER_ADDR", &cfg.Server.Addr},
+		{"XZYQ_SERVER_MODE", &cfg.Server.Mode},
+		{"XZYQ_SERVER_READ_TIMEOUT", &cfg.Server.ReadTimeout},
+		{"XZYQ_SERVER_READ_HEADER_TIMEOUT", &cfg.Server.ReadHeaderTimeout},
+		{"XZYQ_SERVER_WRITE_TIMEOUT", &cfg.Server.WriteTimeout},
+		{"XZYQ_SERVER_IDLE_TIMEOUT", &cfg.Server.IdleTimeout},
+		{"XZYQ_SERVER_SHUTDOWN_TIMEOUT", &cfg.Server.ShutdownTimeout},
+		{"XZYQ_TLS_CERT_FILE", &cfg.Server.TLS.CertFile},
+		{"XZYQ_TLS_KEY_FILE", &cfg.Server.TLS.KeyFile},
+		{"XZYQ_DB_DRIVER", &cfg.Database.Driver},
+		{"XZYQ_DB_PATH", &cfg.Database.Path},
+		{"XZYQ_DB_HOST", &cfg.Database.Host},
+		{"XZYQ_DB_PORT", &cfg.Database.Port},
+		{"XZYQ_DB_NAME", &cfg.Database.Name},
+		{"XZYQ_DB_USER", &cfg.Database.User},
+		{"XZYQ_DB_PASSWORD", &cfg.Database.Password},
+		{"XZYQ_DB_SSLMODE", &cfg.Database.SSLMode},
+		{"XZYQ_DB_MAX_IDLE_CONNS", &cfg.Database.MaxIdleConns},
+		{"XZYQ_DB_MAX_OPEN_CONNS", &cfg.Database.MaxOpenConns},
+		{"XZYQ_DB_CONN_MAX_LIFETIME", &cfg.Database.ConnMaxLifetime},
+		{"XZYQ_DB_AUTO_MIGRATE", &cfg.Database.AutoMigrate},
+		{"XZYQ_DB_SEED", &cfg.Database.Seed},
+		{"XZYQ_JWT_SECRET", &cfg.JWT.Secret},
+		{"XZYQ_JWT_SIGNING_KEY", &cfg.JWT.SigningKey},
+		{"XZYQ_JWT_VERIFICATION_KEYS", &cfg.JWT.VerificationKeys},
+		{"XZYQ_JWT_EXPIRE", &cfg.JWT.Expire},
+		{"XZYQ_JWT_REFRESH_EXPIRE", &cfg.JWT.RefreshExpire},
+		{"XZYQ_JWT_REVOCATION_SYNC", &cfg.JWT.RevocationSync},
+		{"XZYQ_AUTH_LOCKOUT_MAX_FAILURES", &cfg.Auth.Lockout.MaxFailures},
+		{"XZYQ_AUTH_LOCKOUT_IP_MAX_FAILURES", &cfg.Auth.Lockout.IPMaxFailures},
+		{"XZYQ_AUTH_LOCKOUT_DELAY_AFTER", &cfg.Auth.Lockout.DelayAfter},
+		{"XZYQ_AUTH_LOCKOUT_IP_DELAY_AFTER", &cfg.Auth.Lockout.IPDelayAfter},
+		{"XZYQ_AUTH_LOCKOUT_BASE_DELAY", &cfg.Auth.Lockout.BaseDelay},
+		{"XZYQ_AUTH_LOCKOUT_DURATION", &cfg.Auth.Lockout.Duration},
+		{"XZYQ_AUTH_LOCKOUT_WINDOW", &cfg.Auth.Lockout.Window},
+		{"XZYQ_AUTH_MFA_ISSUER", &cfg.Auth.MFA.Issuer},
+		{"XZYQ_AUTH_MFA_CHALLENGE_EXPIRE", &cfg.Auth.MFA.ChallengeExpire},
+		{"XZYQ_AUTH_PASSWORD_RESET_EXPIRE", &cfg.Auth.PasswordReset.Expire},
+		{"XZYQ_AUTH_PASSWORD_RESET_URL", &cfg.Auth.PasswordReset.URL},
+		{"XZYQ_AUTH_INVITATION_EXPIRE", &cfg.Auth.Invitation.Expire},
+		{"XZYQ_AUTH_INVITATION_URL", &cfg.Auth.Invitation.URL},
+		{"XZYQ_AUTH_PASSWORD_MIN_LENGTH", &cfg.Auth.Password.MinLength},
+		{"XZYQ_AUTH_PASSWORD_MAX_LENGTH", &cfg.Auth.Password.MaxLength},
+		{"XZYQ_AUTH_PASSWORD_REQUIRE_UPPERCASE", &cfg.Auth.Password.RequireUppercase},
+		{"XZYQ_AUTH_PASSWORD_REQUIRE_LOWERCASE", &cfg.Auth.Password.RequireLowercase},
+		{"XZYQ_AUTH_PASSWORD_REQUIRE_DIGIT", &cfg.Auth.Password.RequireDigit},
+		{"XZYQ_AUTH_PASSWORD_REQUIRE_SYMBOL", &cfg.Auth.Password.RequireSymbol},
+		{"XZYQ_AUTH_PASSWORD_BANNED_FILE", &cfg.Auth.Password.BannedFile},
+		{"XZYQ_AUTH_PASSWORD_HISTORY", &cfg.Auth.Password.History},
+		{"XZYQ_AUTH_PASSWORD_MAX_AGE", &cfg.Auth.Password.MaxAge},
+		{"XZYQ_AUTH_PASSWORD_HASH_MEMORY", &cfg.Auth.PasswordHash.Memory},
+		{"XZYQ_AUTH_PASSWORD_HASH_ITERATIONS", &cfg.Auth.PasswordHash.Iterations},
+		{"XZYQ_AUTH_PASSWORD_HASH_PARALLELISM", &cfg.Auth.PasswordHash.Parallelism},
+		{"XZYQ_AUTH_OIDC_REDIRECT_URL", &cfg.Auth.OIDC.RedirectURL},
+		{"XZYQ_AUTH_OIDC_STATE_EXPIRE", &cfg.Auth.OIDC.StateExpire},
+		{"XZYQ_AUTH_OIDC_HTTP_TIMEOUT", &cfg.Auth.OIDC.HTTPTimeout},
+		{"XZYQ_AUTH_LDAP_TIMEOUT", &cfg.Auth.LDAP.Timeout},
+		{"XZYQ_AUTH_API_KEYS_DEFAULT_EXPIRE", &cfg.Auth.APIKeys.DefaultExpire},
+		{"XZYQ_AUTH_API_KEYS_MAX_EXPIRE", &cfg.Auth.APIKeys.MaxExpire},
+		{"XZYQ_AUTH_API_KEYS_MAX_PER_USER", &cfg.Auth.APIKeys.MaxPerUser},
+		{"XZYQ_AUTH_SESSIONS_MAX_PER_USER", &cfg.Auth.Sessions.MaxPerUser},
+		{"XZYQ_MAIL_DRIVER", &cfg.Mail.Driver},
+		{"XZYQ_MAIL_FROM", &cfg.Mail.From},
+		{"XZYQ_MAIL_FILE", &cfg.Mail.File},
+		{"XZYQ_SMTP_HOST", &cfg.Mail.SMTP.Host},
+		{"XZYQ_SMTP_PORT", &cfg.Mail.SMTP.Port},
+		{"XZYQ_SMTP_USERNAME", &cfg.Mail.SMTP.Username},
+		{"XZYQ_SMTP_PASSWORD", &cfg.Mail.SMTP.Password},
+		{"XZYQ_SMTP_TLS", &cfg.Mail.SMTP.TLS},
+		{"XZYQ_LOG_LEVEL", &cfg.Log.Level},
+		{"XZYQ_LOG_FORMAT", &cfg.Log.Format},
+	}
+
+	for _, v := range vars {
+		value, ok := os.LookupEnv(v.name)
+		if !ok {
+			continue
+		}
+		if err := setValue(v.target, value); err != nil {
+			return fmt.Errorf("invalid %s: %w", v.name, err)
+		}
+	}
+	return nil
+}
+
+// setValue 将字符串解析后写入目标字段
+func setValue(target interface{}, value string) error {
+	switch t := target.(type) {
+	case *string:
+		*t = value
+	case *bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		*t = b
+	case *int:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return err
+		}
+		*t = n
+	case *time.Duration:
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		*t = d
+	case *[]string:
+		*t = splitList(value)
+	default:
+		return fmt.Errorf("unsupported config type %T", target)
+	}
+	return nil
+}
+
+// newFlagSet 创建绑定到cfg的命令行参数集，参数默认值取自cfg当前的值
+func newFlagSet(cfg *Config) *flag.FlagSet {
+	fs := flag.NewFlagSet("xzyq", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: xzyq [flags] [migrate <command> | seed | rehash]\n\nflags:\n")
+		fs.PrintDefaults()
+	}
+
+	fs.String("config", "", "path to YAML config file (env XZYQ_CONFIG)")
+
+	fs.StringVar(&cfg.Server.Addr, "addr", cfg.Server.Addr, "HTTP listen address")
+	fs.StringVar(&cfg.Server.Mode, "mode", cfg.Server.Mode, "gin mode: debug, release or test")
+	fs.DurationVar(&cfg.Server.ShutdownTimeout, "shutdown-timeout", cfg.Server.ShutdownTimeout, "how long to wait for in-flight requests on shutdown")
+	fs.StringVar(&cfg.Server.TLS.CertFile, "tls-cert", cfg.Server.TLS.CertFile, "TLS certificate file, enables HTTPS")
+	fs.StringVar(&cfg.Server.TLS.KeyFile, "tls-key", cfg.Server.TLS.KeyFile, "TLS private key file")
+
+	fs.StringVar(&cfg.Database.Driver, "db-driver", cfg.Database.Driver, "database driver: postgres or sqlite")
+	fs.StringVar(&cfg.Database.Path, "db-path", cfg.Database.Path, "SQLite database file, :memory: for an in-memory database")
+	fs.StringVar(&cfg.Database.Host, "db-host", cfg.Database.Host, "database host")
+	fs.IntVar(&cfg.Database.Port, "db-port", cfg.Database.Port, "database port")
+	fs.StringVar(&cfg.Database.Name, "db-name", cfg.Database.Name, "database name")
+	fs.StringVar(&cfg.Database.User, "db-user", cfg.Database.User, "database user")
+	fs.StringVar(&cfg.Database.Password, "db-password", cfg.Database.Password, "database password")
+	fs.StringVar(&cfg.Database.SSLMode, "db-sslmode", cfg.Database.SSLMode, "database sslmode")
+	fs.BoolVar(&cfg.Database.AutoMigrate, "auto-migrate", cfg.Database.AutoMigrate, "apply pending migrations when the server starts")
+	fs.BoolVar(&cfg.Database.Seed, "seed", cfg.Database.Seed, "write seed data when the server starts")
+
+	fs.StringVar(&cfg.JWT.Secret, "jwt-secret", cfg.JWT.Secret, "HS256 signing secret, used when no signing key is configured")
+	fs.StringVar(&cfg.JWT.SigningKey, "jwt-signing-key", cfg.JWT.SigningKey, "PEM private key file for RS256 or EdDSA signing")
+	fs.Var((*listValue)(&cfg.JWT.VerificationKeys), "jwt-verification-keys", "comma-separated PEM public key files still accepted for verification")
+	fs.DurationVar(&cfg.JWT.Expire, "jwt-expire", cfg.JWT.Expire, "access token lifetime")
+	fs.DurationVar(&cfg.JWT.RefreshExpire, "jwt-refresh-expire", cfg.JWT.RefreshExpire, "refresh token lifetime")
+	fs.DurationVar(&cfg.JWT.RevocationSync, "jwt-revocation-sync", cfg.JWT.RevocationSync, "interval for loading token revocations written by other instances")
+
+	fs.IntVar(&cfg.Auth.Lockout.MaxFailures, "lockout-max-failures", cfg.Auth.Lockout.MaxFailures, "consecutive failed logins before an account is locked")
+	fs.DurationVar(&cfg.Auth.Lockout.Duration, "lockout-duration", cfg.Auth.Lockout.Duration, "how long a locked account or IP stays locked")
+
+	fs.StringVar(&cfg.Mail.Driver, "mail-driver", cfg.Mail.Driver, "mail driver: smtp, file or console")
+	fs.StringVar(&cfg.Mail.File, "mail-file", cfg.Mail.File, "file that the file mail driver appends messages to")
+
+	fs.StringVar(&cfg.Log.Level, "log-level", cfg.Log.Level, "log level: debug, info, warn or error")
+	fs.StringVar(&cfg.Log.Format, "log-format", cfg.Log.Format, "log format: json or text")
+
+	return fs
+}
+
+// listValue 逗号分隔的字符串列表参数
+type listValue []string
+
+func (l *listValue) String() string {
+	if l == nil {
+		return ""
+	}
+	return strings.Join(*l, ",")
+}
+
+func (l *listValue) Set(value string) error {
+	*l = splitList(value)
+	return nil
+}
+
+// splitList 按逗号拆分并去掉空项
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -3,43 +3,28 @@ package database
 import (
 	"fmt"
 	"log"
-	"os"
+	"log/slog"
 	"time"
+	"xzyq/config"
+	"xzyq/logging"
 
+	"github.com/glebarez/sqlite"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
-	"gorm.io/gorm/logger"
 )
 
 var DB *gorm.DB
 
 // 初始化数据库连接
-func InitDB() {
-	// 配置数据库连接参数
-	host := "47.121.141.235"
-	port := 5432
-	dbname := "postgres"
-	user := "myuser"
-	password := "mysecretpassword"
-
-	// 构建数据库连接字符串
-	dsn := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=disable",
-		host, port, user, password, dbname)
-
-	// 配置GORM日志
-	newLogger := logger.New(
-		log.New(os.Stdout, "\r\n", log.LstdFlags),
-		logger.Config{
-			SlowThreshold:             time.Second,
-			LogLevel:                  logger.Info,
-			IgnoreRecordNotFoundError: true,
-			Colorful:                  true,
-		},
-	)
+func InitDB(cfg config.DatabaseConfig) {
+	dialector, err := openDialector(cfg)
+	if err != nil {
+		log.Fatalf("Failed to configure database: %v", err)
+	}
 
-	// 打开数据库连接
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{
-		Logger: newLogger,
+	// 打开数据库连接，SQL日志输出到结构化日志
+	db, err := gorm.Open(dialector, &gorm.Config{
+		Logger: logging.NewGORMLogger(time.Second),
 	})
 
 	if err != nil {
@@ -53,17 +38,60 @@ func InitDB() {
 	}
 
 	// 设置最大连接数
-	sqlDB.SetMaxIdleConns(10)
-	sqlDB.SetMaxOpenConns(100)
-	sqlDB.SetConnMaxLifetime(time.Hour)
+	sqlDB.SetMaxIdleConns(cfg.MaxIdleConns)
+	sqlDB.SetMaxOpenConns(cfg.MaxOpenConns)
+	sqlDB.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+
+	// SQLite内存数据库的每个连接都是独立的数据库，只能使用一个连接
+	if cfg.Driver == "sqlite" && isMemoryPath(cfg.Path) {
+		sqlDB.SetMaxOpenConns(1)
+		sqlDB.SetMaxIdleConns(1)
+		sqlDB.SetConnMaxLifetime(0)
+	}
 
 	// 将连接赋值给全局变量
 	DB = db
 
-	log.Println("Database connected successfully")
+	slog.Info("database connected", "driver", cfg.Driver)
+}
+
+// openDialector 根据配置的驱动创建GORM方言
+func openDialector(cfg config.DatabaseConfig) (gorm.Dialector, error) {
+	switch cfg.Driver {
+	case "postgres":
+		dsn := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
+			cfg.Host, cfg.Port, cfg.User, cfg.Password, cfg.Name, cfg.SSLMode)
+		return postgres.Open(dsn), nil
+	case "sqlite":
+		// SQLite默认不检查外键，需要显式开启
+		path := cfg.Path
+		if isMemoryPath(path) {
+			path = ":memory:"
+		}
+		return sqlite.Open(path + "?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)"), nil
+	default:
+		return nil, fmt.Errorf("unsupported database driver %q", cfg.Driver)
+	}
+}
+
+// isMemoryPath 判断SQLite路径是否表示内存数据库
+func isMemoryPath(path string) bool {
+	return path == ":memory:" || path == "file::memory:"
 }
 
 // GetDB 返回数据库实例
 func GetDB() *gorm.DB {
 	return DB
 }
+
+// Close 关闭数据库连接池
+func Close() error {
+	if DB == nil {
+		return nil
+	}
+	sqlDB, err := DB.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.Close()
+}
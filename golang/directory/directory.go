@@ -0,0 +1,198 @@
+// Package directory 通过LDAP/Active Directory校验用户名和密码
+//
+// 先用服务账号（未配置时匿名）绑定并按过滤条件查找用户，再用查到的DN和用户提交的密码绑定来校验密码。
+// 用户所属的组可以在组的子树中按成员查询，也可以直接读取用户的memberOf属性（Active Directory）。
+package directory
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+// 过滤条件中的占位符，替换前按RFC 4515转义
+const (
+	usernamePlaceholder = "{username}"
+	dnPlaceholder       = "{dn}"
+)
+
+var (
+	// ErrInvalidCredentials 密码错误
+	ErrInvalidCredentials = errors.New("directory: invalid credentials")
+	// ErrUserNotFound 目录中没有该用户
+	ErrUserNotFound = errors.New("directory: user not found")
+)
+
+// Config 目录服务的连接和查询配置
+type Config struct {
+	URL                string // ldap://host:389 或 ldaps://host:636
+	StartTLS           bool   // 用ldap://连接后升级为TLS
+	InsecureSkipVerify bool   // 不校验服务器证书，只用于测试环境
+	BindDN             string // 查询用户的服务账号，为空时匿名查询
+	BindPassword       string
+
+	UserBaseDN        string
+	UserFilter        string // 如(uid={username})，Active Directory一般为(sAMAccountName={username})
+	UsernameAttribute string // 与登录用户名完全一致才认为是同一用户，防止大小写不同的用户名被当成不同用户
+	EmailAttribute    string
+
+	GroupBaseDN string // 为空时读取用户的memberOf属性
+	GroupFilter string // 如(member={dn})，{dn}为用户的DN，也可以使用{username}
+
+	Timeout time.Duration
+}
+
+// Entry 目录中的用户
+type Entry struct {
+	DN     string   `json:"dn"`
+	Email  string   `json:"email"`
+	Groups []string `json:"groups"` // 用户所属组的DN
+}
+
+// Authenticate 校验用户名和密码，成功时返回用户及其所属的组
+//
+// 密码错误返回ErrInvalidCredentials，目录中没有该用户返回ErrUserNotFound，其他错误表示目录服务不可用或配置有误。
+func Authenticate(ctx context.Context, cfg Config, username, password string) (*Entry, error) {
+	// 空密码会被当成匿名绑定而成功，必须在客户端拒绝
+	if password == "" {
+		return nil, ErrInvalidCredentials
+	}
+
+	conn, err := connect(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	entry, err := findUser(conn, cfg, username)
+	if err != nil {
+		return nil, err
+	}
+
+	// 用服务账号查询组，用户自己不一定有权限读取组
+	if cfg.GroupBaseDN != "" {
+		if entry.Groups, err = findGroups(conn, cfg, username, entry.DN); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := conn.Bind(entry.DN, password); err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, fmt.Errorf("bind as user: %w", err)
+	}
+	return entry, nil
+}
+
+// Check 检查能否连接目录服务并以服务账号绑定，用于保存配置前确认配置正确
+func Check(ctx context.Context, cfg Config) error {
+	conn, err := connect(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// connect 连接目录服务并以服务账号绑定
+func connect(ctx context.Context, cfg Config) (*ldap.Conn, error) {
+	u, err := url.Parse(cfg.URL)
+	if err != nil {
+		return nil, fmt.Errorf("parse directory url: %w", err)
+	}
+	tlsConfig := &tls.Config{
+		ServerName:         u.Hostname(),
+		InsecureSkipVerify: cfg.InsecureSkipVerify,
+	}
+
+	// 不能直接传入ctx，用截止时间限制连接时间
+	dialer := &net.Dialer{Timeout: cfg.Timeout}
+	if deadline, ok := ctx.Deadline(); ok {
+		dialer.Deadline = deadline
+	}
+	conn, err := ldap.DialURL(cfg.URL, ldap.DialWithDialer(dialer), ldap.DialWithTLSConfig(tlsConfig))
+	if err != nil {
+		return nil, fmt.Errorf("connect to directory: %w", err)
+	}
+	conn.SetTimeout(cfg.Timeout)
+
+	if cfg.StartTLS {
+		if err := conn.StartTLS(tlsConfig); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("start tls: %w", err)
+		}
+	}
+	if cfg.BindDN != "" {
+		if err := conn.Bind(cfg.BindDN, cfg.BindPassword); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("bind as service account: %w", err)
+		}
+	}
+	return conn, nil
+}
+
+// findUser 按过滤条件查找唯一的用户
+func findUser(conn *ldap.Conn, cfg Config, username string) (*Entry, error) {
+	attributes := []string{cfg.UsernameAttribute, cfg.EmailAttribute}
+	if cfg.GroupBaseDN == "" {
+		attributes = append(attributes, "memberOf")
+	}
+	result, err := conn.Search(ldap.NewSearchRequest(
+		cfg.UserBaseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases,
+		2, int(cfg.Timeout/time.Second), false,
+		expandFilter(cfg.UserFilter, username, ""),
+		attributes, nil,
+	))
+	if err != nil && !ldap.IsErrorWithCode(err, ldap.LDAPResultSizeLimitExceeded) {
+		return nil, fmt.Errorf("search user: %w", err)
+	}
+	if result == nil || len(result.Entries) == 0 {
+		return nil, ErrUserNotFound
+	}
+	if len(result.Entries) > 1 {
+		return nil, fmt.Errorf("search user: filter matched more than one entry")
+	}
+
+	found := result.Entries[0]
+	if found.GetEqualFoldAttributeValue(cfg.UsernameAttribute) != username {
+		return nil, ErrUserNotFound
+	}
+	entry := &Entry{DN: found.DN, Email: found.GetEqualFoldAttributeValue(cfg.EmailAttribute)}
+	if cfg.GroupBaseDN == "" {
+		entry.Groups = found.GetEqualFoldAttributeValues("memberOf")
+	}
+	return entry, nil
+}
+
+// findGroups 在组的子树中查询用户所属的组
+func findGroups(conn *ldap.Conn, cfg Config, username, dn string) ([]string, error) {
+	result, err := conn.Search(ldap.NewSearchRequest(
+		cfg.GroupBaseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases,
+		0, int(cfg.Timeout/time.Second), false,
+		expandFilter(cfg.GroupFilter, username, dn),
+		[]string{"1.1"}, nil, // 只需要DN
+	))
+	if err != nil {
+		return nil, fmt.Errorf("search groups: %w", err)
+	}
+	groups := make([]string, len(result.Entries))
+	for i, e := range result.Entries {
+		groups[i] = e.DN
+	}
+	return groups, nil
+}
+
+// expandFilter 替换过滤条件中的占位符
+func expandFilter(filter, username, dn string) string {
+	return strings.NewReplacer(
+		usernamePlaceholder, ldap.EscapeFilter(username),
+		dnPlaceholder, ldap.EscapeFilter(dn),
+	).Replace(filter)
+}
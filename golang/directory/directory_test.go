@@ -0,0 +1,299 @@
+package directory
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	ber "github.com/go-asn1-ber/asn1-ber"
+	"github.com/go-ldap/ldap/v3"
+)
+
+// LDAP协议中用到的操作和过滤条件的标签，见RFC 4511
+const (
+	opBindRequest       = 0
+	opBindResponse      = 1
+	opUnbindRequest     = 2
+	opSearchRequest     = 3
+	opSearchResultEntry = 4
+	opSearchResultDone  = 5
+
+	filterAnd      = 0
+	filterOr       = 1
+	filterNot      = 2
+	filterEquality = 3
+	filterPresent  = 7
+)
+
+// testEntry 测试目录中的条目，password为空的条目不能绑定
+type testEntry struct {
+	dn         string
+	password   string
+	attributes map[string][]string
+}
+
+// testServer 进程内的最小LDAP服务器，只支持简单绑定和按与、或、非、等值、存在条件查询
+type testServer struct {
+	listener net.Listener
+	entries  []testEntry
+
+	mu       sync.Mutex
+	searches []string // 收到的查询条件
+}
+
+func newTestServer(t *testing.T, entries []testEntry) *testServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &testServer{listener: listener, entries: entries}
+	go s.serve()
+	t.Cleanup(func() { listener.Close() })
+	return s
+}
+
+func (s *testServer) url() string {
+	return "ldap://" + s.listener.Addr().String()
+}
+
+func (s *testServer) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+// handle 处理一个连接上的请求，绑定状态不影响查询权限
+func (s *testServer) handle(conn net.Conn) {
+	defer conn.Close()
+	for {
+		packet, err := ber.ReadPacket(conn)
+		if err != nil {
+			return
+		}
+		if len(packet.Children) < 2 {
+			return
+		}
+		id := packet.Children[0].Value.(int64)
+		op := packet.Children[1]
+
+		switch op.Tag {
+		case opBindRequest:
+			code := s.bind(op.Children[1].Value.(string), op.Children[2].Data.String())
+			s.write(conn, id, result(opBindResponse, code))
+		case opSearchRequest:
+			base := strings.ToLower(op.Children[0].Value.(string))
+			filter, _ := ldap.DecompileFilter(op.Children[6])
+			s.mu.Lock()
+			s.searches = append(s.searches, filter)
+			s.mu.Unlock()
+			for _, e := range s.entries {
+				if strings.HasSuffix(strings.ToLower(e.dn), base) && matches(e, op.Children[6]) {
+					s.write(conn, id, searchEntry(e))
+				}
+			}
+			s.write(conn, id, result(opSearchResultDone, ldap.LDAPResultSuccess))
+		case opUnbindRequest:
+			return
+		default:
+			return
+		}
+	}
+}
+
+func (s *testServer) bind(dn, password string) int {
+	for _, e := range s.entries {
+		if strings.EqualFold(e.dn, dn) && e.password != "" && e.password == password {
+			return ldap.LDAPResultSuccess
+		}
+	}
+	return ldap.LDAPResultInvalidCredentials
+}
+
+func (s *testServer) write(w io.Writer, id int64, op *ber.Packet) {
+	envelope := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "")
+	envelope.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, id, ""))
+	envelope.AppendChild(op)
+	_, _ = w.Write(envelope.Bytes())
+}
+
+func result(tag ber.Tag, code int) *ber.Packet {
+	op := ber.Encode(ber.ClassApplication, ber.TypeConstructed, tag, nil, "")
+	op.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagEnumerated, code, ""))
+	op.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", ""))
+	op.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", ""))
+	return op
+}
+
+func searchEntry(e testEntry) *ber.Packet {
+	op := ber.Encode(ber.ClassApplication, ber.TypeConstructed, opSearchResultEntry, nil, "")
+	op.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, e.dn, ""))
+	attributes := ber.NewSequence("")
+	for name, values := range e.attributes {
+		attribute := ber.NewSequence("")
+		attribute.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, name, ""))
+		set := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSet, nil, "")
+		for _, v := range values {
+			set.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, v, ""))
+		}
+		attribute.AppendChild(set)
+		attributes.AppendChild(attribute)
+	}
+	op.AppendChild(attributes)
+	return op
+}
+
+// matches 判断条目是否满足查询条件，属性名和值都不区分大小写
+func matches(e testEntry, filter *ber.Packet) bool {
+	switch filter.Tag {
+	case filterAnd:
+		for _, f := range filter.Children {
+			if !matches(e, f) {
+				return false
+			}
+		}
+		return true
+	case filterOr:
+		for _, f := range filter.Children {
+			if matches(e, f) {
+				return true
+			}
+		}
+		return false
+	case filterNot:
+		return !matches(e, filter.Children[0])
+	case filterEquality:
+		name, value := filter.Children[0].Value.(string), filter.Children[1].Value.(string)
+		for _, v := range attributeValues(e, name) {
+			if strings.EqualFold(v, value) {
+				return true
+			}
+		}
+		return false
+	case filterPresent:
+		return len(attributeValues(e, filter.Data.String())) > 0
+	default:
+		return false
+	}
+}
+
+func attributeValues(e testEntry, name string) []string {
+	for n, values := range e.attributes {
+		if strings.EqualFold(n, name) {
+			return values
+		}
+	}
+	return nil
+}
+
+const (
+	serviceDN       = "cn=svc,dc=example,dc=com"
+	servicePassword = "svc-secret"
+	aliceDN         = "uid=alice,ou=people,dc=example,dc=com"
+	adminsDN        = "cn=admins,ou=groups,dc=example,dc=com"
+	staffDN         = "cn=staff,ou=groups,dc=example,dc=com"
+)
+
+func testDirectory(t *testing.T) (*testServer, Config) {
+	server := newTestServer(t, []testEntry{
+		{dn: serviceDN, password: servicePassword},
+		{dn: aliceDN, password: "alice-secret", attributes: map[string][]string{
+			"objectClass": {"inetOrgPerson"},
+			"uid":         {"alice"},
+			"mail":        {"alice@example.com"},
+			"memberOf":    {staffDN},
+		}},
+		{dn: "uid=bob,ou=people,dc=example,dc=com", password: "bob-secret", attributes: map[string][]string{
+			"objectClass": {"inetOrgPerson"},
+			"uid":         {"bob"},
+		}},
+		{dn: adminsDN, attributes: map[string][]string{"objectClass": {"groupOfNames"}, "member": {aliceDN}}},
+		{dn: staffDN, attributes: map[string][]string{"objectClass": {"groupOfNames"}, "member": {aliceDN}}},
+	})
+	return server, Config{
+		URL:               server.url(),
+		BindDN:            serviceDN,
+		BindPassword:      servicePassword,
+		UserBaseDN:        "ou=people,dc=example,dc=com",
+		UserFilter:        "(&(objectClass=inetOrgPerson)(uid={username}))",
+		UsernameAttribute: "uid",
+		EmailAttribute:    "mail",
+		GroupBaseDN:       "ou=groups,dc=example,dc=com",
+		GroupFilter:       "(&(objectClass=groupOfNames)(member={dn}))",
+		Timeout:           5 * time.Second,
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	_, cfg := testDirectory(t)
+	ctx := context.Background()
+
+	entry, err := Authenticate(ctx, cfg, "alice", "alice-secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry.DN != aliceDN || entry.Email != "alice@example.com" {
+		t.Errorf("unexpected entry %+v", entry)
+	}
+	if len(entry.Groups) != 2 || entry.Groups[0] != adminsDN || entry.Groups[1] != staffDN {
+		t.Errorf("groups = %v, want [%s %s]", entry.Groups, adminsDN, staffDN)
+	}
+
+	// 不配置组的子树时读取memberOf
+	cfg.GroupBaseDN = ""
+	entry, err = Authenticate(ctx, cfg, "alice", "alice-secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entry.Groups) != 1 || entry.Groups[0] != staffDN {
+		t.Errorf("memberOf groups = %v, want [%s]", entry.Groups, staffDN)
+	}
+}
+
+func TestAuthenticateRejects(t *testing.T) {
+	server, cfg := testDirectory(t)
+	ctx := context.Background()
+
+	cases := map[string]struct {
+		username, password string
+		want               error
+	}{
+		"wrong password":  {"alice", "wrong", ErrInvalidCredentials},
+		"empty password":  {"alice", "", ErrInvalidCredentials},
+		"unknown user":    {"carol", "secret", ErrUserNotFound},
+		"different case":  {"Alice", "alice-secret", ErrUserNotFound},
+		"filter wildcard": {"*", "alice-secret", ErrUserNotFound},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			if _, err := Authenticate(ctx, cfg, tc.username, tc.password); !errors.Is(err, tc.want) {
+				t.Errorf("err = %v, want %v", err, tc.want)
+			}
+		})
+	}
+
+	// 用户名中的特殊字符必须转义，不能改变查询条件
+	server.mu.Lock()
+	searches := strings.Join(server.searches, "\n")
+	server.mu.Unlock()
+	if !strings.Contains(searches, `(uid=\2a)`) {
+		t.Errorf("username was not escaped in filters:\n%s", searches)
+	}
+
+	// 服务账号密码错误是配置问题，不能当成用户密码错误
+	cfg.BindPassword = "wrong"
+	if _, err := Authenticate(ctx, cfg, "alice", "alice-secret"); err == nil || errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("bad service account: err = %v, want configuration error", err)
+	}
+	if err := Check(ctx, cfg); err == nil {
+		t.Error("Check accepted a bad service account password")
+	}
+}
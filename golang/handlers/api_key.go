@@ -0,0 +1,206 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+	"xzyq/apperr"
+	"xzyq/models"
+	"xzyq/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// APIKeyHandler API密钥和服务账号接口
+type APIKeyHandler struct {
+	users   *service.UserService
+	apiKeys *service.APIKeyService
+}
+
+// NewAPIKeyHandler 创建APIKeyHandler
+func NewAPIKeyHandler(users *service.UserService, apiKeys *service.APIKeyService) *APIKeyHandler {
+	return &APIKeyHandler{users: users, apiKeys: apiKeys}
+}
+
+// CreateAPIKeyRequest 创建API密钥，scopes为空时只有read权限
+type CreateAPIKeyRequest struct {
+	Name          string   `json:"name" binding:"required,max=100"`
+	Scopes        []string `json:"scopes" binding:"max=3,dive,oneof=read write admin"`
+	ExpiresInDays int      `json:"expires_in_days" binding:"min=0"` // 为0时使用默认有效期
+}
+
+// CreateServiceAccountRequest 管理员创建服务账号，服务账号没有密码
+type CreateServiceAccountRequest struct {
+	Username string `json:"username" binding:"required,max=50"`
+	Email    string `json:"email" binding:"omitempty,email,max=100"`
+	Role     string `json:"role" binding:"required,oneof=admin user"`
+	OrgID    *uint  `json:"org_id"` // 只有平台管理员可以指定，组织管理员创建的服务账号属于自己的组织
+}
+
+// ListMine 当前用户的API密钥
+func (h *APIKeyHandler) ListMine(c *gin.Context) {
+	owner, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+	h.list(c, owner)
+}
+
+// CreateMine 为当前用户创建API密钥
+func (h *APIKeyHandler) CreateMine(c *gin.Context) {
+	owner, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+	h.create(c, owner)
+}
+
+// RevokeMine 吊销当前用户的API密钥
+func (h *APIKeyHandler) RevokeMine(c *gin.Context) {
+	owner, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+	h.revoke(c, owner)
+}
+
+// ListServiceAccounts 服务账号列表（管理员）
+func (h *APIKeyHandler) ListServiceAccounts(c *gin.Context) {
+	users, err := h.users.ListServiceAccounts(c.Request.Context())
+	if err != nil {
+		apperr.Respond(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, users)
+}
+
+// CreateServiceAccount 创建服务账号（管理员）
+func (h *APIKeyHandler) CreateServiceAccount(c *gin.Context) {
+	userID, exists := currentUserID(c)
+	if !exists {
+		apperr.Respond(c, apperr.ErrUnauthorized)
+		return
+	}
+
+	var req CreateServiceAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apperr.Respond(c, apperr.ErrBadRequest.Wrap(err))
+		return
+	}
+
+	user := models.User{
+		Username:       req.Username,
+		Email:          req.Email,
+		Role:           req.Role,
+		OrgID:          req.OrgID,
+		IsActive:       true,
+		ServiceAccount: true,
+	}
+	if err := h.users.Create(c.Request.Context(), userID, &user); err != nil {
+		apperr.Respond(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, user)
+}
+
+// ListServiceAccountKeys 服务账号的API密钥（管理员）
+func (h *APIKeyHandler) ListServiceAccountKeys(c *gin.Context) {
+	owner, ok := h.serviceAccount(c)
+	if !ok {
+		return
+	}
+	h.list(c, owner)
+}
+
+// CreateServiceAccountKey 为服务账号创建API密钥（管理员）
+func (h *APIKeyHandler) CreateServiceAccountKey(c *gin.Context) {
+	owner, ok := h.serviceAccount(c)
+	if !ok {
+		return
+	}
+	h.create(c, owner)
+}
+
+// RevokeServiceAccountKey 吊销服务账号的API密钥（管理员）
+func (h *APIKeyHandler) RevokeServiceAccountKey(c *gin.Context) {
+	owner, ok := h.serviceAccount(c)
+	if !ok {
+		return
+	}
+	h.revoke(c, owner)
+}
+
+func (h *APIKeyHandler) list(c *gin.Context, owner *models.User) {
+	keys, err := h.apiKeys.List(c.Request.Context(), owner)
+	if err != nil {
+		apperr.Respond(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, keys)
+}
+
+func (h *APIKeyHandler) create(c *gin.Context, owner *models.User) {
+	userID, exists := currentUserID(c)
+	if !exists {
+		apperr.Respond(c, apperr.ErrUnauthorized)
+		return
+	}
+
+	var req CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apperr.Respond(c, apperr.ErrBadRequest.Wrap(err))
+		return
+	}
+
+	key, err := h.apiKeys.Create(c.Request.Context(), owner, userID, service.APIKeyCreate{
+		Name:      req.Name,
+		Scopes:    req.Scopes,
+		ExpiresIn: time.Duration(req.ExpiresInDays) * 24 * time.Hour,
+	}, c.ClientIP())
+	if err != nil {
+		apperr.Respond(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, key)
+}
+
+func (h *APIKeyHandler) revoke(c *gin.Context, owner *models.User) {
+	keyID, ok := parseID(c, "key_id")
+	if !ok {
+		return
+	}
+
+	if err := h.apiKeys.Revoke(c.Request.Context(), owner, keyID, c.ClientIP()); err != nil {
+		apperr.Respond(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, MessageResponse{Message: "API密钥已吊销"})
+}
+
+// currentUser 获取当前登录的用户
+func (h *APIKeyHandler) currentUser(c *gin.Context) (*models.User, bool) {
+	userID, exists := currentUserID(c)
+	if !exists {
+		apperr.Respond(c, apperr.ErrUnauthorized)
+		return nil, false
+	}
+	user, err := h.users.Profile(c.Request.Context(), userID)
+	if err != nil {
+		apperr.Respond(c, err)
+		return nil, false
+	}
+	return user, true
+}
+
+// serviceAccount 获取路径中的服务账号，不在管理员的组织内时返回404
+func (h *APIKeyHandler) serviceAccount(c *gin.Context) (*models.User, bool) {
+	id, ok := parseID(c, "id")
+	if !ok {
+		return nil, false
+	}
+	user, err := h.apiKeys.ServiceAccount(c.Request.Context(), id)
+	if err != nil {
+		apperr.Respond(c, err)
+		return nil, false
+	}
+	return user, true
+}
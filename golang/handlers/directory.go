@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"net/http"
+	"xzyq/apperr"
+	"xzyq/models"
+	"xzyq/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DirectoryHandler 组织目录服务（LDAP/Active Directory）配置接口
+type DirectoryHandler struct {
+	directories *service.DirectoryService
+}
+
+// NewDirectoryHandler 创建DirectoryHandler
+func NewDirectoryHandler(directories *service.DirectoryService) *DirectoryHandler {
+	return &DirectoryHandler{directories: directories}
+}
+
+// DirectoryRoleMappingRequest 目录中的组对应的角色
+type DirectoryRoleMappingRequest struct {
+	GroupDN string `json:"group_dn" binding:"required,max=255"`
+	Role    string `json:"role" binding:"required,oneof=admin user"`
+}
+
+// OrgDirectoryRequest 保存组织的目录服务，不提交bind_password表示不修改；查询条件留空时使用OpenLDAP的默认值
+type OrgDirectoryRequest struct {
+	Enabled            *bool                         `json:"enabled"` // 默认为true
+	URL                string                        `json:"url" binding:"required,max=255"`
+	StartTLS           bool                          `json:"start_tls"`
+	InsecureSkipVerify bool                          `json:"insecure_skip_verify"`
+	BindDN             string                        `json:"bind_dn" binding:"max=255"`
+	BindPassword       *string                       `json:"bind_password" binding:"omitempty,max=255"`
+	UserBaseDN         string                        `json:"user_base_dn" binding:"required,max=255"`
+	UserFilter         string                        `json:"user_filter" binding:"max=255"`
+	UsernameAttribute  string                        `json:"username_attribute" binding:"max=50"`
+	EmailAttribute     string                        `json:"email_attribute" binding:"max=50"`
+	GroupBaseDN        string                        `json:"group_base_dn" binding:"max=255"`
+	GroupFilter        string                        `json:"group_filter" binding:"max=255"`
+	AutoProvision      bool                          `json:"auto_provision"`
+	DefaultRole        string                        `json:"default_role" binding:"omitempty,oneof=admin user"` // 为空表示不属于任何映射的组时不允许登录
+	RoleMappings       []DirectoryRoleMappingRequest `json:"role_mappings" binding:"max=50,dive"`
+}
+
+// Get 获取组织的目录服务配置
+func (h *DirectoryHandler) Get(c *gin.Context) {
+	orgID, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+
+	dir, err := h.directories.Get(c.Request.Context(), orgID)
+	if err != nil {
+		apperr.Respond(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, dir)
+}
+
+// Save 保存组织的目录服务配置
+func (h *DirectoryHandler) Save(c *gin.Context) {
+	orgID, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+
+	var req OrgDirectoryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apperr.Respond(c, apperr.ErrBadRequest.Wrap(err))
+		return
+	}
+
+	enabled := true
+	if req.Enabled != nil {
+		enabled = *req.Enabled
+	}
+	mappings := make([]models.DirectoryRoleMapping, len(req.RoleMappings))
+	for i, m := range req.RoleMappings {
+		mappings[i] = models.DirectoryRoleMapping{GroupDN: m.GroupDN, Role: m.Role}
+	}
+
+	dir, err := h.directories.Save(c.Request.Context(), orgID, service.OrgDirectoryUpdate{
+		Enabled:            enabled,
+		URL:                req.URL,
+		StartTLS:           req.StartTLS,
+		InsecureSkipVerify: req.InsecureSkipVerify,
+		BindDN:             req.BindDN,
+		BindPassword:       req.BindPassword,
+		UserBaseDN:         req.UserBaseDN,
+		UserFilter:         req.UserFilter,
+		UsernameAttribute:  req.UsernameAttribute,
+		EmailAttribute:     req.EmailAttribute,
+		GroupBaseDN:        req.GroupBaseDN,
+		GroupFilter:        req.GroupFilter,
+		AutoProvision:      req.AutoProvision,
+		DefaultRole:        req.DefaultRole,
+		RoleMappings:       mappings,
+	})
+	if err != nil {
+		apperr.Respond(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, dir)
+}
+
+// Delete 删除组织的目录服务配置
+func (h *DirectoryHandler) Delete(c *gin.Context) {
+	orgID, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.directories.Delete(c.Request.Context(), orgID); err != nil {
+		apperr.Respond(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, MessageResponse{Message: "目录服务已删除"})
+}
@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"strconv"
+	"xzyq/apperr"
+	"xzyq/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MessageResponse 只包含提示信息的响应
+type MessageResponse struct {
+	Message string `json:"message"`
+}
+
+// parseID 解析路径中的ID参数，解析失败时直接返回400
+func parseID(c *gin.Context, name string) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param(name), 10, 64)
+	if err != nil {
+		apperr.Respond(c, apperr.ErrInvalidID.Wrap(err))
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// currentUserID 获取认证中间件写入的当前用户ID
+func currentUserID(c *gin.Context) (uint, bool) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		return 0, false
+	}
+	id, ok := userID.(uint)
+	return id, ok
+}
+
+// currentClaims 获取认证中间件写入的当前令牌
+func currentClaims(c *gin.Context) (*utils.Claims, bool) {
+	claims, exists := c.Get("claims")
+	if !exists {
+		return nil, false
+	}
+	parsed, ok := claims.(*utils.Claims)
+	return parsed, ok
+}
@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"net/http"
+	"xzyq/health"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HealthHandler 健康检查接口
+type HealthHandler struct {
+	registry *health.Registry
+}
+
+// NewHealthHandler 创建HealthHandler
+func NewHealthHandler(registry *health.Registry) *HealthHandler {
+	return &HealthHandler{registry: registry}
+}
+
+// LivenessResponse 存活检查的响应
+type LivenessResponse struct {
+	Status string           `json:"status"`
+	Build  health.BuildInfo `json:"build"`
+}
+
+// Liveness 存活检查，进程能够处理请求即返回成功，不检查外部依赖
+func (h *HealthHandler) Liveness(c *gin.Context) {
+	c.JSON(http.StatusOK, LivenessResponse{
+		Status: health.StatusUp,
+		Build:  health.Build(),
+	})
+}
+
+// Readiness 就绪检查，必需的依赖检查失败时返回503
+func (h *HealthHandler) Readiness(c *gin.Context) {
+	report := h.registry.Run(c.Request.Context())
+
+	status := http.StatusOK
+	if report.Status != health.StatusUp {
+		status = http.StatusServiceUnavailable
+	}
+	c.JSON(status, report)
+}
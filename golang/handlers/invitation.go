@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"net/http"
+	"xzyq/apperr"
+	"xzyq/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// InvitationHandler 邀请用户加入组织的接口
+type InvitationHandler struct {
+	invitations *service.InvitationService
+}
+
+// NewInvitationHandler 创建InvitationHandler
+func NewInvitationHandler(invitations *service.InvitationService) *InvitationHandler {
+	return &InvitationHandler{invitations: invitations}
+}
+
+// CreateInvitationRequest 邀请邮箱加入组织
+type CreateInvitationRequest struct {
+	Email string `json:"email" binding:"required,email,max=100"`
+	Role  string `json:"role" binding:"required,oneof=admin user"`
+	OrgID *uint  `json:"org_id"` // 只有平台管理员需要指定，组织管理员只能邀请用户加入自己的组织
+}
+
+// AcceptInvitationRequest 使用邮件中的令牌接受邀请，邮箱和角色以邀请为准
+type AcceptInvitationRequest struct {
+	Token    string `json:"token" binding:"required"`
+	Username string `json:"username" binding:"required,max=50"`
+	Password string `json:"password" binding:"required"`
+	Phone    string `json:"phone" binding:"max=20"`
+}
+
+// List 管理员组织范围内的邀请
+func (h *InvitationHandler) List(c *gin.Context) {
+	invitations, err := h.invitations.List(c.Request.Context())
+	if err != nil {
+		apperr.Respond(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, invitations)
+}
+
+// Create 发出邀请
+func (h *InvitationHandler) Create(c *gin.Context) {
+	adminID, ok := currentUserID(c)
+	if !ok {
+		apperr.Respond(c, apperr.ErrUnauthorized)
+		return
+	}
+
+	var req CreateInvitationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apperr.Respond(c, apperr.ErrBadRequest.Wrap(err))
+		return
+	}
+
+	invitation, err := h.invitations.Create(c.Request.Context(), adminID, req.OrgID, req.Email, req.Role, c.ClientIP())
+	if err != nil {
+		apperr.Respond(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, invitation)
+}
+
+// Resend 重新发送邀请邮件，之前的链接失效
+func (h *InvitationHandler) Resend(c *gin.Context) {
+	adminID, ok := currentUserID(c)
+	if !ok {
+		apperr.Respond(c, apperr.ErrUnauthorized)
+		return
+	}
+	id, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+
+	invitation, err := h.invitations.Resend(c.Request.Context(), adminID, id, c.ClientIP())
+	if err != nil {
+		apperr.Respond(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, invitation)
+}
+
+// Revoke 撤销邀请
+func (h *InvitationHandler) Revoke(c *gin.Context) {
+	adminID, ok := currentUserID(c)
+	if !ok {
+		apperr.Respond(c, apperr.ErrUnauthorized)
+		return
+	}
+	id, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.invitations.Revoke(c.Request.Context(), adminID, id, c.ClientIP()); err != nil {
+		apperr.Respond(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, MessageResponse{Message: "邀请已撤销"})
+}
+
+// Accept 接受邀请并创建用户，之后使用设置的用户名和密码登录
+func (h *InvitationHandler) Accept(c *gin.Context) {
+	var req AcceptInvitationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apperr.Respond(c, apperr.ErrBadRequest.Wrap(err))
+		return
+	}
+
+	user, err := h.invitations.Accept(c.Request.Context(), req.Token, req.Username, req.Password, req.Phone, c.ClientIP())
+	if err != nil {
+		apperr.Respond(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, RegisterResponse{
+		Message: "已加入组织，请使用设置的用户名和密码登录",
+		User:    *user,
+	})
+}
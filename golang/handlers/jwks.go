@@ -0,0 +1,15 @@
+package handlers
+
+import (
+	"net/http"
+	"xzyq/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// JWKS 公开验证访问令牌的公钥，供其他服务校验本服务签发的令牌
+func JWKS(c *gin.Context) {
+	// 公钥只在重启时变化，允许短时间缓存
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, utils.JWKS())
+}
@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"net/http"
+	"xzyq/apperr"
+	"xzyq/models"
+	"xzyq/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MFAHandler 两步验证接口
+type MFAHandler struct {
+	users *service.UserService
+	mfa   *service.MFAService
+}
+
+// NewMFAHandler 创建MFAHandler
+func NewMFAHandler(users *service.UserService, mfa *service.MFAService) *MFAHandler {
+	return &MFAHandler{users: users, mfa: mfa}
+}
+
+// MFACodeRequest 提交TOTP验证码
+type MFACodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// MFADisableRequest 关闭两步验证，提交TOTP验证码或恢复码
+type MFADisableRequest struct {
+	Code         string `json:"code" binding:"required_without=RecoveryCode"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+// RecoveryCodesResponse 新生成的恢复码，只显示一次
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// Status 当前用户的两步验证状态
+func (h *MFAHandler) Status(c *gin.Context) {
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	status, err := h.mfa.Status(c.Request.Context(), user)
+	if err != nil {
+		apperr.Respond(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, status)
+}
+
+// Enroll 生成TOTP密钥，返回密钥和otpauth URI
+func (h *MFAHandler) Enroll(c *gin.Context) {
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	enrollment, err := h.mfa.Enroll(c.Request.Context(), user)
+	if err != nil {
+		apperr.Respond(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, enrollment)
+}
+
+// Activate 验证第一个验证码后启用两步验证
+func (h *MFAHandler) Activate(c *gin.Context) {
+	var req MFACodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apperr.Respond(c, apperr.ErrBadRequest.Wrap(err))
+		return
+	}
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	codes, err := h.mfa.Activate(c.Request.Context(), user, req.Code)
+	if err != nil {
+		apperr.Respond(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// RegenerateRecoveryCodes 重新生成恢复码
+func (h *MFAHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	var req MFACodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apperr.Respond(c, apperr.ErrBadRequest.Wrap(err))
+		return
+	}
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	codes, err := h.mfa.RegenerateRecoveryCodes(c.Request.Context(), user, req.Code)
+	if err != nil {
+		apperr.Respond(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// Disable 关闭两步验证
+func (h *MFAHandler) Disable(c *gin.Context) {
+	var req MFADisableRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apperr.Respond(c, apperr.ErrBadRequest.Wrap(err))
+		return
+	}
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	if err := h.mfa.Disable(c.Request.Context(), user, req.Code, req.RecoveryCode); err != nil {
+		apperr.Respond(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, MessageResponse{Message: "两步验证已关闭"})
+}
+
+// Reset 管理员为丢失设备的用户重置两步验证
+func (h *MFAHandler) Reset(c *gin.Context) {
+	id, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+
+	user, err := h.users.Get(c.Request.Context(), id)
+	if err != nil {
+		apperr.Respond(c, err)
+		return
+	}
+	if err := h.mfa.Reset(c.Request.Context(), user); err != nil {
+		apperr.Respond(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, MessageResponse{Message: "用户的两步验证已重置"})
+}
+
+// currentUser 加载当前用户，失败时直接返回错误
+func (h *MFAHandler) currentUser(c *gin.Context) (*models.User, bool) {
+	userID, exists := currentUserID(c)
+	if !exists {
+		apperr.Respond(c, apperr.ErrUnauthorized)
+		return nil, false
+	}
+
+	user, err := h.users.Profile(c.Request.Context(), userID)
+	if err != nil {
+		apperr.Respond(c, err)
+		return nil, false
+	}
+	return user, true
+}
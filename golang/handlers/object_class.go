@@ -2,35 +2,49 @@ package handlers
 
 import (
 	"net/http"
-	"time"
-	"xzyq/database"
+	"xzyq/apperr"
 	"xzyq/models"
+	"xzyq/service"
 
 	"github.com/gin-gonic/gin"
 )
 
+// ObjectClassHandler 对象类接口
+type ObjectClassHandler struct {
+	classes *service.ObjectClassService
+}
+
+// NewObjectClassHandler 创建ObjectClassHandler
+func NewObjectClassHandler(classes *service.ObjectClassService) *ObjectClassHandler {
+	return &ObjectClassHandler{classes: classes}
+}
+
+// ObjectClassUpdateRequest 对象类更新请求
+type ObjectClassUpdateRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
 // GetObjectClasses 获取对象类列表
-func GetObjectClasses(c *gin.Context) {
-	var classes []models.ObjectClass
-	if err := database.DB.Preload("Organization").
-		Preload("CreatedByUser").
-		Find(&classes).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取对象类列表失败"})
+func (h *ObjectClassHandler) GetObjectClasses(c *gin.Context) {
+	classes, err := h.classes.List(c.Request.Context())
+	if err != nil {
+		apperr.Respond(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, classes)
 }
 
 // GetObjectClass 获取单个对象类
-func GetObjectClass(c *gin.Context) {
-	id := c.Param("id")
-	var class models.ObjectClass
+func (h *ObjectClassHandler) GetObjectClass(c *gin.Context) {
+	id, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
 
-	if err := database.DB.Preload("Organization").
-		Preload("Parent").
-		Preload("CreatedByUser").
-		First(&class, id).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "对象类不存在"})
+	class, err := h.classes.Get(c.Request.Context(), id)
+	if err != nil {
+		apperr.Respond(c, err)
 		return
 	}
 
@@ -38,15 +52,15 @@ func GetObjectClass(c *gin.Context) {
 }
 
 // GetObjectClassChildren 获取对象类的子对象类列表
-func GetObjectClassChildren(c *gin.Context) {
-	id := c.Param("id")
-	var children []models.ObjectClass
-
-	if err := database.DB.Preload("Organization").
-		Preload("CreatedByUser").
-		Where("parent_id = ?", id).
-		Find(&children).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取子对象类列表失败"})
+func (h *ObjectClassHandler) GetObjectClassChildren(c *gin.Context) {
+	id, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+
+	children, err := h.classes.ListChildren(c.Request.Context(), id)
+	if err != nil {
+		apperr.Respond(c, err)
 		return
 	}
 
@@ -54,131 +68,80 @@ func GetObjectClassChildren(c *gin.Context) {
 }
 
 // CreateObjectClass 创建对象类
-func CreateObjectClass(c *gin.Context) {
+func (h *ObjectClassHandler) CreateObjectClass(c *gin.Context) {
 	var class models.ObjectClass
 	if err := c.ShouldBindJSON(&class); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求数据"})
+		apperr.Respond(c, apperr.ErrBadRequest.Wrap(err))
 		return
 	}
 
-	// 获取当前用户ID和组织ID
-	userID, _ := c.Get("userID")
-	class.CreatedBy = userID.(uint)
-
-	// 获取当前用户信息以获取其组织ID
-	var currentUser models.User
-	if err := database.DB.First(&currentUser, userID).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取用户信息失败"})
+	userID, _ := currentUserID(c)
+	created, err := h.classes.Create(c.Request.Context(), &class, userID)
+	if err != nil {
+		apperr.Respond(c, err)
 		return
 	}
 
-	// 使用当前用户的组织ID
-	class.OrgID = *currentUser.OrgID
-	class.UpdatedAt = time.Now()
-
-	if err := database.DB.Create(&class).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "创建对象类失败"})
-		return
-	}
-
-	// 重新获取完整的对象类信息
-	database.DB.Preload("Organization").
-		Preload("CreatedByUser").
-		First(&class, class.ID)
-
-	c.JSON(http.StatusCreated, class)
+	c.JSON(http.StatusCreated, created)
 }
 
 // CreateChildObjectClass 创建子对象类
-func CreateChildObjectClass(c *gin.Context) {
-	parentID := c.Param("id")
-	var parent models.ObjectClass
-	if err := database.DB.First(&parent, parentID).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "父对象类不存在"})
+func (h *ObjectClassHandler) CreateChildObjectClass(c *gin.Context) {
+	parentID, ok := parseID(c, "id")
+	if !ok {
 		return
 	}
 
 	var class models.ObjectClass
 	if err := c.ShouldBindJSON(&class); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求数据"})
+		apperr.Respond(c, apperr.ErrBadRequest.Wrap(err))
 		return
 	}
 
-	// 获取当前用户ID
-	userID, _ := c.Get("userID")
-	class.CreatedBy = userID.(uint)
-
-	// 使用父对象类的组织ID
-	class.OrgID = parent.OrgID
-	// 设置父ID
-	parentIDUint := parent.ID
-	class.ParentID = &parentIDUint
-	class.UpdatedAt = time.Now()
-
-	if err := database.DB.Create(&class).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "创建子对象类失败"})
+	userID, _ := currentUserID(c)
+	created, err := h.classes.CreateChild(c.Request.Context(), parentID, &class, userID)
+	if err != nil {
+		apperr.Respond(c, err)
 		return
 	}
 
-	// 重新获取完整的对象类信息
-	database.DB.Preload("Organization").
-		Preload("Parent").
-		Preload("CreatedByUser").
-		First(&class, class.ID)
-
-	c.JSON(http.StatusCreated, class)
+	c.JSON(http.StatusCreated, created)
 }
 
 // UpdateObjectClass 更新对象类
-func UpdateObjectClass(c *gin.Context) {
-	id := c.Param("id")
-	var class models.ObjectClass
-
-	// 检查对象类是否存在
-	if err := database.DB.First(&class, id).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "对象类不存在"})
+func (h *ObjectClassHandler) UpdateObjectClass(c *gin.Context) {
+	id, ok := parseID(c, "id")
+	if !ok {
 		return
 	}
 
 	// 绑定更新数据
-	var updateData struct {
-		Name        string `json:"name"`
-		Description string `json:"description"`
-	}
+	var updateData ObjectClassUpdateRequest
 	if err := c.ShouldBindJSON(&updateData); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的更新数据"})
+		apperr.Respond(c, apperr.ErrBadRequest.Wrap(err))
 		return
 	}
 
-	// 更新对象类（只更新允许的字段）
-	updates := map[string]interface{}{
-		"name":        updateData.Name,
-		"description": updateData.Description,
-		"updated_at":  time.Now(),
-	}
-
-	if err := database.DB.Model(&class).Updates(updates).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新对象类失败"})
+	class, err := h.classes.Update(c.Request.Context(), id, updateData.Name, updateData.Description)
+	if err != nil {
+		apperr.Respond(c, err)
 		return
 	}
 
-	// 重新获取完整的对象类信息
-	database.DB.Preload("Organization").
-		Preload("CreatedByUser").
-		First(&class, id)
-
 	c.JSON(http.StatusOK, class)
 }
 
 // DeleteObjectClass 删除对象类
-func DeleteObjectClass(c *gin.Context) {
-	id := c.Param("id")
+func (h *ObjectClassHandler) DeleteObjectClass(c *gin.Context) {
+	id, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
 
-	// 执行删除
-	if err := database.DB.Delete(&models.ObjectClass{}, id).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除对象类失败"})
+	if err := h.classes.Delete(c.Request.Context(), id); err != nil {
+		apperr.Respond(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "对象类删除成功"})
+	c.JSON(http.StatusOK, MessageResponse{Message: "对象类删除成功"})
 }
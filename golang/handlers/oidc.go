@@ -0,0 +1,177 @@
+package handlers
+
+import (
+	"net/http"
+	"xzyq/apperr"
+	"xzyq/metrics"
+	"xzyq/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// OIDCHandler OpenID Connect单点登录接口
+type OIDCHandler struct {
+	oidc *service.OIDCService
+}
+
+// NewOIDCHandler 创建OIDCHandler
+func NewOIDCHandler(oidc *service.OIDCService) *OIDCHandler {
+	return &OIDCHandler{oidc: oidc}
+}
+
+// OIDCAuthorizeResponse 跳转到身份提供方的地址
+type OIDCAuthorizeResponse struct {
+	AuthorizationURL string `json:"authorization_url"`
+}
+
+// OIDCCallbackRequest 身份提供方跳转回前端回调页面时带回的参数
+type OIDCCallbackRequest struct {
+	State string `json:"state" binding:"required"`
+	Code  string `json:"code" binding:"required"`
+}
+
+// OIDCProviderRequest 创建或修改身份提供方，修改时不提交client_secret表示不修改
+type OIDCProviderRequest struct {
+	Name          string  `json:"name" binding:"required,max=50"`
+	DisplayName   string  `json:"display_name" binding:"required,max=100"`
+	Issuer        string  `json:"issuer" binding:"required,url,max=255"`
+	ClientID      string  `json:"client_id" binding:"required,max=255"`
+	ClientSecret  *string `json:"client_secret" binding:"omitempty,max=255"`
+	Scopes        string  `json:"scopes" binding:"max=255"`
+	Enabled       *bool   `json:"enabled"` // 默认为true
+	LinkByEmail   bool    `json:"link_by_email"`
+	AutoProvision bool    `json:"auto_provision"`
+	DefaultRole   string  `json:"default_role" binding:"omitempty,oneof=admin user"`
+}
+
+// update 转换为service层的修改内容
+func (r *OIDCProviderRequest) update() service.OIDCProviderUpdate {
+	enabled := true
+	if r.Enabled != nil {
+		enabled = *r.Enabled
+	}
+	return service.OIDCProviderUpdate{
+		Name:          r.Name,
+		DisplayName:   r.DisplayName,
+		Issuer:        r.Issuer,
+		ClientID:      r.ClientID,
+		ClientSecret:  r.ClientSecret,
+		Scopes:        r.Scopes,
+		Enabled:       enabled,
+		LinkByEmail:   r.LinkByEmail,
+		AutoProvision: r.AutoProvision,
+		DefaultRole:   r.DefaultRole,
+	}
+}
+
+// Providers 登录页面可用的身份提供方
+func (h *OIDCHandler) Providers(c *gin.Context) {
+	providers, err := h.oidc.Providers(c.Request.Context())
+	if err != nil {
+		apperr.Respond(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, providers)
+}
+
+// Authorize 开始单点登录，返回跳转地址
+func (h *OIDCHandler) Authorize(c *gin.Context) {
+	authURL, err := h.oidc.Authorize(c.Request.Context(), c.Param("name"), c.ClientIP())
+	if err != nil {
+		apperr.Respond(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, OIDCAuthorizeResponse{AuthorizationURL: authURL})
+}
+
+// Callback 用身份提供方返回的授权码完成登录，响应与密码登录相同
+func (h *OIDCHandler) Callback(c *gin.Context) {
+	var req OIDCCallbackRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		metrics.RecordLogin(metrics.LoginFailure, "bad_request")
+		apperr.Respond(c, apperr.ErrBadRequest.Wrap(err))
+		return
+	}
+
+	result, err := h.oidc.Callback(c.Request.Context(), req.State, req.Code, c.ClientIP(), c.Request.UserAgent())
+	respondLogin(c, result, err)
+}
+
+// ListProviders 获取组织的身份提供方
+func (h *OIDCHandler) ListProviders(c *gin.Context) {
+	orgID, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+
+	providers, err := h.oidc.ListProviders(c.Request.Context(), orgID)
+	if err != nil {
+		apperr.Respond(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, providers)
+}
+
+// CreateProvider 为组织添加身份提供方
+func (h *OIDCHandler) CreateProvider(c *gin.Context) {
+	orgID, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+
+	var req OIDCProviderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apperr.Respond(c, apperr.ErrBadRequest.Wrap(err))
+		return
+	}
+
+	provider, err := h.oidc.CreateProvider(c.Request.Context(), orgID, req.update())
+	if err != nil {
+		apperr.Respond(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, provider)
+}
+
+// UpdateProvider 修改组织的身份提供方
+func (h *OIDCHandler) UpdateProvider(c *gin.Context) {
+	orgID, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+	id, ok := parseID(c, "provider_id")
+	if !ok {
+		return
+	}
+
+	var req OIDCProviderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apperr.Respond(c, apperr.ErrBadRequest.Wrap(err))
+		return
+	}
+
+	provider, err := h.oidc.UpdateProvider(c.Request.Context(), orgID, id, req.update())
+	if err != nil {
+		apperr.Respond(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, provider)
+}
+
+// DeleteProvider 删除组织的身份提供方
+func (h *OIDCHandler) DeleteProvider(c *gin.Context) {
+	orgID, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+	id, ok := parseID(c, "provider_id")
+	if !ok {
+		return
+	}
+
+	if err := h.oidc.DeleteProvider(c.Request.Context(), orgID, id); err != nil {
+		apperr.Respond(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, MessageResponse{Message: "身份提供方已删除"})
+}
@@ -3,275 +3,275 @@ package handlers
 import (
 	"fmt"
 	"net/http"
-	"xzyq/database"
+	"xzyq/apperr"
 	"xzyq/models"
-	"xzyq/utils"
+	"xzyq/service"
 
 	"github.com/gin-gonic/gin"
 )
 
-// GetOrganizations 获取所有组织
-func GetOrganizations(c *gin.Context) {
-	type OrgWithDetails struct {
-		models.Organization
-		UserCount int64                `json:"user_count"`
-		ParentOrg *models.Organization `json:"parent_org,omitempty"`
-	}
+// OrganizationHandler 组织接口
+type OrganizationHandler struct {
+	orgs *service.OrganizationService
+}
+
+// NewOrganizationHandler 创建OrganizationHandler
+func NewOrganizationHandler(orgs *service.OrganizationService) *OrganizationHandler {
+	return &OrganizationHandler{orgs: orgs}
+}
+
+// CreateOrganizationResponse 创建组织的响应，包含自动创建的管理员账号
+type CreateOrganizationResponse struct {
+	Organization models.Organization        `json:"organization"`
+	AdminUser    *service.OrganizationAdmin `json:"admin_user"`
+}
+
+// CreateOrganizationRequest 创建组织，新组织不开放自助注册，注册设置和两步验证要求使用管理员接口修改
+type CreateOrganizationRequest struct {
+	Name        string `json:"name" binding:"required,max=100"`
+	Description string `json:"description"`
+}
+
+// UpdateOrganizationRequest 更新组织，未提交的字段保持不变；只有平台管理员可以修改parent_id
+type UpdateOrganizationRequest struct {
+	Name        string `json:"name" binding:"required,max=100"`
+	Description string `json:"description"`
+	ParentID    *uint  `json:"parent_id"`
+}
+
+// OrgRegistrationRequest 组织的自助注册设置
+type OrgRegistrationRequest struct {
+	SelfRegistration     bool   `json:"self_registration"`
+	SelfRegistrationRole string `json:"self_registration_role" binding:"required,oneof=admin user"`
+}
+
+// OrgMFAPolicyRequest 组织的两步验证要求
+type OrgMFAPolicyRequest struct {
+	RequireAdminMFA bool `json:"require_admin_mfa"`
+}
 
+// GetOrganizations 获取当前用户创建的组织
+func (h *OrganizationHandler) GetOrganizations(c *gin.Context) {
 	// 从上下文中获取当前用户ID
-	userID, exists := c.Get("userID")
+	userID, exists := currentUserID(c)
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权的操作"})
+		apperr.Respond(c, apperr.ErrUnauthorized)
 		return
 	}
 
-	var orgsWithDetails []OrgWithDetails
-	db := database.GetDB()
-
-	// 查询当前用户创建的组织
-	var organizations []models.Organization
-	if err := db.Where("created_by = ?", userID).Find(&organizations).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取组织列表失败"})
+	orgs, err := h.orgs.ListCreatedBy(c.Request.Context(), userID)
+	if err != nil {
+		apperr.Respond(c, err)
 		return
 	}
 
-	// 为每个组织获取详细信息
-	for _, org := range organizations {
-		var count int64
-		if err := db.Unscoped().Model(&models.User{}).Where("org_id = ?", org.ID).Count(&count).Error; err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "统计用户数量失败"})
-			return
-		}
-
-		details := OrgWithDetails{
-			Organization: org,
-			UserCount:    count,
-		}
-
-		// 如果有父组织ID，查询父组织信息
-		if org.ParentID != nil {
-			var parentOrg models.Organization
-			if err := db.First(&parentOrg, *org.ParentID).Error; err == nil {
-				details.ParentOrg = &parentOrg
-			}
-		}
-
-		orgsWithDetails = append(orgsWithDetails, details)
-	}
-
-	c.JSON(http.StatusOK, orgsWithDetails)
+	c.JSON(http.StatusOK, orgs)
 }
 
 // GetOrganization 获取单个组织
-func GetOrganization(c *gin.Context) {
-	id := c.Param("id")
-	var organization models.Organization
-	result := database.DB.First(&organization, id)
-	if result.Error != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "组织不存在"})
+func (h *OrganizationHandler) GetOrganization(c *gin.Context) {
+	id, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+
+	organization, err := h.orgs.Get(c.Request.Context(), id)
+	if err != nil {
+		apperr.Respond(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, organization)
 }
 
 // CreateOrganization 创建组织
-func CreateOrganization(c *gin.Context) {
+func (h *OrganizationHandler) CreateOrganization(c *gin.Context) {
 	// 从上下文中获取用户ID
-	userID, exists := c.Get("userID")
+	userID, exists := currentUserID(c)
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权的操作"})
+		apperr.Respond(c, apperr.ErrUnauthorized)
 		return
 	}
 
-	var organization models.Organization
-	if err := c.ShouldBindJSON(&organization); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求数据"})
+	var req CreateOrganizationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apperr.Respond(c, apperr.ErrBadRequest.Wrap(err))
 		return
 	}
+	organization := models.Organization{
+		Name:        req.Name,
+		Description: req.Description,
+	}
 
-	// 设置创建者ID
-	organization.CreatedBy = userID.(uint)
-	// 设置父组织ID为null，因为这是一个新的顶级组织
-	organization.ParentID = nil
+	admin, err := h.orgs.Create(c.Request.Context(), &organization, userID)
+	if err != nil {
+		apperr.Respond(c, err)
+		return
+	}
 
-	// 开启数据库事务
-	tx := database.DB.Begin()
+	// 返回组织信息和管理员账号信息
+	c.JSON(http.StatusCreated, CreateOrganizationResponse{
+		Organization: organization,
+		AdminUser:    admin,
+	})
+}
 
-	// 创建组织
-	result := tx.Create(&organization)
-	if result.Error != nil {
-		tx.Rollback()
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "创建组织失败"})
+// UpdateOrganization 更新组织
+func (h *OrganizationHandler) UpdateOrganization(c *gin.Context) {
+	userID, exists := currentUserID(c)
+	if !exists {
+		apperr.Respond(c, apperr.ErrUnauthorized)
+		return
+	}
+	id, ok := parseID(c, "id")
+	if !ok {
 		return
 	}
 
-	// 创建管理员用户
-	adminUser := models.User{
-		Username:  "admin_" + organization.Name, // 使用组织名称创建唯一的管理员用户名
-		Password:  "123456",                     // 默认密码，建议后续要求用户修改
-		Email:     "",
-		Phone:     "",
-		IsActive:  true,
-		Role:      "admin",
-		OrgID:     &organization.ID, // 设置组织ID
-		CreatedBy: userID.(uint),    // 设置创建者ID为当前用户
+	organization, err := h.orgs.Get(c.Request.Context(), id)
+	if err != nil {
+		apperr.Respond(c, err)
+		return
 	}
 
-	// 检查用户名是否已存在
-	var existingUser models.User
-	if err := tx.Where("username = ?", adminUser.Username).First(&existingUser).Error; err == nil {
-		tx.Rollback()
-		c.JSON(http.StatusConflict, gin.H{"error": "管理员用户名已存在"})
+	// 未提交的字段保持原值
+	req := UpdateOrganizationRequest{
+		Name:        organization.Name,
+		Description: organization.Description,
+		ParentID:    organization.ParentID,
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apperr.Respond(c, apperr.ErrBadRequest.Wrap(err))
 		return
 	}
 
-	// 对管理员密码进行加密
-	hashedPassword, err := utils.HashPassword(adminUser.Password)
+	organization, err = h.orgs.Update(c.Request.Context(), userID, id, service.OrganizationUpdate{
+		Name:        req.Name,
+		Description: req.Description,
+		ParentID:    req.ParentID,
+	})
 	if err != nil {
-		tx.Rollback()
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash password"})
+		apperr.Respond(c, err)
 		return
 	}
-	adminUser.Password = hashedPassword
 
-	// 创建管理员用户
-	if err := tx.Create(&adminUser).Error; err != nil {
-		tx.Rollback()
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "创建管理员用户失败"})
+	c.JSON(http.StatusOK, organization)
+}
+
+// DeleteOrganization 删除组织
+func (h *OrganizationHandler) DeleteOrganization(c *gin.Context) {
+	id, ok := parseID(c, "id")
+	if !ok {
 		return
 	}
 
-	// 提交事务
-	if err := tx.Commit().Error; err != nil {
-		tx.Rollback()
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "提交事务失败"})
+	organization, err := h.orgs.Delete(c.Request.Context(), id)
+	if err != nil {
+		apperr.Respond(c, err)
 		return
 	}
 
-	// 返回组织信息和管理员账号信息
-	c.JSON(http.StatusCreated, gin.H{
-		"organization": organization,
-		"admin_user": gin.H{
-			"username": adminUser.Username,
-			"password": "123456", // 返回默认密码给前端显示
-		},
+	c.JSON(http.StatusOK, MessageResponse{
+		Message: fmt.Sprintf("组织[%s]及其相关数据已成功删除", organization.Name),
 	})
 }
 
-// UpdateOrganization 更新组织
-func UpdateOrganization(c *gin.Context) {
-	id := c.Param("id")
-	var organization models.Organization
-	if err := database.DB.First(&organization, id).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "组织不存在"})
+// GetAllOrganizations 获取所有组织（用于父级租户选择）
+func (h *OrganizationHandler) GetAllOrganizations(c *gin.Context) {
+	organizations, err := h.orgs.List(c.Request.Context())
+	if err != nil {
+		apperr.Respond(c, err)
 		return
 	}
+	c.JSON(http.StatusOK, organizations)
+}
 
-	if err := c.ShouldBindJSON(&organization); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求数据"})
+// GetOrganizationUsers 获取组织下的用户列表
+func (h *OrganizationHandler) GetOrganizationUsers(c *gin.Context) {
+	id, ok := parseID(c, "id")
+	if !ok {
 		return
 	}
 
-	result := database.DB.Save(&organization)
-	if result.Error != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新组织失败"})
+	users, err := h.orgs.ListUsers(c.Request.Context(), id)
+	if err != nil {
+		apperr.Respond(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, organization)
+	c.JSON(http.StatusOK, users)
 }
 
-// DeleteOrganization 删除组织
-func DeleteOrganization(c *gin.Context) {
-	id := c.Param("id")
-
-	// 开启事务
-	tx := database.DB.Begin()
-
-	// 查找组织
-	var organization models.Organization
-	if err := tx.First(&organization, id).Error; err != nil {
-		tx.Rollback()
-		c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("组织(ID:%s)不存在", id)})
+// GetRegistration 获取组织的自助注册设置
+func (h *OrganizationHandler) GetRegistration(c *gin.Context) {
+	id, ok := parseID(c, "id")
+	if !ok {
 		return
 	}
 
-	// 查找该组织下的用户数量
-	var userCount int64
-	if err := tx.Model(&models.User{}).Where("org_id = ?", organization.ID).Count(&userCount).Error; err != nil {
-		tx.Rollback()
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("查询组织(ID:%d)的用户数量失败: %v", organization.ID, err)})
+	registration, err := h.orgs.Registration(c.Request.Context(), id)
+	if err != nil {
+		apperr.Respond(c, err)
 		return
 	}
+	c.JSON(http.StatusOK, registration)
+}
 
-	// 如果组织下还有用户，则不允许删除
-	if userCount > 0 {
-		tx.Rollback()
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": fmt.Sprintf("无法删除组织[%s]，该组织下还有 %d 个用户", organization.Name, userCount),
-		})
+// UpdateRegistration 设置组织是否开放自助注册及自助注册的用户的角色
+func (h *OrganizationHandler) UpdateRegistration(c *gin.Context) {
+	id, ok := parseID(c, "id")
+	if !ok {
 		return
 	}
 
-	// 删除组织相关的所有数据
-	// 1. 删除组织下的用户（虽然已经确认数量为0，但为了保险起见）
-	if err := tx.Where("org_id = ?", organization.ID).Delete(&models.User{}).Error; err != nil {
-		tx.Rollback()
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("删除组织[%s]的用户数据失败: %v", organization.Name, err)})
+	var req OrgRegistrationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apperr.Respond(c, apperr.ErrBadRequest.Wrap(err))
 		return
 	}
 
-	// 2. 删除组织本身
-	if err := tx.Delete(&organization).Error; err != nil {
-		tx.Rollback()
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("删除组织[%s]失败: %v", organization.Name, err)})
+	registration, err := h.orgs.SaveRegistration(c.Request.Context(), id, models.OrgRegistration{
+		SelfRegistration:     req.SelfRegistration,
+		SelfRegistrationRole: req.SelfRegistrationRole,
+	})
+	if err != nil {
+		apperr.Respond(c, err)
 		return
 	}
+	c.JSON(http.StatusOK, registration)
+}
 
-	// 提交事务
-	if err := tx.Commit().Error; err != nil {
-		tx.Rollback()
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("删除组织[%s]时提交事务失败: %v", organization.Name, err)})
+// GetMFAPolicy 获取组织的两步验证要求
+func (h *OrganizationHandler) GetMFAPolicy(c *gin.Context) {
+	id, ok := parseID(c, "id")
+	if !ok {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"message": fmt.Sprintf("组织[%s]及其相关数据已成功删除", organization.Name),
-	})
-}
-
-// GetAllOrganizations 获取所有组织（用于父级租户选择）
-func GetAllOrganizations(c *gin.Context) {
-	var organizations []models.Organization
-	if err := database.DB.Find(&organizations).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取组织列表失败"})
+	policy, err := h.orgs.MFAPolicy(c.Request.Context(), id)
+	if err != nil {
+		apperr.Respond(c, err)
 		return
 	}
-	c.JSON(http.StatusOK, organizations)
+	c.JSON(http.StatusOK, policy)
 }
 
-// GetOrganizationUsers 获取组织下的用户列表
-func GetOrganizationUsers(c *gin.Context) {
-	id := c.Param("id")
-
-	// 验证组织是否存在
-	var organization models.Organization
-	if err := database.DB.First(&organization, id).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "组织不存在"})
+// UpdateMFAPolicy 设置组织管理员是否必须启用两步验证
+func (h *OrganizationHandler) UpdateMFAPolicy(c *gin.Context) {
+	id, ok := parseID(c, "id")
+	if !ok {
 		return
 	}
 
-	// 获取组织下的用户列表，包括软删除的用户
-	var users []models.User
-	if err := database.DB.Unscoped().Where("org_id = ?", id).Find(&users).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取用户列表失败"})
+	var req OrgMFAPolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apperr.Respond(c, apperr.ErrBadRequest.Wrap(err))
 		return
 	}
 
-	// 如果没有找到用户，返回空数组而不是 null
-	if users == nil {
-		users = make([]models.User, 0)
+	policy, err := h.orgs.SaveMFAPolicy(c.Request.Context(), id, service.OrgMFAPolicy{RequireAdminMFA: req.RequireAdminMFA})
+	if err != nil {
+		apperr.Respond(c, err)
+		return
 	}
-
-	c.JSON(http.StatusOK, users)
+	c.JSON(http.StatusOK, policy)
 }
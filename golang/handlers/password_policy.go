@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"net/http"
+	"xzyq/apperr"
+	"xzyq/models"
+	"xzyq/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PasswordPolicyHandler 组织密码策略接口
+type PasswordPolicyHandler struct {
+	passwords *service.PasswordService
+}
+
+// NewPasswordPolicyHandler 创建PasswordPolicyHandler
+func NewPasswordPolicyHandler(passwords *service.PasswordService) *PasswordPolicyHandler {
+	return &PasswordPolicyHandler{passwords: passwords}
+}
+
+// OrgPasswordPolicyRequest 组织密码策略，只能比全局策略更严格，零值表示沿用全局策略
+type OrgPasswordPolicyRequest struct {
+	MinLength        int  `json:"min_length" binding:"min=0,max=72"`
+	RequireUppercase bool `json:"require_uppercase"`
+	RequireLowercase bool `json:"require_lowercase"`
+	RequireDigit     bool `json:"require_digit"`
+	RequireSymbol    bool `json:"require_symbol"`
+	History          int  `json:"history" binding:"min=0,max=24"`
+	MaxAgeDays       int  `json:"max_age_days" binding:"min=0,max=3650"`
+}
+
+// Get 获取组织的密码策略及实际生效的策略
+func (h *PasswordPolicyHandler) Get(c *gin.Context) {
+	id, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+
+	detail, err := h.passwords.OrgPolicy(c.Request.Context(), id)
+	if err != nil {
+		apperr.Respond(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, detail)
+}
+
+// Update 设置组织的密码策略
+func (h *PasswordPolicyHandler) Update(c *gin.Context) {
+	id, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+
+	var req OrgPasswordPolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apperr.Respond(c, apperr.ErrBadRequest.Wrap(err))
+		return
+	}
+
+	detail, err := h.passwords.SaveOrgPolicy(c.Request.Context(), id, models.OrgPasswordPolicy{
+		MinLength:        req.MinLength,
+		RequireUppercase: req.RequireUppercase,
+		RequireLowercase: req.RequireLowercase,
+		RequireDigit:     req.RequireDigit,
+		RequireSymbol:    req.RequireSymbol,
+		History:          req.History,
+		MaxAgeDays:       req.MaxAgeDays,
+	})
+	if err != nil {
+		apperr.Respond(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, detail)
+}
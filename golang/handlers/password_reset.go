@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"net/http"
+	"xzyq/apperr"
+	"xzyq/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PasswordResetHandler 忘记密码接口
+type PasswordResetHandler struct {
+	resets *service.PasswordResetService
+}
+
+// NewPasswordResetHandler 创建PasswordResetHandler
+func NewPasswordResetHandler(resets *service.PasswordResetService) *PasswordResetHandler {
+	return &PasswordResetHandler{resets: resets}
+}
+
+// ForgotPasswordRequest 申请重置密码
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email,max=100"`
+}
+
+// ResetPasswordRequest 使用邮件中的令牌设置新密码
+type ResetPasswordRequest struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required"`
+}
+
+// Forgot 发送重置密码邮件，邮箱是否存在都返回相同的响应
+func (h *PasswordResetHandler) Forgot(c *gin.Context) {
+	var req ForgotPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apperr.Respond(c, apperr.ErrBadRequest.Wrap(err))
+		return
+	}
+
+	if err := h.resets.Forgot(c.Request.Context(), req.Email, c.ClientIP()); err != nil {
+		apperr.Respond(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, MessageResponse{Message: "如果该邮箱已注册，我们已向其发送重置密码的邮件"})
+}
+
+// Reset 设置新密码，成功后所有设备需要重新登录
+func (h *PasswordResetHandler) Reset(c *gin.Context) {
+	var req ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apperr.Respond(c, apperr.ErrBadRequest.Wrap(err))
+		return
+	}
+
+	if err := h.resets.Reset(c.Request.Context(), req.Token, req.NewPassword, c.ClientIP()); err != nil {
+		apperr.Respond(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, MessageResponse{Message: "密码已重置，请使用新密码登录"})
+}
@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"net/http"
+	"xzyq/apperr"
+	"xzyq/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SessionHandler 登录会话接口
+type SessionHandler struct {
+	sessions *service.SessionService
+}
+
+// NewSessionHandler 创建SessionHandler
+func NewSessionHandler(sessions *service.SessionService) *SessionHandler {
+	return &SessionHandler{sessions: sessions}
+}
+
+// List 当前用户的有效会话
+func (h *SessionHandler) List(c *gin.Context) {
+	claims, exists := currentClaims(c)
+	if !exists {
+		apperr.Respond(c, apperr.ErrUnauthorized)
+		return
+	}
+
+	sessions, err := h.sessions.List(c.Request.Context(), claims.UserID, claims)
+	if err != nil {
+		apperr.Respond(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, sessions)
+}
+
+// Revoke 吊销当前用户的会话，该会话的设备需要重新登录
+func (h *SessionHandler) Revoke(c *gin.Context) {
+	userID, exists := currentUserID(c)
+	if !exists {
+		apperr.Respond(c, apperr.ErrUnauthorized)
+		return
+	}
+	id, ok := parseID(c, "session_id")
+	if !ok {
+		return
+	}
+
+	if err := h.sessions.Revoke(c.Request.Context(), userID, id, c.ClientIP()); err != nil {
+		apperr.Respond(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, MessageResponse{Message: "会话已退出登录"})
+}
+
+// UserSessions 用户的有效会话（管理员）
+func (h *SessionHandler) UserSessions(c *gin.Context) {
+	userID, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+	claims, _ := currentClaims(c)
+
+	sessions, err := h.sessions.UserSessions(c.Request.Context(), userID, claims)
+	if err != nil {
+		apperr.Respond(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, sessions)
+}
+
+// RevokeUserSession 吊销用户的会话（管理员）
+func (h *SessionHandler) RevokeUserSession(c *gin.Context) {
+	userID, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+	id, ok := parseID(c, "session_id")
+	if !ok {
+		return
+	}
+
+	if err := h.sessions.RevokeUserSession(c.Request.Context(), userID, id, c.ClientIP()); err != nil {
+		apperr.Respond(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, MessageResponse{Message: "会话已退出登录"})
+}
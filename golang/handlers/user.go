@@ -1,185 +1,331 @@
 package handlers
 
 import (
-	"fmt"
+	"errors"
+	"io"
 	"net/http"
+	"strings"
 	"time"
-	"xzyq/database"
+	"xzyq/apperr"
+	"xzyq/metrics"
 	"xzyq/models"
-	"xzyq/utils"
+	"xzyq/service"
 
 	"github.com/gin-gonic/gin"
 )
 
-// RegisterUser 注册新用户
-func RegisterUser(c *gin.Context) {
-	var user models.User
+// UserHandler 用户接口
+type UserHandler struct {
+	users *service.UserService
+}
 
-	// 绑定JSON数据
-	if err := c.ShouldBindJSON(&user); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
-		return
-	}
+// NewUserHandler 创建UserHandler
+func NewUserHandler(users *service.UserService) *UserHandler {
+	return &UserHandler{users: users}
+}
 
-	// 检查用户名是否已存在
-	var existingUser models.User
-	result := database.DB.Where("username = ?", user.Username).First(&existingUser)
-	if result.RowsAffected > 0 {
-		c.JSON(http.StatusConflict, gin.H{"error": "Username already exists"})
-		return
-	}
+// RegisterRequest 自助注册请求，组织需要开放自助注册，角色由组织设置；密码需要符合密码策略
+type RegisterRequest struct {
+	Username string `json:"username" binding:"required,max=50"`
+	Password string `json:"password" binding:"required"`
+	Email    string `json:"email" binding:"omitempty,email,max=100"`
+	Phone    string `json:"phone" binding:"max=20"`
+	OrgID    uint   `json:"org_id" binding:"required"`
+}
 
-	// 如果指定了组织ID，检查组织是否存在
-	if user.OrgID != nil {
-		var org models.Organization
-		if err := database.DB.First(&org, *user.OrgID).Error; err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Organization not found"})
-			return
-		}
-	}
+// CreateUserRequest 管理员创建用户，用户首次登录时必须修改密码
+type CreateUserRequest struct {
+	Username string `json:"username" binding:"required,max=50"`
+	Password string `json:"password" binding:"required"`
+	Email    string `json:"email" binding:"omitempty,email,max=100"`
+	Phone    string `json:"phone" binding:"max=20"`
+	Role     string `json:"role" binding:"required,oneof=admin user"`
+	IsActive *bool  `json:"is_active"` // 默认为true
+	OrgID    *uint  `json:"org_id"`    // 只有平台管理员可以指定，组织管理员创建的用户属于自己的组织
+}
 
-	// 对密码进行加密
-	hashedPassword, err := utils.HashPassword(user.Password)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash password"})
+// RegisterResponse 注册成功的响应
+type RegisterResponse struct {
+	Message string      `json:"message"`
+	User    models.User `json:"user"`
+}
+
+// LoginRequest 登录请求，目录服务中的用户首次登录时需要指定组织
+type LoginRequest struct {
+	Username string `json:"username" binding:"required,max=50"`
+	Password string `json:"password" binding:"required"`
+	OrgID    *uint  `json:"org_id"` // 本地已有的用户不需要
+}
+
+// LoginResponse 登录的响应，需要两步验证时只包含mfa，客户端凭mfa_token调用 /api/login/mfa 完成登录
+type LoginResponse struct {
+	*service.TokenPair
+	User          *models.User          `json:"user,omitempty"`
+	MFA           *service.MFAChallenge `json:"mfa,omitempty"`
+	RecoveryCodes []string              `json:"recovery_codes,omitempty"` // 登录时完成两步验证设置后返回，只显示一次
+}
+
+// LoginMFARequest 登录第二步，提交TOTP验证码或恢复码
+type LoginMFARequest struct {
+	MFAToken     string `json:"mfa_token" binding:"required"`
+	Code         string `json:"code" binding:"required_without=RecoveryCode"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+// LoginMFAEnrollRequest 登录过程中设置两步验证
+type LoginMFAEnrollRequest struct {
+	MFAToken string `json:"mfa_token" binding:"required"`
+}
+
+// RefreshRequest 刷新令牌请求
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// LogoutRequest 退出请求，携带刷新令牌时一并吊销
+type LogoutRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// UpdateUserRequest 管理员更新用户，空字段表示不修改；启用和禁用、密码和两步验证使用单独的接口
+type UpdateUserRequest struct {
+	Username   string `json:"username" binding:"max=50"`
+	Email      string `json:"email" binding:"omitempty,email,max=100"`
+	Phone      string `json:"phone" binding:"max=20"`
+	Role       string `json:"role" binding:"omitempty,oneof=admin user"`
+	OrgID      *uint  `json:"org_id"`      // 只有平台管理员可以把用户移到其他组织
+	AuthSource string `json:"auth_source"` // local或ldap
+}
+
+// ProfileUpdateRequest 个人资料更新请求，空字段表示不修改；修改密码或邮箱时需要提供当前密码
+type ProfileUpdateRequest struct {
+	Username    string `json:"username"`
+	Password    string `json:"password"`
+	Email       string `json:"email" binding:"omitempty,email,max=100"`
+	Phone       string `json:"phone"`
+	OldPassword string `json:"old_password"`
+}
+
+// ChangePasswordRequest 修改密码请求，新密码需要符合密码策略
+type ChangePasswordRequest struct {
+	OldPassword string `json:"old_password" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required"`
+}
+
+// ChangeExpiredPasswordRequest 登录时密码已过期，使用原密码设置新密码
+type ChangeExpiredPasswordRequest struct {
+	Username    string `json:"username" binding:"required,max=50"`
+	OldPassword string `json:"old_password" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required"`
+}
+
+// DisableUserRequest 禁用用户请求，reenable_at不为空时到期后自动重新启用
+type DisableUserRequest struct {
+	Reason     string     `json:"reason" binding:"required,max=255"`
+	ReenableAt *time.Time `json:"reenable_at"`
+}
+
+// ChangePasswordResponse 修改密码的响应，原有令牌均已失效，客户端需改用新令牌
+type ChangePasswordResponse struct {
+	Message string `json:"message"`
+	service.TokenPair
+}
+
+// ProfileUpdateResponse 更新个人资料的响应，修改了密码时原有令牌均已失效，客户端需改用tokens中的新令牌
+type ProfileUpdateResponse struct {
+	*models.User
+	Tokens *service.TokenPair `json:"tokens,omitempty"`
+}
+
+// RegisterUser 自助注册为组织的用户
+func (h *UserHandler) RegisterUser(c *gin.Context) {
+	var req RegisterRequest
+
+	// 绑定JSON数据
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apperr.Respond(c, apperr.ErrBadRequest.Wrap(err))
 		return
 	}
-	user.Password = hashedPassword
 
-	// 创建用户
-	if err := database.DB.Create(&user).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create user"})
+	user := models.User{
+		Username: req.Username,
+		Password: req.Password,
+		Email:    req.Email,
+		Phone:    req.Phone,
+	}
+
+	if err := h.users.Register(c.Request.Context(), req.OrgID, &user); err != nil {
+		apperr.Respond(c, err)
 		return
 	}
 
 	// 返回成功响应
-	c.JSON(http.StatusCreated, gin.H{
-		"message": "User registered successfully",
-		"user":    user,
+	c.JSON(http.StatusCreated, RegisterResponse{
+		Message: "User registered successfully",
+		User:    user,
 	})
 }
 
 // Login 用户登录
-func Login(c *gin.Context) {
-	var loginData struct {
-		Username string `json:"username" binding:"required"`
-		Password string `json:"password" binding:"required"`
-	}
+func (h *UserHandler) Login(c *gin.Context) {
+	var loginData LoginRequest
 
 	// 绑定JSON数据
 	if err := c.ShouldBindJSON(&loginData); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "请输入用户名和密码"})
+		metrics.RecordLogin(metrics.LoginFailure, "bad_request")
+		apperr.Respond(c, apperr.ErrBadRequest.Wrap(err))
 		return
 	}
 
-	// 查找用户（包括软删除的用户）
-	var user models.User
-	result := database.DB.Unscoped().Where("username = ?", loginData.Username).First(&user)
-	if result.Error != nil {
-		fmt.Printf("用户登录失败: %s, 错误: %v\n", loginData.Username, result.Error)
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "用户名或密码错误"})
+	result, err := h.users.Login(c.Request.Context(), loginData.Username, loginData.Password, loginData.OrgID, c.ClientIP(), c.Request.UserAgent())
+	respondLogin(c, result, err)
+}
+
+// LoginMFA 登录第二步，验证TOTP验证码或恢复码后签发令牌
+func (h *UserHandler) LoginMFA(c *gin.Context) {
+	var req LoginMFARequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		metrics.RecordLogin(metrics.LoginFailure, "bad_request")
+		apperr.Respond(c, apperr.ErrBadRequest.Wrap(err))
 		return
 	}
 
-	// 检查用户是否被删除
-	if user.DeletedAt.Valid {
-		fmt.Printf("已删除用户尝试登录: %s\n", loginData.Username)
-		c.JSON(http.StatusForbidden, gin.H{"error": "该账号已被禁用"})
+	result, err := h.users.LoginMFA(c.Request.Context(), req.MFAToken, req.Code, req.RecoveryCode, c.ClientIP(), c.Request.UserAgent())
+	respondLogin(c, result, err)
+}
+
+// LoginMFAEnroll 组织要求启用两步验证的用户在登录过程中获取TOTP密钥
+func (h *UserHandler) LoginMFAEnroll(c *gin.Context) {
+	var req LoginMFAEnrollRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apperr.Respond(c, apperr.ErrBadRequest.Wrap(err))
 		return
 	}
 
-	// 首先尝试验证密码是否已经是加密的
-	passwordValid := utils.CheckPassword(loginData.Password, user.Password)
+	enrollment, err := h.users.LoginMFAEnroll(c.Request.Context(), req.MFAToken)
+	if err != nil {
+		apperr.Respond(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, enrollment)
+}
 
-	// 如果密码验证失败，检查是否是明文密码
-	if !passwordValid && loginData.Password == user.Password {
-		// 密码是明文且匹配，更新为加密密码
-		hashedPassword, err := utils.HashPassword(loginData.Password)
-		if err != nil {
-			fmt.Printf("加密密码失败: %s, 错误: %v\n", loginData.Username, err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
-			return
-		}
+// respondLogin 返回登录结果并记录登录指标
+func respondLogin(c *gin.Context, result *service.LoginResult, err error) {
+	if err != nil {
+		metrics.RecordLogin(metrics.LoginFailure, strings.ToLower(string(apperr.From(err).Code)))
+		apperr.Respond(c, err)
+		return
+	}
+	if result.MFA != nil {
+		metrics.RecordLogin(metrics.LoginMFARequired, "")
+	} else {
+		metrics.RecordLogin(metrics.LoginSuccess, "")
+	}
 
-		// 更新用户密码
-		if err := database.DB.Model(&user).Update("password", hashedPassword).Error; err != nil {
-			fmt.Printf("更新加密密码失败: %s, 错误: %v\n", loginData.Username, err)
-			// 不返回错误，继续登录流程
-		}
+	// 返回令牌和用户信息
+	c.JSON(http.StatusOK, LoginResponse{
+		TokenPair:     result.Tokens,
+		User:          result.User,
+		MFA:           result.MFA,
+		RecoveryCodes: result.RecoveryCodes,
+	})
+}
 
-		passwordValid = true
+// RefreshToken 使用刷新令牌换取新的访问令牌和刷新令牌
+func (h *UserHandler) RefreshToken(c *gin.Context) {
+	var req RefreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apperr.Respond(c, apperr.ErrBadRequest.Wrap(err))
+		return
 	}
 
-	if !passwordValid {
-		fmt.Printf("密码验证失败: %s\n", loginData.Username)
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "用户名或密码错误"})
+	tokens, err := h.users.Refresh(c.Request.Context(), req.RefreshToken, c.ClientIP())
+	if err != nil {
+		apperr.Respond(c, err)
 		return
 	}
 
-	// 生成JWT token
-	token, err := utils.GenerateToken(user.ID, user.Username, user.Role)
-	if err != nil {
-		fmt.Printf("生成token失败: %s, 错误: %v\n", loginData.Username, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "生成登录凭证失败"})
+	c.JSON(http.StatusOK, tokens)
+}
+
+// ChangeExpiredPassword 密码过期的用户设置新密码，之后使用新密码重新登录
+func (h *UserHandler) ChangeExpiredPassword(c *gin.Context) {
+	var req ChangeExpiredPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apperr.Respond(c, apperr.ErrBadRequest.Wrap(err))
 		return
 	}
 
-	// 更新最后登录时间
-	user.LastLoginAt = time.Now()
-	if err := database.DB.Save(&user).Error; err != nil {
-		fmt.Printf("更新最后登录时间失败: %s, 错误: %v\n", loginData.Username, err)
+	if err := h.users.ChangeExpiredPassword(c.Request.Context(), req.Username, req.OldPassword, req.NewPassword, c.ClientIP()); err != nil {
+		apperr.Respond(c, err)
+		return
 	}
 
-	// 记录登录日志
-	log := models.Log{
-		UserID:    user.ID,
-		Username:  user.Username,
-		Action:    "login",
-		IP:        c.ClientIP(),
-		Timestamp: time.Now(),
+	c.JSON(http.StatusOK, MessageResponse{Message: "密码已修改，请使用新密码登录"})
+}
+
+// Logout 用户退出
+func (h *UserHandler) Logout(c *gin.Context) {
+	// 从上下文中获取当前令牌
+	claims, exists := currentClaims(c)
+	if !exists {
+		apperr.Respond(c, apperr.ErrUnauthorized)
+		return
 	}
-	if err := database.DB.Create(&log).Error; err != nil {
-		fmt.Printf("创建登录日志失败: %s, 错误: %v\n", loginData.Username, err)
+
+	// 请求体可选
+	var req LogoutRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		apperr.Respond(c, apperr.ErrBadRequest.Wrap(err))
+		return
 	}
 
-	fmt.Printf("用户登录成功: %s\n", loginData.Username)
+	// 吊销令牌并记录退出日志
+	if err := h.users.Logout(c.Request.Context(), claims, req.RefreshToken, c.ClientIP()); err != nil {
+		apperr.Respond(c, err)
+		return
+	}
 
-	// 返回token和用户信息
-	c.JSON(http.StatusOK, gin.H{
-		"token": token,
-		"user":  user,
-	})
+	c.JSON(http.StatusOK, MessageResponse{Message: "Logged out successfully"})
 }
 
-// Logout 用户退出
-func Logout(c *gin.Context) {
-	// 从上下文中获取用户信息
-	userID, exists := c.Get("userID")
+// CreateUser 管理员创建用户
+func (h *UserHandler) CreateUser(c *gin.Context) {
+	userID, exists := currentUserID(c)
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+		apperr.Respond(c, apperr.ErrUnauthorized)
 		return
 	}
 
-	username, _ := c.Get("username")
+	var req CreateUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apperr.Respond(c, apperr.ErrBadRequest.Wrap(err))
+		return
+	}
 
-	// 记录退出日志
-	log := models.Log{
-		UserID:    userID.(uint),
-		Username:  username.(string),
-		Action:    "logout",
-		IP:        c.ClientIP(),
-		Timestamp: time.Now(),
+	user := models.User{
+		Username: req.Username,
+		Password: req.Password,
+		Email:    req.Email,
+		Phone:    req.Phone,
+		Role:     req.Role,
+		OrgID:    req.OrgID,
+		IsActive: req.IsActive == nil || *req.IsActive,
+	}
+	if err := h.users.Create(c.Request.Context(), userID, &user); err != nil {
+		apperr.Respond(c, err)
+		return
 	}
-	database.DB.Create(&log)
 
-	c.JSON(http.StatusOK, gin.H{"message": "Logged out successfully"})
+	c.JSON(http.StatusCreated, user)
 }
 
 // GetUsers 获取用户列表
-func GetUsers(c *gin.Context) {
-	var users []models.User
-	if err := database.DB.Preload("Org").Find(&users).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch users"})
+func (h *UserHandler) GetUsers(c *gin.Context) {
+	users, err := h.users.List(c.Request.Context())
+	if err != nil {
+		apperr.Respond(c, err)
 		return
 	}
 
@@ -187,213 +333,212 @@ func GetUsers(c *gin.Context) {
 }
 
 // GetUser 获取单个用户信息
-func GetUser(c *gin.Context) {
-	id := c.Param("id")
+func (h *UserHandler) GetUser(c *gin.Context) {
+	id, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
 
-	var user models.User
-	if err := database.DB.First(&user, id).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+	user, err := h.users.Get(c.Request.Context(), id)
+	if err != nil {
+		apperr.Respond(c, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, user)
 }
 
-// UpdateUser 更新用户信息
-func UpdateUser(c *gin.Context) {
-	id := c.Param("id")
-
-	var user models.User
-	if err := database.DB.First(&user, id).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+// UpdateUser 管理员更新用户信息，用户修改自己的资料使用UpdateProfile
+func (h *UserHandler) UpdateUser(c *gin.Context) {
+	id, ok := parseID(c, "id")
+	if !ok {
 		return
 	}
 
 	// 绑定更新数据
-	var updateData models.User
-	if err := c.ShouldBindJSON(&updateData); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid update data"})
+	var req UpdateUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apperr.Respond(c, apperr.ErrBadRequest.Wrap(err))
 		return
 	}
 
-	// 如果指定了组织ID，检查组织是否存在
-	if updateData.OrgID != nil {
-		var org models.Organization
-		if err := database.DB.First(&org, *updateData.OrgID).Error; err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Organization not found"})
-			return
-		}
-	}
-
-	// 更新用户信息
-	if err := database.DB.Model(&user).Updates(updateData).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user"})
+	user, err := h.users.Update(c.Request.Context(), id, service.UserUpdate{
+		Username:   req.Username,
+		Email:      req.Email,
+		Phone:      req.Phone,
+		Role:       req.Role,
+		OrgID:      req.OrgID,
+		AuthSource: req.AuthSource,
+	})
+	if err != nil {
+		apperr.Respond(c, err)
 		return
 	}
 
-	// 重新查询用户信息以获取关联的组织数据
-	database.DB.Preload("Org").First(&user, id)
-
 	c.JSON(http.StatusOK, user)
 }
 
 // DeleteUser 删除用户
-func DeleteUser(c *gin.Context) {
-	id := c.Param("id")
-
-	// 开启事务
-	tx := database.DB.Begin()
+func (h *UserHandler) DeleteUser(c *gin.Context) {
+	id, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
 
-	// 查找用户 - 使用 Unscoped() 忽略软删除
-	var user models.User
-	if err := tx.Unscoped().First(&user, id).Error; err != nil {
-		tx.Rollback()
-		c.JSON(http.StatusNotFound, gin.H{"error": "用户不存在"})
+	if err := h.users.Delete(c.Request.Context(), id); err != nil {
+		apperr.Respond(c, err)
 		return
 	}
 
-	// 执行删除操作（硬删除）
-	if err := tx.Unscoped().Delete(&user).Error; err != nil {
-		tx.Rollback()
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("删除用户失败: %v", err)})
+	c.JSON(http.StatusOK, MessageResponse{Message: "用户删除成功"})
+}
+
+// ForceSignOut 管理员强制用户在所有设备上退出登录
+func (h *UserHandler) ForceSignOut(c *gin.Context) {
+	id, ok := parseID(c, "id")
+	if !ok {
 		return
 	}
 
-	// 提交事务
-	if err := tx.Commit().Error; err != nil {
-		tx.Rollback()
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("提交事务失败: %v", err)})
+	if err := h.users.ForceSignOut(c.Request.Context(), id); err != nil {
+		apperr.Respond(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "用户删除成功"})
+	c.JSON(http.StatusOK, MessageResponse{Message: "用户已被强制退出登录"})
 }
 
-// GetProfile 获取当前用户的个人资料
-func GetProfile(c *gin.Context) {
-	// 从上下文中获取用户ID
-	userID, exists := c.Get("userID")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+// Unlock 管理员解锁因连续登录失败被锁定的用户
+func (h *UserHandler) Unlock(c *gin.Context) {
+	id, ok := parseID(c, "id")
+	if !ok {
 		return
 	}
 
-	// 查询用户信息
-	var user models.User
-	if err := database.DB.First(&user, userID).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+	if err := h.users.Unlock(c.Request.Context(), id, c.ClientIP()); err != nil {
+		apperr.Respond(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, user)
+	c.JSON(http.StatusOK, MessageResponse{Message: "用户已解锁"})
 }
 
-// UpdateProfile 更新当前用户的个人资料
-func UpdateProfile(c *gin.Context) {
-	// 从上下文中获取用户ID
-	userID, exists := c.Get("userID")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+// DisableUser 管理员禁用用户，用户已签发的令牌和API密钥立即失效
+func (h *UserHandler) DisableUser(c *gin.Context) {
+	adminID, ok := currentUserID(c)
+	if !ok {
+		apperr.Respond(c, apperr.ErrUnauthorized)
 		return
 	}
-
-	// 查询用户信息
-	var user models.User
-	if err := database.DB.First(&user, userID).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+	id, ok := parseID(c, "id")
+	if !ok {
 		return
 	}
 
-	// 绑定更新数据
-	var updateData struct {
-		Username string `json:"username"`
-		Password string `json:"password"`
-		Email    string `json:"email"`
-		Phone    string `json:"phone"`
+	var req DisableUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apperr.Respond(c, apperr.ErrBadRequest.Wrap(err))
+		return
 	}
 
-	if err := c.ShouldBindJSON(&updateData); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid update data"})
+	user, err := h.users.Disable(c.Request.Context(), adminID, id, req.Reason, req.ReenableAt, c.ClientIP())
+	if err != nil {
+		apperr.Respond(c, err)
 		return
 	}
 
-	// 如果要更新用户名，检查是否已存在
-	if updateData.Username != "" && updateData.Username != user.Username {
-		var existingUser models.User
-		result := database.DB.Where("username = ?", updateData.Username).First(&existingUser)
-		if result.RowsAffected > 0 {
-			c.JSON(http.StatusConflict, gin.H{"error": "Username already exists"})
-			return
-		}
-		user.Username = updateData.Username
-	}
+	c.JSON(http.StatusOK, user)
+}
 
-	// 更新其他信息
-	if updateData.Password != "" {
-		user.Password = updateData.Password
-	}
-	if updateData.Email != "" {
-		user.Email = updateData.Email
-	}
-	if updateData.Phone != "" {
-		user.Phone = updateData.Phone
+// EnableUser 管理员重新启用被禁用的用户
+func (h *UserHandler) EnableUser(c *gin.Context) {
+	id, ok := parseID(c, "id")
+	if !ok {
+		return
 	}
 
-	// 保存更新
-	if err := database.DB.Save(&user).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update profile"})
+	user, err := h.users.Enable(c.Request.Context(), id, c.ClientIP())
+	if err != nil {
+		apperr.Respond(c, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, user)
 }
 
-// ChangePassword 修改用户密码
-func ChangePassword(c *gin.Context) {
+// GetProfile 获取当前用户的个人资料
+func (h *UserHandler) GetProfile(c *gin.Context) {
 	// 从上下文中获取用户ID
-	userID, exists := c.Get("userID")
+	userID, exists := currentUserID(c)
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+		apperr.Respond(c, apperr.ErrUnauthorized)
 		return
 	}
 
-	// 获取请求数据
-	var passwordData struct {
-		OldPassword string `json:"old_password" binding:"required"`
-		NewPassword string `json:"new_password" binding:"required,min=6"`
+	user, err := h.users.Profile(c.Request.Context(), userID)
+	if err != nil {
+		apperr.Respond(c, err)
+		return
 	}
 
-	if err := c.ShouldBindJSON(&passwordData); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid password data"})
+	c.JSON(http.StatusOK, user)
+}
+
+// UpdateProfile 更新当前用户的个人资料
+func (h *UserHandler) UpdateProfile(c *gin.Context) {
+	// 从上下文中获取用户ID
+	userID, exists := currentUserID(c)
+	if !exists {
+		apperr.Respond(c, apperr.ErrUnauthorized)
 		return
 	}
 
-	// 查询用户信息
-	var user models.User
-	if err := database.DB.First(&user, userID).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+	// 绑定更新数据
+	var updateData ProfileUpdateRequest
+	if err := c.ShouldBindJSON(&updateData); err != nil {
+		apperr.Respond(c, apperr.ErrBadRequest.Wrap(err))
 		return
 	}
 
-	// 验证原密码
-	if !utils.CheckPassword(passwordData.OldPassword, user.Password) {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid old password"})
+	user, tokens, err := h.users.UpdateProfile(c.Request.Context(), userID, service.ProfileUpdate{
+		Username:    updateData.Username,
+		Password:    updateData.Password,
+		Email:       updateData.Email,
+		Phone:       updateData.Phone,
+		OldPassword: updateData.OldPassword,
+	}, c.ClientIP(), c.Request.UserAgent())
+	if err != nil {
+		apperr.Respond(c, err)
 		return
 	}
 
-	// 对新密码进行加密
-	hashedPassword, err := utils.HashPassword(passwordData.NewPassword)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash password"})
+	c.JSON(http.StatusOK, ProfileUpdateResponse{User: user, Tokens: tokens})
+}
+
+// ChangePassword 修改用户密码
+func (h *UserHandler) ChangePassword(c *gin.Context) {
+	// 从上下文中获取用户ID
+	userID, exists := currentUserID(c)
+	if !exists {
+		apperr.Respond(c, apperr.ErrUnauthorized)
+		return
+	}
+
+	// 获取请求数据
+	var passwordData ChangePasswordRequest
+	if err := c.ShouldBindJSON(&passwordData); err != nil {
+		apperr.Respond(c, apperr.ErrBadRequest.Wrap(err))
 		return
 	}
 
-	// 更新密码
-	user.Password = hashedPassword
-	if err := database.DB.Save(&user).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update password"})
+	tokens, err := h.users.ChangePassword(c.Request.Context(), userID, passwordData.OldPassword, passwordData.NewPassword, c.ClientIP(), c.Request.UserAgent())
+	if err != nil {
+		apperr.Respond(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Password updated successfully"})
+	c.JSON(http.StatusOK, ChangePasswordResponse{
+		Message:   "Password updated successfully",
+		TokenPair: *tokens,
+	})
 }
@@ -0,0 +1,53 @@
+package health
+
+import (
+	"runtime"
+	"runtime/debug"
+	"time"
+)
+
+// 构建信息，通过 -ldflags "-X xzyq/health.Version=..." 在构建时注入
+var (
+	Version   = "dev"
+	Commit    = ""
+	BuildTime = ""
+)
+
+// startTime 进程启动时间
+var startTime = time.Now()
+
+// BuildInfo 构建和运行信息
+type BuildInfo struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit,omitempty"`
+	BuildTime string `json:"build_time,omitempty"`
+	GoVersion string `json:"go_version"`
+	Uptime    string `json:"uptime"`
+}
+
+// Build 返回构建信息，未注入提交号时使用Go工具链记录的VCS信息
+func Build() BuildInfo {
+	info := BuildInfo{
+		Version:   Version,
+		Commit:    Commit,
+		BuildTime: BuildTime,
+		GoVersion: runtime.Version(),
+		Uptime:    time.Since(startTime).Round(time.Second).String(),
+	}
+
+	if info.Commit == "" {
+		if bi, ok := debug.ReadBuildInfo(); ok {
+			for _, setting := range bi.Settings {
+				switch setting.Key {
+				case "vcs.revision":
+					info.Commit = setting.Value
+				case "vcs.time":
+					if info.BuildTime == "" {
+						info.BuildTime = setting.Value
+					}
+				}
+			}
+		}
+	}
+	return info
+}
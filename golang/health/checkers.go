@@ -0,0 +1,75 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"xzyq/migrate"
+
+	"gorm.io/gorm"
+)
+
+// DatabaseChecker 检查数据库连通性并报告连接池状态
+func DatabaseChecker(db *gorm.DB) Checker {
+	return CheckerFunc(func(ctx context.Context) (map[string]interface{}, error) {
+		sqlDB, err := db.DB()
+		if err != nil {
+			return nil, err
+		}
+
+		stats := sqlDB.Stats()
+		details := map[string]interface{}{
+			"driver":               db.Dialector.Name(),
+			"max_open_connections": stats.MaxOpenConnections,
+			"open_connections":     stats.OpenConnections,
+			"in_use":               stats.InUse,
+			"idle":                 stats.Idle,
+			"wait_count":           stats.WaitCount,
+			"wait_duration":        stats.WaitDuration.String(),
+			"max_idle_closed":      stats.MaxIdleClosed,
+			"max_lifetime_closed":  stats.MaxLifetimeClosed,
+		}
+
+		if err := sqlDB.PingContext(ctx); err != nil {
+			return details, fmt.Errorf("ping database: %w", err)
+		}
+		return details, nil
+	})
+}
+
+// MigrationChecker 报告数据库迁移状态，存在未执行或被修改的迁移时检查失败；只读取迁移记录，不修改数据库
+func MigrationChecker(m *migrate.Migrator) Checker {
+	return CheckerFunc(func(ctx context.Context) (map[string]interface{}, error) {
+		statuses, err := m.Status(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		var applied, pending, modified int
+		var current int64
+		for _, s := range statuses {
+			switch {
+			case !s.Applied:
+				pending++
+			case s.Modified:
+				modified++
+				applied++
+			default:
+				applied++
+			}
+			if s.Applied && s.Version > current {
+				current = s.Version
+			}
+		}
+
+		details := map[string]interface{}{
+			"current_version": current,
+			"applied":         applied,
+			"pending":         pending,
+			"modified":        modified,
+		}
+		if pending > 0 || modified > 0 {
+			return details, fmt.Errorf("%d pending and %d modified migrations", pending, modified)
+		}
+		return details, nil
+	})
+}
@@ -0,0 +1,126 @@
+package health
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// 检查状态
+const (
+	StatusUp   = "up"
+	StatusDown = "down"
+)
+
+// Checker 依赖健康检查，返回的details会原样出现在检查报告中
+type Checker interface {
+	Check(ctx context.Context) (details map[string]interface{}, err error)
+}
+
+// CheckerFunc 将函数适配为Checker
+type CheckerFunc func(ctx context.Context) (map[string]interface{}, error)
+
+// Check 实现Checker
+func (f CheckerFunc) Check(ctx context.Context) (map[string]interface{}, error) {
+	return f(ctx)
+}
+
+// Result 单项检查结果
+type Result struct {
+	Status   string                 `json:"status"`
+	Required bool                   `json:"required"`
+	Latency  string                 `json:"latency"`
+	Error    string                 `json:"error,omitempty"`
+	Details  map[string]interface{} `json:"details,omitempty"`
+}
+
+// Report 所有检查的汇总结果
+type Report struct {
+	Status string            `json:"status"`
+	Checks map[string]Result `json:"checks"`
+	Build  BuildInfo         `json:"build"`
+}
+
+// check 已注册的检查项
+type check struct {
+	name     string
+	checker  Checker
+	required bool
+}
+
+// Registry 检查项注册表
+type Registry struct {
+	timeout time.Duration
+
+	mu     sync.RWMutex
+	checks []check
+}
+
+// NewRegistry 创建注册表，timeout为单项检查的超时时间
+func NewRegistry(timeout time.Duration) *Registry {
+	return &Registry{timeout: timeout}
+}
+
+// Register 注册检查项，required为true时该项失败会导致服务未就绪
+func (r *Registry) Register(name string, checker Checker, required bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.checks = append(r.checks, check{name: name, checker: checker, required: required})
+	sort.Slice(r.checks, func(i, j int) bool {
+		return r.checks[i].name < r.checks[j].name
+	})
+}
+
+// Run 并发执行所有检查项
+func (r *Registry) Run(ctx context.Context) Report {
+	r.mu.RLock()
+	checks := append([]check(nil), r.checks...)
+	r.mu.RUnlock()
+
+	results := make([]Result, len(checks))
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func(i int, c check) {
+			defer wg.Done()
+			results[i] = r.runOne(ctx, c)
+		}(i, c)
+	}
+	wg.Wait()
+
+	report := Report{
+		Status: StatusUp,
+		Checks: make(map[string]Result, len(checks)),
+		Build:  Build(),
+	}
+	for i, c := range checks {
+		report.Checks[c.name] = results[i]
+		if c.required && results[i].Status != StatusUp {
+			report.Status = StatusDown
+		}
+	}
+	return report
+}
+
+// runOne 执行单项检查
+func (r *Registry) runOne(ctx context.Context, c check) Result {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	start := time.Now()
+	details, err := c.checker.Check(ctx)
+
+	result := Result{
+		Status:   StatusUp,
+		Required: c.required,
+		Latency:  time.Since(start).String(),
+		Details:  details,
+	}
+	if err != nil {
+		result.Status = StatusDown
+		result.Error = err.Error()
+	}
+	return result
+}
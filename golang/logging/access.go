@@ -0,0 +1,44 @@
+package logging
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AccessLog 记录每个请求的访问日志，替代gin默认的文本日志
+func AccessLog() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		c.Next()
+
+		status := c.Writer.Status()
+		level := slog.LevelInfo
+		switch {
+		case status >= 500:
+			level = slog.LevelError
+		case status >= 400:
+			level = slog.LevelWarn
+		}
+
+		attrs := []any{
+			"method", c.Request.Method,
+			"path", c.Request.URL.Path,
+			"route", c.FullPath(),
+			"status", status,
+			"elapsed_ms", float64(time.Since(start).Microseconds()) / 1000,
+			"ip", c.ClientIP(),
+			"size", c.Writer.Size(),
+		}
+		if userID, ok := c.Get("userID"); ok {
+			attrs = append(attrs, "user_id", userID)
+		}
+		if len(c.Errors) > 0 {
+			attrs = append(attrs, "errors", c.Errors.String())
+		}
+
+		FromContext(c.Request.Context()).Log(c.Request.Context(), level, "http request", attrs...)
+	}
+}
@@ -0,0 +1,108 @@
+package logging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// gormLogger 将GORM日志输出到结构化日志，并附带请求ID
+//
+// SQL语句以debug级别输出，慢查询以warn级别输出，执行错误以error级别输出。
+type gormLogger struct {
+	level         gormlogger.LogLevel
+	slowThreshold time.Duration
+}
+
+// NewGORMLogger 创建GORM日志
+func NewGORMLogger(slowThreshold time.Duration) gormlogger.Interface {
+	return &gormLogger{
+		level:         gormlogger.Info,
+		slowThreshold: slowThreshold,
+	}
+}
+
+// LogMode 实现gormlogger.Interface
+func (l *gormLogger) LogMode(level gormlogger.LogLevel) gormlogger.Interface {
+	copied := *l
+	copied.level = level
+	return &copied
+}
+
+// Info 实现gormlogger.Interface
+func (l *gormLogger) Info(ctx context.Context, msg string, data ...interface{}) {
+	if l.level >= gormlogger.Info {
+		FromContext(ctx).Info(fmt.Sprintf(msg, data...), "source", sqlSource())
+	}
+}
+
+// Warn 实现gormlogger.Interface
+func (l *gormLogger) Warn(ctx context.Context, msg string, data ...interface{}) {
+	if l.level >= gormlogger.Warn {
+		FromContext(ctx).Warn(fmt.Sprintf(msg, data...), "source", sqlSource())
+	}
+}
+
+// Error 实现gormlogger.Interface
+func (l *gormLogger) Error(ctx context.Context, msg string, data ...interface{}) {
+	if l.level >= gormlogger.Error {
+		FromContext(ctx).Error(fmt.Sprintf(msg, data...), "source", sqlSource())
+	}
+}
+
+// Trace 实现gormlogger.Interface，记录每条SQL
+func (l *gormLogger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	if l.level <= gormlogger.Silent {
+		return
+	}
+
+	elapsed := time.Since(begin)
+	logger := FromContext(ctx)
+
+	switch {
+	case err != nil && l.level >= gormlogger.Error && !errors.Is(err, gorm.ErrRecordNotFound):
+		sql, rows := fc()
+		logger.Error("sql error", sqlAttrs(sql, rows, elapsed, "error", err.Error())...)
+	case l.slowThreshold > 0 && elapsed > l.slowThreshold && l.level >= gormlogger.Warn:
+		sql, rows := fc()
+		logger.Warn("slow sql", sqlAttrs(sql, rows, elapsed, "threshold", l.slowThreshold.String())...)
+	case l.level >= gormlogger.Info && logger.Enabled(ctx, slog.LevelDebug):
+		sql, rows := fc()
+		logger.Debug("sql", sqlAttrs(sql, rows, elapsed)...)
+	}
+}
+
+// sqlAttrs SQL日志的公共字段
+func sqlAttrs(sql string, rows int64, elapsed time.Duration, extra ...any) []any {
+	attrs := []any{
+		"sql", sql,
+		"rows", rows,
+		"elapsed_ms", float64(elapsed.Microseconds()) / 1000,
+		"source", sqlSource(),
+	}
+	return append(attrs, extra...)
+}
+
+// sqlSource 返回发起SQL的业务代码位置，跳过GORM和本日志包自身的调用栈
+func sqlSource() string {
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(3, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		frame, more := frames.Next()
+		if !strings.Contains(frame.File, "gorm.io/") && !strings.HasSuffix(frame.File, "logging/gorm.go") {
+			return frame.File + ":" + strconv.Itoa(frame.Line)
+		}
+		if !more {
+			return ""
+		}
+	}
+}
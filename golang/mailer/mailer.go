@@ -0,0 +1,41 @@
+// Package mailer 发送邮件
+//
+// 生产环境使用SMTP，开发环境可以把邮件输出到控制台或文件，便于在本地查看邮件中的链接。
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"xzyq/config"
+)
+
+// Message 一封纯文本邮件
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer 邮件发送接口
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// New 根据配置创建Mailer
+func New(cfg config.MailConfig) (Mailer, error) {
+	switch cfg.Driver {
+	case "smtp":
+		return NewSMTPMailer(cfg.From, cfg.SMTP), nil
+	case "file":
+		f, err := os.OpenFile(cfg.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+		if err != nil {
+			return nil, fmt.Errorf("open mail file: %w", err)
+		}
+		return NewWriterMailer(cfg.From, f), nil
+	case "console":
+		return NewWriterMailer(cfg.From, os.Stdout), nil
+	default:
+		return nil, fmt.Errorf("unknown mail driver %q", cfg.Driver)
+	}
+}
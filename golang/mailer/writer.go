@@ -0,0 +1,36 @@
+package mailer
+
+import (
+	"context"
+	"io"
+	"sync"
+)
+
+// WriterMailer 把邮件原文写入io.Writer，用于开发环境的console和file驱动
+type WriterMailer struct {
+	from string
+
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewWriterMailer 创建WriterMailer
+func NewWriterMailer(from string, w io.Writer) *WriterMailer {
+	return &WriterMailer{from: from, w: w}
+}
+
+// Send 写入邮件原文，多封邮件之间用空行分隔
+func (m *WriterMailer) Send(ctx context.Context, msg Message) error {
+	data, err := buildMessage(m.from, msg, false)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, err := m.w.Write(data); err != nil {
+		return err
+	}
+	_, err = io.WriteString(m.w, "\r\n")
+	return err
+}
@@ -1,108 +1,180 @@
 package main
 
 import (
+	"context"
 	"log"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+	"xzyq/config"
 	"xzyq/database"
 	"xzyq/handlers"
-	"xzyq/middleware"
-	"xzyq/models"
+	"xzyq/health"
+	"xzyq/logging"
+	"xzyq/mailer"
+	"xzyq/metrics"
+	"xzyq/migrate"
+	"xzyq/rehash"
+	"xzyq/routes"
+	"xzyq/seed"
+	"xzyq/server"
+	"xzyq/service"
+	"xzyq/store"
+	"xzyq/tenant"
+	"xzyq/utils"
 
 	"github.com/gin-gonic/gin"
 )
 
 func main() {
-	// 初始化数据库连接
-	database.InitDB()
+	// 加载配置
+	cfg, args, err := config.Load(os.Args[1:])
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
 
-	// 自动迁移数据库表
-	db := database.GetDB()
+	// 初始化结构化日志
+	if err := logging.Init(cfg.Log); err != nil {
+		log.Fatalf("Failed to init logging: %v", err)
+	}
 
-	// 删除现有的外键约束
-	if err := db.Migrator().DropConstraint(&models.User{}, "fk_users_org"); err != nil {
-		log.Printf("删除外键约束失败: %v", err)
+	// 初始化数据库连接
+	database.InitDB(cfg.Database)
+	if err := metrics.RegisterGORMCallbacks(database.GetDB()); err != nil {
+		log.Fatalf("Failed to register database metrics: %v", err)
+	}
+	if err := tenant.RegisterCallbacks(database.GetDB()); err != nil {
+		log.Fatalf("Failed to register tenant scope: %v", err)
 	}
 
-	// 自动迁移数据库表
-	db.AutoMigrate(&models.User{}, &models.Log{}, &models.Organization{}, &models.ObjectClass{})
+	// 初始化JWT
+	if err := utils.InitJWT(cfg.JWT); err != nil {
+		log.Fatalf("Failed to initialize JWT: %v", err)
+	}
 
-	// 手动添加外键约束
-	if err := db.Exec(`ALTER TABLE users 
-		ADD CONSTRAINT fk_users_org 
-		FOREIGN KEY (org_id) 
-		REFERENCES organization(id) 
-		ON DELETE SET NULL`).Error; err != nil {
-		log.Printf("添加外键约束失败: %v", err)
+	migrator, err := migrate.New(database.GetDB())
+	if err != nil {
+		log.Fatalf("Failed to load migrations: %v", err)
 	}
 
-	// 创建Gin路由
-	r := gin.Default()
-
-	// 允许跨域
-	r.Use(func(c *gin.Context) {
-		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
-		c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With")
-		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, DELETE")
-
-		if c.Request.Method == "OPTIONS" {
-			c.AbortWithStatus(204)
-			return
+	// 组装存储层和业务层
+	st := store.NewGormStore(database.GetDB())
+	revocations := service.NewRevocationService(st, cfg.JWT)
+	sessions := service.NewSessionService(st, revocations, cfg.Auth.Sessions)
+	tokenService := service.NewTokenService(st, sessions, cfg.JWT)
+	loginGuard := service.NewLoginGuard(st, cfg.Auth.Lockout)
+	mfaService := service.NewMFAService(st, cfg.Auth.MFA)
+	passwords, err := service.NewPasswordService(st, cfg.Auth.Password, cfg.Auth.PasswordHash)
+	if err != nil {
+		log.Fatalf("Failed to load password policy: %v", err)
+	}
+	// 本地没有的用户按这里的顺序尝试各认证方式
+	directories := service.NewDirectoryService(st, cfg.Auth.LDAP)
+	userService := service.NewUserService(st, tokenService, revocations, loginGuard, mfaService, passwords,
+		service.NewPasswordAuthenticator(passwords), directories)
+	orgService := service.NewOrganizationService(st, passwords)
+	mail, err := mailer.New(cfg.Mail)
+	if err != nil {
+		log.Fatalf("Failed to create mailer: %v", err)
+	}
+	passwordResets := service.NewPasswordResetService(st, revocations, loginGuard, passwords, mail, cfg.Auth.PasswordReset)
+	invitations := service.NewInvitationService(st, passwords, mail, cfg.Auth.Invitation)
+	oidcService := service.NewOIDCService(st, userService, cfg.Auth.OIDC)
+	apiKeys := service.NewAPIKeyService(st, cfg.Auth.APIKeys)
+
+	// 子命令
+	if len(args) > 0 {
+		switch args[0] {
+		case "migrate":
+			if err := migrate.Run(context.Background(), migrator, args[1:], os.Stdout); err != nil {
+				log.Fatalf("Migration failed: %v", err)
+			}
+		case "rehash":
+			if err := rehash.Run(context.Background(), passwords, args[1:], os.Stdout); err != nil {
+				log.Fatalf("Rehash failed: %v", err)
+			}
+		case "seed":
+			if err := seed.Run(context.Background(), userService, orgService, passwords, os.Stdout); err != nil {
+				log.Fatalf("Seed failed: %v", err)
+			}
+		default:
+			log.Fatalf("Unknown command %q", args[0])
+		}
+		return
+	}
+
+	// 数据库迁移
+	if cfg.Database.AutoMigrate {
+		if _, err := migrator.Up(context.Background()); err != nil {
+			log.Fatalf("Migration failed: %v", err)
 		}
+	} else if pending, err := migrator.Pending(context.Background()); err != nil {
+		slog.Error("check migration status failed", "error", err)
+	} else if pending > 0 {
+		slog.Warn("database migrations pending, run migrate up", "pending", pending)
+	}
 
-		c.Next()
-	})
+	// 初始数据
+	if cfg.Database.Seed {
+		if err := seed.Run(context.Background(), userService, orgService, passwords, os.Stdout); err != nil {
+			log.Fatalf("Seed failed: %v", err)
+		}
+	}
 
-	// 公开路由
-	public := r.Group("/api")
-	{
-		public.POST("/register", handlers.RegisterUser)
-		public.POST("/login", handlers.Login)
+	// 加载令牌吊销记录
+	if err := revocations.Sync(context.Background()); err != nil {
+		log.Fatalf("Failed to load token revocations: %v", err)
 	}
 
-	// 需要认证的路由
-	protected := r.Group("/api")
-	protected.Use(middleware.AuthMiddleware())
-	{
-		// 用户相关路由
-		protected.POST("/logout", handlers.Logout)
-		protected.GET("/users", handlers.GetUsers)
-		protected.GET("/users/:id", handlers.GetUser)
-		protected.PUT("/users/:id", handlers.UpdateUser)
-		protected.DELETE("/users/:id", handlers.DeleteUser)
-
-		// 个人资料相关路由
-		protected.GET("/user/profile", handlers.GetProfile)
-		protected.PUT("/user/profile", handlers.UpdateProfile)
-		protected.PUT("/user/change-password", handlers.ChangePassword)
-
-		// 组织管理路由
-		protected.GET("/organizations", handlers.GetOrganizations)
-		protected.GET("/organizations/all", handlers.GetAllOrganizations)
-		protected.GET("/organizations/:id", handlers.GetOrganization)
-		protected.GET("/organizations/:id/users", handlers.GetOrganizationUsers)
-		protected.POST("/organizations", handlers.CreateOrganization)
-		protected.PUT("/organizations/:id", handlers.UpdateOrganization)
-		protected.DELETE("/organizations/:id", handlers.DeleteOrganization)
-
-		// 对象类管理路由
-		protected.GET("/object-classes", handlers.GetObjectClasses)
-		protected.GET("/object-classes/:id", handlers.GetObjectClass)
-		protected.POST("/object-classes", handlers.CreateObjectClass)
-		protected.PUT("/object-classes/:id", handlers.UpdateObjectClass)
-		protected.DELETE("/object-classes/:id", handlers.DeleteObjectClass)
-		protected.GET("/object-classes/:id/children", handlers.GetObjectClassChildren)
-		protected.POST("/object-classes/:id/children", handlers.CreateChildObjectClass)
+	// 健康检查，数据库不可用时服务未就绪
+	checks := health.NewRegistry(2 * time.Second)
+	checks.Register("database", health.DatabaseChecker(database.GetDB()), true)
+	checks.Register("migrations", health.MigrationChecker(migrator), false)
+
+	// 组装接口层
+	h := routes.Handlers{
+		User:           handlers.NewUserHandler(userService),
+		MFA:            handlers.NewMFAHandler(userService, mfaService),
+		PasswordReset:  handlers.NewPasswordResetHandler(passwordResets),
+		PasswordPolicy: handlers.NewPasswordPolicyHandler(passwords),
+		OIDC:           handlers.NewOIDCHandler(oidcService),
+		Directory:      handlers.NewDirectoryHandler(directories),
+		APIKey:         handlers.NewAPIKeyHandler(userService, apiKeys),
+		Session:        handlers.NewSessionHandler(sessions),
+		Invitation:     handlers.NewInvitationHandler(invitations),
+		Organization:   handlers.NewOrganizationHandler(orgService),
+		ObjectClass:    handlers.NewObjectClassHandler(service.NewObjectClassService(st)),
+		Health:         handlers.NewHealthHandler(checks),
+		Revocations:    revocations,
+		Users:          userService,
+		APIKeys:        apiKeys,
 	}
 
-	// 管理员路由
-	admin := protected.Group("/admin")
-	admin.Use(middleware.AdminAuthMiddleware())
-	{
-		// 这里可以添加管理员特有的路由
+	// 创建Gin路由
+	gin.SetMode(cfg.Server.Mode)
+	r := gin.New()
+	routes.Setup(r, h)
+
+	// 启动服务器，收到SIGINT或SIGTERM后优雅停机
+	srv, err := server.New(cfg.Server, r)
+	if err != nil {
+		log.Fatalf("Failed to create server: %v", err)
 	}
+	srv.OnShutdown(func(ctx context.Context) error {
+		return database.Close()
+	})
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	// 定期同步其他实例写入的吊销记录
+	go revocations.Run(ctx, cfg.JWT.RevocationSync)
+	// 重新启用计划启用时间已到的用户
+	go userService.RunScheduledEnable(ctx, time.Minute)
 
-	// 启动服务器
-	if err := r.Run(":8080"); err != nil {
-		log.Fatalf("Failed to start server: %v", err)
+	if err := srv.Run(ctx); err != nil {
+		log.Fatalf("Server error: %v", err)
 	}
 }
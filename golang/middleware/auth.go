@@ -1,63 +1,164 @@
 package middleware
 
 import (
+	"context"
+	"fmt"
 	"net/http"
 	"strings"
+	"xzyq/apperr"
+	"xzyq/models"
+	"xzyq/tenant"
 	"xzyq/utils"
 
 	"github.com/gin-gonic/gin"
 )
 
-// AuthMiddleware JWT认证中间件
-func AuthMiddleware() gin.HandlerFunc {
+var (
+	errAuthHeaderMissing = apperr.New(apperr.CodeAuthHeaderMissing, http.StatusUnauthorized)
+	errAuthHeaderInvalid = apperr.New(apperr.CodeAuthHeaderInvalid, http.StatusUnauthorized)
+	errTokenInvalid      = apperr.New(apperr.CodeTokenInvalid, http.StatusUnauthorized)
+	errAdminRequired     = apperr.New(apperr.CodeAdminRequired, http.StatusForbidden)
+	errAPIKeyScopeDenied = apperr.New(apperr.CodeAPIKeyScopeDenied, http.StatusForbidden)
+)
+
+// apiKeyPrefix API密钥的固定开头，与service.APIKeyPrefix一致
+const apiKeyPrefix = "xzyq_"
+
+// RevocationChecker 判断令牌是否已被吊销
+type RevocationChecker interface {
+	IsRevoked(claims *utils.Claims) bool
+}
+
+// UserStatusChecker 查询用户是否存在且未被禁用
+type UserStatusChecker interface {
+	Active(ctx context.Context, userID uint) (bool, error)
+}
+
+// APIKeyVerifier 校验API密钥并记录密钥的使用
+type APIKeyVerifier interface {
+	Verify(ctx context.Context, key, ip string) (*utils.Claims, *models.APIKey, error)
+	RecordUsage(ctx context.Context, key *models.APIKey, claims *utils.Claims, ip, detail string)
+}
+
+// AuthMiddleware 认证中间件，接受JWT或以xzyq_开头的API密钥，已吊销的令牌视为无效
+//
+// 吊销记录由各实例定期同步，禁用用户后其他实例可能还没有同步到，因此每个请求还要查询用户是否已被禁用；
+// API密钥在校验时同样检查。使用API密钥的请求受密钥权限范围限制，请求结束后写入日志。
+func AuthMiddleware(revocations RevocationChecker, users UserStatusChecker, apiKeys APIKeyVerifier) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// 获取Authorization header
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header is required"})
-			c.Abort()
+			apperr.Respond(c, errAuthHeaderMissing)
 			return
 		}
 
 		// 检查Bearer前缀
 		parts := strings.SplitN(authHeader, " ", 2)
 		if !(len(parts) == 2 && parts[0] == "Bearer") {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid authorization format"})
-			c.Abort()
+			apperr.Respond(c, errAuthHeaderInvalid)
+			return
+		}
+
+		if strings.HasPrefix(parts[1], apiKeyPrefix) {
+			authenticateAPIKey(c, apiKeys, parts[1])
 			return
 		}
 
 		// 解析token
 		claims, err := utils.ParseToken(parts[1])
 		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
-			c.Abort()
+			apperr.Respond(c, errTokenInvalid.Wrap(err))
+			return
+		}
+
+		// 没有jti的令牌无法吊销，不再接受
+		if claims.ID == "" || revocations.IsRevoked(claims) {
+			apperr.Respond(c, errTokenInvalid)
+			return
+		}
+		// 已禁用或已删除的用户的令牌立即失效
+		active, err := users.Active(c.Request.Context(), claims.UserID)
+		if err != nil {
+			apperr.Respond(c, err)
+			return
+		}
+		if !active {
+			apperr.Respond(c, errTokenInvalid)
 			return
 		}
 
-		// 将用户信息存储到上下文中
-		c.Set("userID", claims.UserID)
-		c.Set("OrgID", claims.OrgID)
-		c.Set("username", claims.Username)
-		c.Set("role", claims.Role)
+		setIdentity(c, claims)
+		c.Set("claims", claims)
 
 		c.Next()
 	}
 }
 
+// authenticateAPIKey 校验API密钥并检查权限范围，请求处理完后记录使用日志
+func authenticateAPIKey(c *gin.Context, apiKeys APIKeyVerifier, key string) {
+	claims, apiKey, err := apiKeys.Verify(c.Request.Context(), key, c.ClientIP())
+	if err != nil {
+		apperr.Respond(c, err)
+		return
+	}
+	// 超出权限范围的请求同样记录日志
+	if apiKey.AllowsMethod(c.Request.Method) {
+		setIdentity(c, claims)
+		c.Set("apiKey", apiKey)
+		c.Next()
+	} else {
+		apperr.Respond(c, errAPIKeyScopeDenied)
+	}
+
+	detail := fmt.Sprintf("%s %s %d", c.Request.Method, c.Request.URL.Path, c.Writer.Status())
+	apiKeys.RecordUsage(c.Request.Context(), apiKey, claims, c.ClientIP(), detail)
+}
+
+// setIdentity 限制后续数据访问的组织范围，并将用户信息存储到上下文中
+func setIdentity(c *gin.Context, claims *utils.Claims) {
+	// 平台管理员不受组织范围限制
+	ctx := c.Request.Context()
+	if claims.IsPlatformAdmin() {
+		ctx = tenant.Unscoped(ctx)
+	} else {
+		ctx = tenant.WithOrg(ctx, claims.OrgID)
+	}
+	c.Request = c.Request.WithContext(ctx)
+
+	c.Set("userID", claims.UserID)
+	c.Set("OrgID", claims.OrgID)
+	c.Set("username", claims.Username)
+	c.Set("role", claims.Role)
+}
+
+// SessionOnly 只允许登录后的令牌访问，用于退出登录、修改密码、两步验证和管理API密钥等接口
+func SessionOnly() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := c.Get("apiKey"); ok {
+			apperr.Respond(c, errAPIKeyScopeDenied)
+			return
+		}
+		c.Next()
+	}
+}
+
 // AdminAuthMiddleware 管理员权限中间件
 func AdminAuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		role, exists := c.Get("role")
 		if !exists {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "User role not found"})
-			c.Abort()
+			apperr.Respond(c, apperr.ErrUnauthorized)
 			return
 		}
 
 		if role != "admin" {
-			c.JSON(http.StatusForbidden, gin.H{"error": "Admin privileges required"})
-			c.Abort()
+			apperr.Respond(c, errAdminRequired)
+			return
+		}
+		// API密钥还需要有admin权限范围
+		if key, ok := c.Get("apiKey"); ok && !key.(*models.APIKey).HasScope(models.APIKeyScopeAdmin) {
+			apperr.Respond(c, errAPIKeyScopeDenied)
 			return
 		}
 
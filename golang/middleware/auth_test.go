@@ -0,0 +1,75 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"xzyq/config"
+	"xzyq/models"
+	"xzyq/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// fakeAuth 内存中的吊销记录和用户状态
+type fakeAuth struct {
+	revoked  map[string]bool // jti
+	inactive map[uint]bool
+}
+
+func (f *fakeAuth) IsRevoked(claims *utils.Claims) bool {
+	return f.revoked[claims.ID]
+}
+
+func (f *fakeAuth) Active(_ context.Context, userID uint) (bool, error) {
+	return !f.inactive[userID], nil
+}
+
+func (f *fakeAuth) Verify(context.Context, string, string) (*utils.Claims, *models.APIKey, error) {
+	return nil, nil, errTokenInvalid
+}
+
+func (f *fakeAuth) RecordUsage(context.Context, *models.APIKey, *utils.Claims, string, string) {}
+
+func TestAuthMiddlewareRejectsInactiveUser(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	if err := utils.InitJWT(config.JWTConfig{Secret: "test", Expire: time.Minute}); err != nil {
+		t.Fatalf("InitJWT: %v", err)
+	}
+
+	auth := &fakeAuth{revoked: make(map[string]bool), inactive: make(map[uint]bool)}
+	r := gin.New()
+	r.GET("/me", AuthMiddleware(auth, auth, auth), func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+
+	token, jti, err := utils.GenerateToken(1, 1, "alice", "user", "")
+	if err != nil {
+		t.Fatalf("GenerateToken: %v", err)
+	}
+	get := func() int {
+		req := httptest.NewRequest(http.MethodGet, "/me", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	if code := get(); code != http.StatusNoContent {
+		t.Fatalf("active user: status = %d, want %d", code, http.StatusNoContent)
+	}
+
+	// 其他实例禁用了用户，本实例还没有同步到吊销记录
+	auth.inactive[1] = true
+	if code := get(); code != http.StatusUnauthorized {
+		t.Errorf("disabled user: status = %d, want %d", code, http.StatusUnauthorized)
+	}
+
+	auth.inactive[1] = false
+	auth.revoked[jti] = true
+	if code := get(); code != http.StatusUnauthorized {
+		t.Errorf("revoked token: status = %d, want %d", code, http.StatusUnauthorized)
+	}
+}
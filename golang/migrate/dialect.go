@@ -0,0 +1,43 @@
+package migrate
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// lockKey 迁移使用的advisory lock键，取值无特殊含义，只需在本应用内唯一
+const lockKey int64 = 7_239_105_118
+
+// dialect 不同数据库的迁移差异
+type dialect struct {
+	dir    string // migrations下的子目录
+	lock   func(conn *gorm.DB) error
+	unlock func(conn *gorm.DB) error
+}
+
+// dialectFor 根据GORM方言名称返回对应的迁移配置
+func dialectFor(name string) (dialect, error) {
+	switch name {
+	case "postgres":
+		return dialect{
+			dir: "postgres",
+			lock: func(conn *gorm.DB) error {
+				return conn.Exec("SELECT pg_advisory_lock(?)", lockKey).Error
+			},
+			unlock: func(conn *gorm.DB) error {
+				return conn.Exec("SELECT pg_advisory_unlock(?)", lockKey).Error
+			},
+		}, nil
+	case "sqlite":
+		// SQLite用于本地开发和测试，只有单个进程访问数据库，
+		// 每个迁移都在事务中执行，不需要额外的锁
+		return dialect{
+			dir:    "sqlite",
+			lock:   func(conn *gorm.DB) error { return nil },
+			unlock: func(conn *gorm.DB) error { return nil },
+		}, nil
+	default:
+		return dialect{}, fmt.Errorf("migrations are not supported for database %q", name)
+	}
+}
@@ -0,0 +1,359 @@
+package migrate
+
+import (
+	"context"
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"xzyq/logging"
+
+	"gorm.io/gorm"
+)
+
+//go:embed migrations
+var migrationFS embed.FS
+
+// Migration 一个版本的数据库结构变更
+type Migration struct {
+	Version  int64
+	Name     string
+	Up       string
+	Down     string
+	Checksum string // up脚本的sha256
+}
+
+// Status 迁移的执行状态
+type Status struct {
+	Migration
+	Applied   bool
+	AppliedAt time.Time
+	Modified  bool // 已执行的迁移文件内容在执行后被修改
+}
+
+// schemaMigration schema_migrations表中的记录
+type schemaMigration struct {
+	Version   int64
+	Name      string
+	Checksum  string
+	AppliedAt time.Time
+}
+
+// TableName 指定表名
+func (schemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+// Migrator 数据库迁移执行器
+type Migrator struct {
+	db         *gorm.DB
+	dialect    dialect
+	migrations []Migration
+}
+
+// New 根据数据库方言加载内置的迁移文件
+func New(db *gorm.DB) (*Migrator, error) {
+	d, err := dialectFor(db.Dialector.Name())
+	if err != nil {
+		return nil, err
+	}
+
+	sub, err := fs.Sub(migrationFS, path.Join("migrations", d.dir))
+	if err != nil {
+		return nil, err
+	}
+	migrations, err := Load(sub)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Migrator{db: db, dialect: d, migrations: migrations}, nil
+}
+
+// Load 从目录中读取迁移文件
+//
+// 文件名格式为 <版本号>_<名称>.up.sql 和 <版本号>_<名称>.down.sql，按版本号升序执行。
+func Load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".sql") {
+			continue
+		}
+
+		base := strings.TrimSuffix(entry.Name(), ".sql")
+		var direction string
+		switch {
+		case strings.HasSuffix(base, ".up"):
+			direction = "up"
+		case strings.HasSuffix(base, ".down"):
+			direction = "down"
+		default:
+			return nil, fmt.Errorf("migration %s: missing .up or .down suffix", entry.Name())
+		}
+		base = strings.TrimSuffix(base, "."+direction)
+
+		prefix, name, ok := strings.Cut(base, "_")
+		if !ok {
+			return nil, fmt.Errorf("migration %s: expected <version>_<name>", entry.Name())
+		}
+		version, err := strconv.ParseInt(prefix, 10, 64)
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("migration %s: invalid version %q", entry.Name(), prefix)
+		}
+
+		content, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		m, exists := byVersion[version]
+		if !exists {
+			m = &Migration{Version: version, Name: name}
+			byVersion[version] = m
+		} else if m.Name != name {
+			return nil, fmt.Errorf("migration version %d used by both %q and %q", version, m.Name, name)
+		}
+
+		if direction == "up" {
+			m.Up = string(content)
+			sum := sha256.Sum256(content)
+			m.Checksum = hex.EncodeToString(sum[:])
+		} else {
+			m.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %d_%s: missing up script", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+// Up 执行所有未执行的迁移，返回本次执行的迁移
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	var done []Migration
+	err := m.withLock(ctx, func(conn *gorm.DB) error {
+		applied, err := m.applied(conn)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range m.migrations {
+			if _, ok := applied[migration.Version]; ok {
+				continue
+			}
+			if err := m.apply(conn, migration); err != nil {
+				return err
+			}
+			done = append(done, migration)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Down 回滚最近执行的steps个迁移，返回本次回滚的迁移
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	var done []Migration
+	err := m.withLock(ctx, func(conn *gorm.DB) error {
+		var err error
+		done, err = m.down(conn, steps)
+		return err
+	})
+	return done, err
+}
+
+// Redo 回滚并重新执行最近一次迁移
+func (m *Migrator) Redo(ctx context.Context) (*Migration, error) {
+	var redone *Migration
+	err := m.withLock(ctx, func(conn *gorm.DB) error {
+		reverted, err := m.down(conn, 1)
+		if err != nil {
+			return err
+		}
+		if len(reverted) == 0 {
+			return fmt.Errorf("no applied migration to redo")
+		}
+		if err := m.apply(conn, reverted[0]); err != nil {
+			return err
+		}
+		redone = &reverted[0]
+		return nil
+	})
+	return redone, err
+}
+
+// Status 返回所有迁移的执行状态
+//
+// 只读取数据库，不创建schema_migrations表，可以用于就绪检查；表不存在时所有迁移都未执行。
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	conn := m.db.WithContext(ctx)
+
+	var records []schemaMigration
+	if conn.Migrator().HasTable(&schemaMigration{}) {
+		if err := conn.Order("version").Find(&records).Error; err != nil {
+			return nil, fmt.Errorf("load schema_migrations: %w", err)
+		}
+	}
+	applied := make(map[int64]schemaMigration, len(records))
+	for _, r := range records {
+		applied[r.Version] = r
+	}
+
+	statuses := make([]Status, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		s := Status{Migration: migration}
+		if r, ok := applied[migration.Version]; ok {
+			s.Applied = true
+			s.AppliedAt = r.AppliedAt
+			s.Modified = r.Checksum != migration.Checksum
+		}
+		statuses = append(statuses, s)
+	}
+	return statuses, nil
+}
+
+// Pending 返回未执行的迁移数量
+func (m *Migrator) Pending(ctx context.Context) (int, error) {
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return 0, err
+	}
+	pending := 0
+	for _, s := range statuses {
+		if !s.Applied {
+			pending++
+		}
+	}
+	return pending, nil
+}
+
+// withLock 在独占的数据库连接上加锁后执行fn，防止多个实例同时迁移
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *gorm.DB) error) error {
+	return m.db.WithContext(ctx).Connection(func(conn *gorm.DB) error {
+		if err := m.dialect.lock(conn); err != nil {
+			return fmt.Errorf("acquire migration lock: %w", err)
+		}
+		defer func() {
+			if err := m.dialect.unlock(conn); err != nil {
+				logging.FromContext(ctx).Error("release migration lock failed", "error", err)
+			}
+		}()
+
+		if err := m.ensureTable(conn); err != nil {
+			return err
+		}
+		return fn(conn)
+	})
+}
+
+// ensureTable 创建schema_migrations表
+func (m *Migrator) ensureTable(conn *gorm.DB) error {
+	err := conn.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    BIGINT PRIMARY KEY,
+		name       VARCHAR(255) NOT NULL,
+		checksum   VARCHAR(64) NOT NULL,
+		applied_at TIMESTAMP NOT NULL
+	)`).Error
+	if err != nil {
+		return fmt.Errorf("create schema_migrations: %w", err)
+	}
+	return nil
+}
+
+// applied 读取已执行的迁移，并校验文件内容未被修改
+func (m *Migrator) applied(conn *gorm.DB) (map[int64]schemaMigration, error) {
+	var records []schemaMigration
+	if err := conn.Order("version").Find(&records).Error; err != nil {
+		return nil, fmt.Errorf("load schema_migrations: %w", err)
+	}
+
+	known := make(map[int64]Migration, len(m.migrations))
+	for _, migration := range m.migrations {
+		known[migration.Version] = migration
+	}
+
+	applied := make(map[int64]schemaMigration, len(records))
+	for _, r := range records {
+		migration, ok := known[r.Version]
+		if !ok {
+			return nil, fmt.Errorf("applied migration %d_%s not found in migration files", r.Version, r.Name)
+		}
+		if migration.Checksum != r.Checksum {
+			return nil, fmt.Errorf("migration %d_%s was modified after being applied", r.Version, r.Name)
+		}
+		applied[r.Version] = r
+	}
+	return applied, nil
+}
+
+// apply 在事务中执行一个迁移并记录版本
+func (m *Migrator) apply(conn *gorm.DB, migration Migration) error {
+	err := conn.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec(migration.Up).Error; err != nil {
+			return err
+		}
+		return tx.Create(&schemaMigration{
+			Version:   migration.Version,
+			Name:      migration.Name,
+			Checksum:  migration.Checksum,
+			AppliedAt: time.Now().UTC(),
+		}).Error
+	})
+	if err != nil {
+		return fmt.Errorf("apply migration %d_%s: %w", migration.Version, migration.Name, err)
+	}
+	logging.FromContext(conn.Statement.Context).Info("migration applied", "version", migration.Version, "name", migration.Name)
+	return nil
+}
+
+// down 按执行顺序倒序回滚steps个迁移
+func (m *Migrator) down(conn *gorm.DB, steps int) ([]Migration, error) {
+	applied, err := m.applied(conn)
+	if err != nil {
+		return nil, err
+	}
+
+	var reverted []Migration
+	for i := len(m.migrations) - 1; i >= 0 && len(reverted) < steps; i-- {
+		migration := m.migrations[i]
+		if _, ok := applied[migration.Version]; !ok {
+			continue
+		}
+		if migration.Down == "" {
+			return reverted, fmt.Errorf("migration %d_%s has no down script", migration.Version, migration.Name)
+		}
+
+		err := conn.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Exec(migration.Down).Error; err != nil {
+				return err
+			}
+			return tx.Delete(&schemaMigration{}, "version = ?", migration.Version).Error
+		})
+		if err != nil {
+			return reverted, fmt.Errorf("revert migration %d_%s: %w", migration.Version, migration.Name, err)
+		}
+		logging.FromContext(conn.Statement.Context).Info("migration reverted", "version", migration.Version, "name", migration.Name)
+		reverted = append(reverted, migration)
+	}
+	return reverted, nil
+}
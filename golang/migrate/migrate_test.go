@@ -0,0 +1,259 @@
+package migrate
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// testMigrations 三个依次建表的迁移
+func testMigrations() fstest.MapFS {
+	return fstest.MapFS{
+		"0001_create_a.up.sql":   {Data: []byte("CREATE TABLE a (id INTEGER PRIMARY KEY);")},
+		"0001_create_a.down.sql": {Data: []byte("DROP TABLE a;")},
+		"0002_create_b.up.sql":   {Data: []byte("CREATE TABLE b (id INTEGER PRIMARY KEY);")},
+		"0002_create_b.down.sql": {Data: []byte("DROP TABLE b;")},
+		"0003_create_c.up.sql":   {Data: []byte("CREATE TABLE c (id INTEGER PRIMARY KEY);")},
+		"0003_create_c.down.sql": {Data: []byte("DROP TABLE c;")},
+	}
+}
+
+// openTestDB 打开SQLite内存数据库
+func openTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("get sql.DB: %v", err)
+	}
+	// 内存数据库的每个连接都是独立的数据库
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+	return db
+}
+
+// newTestMigrator 使用fsys中的迁移文件创建Migrator
+func newTestMigrator(t *testing.T, db *gorm.DB, fsys fstest.MapFS) *Migrator {
+	t.Helper()
+
+	d, err := dialectFor(db.Dialector.Name())
+	if err != nil {
+		t.Fatalf("dialect: %v", err)
+	}
+	migrations, err := Load(fsys)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	return &Migrator{db: db, dialect: d, migrations: migrations}
+}
+
+// appliedVersions 返回已执行的迁移版本
+func appliedVersions(t *testing.T, m *Migrator) []int64 {
+	t.Helper()
+
+	statuses, err := m.Status(context.Background())
+	if err != nil {
+		t.Fatalf("Status: %v", err)
+	}
+	var versions []int64
+	for _, s := range statuses {
+		if s.Applied {
+			versions = append(versions, s.Version)
+		}
+	}
+	return versions
+}
+
+func TestMigrator(t *testing.T) {
+	up := func(m *Migrator) error { _, err := m.Up(context.Background()); return err }
+	down := func(steps int) func(m *Migrator) error {
+		return func(m *Migrator) error { _, err := m.Down(context.Background(), steps); return err }
+	}
+	redo := func(m *Migrator) error { _, err := m.Redo(context.Background()); return err }
+
+	tests := []struct {
+		name    string
+		steps   []func(m *Migrator) error
+		applied []int64
+		tables  []string
+	}{
+		{"up", []func(*Migrator) error{up}, []int64{1, 2, 3}, []string{"a", "b", "c"}},
+		{"up twice", []func(*Migrator) error{up, up}, []int64{1, 2, 3}, []string{"a", "b", "c"}},
+		{"down one", []func(*Migrator) error{up, down(1)}, []int64{1, 2}, []string{"a", "b"}},
+		{"down all", []func(*Migrator) error{up, down(10)}, nil, nil},
+		{"down then up", []func(*Migrator) error{up, down(2), up}, []int64{1, 2, 3}, []string{"a", "b", "c"}},
+		{"redo", []func(*Migrator) error{up, redo}, []int64{1, 2, 3}, []string{"a", "b", "c"}},
+		{"redo after down", []func(*Migrator) error{up, down(1), redo}, []int64{1, 2}, []string{"a", "b"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := openTestDB(t)
+			m := newTestMigrator(t, db, testMigrations())
+			for i, step := range tt.steps {
+				if err := step(m); err != nil {
+					t.Fatalf("step %d: %v", i, err)
+				}
+			}
+
+			applied := appliedVersions(t, m)
+			if len(applied) != len(tt.applied) {
+				t.Fatalf("applied = %v, want %v", applied, tt.applied)
+			}
+			for i := range applied {
+				if applied[i] != tt.applied[i] {
+					t.Fatalf("applied = %v, want %v", applied, tt.applied)
+				}
+			}
+
+			want := make(map[string]bool)
+			for _, table := range tt.tables {
+				want[table] = true
+			}
+			for _, table := range []string{"a", "b", "c"} {
+				if got := db.Migrator().HasTable(table); got != want[table] {
+					t.Errorf("table %s exists = %v, want %v", table, got, want[table])
+				}
+			}
+		})
+	}
+}
+
+// 就绪检查使用的Status不能修改数据库
+func TestMigratorStatusIsReadOnly(t *testing.T) {
+	db := openTestDB(t)
+	m := newTestMigrator(t, db, testMigrations())
+
+	pending, err := m.Pending(context.Background())
+	if err != nil {
+		t.Fatalf("Pending: %v", err)
+	}
+	if pending != 3 {
+		t.Errorf("Pending = %d, want 3", pending)
+	}
+	if db.Migrator().HasTable("schema_migrations") {
+		t.Error("Status created schema_migrations")
+	}
+}
+
+func TestMigratorRedoWithoutApplied(t *testing.T) {
+	m := newTestMigrator(t, openTestDB(t), testMigrations())
+	if _, err := m.Redo(context.Background()); err == nil {
+		t.Error("Redo without applied migrations should fail")
+	}
+}
+
+func TestMigratorRejectsModifiedMigration(t *testing.T) {
+	db := openTestDB(t)
+	if _, err := newTestMigrator(t, db, testMigrations()).Up(context.Background()); err != nil {
+		t.Fatalf("Up: %v", err)
+	}
+
+	// 已执行的迁移文件在执行后被修改
+	modified := testMigrations()
+	modified["0002_create_b.up.sql"] = &fstest.MapFile{Data: []byte("CREATE TABLE b (id INTEGER PRIMARY KEY, name TEXT);")}
+	m := newTestMigrator(t, db, modified)
+
+	if _, err := m.Up(context.Background()); err == nil || !strings.Contains(err.Error(), "was modified") {
+		t.Errorf("Up with modified migration: err = %v, want modified error", err)
+	}
+	if _, err := m.Down(context.Background(), 1); err == nil || !strings.Contains(err.Error(), "was modified") {
+		t.Errorf("Down with modified migration: err = %v, want modified error", err)
+	}
+	if !db.Migrator().HasTable("c") {
+		t.Error("Down reverted a migration although another one was modified")
+	}
+
+	statuses, err := m.Status(context.Background())
+	if err != nil {
+		t.Fatalf("Status: %v", err)
+	}
+	for _, s := range statuses {
+		if s.Modified != (s.Version == 2) {
+			t.Errorf("migration %d modified = %v", s.Version, s.Modified)
+		}
+	}
+}
+
+func TestMigratorRejectsUnknownAppliedMigration(t *testing.T) {
+	db := openTestDB(t)
+	if _, err := newTestMigrator(t, db, testMigrations()).Up(context.Background()); err != nil {
+		t.Fatalf("Up: %v", err)
+	}
+
+	// 已执行的迁移文件被删除
+	removed := testMigrations()
+	delete(removed, "0003_create_c.up.sql")
+	delete(removed, "0003_create_c.down.sql")
+	if _, err := newTestMigrator(t, db, removed).Up(context.Background()); err == nil {
+		t.Error("Up with missing applied migration should fail")
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		fsys fstest.MapFS
+	}{
+		{"missing direction", fstest.MapFS{"0001_a.sql": {}}},
+		{"missing name", fstest.MapFS{"0001.up.sql": {}}},
+		{"invalid version", fstest.MapFS{"x_a.up.sql": {}}},
+		{"zero version", fstest.MapFS{"0_a.up.sql": {}}},
+		{"missing up", fstest.MapFS{"0001_a.down.sql": {Data: []byte("DROP TABLE a;")}}},
+		{"duplicate version", fstest.MapFS{
+			"0001_a.up.sql": {Data: []byte("CREATE TABLE a (id INTEGER);")},
+			"0001_b.up.sql": {Data: []byte("CREATE TABLE b (id INTEGER);")},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Load(tt.fsys); err == nil {
+				t.Error("Load should fail")
+			}
+		})
+	}
+}
+
+// 内置的SQLite迁移可以完整执行和回滚
+func TestBuiltinSQLiteMigrations(t *testing.T) {
+	ctx := context.Background()
+	m, err := New(openTestDB(t))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	done, err := m.Up(ctx)
+	if err != nil {
+		t.Fatalf("Up: %v", err)
+	}
+	if len(done) != len(m.migrations) {
+		t.Fatalf("Up applied %d migrations, want %d", len(done), len(m.migrations))
+	}
+	if _, err := m.Redo(ctx); err != nil {
+		t.Fatalf("Redo: %v", err)
+	}
+
+	reverted, err := m.Down(ctx, len(m.migrations))
+	if err != nil {
+		t.Fatalf("Down: %v", err)
+	}
+	if len(reverted) != len(m.migrations) {
+		t.Fatalf("Down reverted %d migrations, want %d", len(reverted), len(m.migrations))
+	}
+	if pending, err := m.Pending(ctx); err != nil || pending != len(m.migrations) {
+		t.Fatalf("Pending after Down = (%d, %v), want %d", pending, err, len(m.migrations))
+	}
+
+	if _, err := m.Up(ctx); err != nil {
+		t.Fatalf("Up after Down: %v", err)
+	}
+}
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS organization (
+    id          BIGSERIAL PRIMARY KEY,
+    name        VARCHAR(100) NOT NULL UNIQUE,
+    description TEXT,
+    parent_id   BIGINT DEFAULT NULL,
+    created_by  BIGINT,
+    created_at  TIMESTAMPTZ,
+    updated_at  TIMESTAMPTZ
+);
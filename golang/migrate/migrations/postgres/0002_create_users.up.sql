@@ -0,0 +1,26 @@
+CREATE TABLE IF NOT EXISTS users (
+    id            BIGSERIAL PRIMARY KEY,
+    created_at    TIMESTAMPTZ,
+    updated_at    TIMESTAMPTZ,
+    deleted_at    TIMESTAMPTZ,
+    username      VARCHAR(50) NOT NULL UNIQUE,
+    password      VARCHAR(255) NOT NULL,
+    email         VARCHAR(100),
+    phone         VARCHAR(20),
+    last_login_at TIMESTAMPTZ,
+    is_active     BOOLEAN DEFAULT true,
+    role          VARCHAR(20) DEFAULT 'user',
+    org_id        BIGINT DEFAULT NULL,
+    created_by    BIGINT
+);
+
+CREATE INDEX IF NOT EXISTS idx_users_deleted_at ON users (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_users_org_id ON users (org_id);
+
+-- 删除组织时保留用户，只清空其组织ID
+ALTER TABLE users DROP CONSTRAINT IF EXISTS fk_users_org;
+ALTER TABLE users
+    ADD CONSTRAINT fk_users_org
+    FOREIGN KEY (org_id)
+    REFERENCES organization (id)
+    ON DELETE SET NULL;
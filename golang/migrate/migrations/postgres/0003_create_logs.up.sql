@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS logs (
+    id         BIGSERIAL PRIMARY KEY,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    deleted_at TIMESTAMPTZ,
+    user_id    BIGINT,
+    username   VARCHAR(50),
+    action     VARCHAR(50),
+    ip         VARCHAR(50),
+    timestamp  TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_logs_deleted_at ON logs (deleted_at);
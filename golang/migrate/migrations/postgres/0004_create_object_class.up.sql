@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS object_class (
+    id          BIGSERIAL PRIMARY KEY,
+    name        VARCHAR(255) NOT NULL,
+    description TEXT,
+    org_id      BIGINT NOT NULL REFERENCES organization (id),
+    parent_id   BIGINT REFERENCES object_class (id),
+    created_by  BIGINT NOT NULL REFERENCES users (id),
+    updated_at  TIMESTAMPTZ
+);
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS token_revocations (
+    id         BIGSERIAL PRIMARY KEY,
+    created_at TIMESTAMPTZ,
+    jti        VARCHAR(64),
+    user_id    BIGINT NOT NULL,
+    not_before TIMESTAMPTZ,
+    expires_at TIMESTAMPTZ NOT NULL,
+    reason     VARCHAR(50)
+);
+
+CREATE INDEX IF NOT EXISTS idx_token_revocations_expires_at ON token_revocations (expires_at);
@@ -0,0 +1,3 @@
+ALTER TABLE logs DROP COLUMN reason;
+
+DROP TABLE IF EXISTS login_attempts;
@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS login_attempts (
+    id             BIGSERIAL PRIMARY KEY,
+    created_at     TIMESTAMPTZ,
+    updated_at     TIMESTAMPTZ,
+    scope          VARCHAR(20) NOT NULL,
+    subject        VARCHAR(100) NOT NULL,
+    failures       BIGINT NOT NULL DEFAULT 0,
+    last_failed_at TIMESTAMPTZ NOT NULL,
+    locked_until   TIMESTAMPTZ
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_login_attempts_scope_subject ON login_attempts (scope, subject);
+CREATE INDEX IF NOT EXISTS idx_login_attempts_last_failed_at ON login_attempts (last_failed_at);
+
+ALTER TABLE logs ADD COLUMN reason VARCHAR(50);
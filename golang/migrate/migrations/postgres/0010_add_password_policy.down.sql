@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS org_password_policies;
+DROP TABLE IF EXISTS password_history;
+
+ALTER TABLE users DROP COLUMN password_changed_at;
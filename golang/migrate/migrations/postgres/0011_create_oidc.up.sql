@@ -0,0 +1,48 @@
+-- 各组织配置的OpenID Connect身份提供方，name用于登录地址，全局唯一
+CREATE TABLE IF NOT EXISTS oidc_providers (
+    id             BIGSERIAL PRIMARY KEY,
+    created_at     TIMESTAMPTZ,
+    updated_at     TIMESTAMPTZ,
+    org_id         BIGINT NOT NULL REFERENCES organization (id) ON DELETE CASCADE,
+    name           VARCHAR(50) NOT NULL,
+    display_name   VARCHAR(100) NOT NULL,
+    issuer         VARCHAR(255) NOT NULL,
+    client_id      VARCHAR(255) NOT NULL,
+    client_secret  VARCHAR(255) NOT NULL DEFAULT '',
+    scopes         VARCHAR(255) NOT NULL DEFAULT 'openid profile email',
+    enabled        BOOLEAN NOT NULL DEFAULT true,
+    link_by_email  BOOLEAN NOT NULL DEFAULT false,
+    auto_provision BOOLEAN NOT NULL DEFAULT false,
+    default_role   VARCHAR(20) NOT NULL DEFAULT 'user'
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_oidc_providers_name ON oidc_providers (name);
+CREATE INDEX IF NOT EXISTS idx_oidc_providers_org_id ON oidc_providers (org_id);
+
+-- 用户与身份提供方中的账号(sub)的关联
+CREATE TABLE IF NOT EXISTS user_identities (
+    id          BIGSERIAL PRIMARY KEY,
+    created_at  TIMESTAMPTZ,
+    user_id     BIGINT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    provider_id BIGINT NOT NULL REFERENCES oidc_providers (id) ON DELETE CASCADE,
+    subject     VARCHAR(255) NOT NULL,
+    email       VARCHAR(100)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_user_identities_provider_subject ON user_identities (provider_id, subject);
+CREATE INDEX IF NOT EXISTS idx_user_identities_user_id ON user_identities (user_id);
+
+-- 跳转到身份提供方期间保存的state、nonce和PKCE校验码
+CREATE TABLE IF NOT EXISTS oidc_login_states (
+    id            BIGSERIAL PRIMARY KEY,
+    created_at    TIMESTAMPTZ,
+    provider_id   BIGINT NOT NULL REFERENCES oidc_providers (id) ON DELETE CASCADE,
+    state_hash    VARCHAR(64) NOT NULL,
+    nonce         VARCHAR(64) NOT NULL,
+    code_verifier VARCHAR(128) NOT NULL,
+    ip            VARCHAR(50),
+    expires_at    TIMESTAMPTZ NOT NULL,
+    used_at       TIMESTAMPTZ
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_oidc_login_states_state_hash ON oidc_login_states (state_hash);
@@ -0,0 +1,33 @@
+-- 用户的认证方式：local为本地密码，ldap为所属组织的目录服务
+ALTER TABLE users ADD COLUMN auth_source VARCHAR(20) NOT NULL DEFAULT 'local';
+
+-- 各组织的LDAP/Active Directory目录服务，每个组织最多一个
+CREATE TABLE IF NOT EXISTS org_directories (
+    org_id               BIGINT PRIMARY KEY REFERENCES organization (id) ON DELETE CASCADE,
+    created_at           TIMESTAMPTZ,
+    updated_at           TIMESTAMPTZ,
+    enabled              BOOLEAN NOT NULL DEFAULT true,
+    url                  VARCHAR(255) NOT NULL,
+    start_tls            BOOLEAN NOT NULL DEFAULT false,
+    insecure_skip_verify BOOLEAN NOT NULL DEFAULT false,
+    bind_dn              VARCHAR(255) NOT NULL DEFAULT '',
+    bind_password        VARCHAR(255) NOT NULL DEFAULT '',
+    user_base_dn         VARCHAR(255) NOT NULL,
+    user_filter          VARCHAR(255) NOT NULL,
+    username_attribute   VARCHAR(50) NOT NULL,
+    email_attribute      VARCHAR(50) NOT NULL,
+    group_base_dn        VARCHAR(255) NOT NULL DEFAULT '',
+    group_filter         VARCHAR(255) NOT NULL DEFAULT '',
+    auto_provision       BOOLEAN NOT NULL DEFAULT false,
+    default_role         VARCHAR(20) NOT NULL DEFAULT ''
+);
+
+-- 目录中的组对应的角色
+CREATE TABLE IF NOT EXISTS directory_role_mappings (
+    id       BIGSERIAL PRIMARY KEY,
+    org_id   BIGINT NOT NULL REFERENCES org_directories (org_id) ON DELETE CASCADE,
+    group_dn VARCHAR(255) NOT NULL,
+    role     VARCHAR(20) NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_directory_role_mappings_org_id ON directory_role_mappings (org_id);
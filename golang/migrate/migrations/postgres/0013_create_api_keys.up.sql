@@ -0,0 +1,25 @@
+-- 服务账号只能使用API密钥访问，不能用密码登录
+ALTER TABLE users ADD COLUMN service_account BOOLEAN NOT NULL DEFAULT false;
+
+-- API密钥只保存哈希，prefix用于在列表中辨认密钥
+CREATE TABLE IF NOT EXISTS api_keys (
+    id           BIGSERIAL PRIMARY KEY,
+    created_at   TIMESTAMPTZ,
+    user_id      BIGINT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    name         VARCHAR(100) NOT NULL,
+    prefix       VARCHAR(20) NOT NULL,
+    key_hash     VARCHAR(64) NOT NULL,
+    scopes       VARCHAR(100) NOT NULL,
+    created_by   BIGINT,
+    expires_at   TIMESTAMPTZ NOT NULL,
+    last_used_at TIMESTAMPTZ,
+    last_used_ip VARCHAR(50),
+    revoked_at   TIMESTAMPTZ
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_api_keys_key_hash ON api_keys (key_hash);
+CREATE INDEX IF NOT EXISTS idx_api_keys_user_id ON api_keys (user_id);
+
+-- 使用API密钥的请求记录到日志，detail为请求方法、路径和状态码
+ALTER TABLE logs ADD COLUMN api_key_id BIGINT;
+ALTER TABLE logs ADD COLUMN detail VARCHAR(255);
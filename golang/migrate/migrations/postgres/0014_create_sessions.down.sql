@@ -0,0 +1,3 @@
+ALTER TABLE token_revocations DROP COLUMN session_id;
+
+DROP TABLE IF EXISTS sessions;
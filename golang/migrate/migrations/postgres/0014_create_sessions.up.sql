@@ -0,0 +1,20 @@
+-- 每次登录创建一个会话，对应一个刷新令牌族；token_id为会话最近签发的访问令牌的jti
+CREATE TABLE IF NOT EXISTS sessions (
+    id            BIGSERIAL PRIMARY KEY,
+    created_at    TIMESTAMPTZ,
+    user_id       BIGINT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    family_id     VARCHAR(64) NOT NULL,
+    token_id      VARCHAR(64),
+    ip            VARCHAR(50),
+    user_agent    VARCHAR(255),
+    last_seen_at  TIMESTAMPTZ,
+    expires_at    TIMESTAMPTZ NOT NULL,
+    revoked_at    TIMESTAMPTZ,
+    revoke_reason VARCHAR(50)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_sessions_family_id ON sessions (family_id);
+CREATE INDEX IF NOT EXISTS idx_sessions_user_id ON sessions (user_id);
+
+-- 吊销会话时该会话签发的所有访问令牌失效
+ALTER TABLE token_revocations ADD COLUMN session_id VARCHAR(64);
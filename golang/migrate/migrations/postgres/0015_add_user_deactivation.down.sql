@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_users_reenable_at;
+
+ALTER TABLE users DROP COLUMN reenable_at;
+ALTER TABLE users DROP COLUMN disabled_reason;
+ALTER TABLE users DROP COLUMN disabled_by;
+ALTER TABLE users DROP COLUMN disabled_at;
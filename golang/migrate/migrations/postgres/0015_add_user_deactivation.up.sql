@@ -0,0 +1,7 @@
+-- 禁用账号的原因和计划重新启用的时间，与删除（deleted_at）相互独立
+ALTER TABLE users ADD COLUMN disabled_at TIMESTAMPTZ;
+ALTER TABLE users ADD COLUMN disabled_by BIGINT;
+ALTER TABLE users ADD COLUMN disabled_reason VARCHAR(255);
+ALTER TABLE users ADD COLUMN reenable_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_users_reenable_at ON users (reenable_at);
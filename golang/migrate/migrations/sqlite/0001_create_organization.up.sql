@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS organization (
+    id          INTEGER PRIMARY KEY AUTOINCREMENT,
+    name        VARCHAR(100) NOT NULL UNIQUE,
+    description TEXT,
+    parent_id   INTEGER DEFAULT NULL,
+    created_by  INTEGER,
+    created_at  DATETIME,
+    updated_at  DATETIME
+);
@@ -0,0 +1,21 @@
+-- SQLite不支持ALTER TABLE ADD CONSTRAINT，外键直接在建表时声明
+CREATE TABLE IF NOT EXISTS users (
+    id            INTEGER PRIMARY KEY AUTOINCREMENT,
+    created_at    DATETIME,
+    updated_at    DATETIME,
+    deleted_at    DATETIME,
+    username      VARCHAR(50) NOT NULL UNIQUE,
+    password      VARCHAR(255) NOT NULL,
+    email         VARCHAR(100),
+    phone         VARCHAR(20),
+    last_login_at DATETIME,
+    is_active     BOOLEAN DEFAULT true,
+    role          VARCHAR(20) DEFAULT 'user',
+    org_id        INTEGER DEFAULT NULL,
+    created_by    INTEGER,
+    -- 删除组织时保留用户，只清空其组织ID
+    CONSTRAINT fk_users_org FOREIGN KEY (org_id) REFERENCES organization (id) ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_users_deleted_at ON users (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_users_org_id ON users (org_id);
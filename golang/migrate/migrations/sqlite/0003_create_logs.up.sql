@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS logs (
+    id         INTEGER PRIMARY KEY AUTOINCREMENT,
+    created_at DATETIME,
+    updated_at DATETIME,
+    deleted_at DATETIME,
+    user_id    INTEGER,
+    username   VARCHAR(50),
+    action     VARCHAR(50),
+    ip         VARCHAR(50),
+    timestamp  DATETIME
+);
+
+CREATE INDEX IF NOT EXISTS idx_logs_deleted_at ON logs (deleted_at);
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS object_class (
+    id          INTEGER PRIMARY KEY AUTOINCREMENT,
+    name        VARCHAR(255) NOT NULL,
+    description TEXT,
+    org_id      INTEGER NOT NULL REFERENCES organization (id),
+    parent_id   INTEGER REFERENCES object_class (id),
+    created_by  INTEGER NOT NULL REFERENCES users (id),
+    updated_at  DATETIME
+);
@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+    id         INTEGER PRIMARY KEY AUTOINCREMENT,
+    created_at DATETIME,
+    user_id    INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    family_id  VARCHAR(64) NOT NULL,
+    token_hash VARCHAR(64) NOT NULL,
+    ip         VARCHAR(50),
+    expires_at DATETIME NOT NULL,
+    used_at    DATETIME,
+    revoked_at DATETIME
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_refresh_tokens_token_hash ON refresh_tokens (token_hash);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_family_id ON refresh_tokens (family_id);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_user_id ON refresh_tokens (user_id);
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS token_revocations (
+    id         INTEGER PRIMARY KEY AUTOINCREMENT,
+    created_at DATETIME,
+    jti        VARCHAR(64),
+    user_id    INTEGER NOT NULL,
+    not_before DATETIME,
+    expires_at DATETIME NOT NULL,
+    reason     VARCHAR(50)
+);
+
+CREATE INDEX IF NOT EXISTS idx_token_revocations_expires_at ON token_revocations (expires_at);
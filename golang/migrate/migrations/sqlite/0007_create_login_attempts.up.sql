@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS login_attempts (
+    id             INTEGER PRIMARY KEY AUTOINCREMENT,
+    created_at     DATETIME,
+    updated_at     DATETIME,
+    scope          VARCHAR(20) NOT NULL,
+    subject        VARCHAR(100) NOT NULL,
+    failures       INTEGER NOT NULL DEFAULT 0,
+    last_failed_at DATETIME NOT NULL,
+    locked_until   DATETIME
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_login_attempts_scope_subject ON login_attempts (scope, subject);
+CREATE INDEX IF NOT EXISTS idx_login_attempts_last_failed_at ON login_attempts (last_failed_at);
+
+ALTER TABLE logs ADD COLUMN reason VARCHAR(50);
@@ -0,0 +1,25 @@
+-- 已有用户从迁移时开始计算密码有效期；为NULL表示必须在下次登录时修改密码
+ALTER TABLE users ADD COLUMN password_changed_at DATETIME;
+UPDATE users SET password_changed_at = CURRENT_TIMESTAMP;
+
+CREATE TABLE IF NOT EXISTS password_history (
+    id            INTEGER PRIMARY KEY AUTOINCREMENT,
+    created_at    DATETIME,
+    user_id       INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    password_hash VARCHAR(255) NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_password_history_user_id ON password_history (user_id);
+
+CREATE TABLE IF NOT EXISTS org_password_policies (
+    org_id            INTEGER PRIMARY KEY REFERENCES organization (id) ON DELETE CASCADE,
+    created_at        DATETIME,
+    updated_at        DATETIME,
+    min_length        INTEGER NOT NULL DEFAULT 0,
+    require_uppercase BOOLEAN NOT NULL DEFAULT false,
+    require_lowercase BOOLEAN NOT NULL DEFAULT false,
+    require_digit     BOOLEAN NOT NULL DEFAULT false,
+    require_symbol    BOOLEAN NOT NULL DEFAULT false,
+    history           INTEGER NOT NULL DEFAULT 0,
+    max_age_days      INTEGER NOT NULL DEFAULT 0
+);
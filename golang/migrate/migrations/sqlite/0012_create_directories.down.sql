@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS directory_role_mappings;
+DROP TABLE IF EXISTS org_directories;
+
+ALTER TABLE users DROP COLUMN auth_source;
@@ -0,0 +1,6 @@
+ALTER TABLE logs DROP COLUMN detail;
+ALTER TABLE logs DROP COLUMN api_key_id;
+
+DROP TABLE IF EXISTS api_keys;
+
+ALTER TABLE users DROP COLUMN service_account;
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS invitations;
+
+ALTER TABLE organization DROP COLUMN self_registration_role;
+ALTER TABLE organization DROP COLUMN self_registration;
@@ -0,0 +1,23 @@
+-- 组织是否开放自助注册，自助注册的用户使用组织设置的角色
+ALTER TABLE organization ADD COLUMN self_registration BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE organization ADD COLUMN self_registration_role VARCHAR(20) NOT NULL DEFAULT 'user';
+
+-- 邀请只保存令牌哈希，只能接受一次，重新发送时更换令牌
+CREATE TABLE IF NOT EXISTS invitations (
+    id               INTEGER PRIMARY KEY AUTOINCREMENT,
+    created_at       DATETIME,
+    updated_at       DATETIME,
+    org_id           INTEGER NOT NULL REFERENCES organization (id) ON DELETE CASCADE,
+    email            VARCHAR(100) NOT NULL,
+    role             VARCHAR(20) NOT NULL,
+    token_hash       VARCHAR(64) NOT NULL,
+    invited_by       INTEGER,
+    sent_at          DATETIME NOT NULL,
+    expires_at       DATETIME NOT NULL,
+    accepted_at      DATETIME,
+    accepted_user_id INTEGER REFERENCES users (id) ON DELETE SET NULL,
+    revoked_at       DATETIME
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_invitations_token_hash ON invitations (token_hash);
+CREATE INDEX IF NOT EXISTS idx_invitations_org_id ON invitations (org_id);
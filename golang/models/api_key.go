@@ -0,0 +1,53 @@
+package models
+
+import (
+	"net/http"
+	"strings"
+	"time"
+)
+
+// API密钥的权限范围
+const (
+	APIKeyScopeRead  = "read"  // 只能发起GET和HEAD请求
+	APIKeyScopeWrite = "write" // 可以发起所有请求
+	APIKeyScopeAdmin = "admin" // 可以访问管理员接口，用户本身必须是管理员
+)
+
+// APIKey 用户或服务账号的API密钥，只保存哈希，密钥只在创建时返回一次
+type APIKey struct {
+	ID         uint       `gorm:"primarykey" json:"id"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UserID     uint       `gorm:"not null;index" json:"user_id"`
+	Name       string     `gorm:"size:100;not null" json:"name"`
+	Prefix     string     `gorm:"size:20;not null" json:"prefix"` // 密钥开头的一段，用于辨认密钥
+	KeyHash    string     `gorm:"size:64;not null;uniqueIndex" json:"-"`
+	Scopes     string     `gorm:"size:100;not null" json:"scopes"` // 以空格分隔
+	CreatedBy  uint       `json:"created_by"`
+	ExpiresAt  time.Time  `gorm:"not null" json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	LastUsedIP string     `gorm:"size:50" json:"last_used_ip"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+}
+
+// TableName 指定表名
+func (APIKey) TableName() string {
+	return "api_keys"
+}
+
+// HasScope 密钥是否有指定的权限范围
+func (k *APIKey) HasScope(scope string) bool {
+	for _, s := range strings.Fields(k.Scopes) {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsMethod 密钥的权限范围是否允许该请求方法
+func (k *APIKey) AllowsMethod(method string) bool {
+	if k.HasScope(APIKeyScopeWrite) {
+		return true
+	}
+	return k.HasScope(APIKeyScopeRead) && (method == http.MethodGet || method == http.MethodHead)
+}
@@ -0,0 +1,74 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// OrgDirectory 组织配置的LDAP/Active Directory目录服务，每个组织最多一个
+type OrgDirectory struct {
+	OrgID              uint      `gorm:"primarykey;autoIncrement:false" json:"org_id"`
+	CreatedAt          time.Time `json:"created_at"`
+	UpdatedAt          time.Time `json:"updated_at"`
+	Enabled            bool      `gorm:"not null" json:"enabled"`      // 关闭后组织内使用目录认证的用户不能登录
+	URL                string    `gorm:"size:255;not null" json:"url"` // ldap://或ldaps://
+	StartTLS           bool      `gorm:"not null" json:"start_tls"`
+	InsecureSkipVerify bool      `gorm:"not null" json:"insecure_skip_verify"`
+	BindDN             string    `gorm:"size:255;not null" json:"bind_dn"` // 查询用户的服务账号，为空时匿名查询
+	BindPassword       string    `gorm:"size:255;not null" json:"-"`
+	UserBaseDN         string    `gorm:"size:255;not null" json:"user_base_dn"`
+	UserFilter         string    `gorm:"size:255;not null" json:"user_filter"` // {username}替换为登录用户名
+	UsernameAttribute  string    `gorm:"size:50;not null" json:"username_attribute"`
+	EmailAttribute     string    `gorm:"size:50;not null" json:"email_attribute"`
+	GroupBaseDN        string    `gorm:"size:255;not null" json:"group_base_dn"` // 为空时读取用户的memberOf属性
+	GroupFilter        string    `gorm:"size:255;not null" json:"group_filter"`  // {dn}替换为用户的DN
+	AutoProvision      bool      `gorm:"not null" json:"auto_provision"`         // 本地没有的用户首次登录时自动创建
+	DefaultRole        string    `gorm:"size:20;not null" json:"default_role"`   // 不属于任何映射的组时的角色，为空表示不允许登录
+
+	RoleMappings []DirectoryRoleMapping `gorm:"foreignKey:OrgID;references:OrgID" json:"role_mappings"`
+}
+
+// TableName 指定表名
+func (OrgDirectory) TableName() string {
+	return "org_directories"
+}
+
+// TenantColumn 组织管理员只能管理自己组织的目录服务
+func (OrgDirectory) TenantColumn() string {
+	return "org_id"
+}
+
+// RoleForGroups 按用户所属的组确定角色，admin优先；不属于任何映射的组时返回DefaultRole
+func (d *OrgDirectory) RoleForGroups(groups []string) string {
+	role := ""
+	for _, m := range d.RoleMappings {
+		for _, g := range groups {
+			// DN不区分大小写
+			if strings.EqualFold(strings.TrimSpace(g), m.GroupDN) && (role == "" || m.Role == "admin") {
+				role = m.Role
+			}
+		}
+	}
+	if role == "" {
+		return d.DefaultRole
+	}
+	return role
+}
+
+// DirectoryRoleMapping 目录中的组对应的角色
+type DirectoryRoleMapping struct {
+	ID      uint   `gorm:"primarykey" json:"id"`
+	OrgID   uint   `gorm:"not null;index" json:"-"`
+	GroupDN string `gorm:"column:group_dn;size:255;not null" json:"group_dn"`
+	Role    string `gorm:"size:20;not null" json:"role"`
+}
+
+// TableName 指定表名
+func (DirectoryRoleMapping) TableName() string {
+	return "directory_role_mappings"
+}
+
+// TenantColumn 映射随目录服务按组织隔离
+func (DirectoryRoleMapping) TenantColumn() string {
+	return "org_id"
+}
@@ -0,0 +1,56 @@
+package models
+
+import "time"
+
+// 邀请的状态，由各时间字段推算，不保存到数据库
+const (
+	InvitationPending  = "pending"
+	InvitationAccepted = "accepted"
+	InvitationRevoked  = "revoked"
+	InvitationExpired  = "expired"
+)
+
+// Invitation 组织管理员发出的邀请，被邀请人通过邮件中的链接设置密码并加入组织
+//
+// 只保存令牌哈希，只能接受一次；重新发送时更换令牌并重新计算有效期。
+type Invitation struct {
+	ID             uint       `gorm:"primarykey" json:"id"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+	OrgID          uint       `gorm:"not null;index" json:"org_id"`
+	Email          string     `gorm:"size:100;not null" json:"email"`
+	Role           string     `gorm:"size:20;not null" json:"role"` // 接受邀请后用户的角色
+	TokenHash      string     `gorm:"size:64;not null;uniqueIndex" json:"-"`
+	InvitedBy      uint       `json:"invited_by"`
+	SentAt         time.Time  `gorm:"not null" json:"sent_at"` // 最近一次发送的时间
+	ExpiresAt      time.Time  `gorm:"not null" json:"expires_at"`
+	AcceptedAt     *time.Time `json:"accepted_at"`
+	AcceptedUserID *uint      `json:"accepted_user_id"`
+	RevokedAt      *time.Time `json:"revoked_at"`
+
+	Status string `gorm:"-" json:"status"` // pending、accepted、revoked或expired
+}
+
+// TableName 指定表名
+func (Invitation) TableName() string {
+	return "invitations"
+}
+
+// TenantColumn 组织管理员只能看到自己组织的邀请
+func (Invitation) TenantColumn() string {
+	return "org_id"
+}
+
+// StatusAt 返回邀请在at时刻的状态
+func (i *Invitation) StatusAt(at time.Time) string {
+	switch {
+	case i.AcceptedAt != nil:
+		return InvitationAccepted
+	case i.RevokedAt != nil:
+		return InvitationRevoked
+	case !at.Before(i.ExpiresAt):
+		return InvitationExpired
+	default:
+		return InvitationPending
+	}
+}
@@ -15,8 +15,11 @@ type Log struct {
 
 	UserID    uint      `json:"user_id"`
 	Username  string    `gorm:"size:50" json:"username"`
-	Action    string    `gorm:"size:50" json:"action"` // login或logout
+	Action    string    `gorm:"size:50" json:"action"` // login、logout、login_failed、account_unlocked、password_reset_requested、password_reset或api_key_used等
+	Reason    string    `gorm:"size:50" json:"reason"` // 登录失败的原因
 	IP        string    `gorm:"size:50" json:"ip"`
+	APIKeyID  *uint     `gorm:"column:api_key_id" json:"api_key_id"` // 使用API密钥的请求对应的密钥
+	Detail    string    `gorm:"size:255" json:"detail"`              // 使用API密钥的请求的方法、路径和状态码
 	Timestamp time.Time `json:"timestamp"`
 }
 
@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// 登录失败计数的维度
+const (
+	LoginScopeUser = "user" // 按用户名计数，包括不存在的用户名
+	LoginScopeIP   = "ip"   // 按客户端IP计数
+)
+
+// LoginAttempt 连续登录失败记录，登录成功或管理员解锁后删除
+type LoginAttempt struct {
+	ID           uint       `gorm:"primarykey" json:"id"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+	Scope        string     `gorm:"size:20;not null;uniqueIndex:idx_login_attempts_scope_subject" json:"scope"`
+	Subject      string     `gorm:"size:100;not null;uniqueIndex:idx_login_attempts_scope_subject" json:"subject"` // 用户名或IP
+	Failures     int        `gorm:"not null" json:"failures"`
+	LastFailedAt time.Time  `gorm:"not null;index" json:"last_failed_at"`
+	LockedUntil  *time.Time `json:"locked_until"`
+}
+
+// TableName 指定表名
+func (LoginAttempt) TableName() string {
+	return "login_attempts"
+}
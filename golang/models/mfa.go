@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+// RecoveryCode 两步验证恢复码，只保存哈希，每个只能使用一次
+type RecoveryCode struct {
+	ID        uint       `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time  `json:"created_at"`
+	UserID    uint       `gorm:"not null;index" json:"user_id"`
+	CodeHash  string     `gorm:"size:64;not null" json:"-"`
+	UsedAt    *time.Time `json:"used_at"`
+}
+
+// TableName 指定表名
+func (RecoveryCode) TableName() string {
+	return "recovery_codes"
+}
+
+// MFAChallenge 密码验证通过后等待第二步验证的登录，只保存令牌的哈希
+type MFAChallenge struct {
+	ID        uint       `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time  `json:"created_at"`
+	UserID    uint       `gorm:"not null" json:"user_id"`
+	TokenHash string     `gorm:"size:64;not null;uniqueIndex" json:"-"`
+	IP        string     `gorm:"size:50" json:"ip"`
+	ExpiresAt time.Time  `gorm:"not null;index" json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at"`
+}
+
+// TableName 指定表名
+func (MFAChallenge) TableName() string {
+	return "mfa_challenges"
+}
@@ -22,3 +22,8 @@ type ObjectClass struct {
 func (ObjectClass) TableName() string {
 	return "object_class"
 }
+
+// TenantColumn 对象类按所属组织隔离
+func (ObjectClass) TenantColumn() string {
+	return "org_id"
+}
@@ -13,9 +13,24 @@ type Organization struct {
 	CreatedBy   uint      `json:"created_by"`
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
+
+	RequireAdminMFA bool `gorm:"column:require_admin_mfa;not null" json:"require_admin_mfa"` // 组织管理员必须启用两步验证
+
+	Registration OrgRegistration `gorm:"embedded" json:"registration"`
+}
+
+// OrgRegistration 组织的自助注册设置，默认只能通过邀请加入组织
+type OrgRegistration struct {
+	SelfRegistration     bool   `gorm:"column:self_registration;not null" json:"self_registration"`                                  // 允许任何人注册为该组织的用户
+	SelfRegistrationRole string `gorm:"column:self_registration_role;size:20;not null;default:'user'" json:"self_registration_role"` // 自助注册的用户的角色，客户端不能指定
 }
 
 // TableName 指定表名
 func (Organization) TableName() string {
 	return "organization"
 }
+
+// TenantColumn 组织内的用户只能看到自己的组织
+func (Organization) TenantColumn() string {
+	return "id"
+}
@@ -0,0 +1,40 @@
+package models
+
+import "time"
+
+// PasswordHistory 用户以前使用过的密码哈希，用于禁止重复使用最近的密码
+type PasswordHistory struct {
+	ID           uint      `gorm:"primarykey" json:"id"`
+	CreatedAt    time.Time `json:"created_at"`
+	UserID       uint      `gorm:"not null;index" json:"user_id"`
+	PasswordHash string    `gorm:"size:255;not null" json:"-"`
+}
+
+// TableName 指定表名
+func (PasswordHistory) TableName() string {
+	return "password_history"
+}
+
+// OrgPasswordPolicy 组织的密码策略，只能比全局策略更严格，零值表示沿用全局策略
+type OrgPasswordPolicy struct {
+	OrgID            uint      `gorm:"primarykey;autoIncrement:false" json:"org_id"`
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+	MinLength        int       `gorm:"not null" json:"min_length"`
+	RequireUppercase bool      `gorm:"not null" json:"require_uppercase"`
+	RequireLowercase bool      `gorm:"not null" json:"require_lowercase"`
+	RequireDigit     bool      `gorm:"not null" json:"require_digit"`
+	RequireSymbol    bool      `gorm:"not null" json:"require_symbol"`
+	History          int       `gorm:"not null" json:"history"`
+	MaxAgeDays       int       `gorm:"not null" json:"max_age_days"`
+}
+
+// TableName 指定表名
+func (OrgPasswordPolicy) TableName() string {
+	return "org_password_policies"
+}
+
+// TenantColumn 组织管理员只能查看和修改自己组织的密码策略
+func (OrgPasswordPolicy) TenantColumn() string {
+	return "org_id"
+}
@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// PasswordResetToken 自助重置密码的令牌，只保存哈希，只能使用一次
+type PasswordResetToken struct {
+	ID        uint       `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time  `json:"created_at"`
+	UserID    uint       `gorm:"not null;index" json:"user_id"`
+	TokenHash string     `gorm:"size:64;not null;uniqueIndex" json:"-"`
+	IP        string     `gorm:"size:50" json:"ip"` // 申请重置的IP
+	ExpiresAt time.Time  `gorm:"not null" json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at"`
+}
+
+// TableName 指定表名
+func (PasswordResetToken) TableName() string {
+	return "password_reset_tokens"
+}
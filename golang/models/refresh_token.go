@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// RefreshToken 刷新令牌，只保存令牌的哈希
+//
+// 每次刷新都会签发同一族(FamilyID)的新令牌并标记旧令牌已使用，
+// 已使用的令牌再次出现说明令牌泄露，此时整个族都会被吊销。
+type RefreshToken struct {
+	ID        uint       `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time  `json:"created_at"`
+	UserID    uint       `gorm:"not null;index" json:"user_id"`
+	FamilyID  string     `gorm:"size:64;not null;index" json:"family_id"`
+	TokenHash string     `gorm:"size:64;not null;uniqueIndex" json:"-"`
+	IP        string     `gorm:"size:50" json:"ip"`
+	ExpiresAt time.Time  `gorm:"not null" json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at"`    // 已轮换为新令牌的时间
+	RevokedAt *time.Time `json:"revoked_at"` // 被吊销的时间
+}
+
+// TableName 指定表名
+func (RefreshToken) TableName() string {
+	return "refresh_tokens"
+}
@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// Session 登录会话，每次登录创建一个，对应一个刷新令牌族
+//
+// 刷新令牌时更新TokenID、最近活动时间和IP；会话被吊销后该令牌族的刷新令牌和已签发的访问令牌都失效。
+type Session struct {
+	ID           uint       `gorm:"primarykey" json:"id"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UserID       uint       `gorm:"not null;index" json:"user_id"`
+	FamilyID     string     `gorm:"size:64;not null;uniqueIndex" json:"-"`
+	TokenID      string     `gorm:"size:64" json:"-"` // 最近签发的访问令牌的jti
+	IP           string     `gorm:"size:50" json:"ip"`
+	UserAgent    string     `gorm:"size:255" json:"user_agent"`
+	LastSeenAt   time.Time  `json:"last_seen_at"`
+	ExpiresAt    time.Time  `gorm:"not null" json:"expires_at"` // 刷新令牌的过期时间
+	RevokedAt    *time.Time `json:"revoked_at"`
+	RevokeReason string     `gorm:"size:50" json:"revoke_reason"`
+
+	Current bool `gorm:"-" json:"current"` // 是否是发起请求的会话
+}
+
+// TableName 指定表名
+func (Session) TableName() string {
+	return "sessions"
+}
@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// TokenRevocation 访问令牌吊销记录
+//
+// JTI不为空时吊销单个令牌；SessionID不为空时吊销该会话签发的所有令牌；否则吊销该用户在NotBefore之前签发的所有令牌。
+// 访问令牌过期后记录即可删除，因此ExpiresAt不晚于被吊销令牌的过期时间。
+type TokenRevocation struct {
+	ID        uint       `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time  `json:"created_at"`
+	JTI       string     `gorm:"column:jti;size:64" json:"jti"`
+	SessionID string     `gorm:"size:64" json:"session_id"`
+	UserID    uint       `gorm:"not null" json:"user_id"`
+	NotBefore *time.Time `json:"not_before"`
+	ExpiresAt time.Time  `gorm:"not null;index" json:"expires_at"`
+	Reason    string     `gorm:"size:50" json:"reason"` // logout、password_change、user_deleted、force_sign_out、session_revoked或session_limit
+}
+
+// TableName 指定表名
+func (TokenRevocation) TableName() string {
+	return "token_revocations"
+}
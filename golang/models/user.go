@@ -6,6 +6,12 @@ import (
 	"gorm.io/gorm"
 )
 
+// 用户的认证方式
+const (
+	AuthSourceLocal = "local" // 本地密码
+	AuthSourceLDAP  = "ldap"  // 所属组织的目录服务，本地不保存密码
+)
+
 // User 用户模型
 type User struct {
 	ID        uint           `gorm:"primarykey" json:"id"`
@@ -13,19 +19,43 @@ type User struct {
 	UpdatedAt time.Time      `json:"updated_at"`
 	DeletedAt gorm.DeletedAt `gorm:"index" json:"deleted_at"`
 
-	Username    string        `gorm:"size:50;not null;unique" json:"username"`
-	Password    string        `gorm:"size:255;not null" json:"-"` // 密码不返回给前端
-	Email       string        `gorm:"size:100" json:"email"`
-	Phone       string        `gorm:"size:20" json:"phone"`
-	LastLoginAt time.Time     `json:"last_login_at"`
-	IsActive    bool          `gorm:"default:true" json:"is_active"`
-	Role        string        `gorm:"size:20;default:'user'" json:"role"`                                     // admin或user
-	OrgID       *uint         `gorm:"index;default:null" json:"org_id"`                                       // 组织ID
-	Org         *Organization `gorm:"foreignKey:OrgID;references:ID;constraint:OnDelete:SET NULL" json:"org"` // 组织关联
-	CreatedBy   uint          `json:"created_by"`                                                             // 创建者ID
+	Username       string        `gorm:"size:50;not null;unique" json:"username"`
+	Password       string        `gorm:"size:255;not null" json:"-"` // 密码不返回给前端
+	Email          string        `gorm:"size:100" json:"email"`
+	Phone          string        `gorm:"size:20" json:"phone"`
+	LastLoginAt    time.Time     `json:"last_login_at"`
+	IsActive       bool          `gorm:"default:true" json:"is_active"`                                          // 为false表示已禁用，见DisabledAt
+	Role           string        `gorm:"size:20;default:'user'" json:"role"`                                     // admin或user
+	OrgID          *uint         `gorm:"index;default:null" json:"org_id"`                                       // 组织ID
+	Org            *Organization `gorm:"foreignKey:OrgID;references:ID;constraint:OnDelete:SET NULL" json:"org"` // 组织关联
+	CreatedBy      uint          `json:"created_by"`                                                             // 创建者ID
+	AuthSource     string        `gorm:"size:20;not null;default:local" json:"auth_source"`                      // 认证方式，见AuthSourceLocal
+	ServiceAccount bool          `gorm:"not null" json:"service_account"`                                        // 服务账号没有密码，只能使用API密钥
+
+	PasswordChangedAt *time.Time `json:"password_changed_at"` // 最近一次设置密码的时间，为空表示下次登录时必须修改密码
+
+	// 禁用的账号不能登录，已签发的令牌和API密钥都失效，可以重新启用；删除（DeletedAt）的账号视为不存在
+	DisabledAt     *time.Time `json:"disabled_at"`
+	DisabledBy     *uint      `json:"disabled_by"`
+	DisabledReason string     `gorm:"size:255" json:"disabled_reason"`
+	ReenableAt     *time.Time `gorm:"index" json:"reenable_at"` // 计划自动重新启用的时间
+
+	MFASecret   string `gorm:"column:mfa_secret;size:64" json:"-"`             // TOTP密钥（base32），已生成但未激活时MFAEnabled为false
+	MFAEnabled  bool   `gorm:"column:mfa_enabled;not null" json:"mfa_enabled"` // 是否已启用两步验证
+	MFALastStep int64  `gorm:"column:mfa_last_step;not null" json:"-"`         // 最近一次使用的TOTP时间步，防止验证码重放
 }
 
 // TableName 指定表名
 func (User) TableName() string {
 	return "users"
 }
+
+// IsPlatformAdmin 不属于任何组织的管理员，可以管理所有组织
+func (u *User) IsPlatformAdmin() bool {
+	return u.Role == "admin" && u.OrgID == nil
+}
+
+// TenantColumn 用户按所属组织隔离
+func (User) TenantColumn() string {
+	return "org_id"
+}
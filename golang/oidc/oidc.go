@@ -0,0 +1,393 @@
+// Package oidc OpenID Connect授权码流程（PKCE）的客户端
+//
+// 只实现登录需要的部分：读取发现文档、生成授权地址、用授权码换取令牌以及校验ID令牌。
+// 发现文档和公钥按签发者缓存，ID令牌中出现未知的kid时重新获取公钥，以支持身份提供方轮换密钥。
+package oidc
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	// metadataTTL 发现文档和公钥的缓存时间
+	metadataTTL = time.Hour
+	// keysRefreshInterval 因未知kid重新获取公钥的最短间隔，避免伪造的令牌导致频繁请求
+	keysRefreshInterval = time.Minute
+	// clockSkew 校验令牌时间时允许的时钟误差
+	clockSkew = time.Minute
+	// maxResponseSize 身份提供方响应的最大字节数
+	maxResponseSize = 1 << 20
+)
+
+// signingMethods 接受的ID令牌签名算法，不接受HS256和none
+var signingMethods = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512", "EdDSA"}
+
+// Provider 一个已在身份提供方登记的客户端
+type Provider struct {
+	Issuer       string
+	ClientID     string
+	ClientSecret string // 公开客户端为空，只使用PKCE
+	RedirectURL  string
+	Scopes       []string
+}
+
+// Metadata 发现文档中用到的字段，见OpenID Connect Discovery 1.0
+type Metadata struct {
+	Issuer                   string   `json:"issuer"`
+	AuthorizationEndpoint    string   `json:"authorization_endpoint"`
+	TokenEndpoint            string   `json:"token_endpoint"`
+	JWKSURI                  string   `json:"jwks_uri"`
+	TokenEndpointAuthMethods []string `json:"token_endpoint_auth_methods_supported"`
+}
+
+// Token 令牌端点的响应
+type Token struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	IDToken     string `json:"id_token"`
+	ExpiresIn   int64  `json:"expires_in"`
+}
+
+// Claims ID令牌中用到的声明
+type Claims struct {
+	jwt.RegisteredClaims
+	Nonce             string `json:"nonce"`
+	AuthorizedParty   string `json:"azp"`
+	Email             string `json:"email"`
+	EmailVerified     bool   `json:"email_verified"`
+	PreferredUsername string `json:"preferred_username"`
+	Name              string `json:"name"`
+}
+
+// Error 身份提供方返回的错误，见RFC 6749 5.2节
+type Error struct {
+	Code        string `json:"error"`
+	Description string `json:"error_description"`
+}
+
+// Error 实现error
+func (e *Error) Error() string {
+	if e.Description != "" {
+		return fmt.Sprintf("oidc: %s: %s", e.Code, e.Description)
+	}
+	return "oidc: " + e.Code
+}
+
+// Client 请求身份提供方并缓存发现文档和公钥，可以在多个goroutine中使用
+type Client struct {
+	http *http.Client
+
+	mu      sync.Mutex
+	issuers map[string]*issuerCache
+}
+
+// issuerCache 一个签发者的发现文档和公钥
+type issuerCache struct {
+	metadata   *Metadata
+	metadataAt time.Time
+	keys       map[string]interface{} // kid -> 公钥
+	keysAt     time.Time
+}
+
+// NewClient 创建Client，timeout为每次请求身份提供方的超时时间
+func NewClient(timeout time.Duration) *Client {
+	return &Client{
+		http:    &http.Client{Timeout: timeout},
+		issuers: make(map[string]*issuerCache),
+	}
+}
+
+// Discover 返回签发者的发现文档
+func (c *Client) Discover(ctx context.Context, issuer string) (*Metadata, error) {
+	c.mu.Lock()
+	cache := c.issuers[issuer]
+	if cache != nil && cache.metadata != nil && time.Since(cache.metadataAt) < metadataTTL {
+		metadata := cache.metadata
+		c.mu.Unlock()
+		return metadata, nil
+	}
+	c.mu.Unlock()
+
+	var metadata Metadata
+	endpoint := strings.TrimSuffix(issuer, "/") + "/.well-known/openid-configuration"
+	if err := c.getJSON(ctx, endpoint, &metadata); err != nil {
+		return nil, fmt.Errorf("oidc discovery: %w", err)
+	}
+	// 防止发现文档被替换为其他签发者的
+	if metadata.Issuer != issuer {
+		return nil, fmt.Errorf("oidc discovery: issuer %q does not match %q", metadata.Issuer, issuer)
+	}
+	if metadata.AuthorizationEndpoint == "" || metadata.TokenEndpoint == "" || metadata.JWKSURI == "" {
+		return nil, errors.New("oidc discovery: missing authorization_endpoint, token_endpoint or jwks_uri")
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cache = c.cache(issuer)
+	cache.metadata = &metadata
+	cache.metadataAt = time.Now()
+	return &metadata, nil
+}
+
+// AuthCodeURL 返回跳转到身份提供方登录的地址，challenge为PKCE的S256质询
+func (c *Client) AuthCodeURL(ctx context.Context, p Provider, state, nonce, challenge string) (string, error) {
+	metadata, err := c.Discover(ctx, p.Issuer)
+	if err != nil {
+		return "", err
+	}
+	endpoint, err := url.Parse(metadata.AuthorizationEndpoint)
+	if err != nil {
+		return "", fmt.Errorf("oidc: parse authorization endpoint: %w", err)
+	}
+
+	query := endpoint.Query()
+	query.Set("response_type", "code")
+	query.Set("client_id", p.ClientID)
+	query.Set("redirect_uri", p.RedirectURL)
+	query.Set("scope", strings.Join(p.Scopes, " "))
+	query.Set("state", state)
+	query.Set("nonce", nonce)
+	query.Set("code_challenge", challenge)
+	query.Set("code_challenge_method", "S256")
+	endpoint.RawQuery = query.Encode()
+	return endpoint.String(), nil
+}
+
+// Exchange 用授权码和PKCE校验码换取令牌
+func (c *Client) Exchange(ctx context.Context, p Provider, code, verifier string) (*Token, error) {
+	metadata, err := c.Discover(ctx, p.Issuer)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.RedirectURL},
+		"code_verifier": {verifier},
+	}
+	// 默认使用client_secret_basic，身份提供方只支持client_secret_post时放在表单中
+	basic := p.ClientSecret != ""
+	if basic && len(metadata.TokenEndpointAuthMethods) > 0 && !contains(metadata.TokenEndpointAuthMethods, "client_secret_basic") {
+		basic = false
+	}
+	if !basic {
+		form.Set("client_id", p.ClientID)
+		if p.ClientSecret != "" {
+			form.Set("client_secret", p.ClientSecret)
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, metadata.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if basic {
+		req.SetBasicAuth(url.QueryEscape(p.ClientID), url.QueryEscape(p.ClientSecret))
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("oidc token request: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		return nil, fmt.Errorf("oidc token response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		var oauthErr Error
+		if json.Unmarshal(body, &oauthErr) == nil && oauthErr.Code != "" {
+			return nil, &oauthErr
+		}
+		return nil, fmt.Errorf("oidc token request: unexpected status %s", resp.Status)
+	}
+
+	var token Token
+	if err := json.Unmarshal(body, &token); err != nil {
+		return nil, fmt.Errorf("oidc token response: %w", err)
+	}
+	if token.IDToken == "" {
+		return nil, errors.New("oidc token response: missing id_token")
+	}
+	return &token, nil
+}
+
+// VerifyIDToken 校验ID令牌的签名、签发者、受众、有效期和nonce
+func (c *Client) VerifyIDToken(ctx context.Context, p Provider, raw, nonce string) (*Claims, error) {
+	var claims Claims
+	_, err := jwt.ParseWithClaims(raw, &claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return c.key(ctx, p.Issuer, kid)
+	},
+		jwt.WithValidMethods(signingMethods),
+		jwt.WithIssuer(p.Issuer),
+		jwt.WithAudience(p.ClientID),
+		jwt.WithLeeway(clockSkew),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("oidc: invalid id token: %w", err)
+	}
+
+	if claims.ExpiresAt == nil {
+		return nil, errors.New("oidc: id token has no exp")
+	}
+	if claims.Subject == "" {
+		return nil, errors.New("oidc: id token has no sub")
+	}
+	// 有多个受众时azp必须是本客户端
+	if len(claims.Audience) > 1 && claims.AuthorizedParty != p.ClientID {
+		return nil, fmt.Errorf("oidc: id token azp %q does not match client", claims.AuthorizedParty)
+	}
+	if claims.Nonce != nonce {
+		return nil, errors.New("oidc: id token nonce mismatch")
+	}
+	return &claims, nil
+}
+
+// key 返回签发者kid对应的公钥，未知的kid会重新获取一次公钥
+func (c *Client) key(ctx context.Context, issuer, kid string) (interface{}, error) {
+	var (
+		keys        map[string]interface{}
+		fresh       bool
+		refreshable = true
+	)
+	c.mu.Lock()
+	if cache := c.issuers[issuer]; cache != nil && cache.keys != nil {
+		keys = cache.keys
+		fresh = time.Since(cache.keysAt) < metadataTTL
+		refreshable = time.Since(cache.keysAt) >= keysRefreshInterval
+	}
+	c.mu.Unlock()
+
+	if key, ok := lookupKey(keys, kid); ok && (fresh || !refreshable) {
+		return key, nil
+	}
+	if !refreshable {
+		return nil, fmt.Errorf("unknown key id %q", kid)
+	}
+
+	keys, err := c.fetchKeys(ctx, issuer)
+	if err != nil {
+		return nil, err
+	}
+	if key, ok := lookupKey(keys, kid); ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown key id %q", kid)
+}
+
+// fetchKeys 重新获取签发者的公钥
+func (c *Client) fetchKeys(ctx context.Context, issuer string) (map[string]interface{}, error) {
+	metadata, err := c.Discover(ctx, issuer)
+	if err != nil {
+		return nil, err
+	}
+
+	var set jwkSet
+	if err := c.getJSON(ctx, metadata.JWKSURI, &set); err != nil {
+		return nil, fmt.Errorf("oidc jwks: %w", err)
+	}
+	keys := make(map[string]interface{}, len(set.Keys))
+	for _, jwk := range set.Keys {
+		// 跳过加密用的公钥和不支持的类型
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.publicKey()
+		if err != nil {
+			continue
+		}
+		keys[jwk.Kid] = key
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cache := c.cache(issuer)
+	cache.keys = keys
+	cache.keysAt = time.Now()
+	return keys, nil
+}
+
+// cache 返回签发者的缓存，调用方需持有锁
+func (c *Client) cache(issuer string) *issuerCache {
+	cache := c.issuers[issuer]
+	if cache == nil {
+		cache = &issuerCache{}
+		c.issuers[issuer] = cache
+	}
+	return cache
+}
+
+// getJSON 请求url并解析JSON响应
+func (c *Client) getJSON(ctx context.Context, endpoint string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: unexpected status %s", endpoint, resp.Status)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, maxResponseSize)).Decode(v)
+}
+
+// lookupKey 按kid查找公钥，令牌没有kid且只有一个公钥时使用该公钥
+func lookupKey(keys map[string]interface{}, kid string) (interface{}, bool) {
+	if key, ok := keys[kid]; ok {
+		return key, true
+	}
+	if kid == "" && len(keys) == 1 {
+		for _, key := range keys {
+			return key, true
+		}
+	}
+	return nil, false
+}
+
+// NewVerifier 生成PKCE校验码，见RFC 7636
+func NewVerifier() (string, error) {
+	return randomString(32)
+}
+
+// NewState 生成state或nonce
+func NewState() (string, error) {
+	return randomString(32)
+}
+
+// S256Challenge 计算PKCE校验码的S256质询
+func S256Challenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
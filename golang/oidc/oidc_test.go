@@ -0,0 +1,226 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	testClientID     = "xzyq"
+	testClientSecret = "secret"
+	testKid          = "test-key"
+)
+
+// mockIdP 本地模拟的身份提供方，颁发授权码并校验PKCE
+type mockIdP struct {
+	*httptest.Server
+	key *rsa.PrivateKey
+
+	mu    sync.Mutex
+	codes map[string]pendingCode
+	// claims 修改即将签发的ID令牌
+	claims func(*Claims)
+}
+
+type pendingCode struct {
+	challenge string
+	nonce     string
+}
+
+func newMockIdP(t *testing.T) *mockIdP {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	idp := &mockIdP{key: key, codes: make(map[string]pendingCode)}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, Metadata{
+			Issuer:                idp.URL,
+			AuthorizationEndpoint: idp.URL + "/authorize",
+			TokenEndpoint:         idp.URL + "/token",
+			JWKSURI:               idp.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, jwkSet{Keys: []jwk{{
+			Kty: "RSA",
+			Kid: testKid,
+			Use: "sig",
+			N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", idp.token)
+	idp.Server = httptest.NewServer(mux)
+	t.Cleanup(idp.Close)
+	return idp
+}
+
+// authorize 模拟用户在身份提供方登录成功，返回授权码
+func (idp *mockIdP) authorize(t *testing.T, authURL string) (code, state string) {
+	u, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := u.Query()
+	if q.Get("code_challenge_method") != "S256" || q.Get("client_id") != testClientID {
+		t.Fatalf("unexpected authorization request %s", authURL)
+	}
+
+	code, err = randomString(16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	idp.mu.Lock()
+	idp.codes[code] = pendingCode{challenge: q.Get("code_challenge"), nonce: q.Get("nonce")}
+	idp.mu.Unlock()
+	return code, q.Get("state")
+}
+
+func (idp *mockIdP) token(w http.ResponseWriter, r *http.Request) {
+	if id, secret, ok := r.BasicAuth(); !ok || id != testClientID || secret != testClientSecret {
+		writeJSON(w, http.StatusUnauthorized, Error{Code: "invalid_client"})
+		return
+	}
+
+	idp.mu.Lock()
+	pending, ok := idp.codes[r.PostFormValue("code")]
+	delete(idp.codes, r.PostFormValue("code"))
+	idp.mu.Unlock()
+	if !ok || S256Challenge(r.PostFormValue("code_verifier")) != pending.challenge {
+		writeJSON(w, http.StatusBadRequest, Error{Code: "invalid_grant"})
+		return
+	}
+
+	now := time.Now()
+	claims := Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    idp.URL,
+			Subject:   "alice-sub",
+			Audience:  jwt.ClaimStrings{testClientID},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(5 * time.Minute)),
+		},
+		Nonce:         pending.nonce,
+		Email:         "alice@example.com",
+		EmailVerified: true,
+	}
+	if idp.claims != nil {
+		idp.claims(&claims)
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = testKid
+	signed, err := token.SignedString(idp.key)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, Error{Code: "server_error"})
+		return
+	}
+	writeJSON(w, http.StatusOK, Token{AccessToken: "at", TokenType: "Bearer", IDToken: signed, ExpiresIn: 300})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// login 走一遍授权码流程，返回ID令牌的校验结果
+func login(t *testing.T, client *Client, p Provider, idp *mockIdP, tamper func(verifier, nonce *string)) (*Claims, error) {
+	ctx := context.Background()
+	verifier, err := NewVerifier()
+	if err != nil {
+		t.Fatal(err)
+	}
+	state, _ := NewState()
+	nonce, _ := NewState()
+
+	authURL, err := client.AuthCodeURL(ctx, p, state, nonce, S256Challenge(verifier))
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, gotState := idp.authorize(t, authURL)
+	if gotState != state {
+		t.Fatalf("state = %q, want %q", gotState, state)
+	}
+
+	if tamper != nil {
+		tamper(&verifier, &nonce)
+	}
+	token, err := client.Exchange(ctx, p, code, verifier)
+	if err != nil {
+		return nil, err
+	}
+	return client.VerifyIDToken(ctx, p, token.IDToken, nonce)
+}
+
+func TestAuthorizationCodeFlow(t *testing.T) {
+	idp := newMockIdP(t)
+	client := NewClient(5 * time.Second)
+	p := Provider{
+		Issuer:       idp.URL,
+		ClientID:     testClientID,
+		ClientSecret: testClientSecret,
+		RedirectURL:  "http://localhost:8081/oidc/callback",
+		Scopes:       []string{"openid", "email"},
+	}
+
+	claims, err := login(t, client, p, idp, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.Subject != "alice-sub" || claims.Email != "alice@example.com" || !claims.EmailVerified {
+		t.Errorf("unexpected claims %+v", claims)
+	}
+
+	// PKCE校验码不匹配时身份提供方拒绝换取令牌
+	_, err = login(t, client, p, idp, func(verifier, nonce *string) { *verifier += "x" })
+	var oauthErr *Error
+	if !errors.As(err, &oauthErr) || oauthErr.Code != "invalid_grant" {
+		t.Errorf("wrong verifier: err = %v, want invalid_grant", err)
+	}
+}
+
+func TestVerifyIDTokenRejects(t *testing.T) {
+	idp := newMockIdP(t)
+	client := NewClient(5 * time.Second)
+	p := Provider{Issuer: idp.URL, ClientID: testClientID, ClientSecret: testClientSecret, Scopes: []string{"openid"}}
+
+	cases := map[string]struct {
+		claims func(*Claims)
+		tamper func(verifier, nonce *string)
+	}{
+		"nonce mismatch": {tamper: func(verifier, nonce *string) { *nonce = "other" }},
+		"wrong audience": {claims: func(c *Claims) { c.Audience = jwt.ClaimStrings{"someone-else"} }},
+		"wrong issuer":   {claims: func(c *Claims) { c.Issuer = "https://evil.example.com" }},
+		"expired":        {claims: func(c *Claims) { c.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Hour)) }},
+		"no expiry":      {claims: func(c *Claims) { c.ExpiresAt = nil }},
+		"azp mismatch": {claims: func(c *Claims) {
+			c.Audience = jwt.ClaimStrings{testClientID, "other"}
+			c.AuthorizedParty = "other"
+		}},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			idp.claims = tc.claims
+			defer func() { idp.claims = nil }()
+			if _, err := login(t, client, p, idp, tc.tamper); err == nil {
+				t.Error("expected id token to be rejected")
+			}
+		})
+	}
+}
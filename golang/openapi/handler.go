@@ -0,0 +1,30 @@
+package openapi
+
+import (
+	_ "embed"
+	"encoding/json"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+//go:embed docs.html
+var docsPage []byte
+
+// Handler 返回输出文档JSON的处理函数，文档只序列化一次
+func Handler(d *Document) gin.HandlerFunc {
+	body, err := json.Marshal(d)
+	if err != nil {
+		panic("openapi: marshal document: " + err.Error())
+	}
+	return func(c *gin.Context) {
+		c.Data(http.StatusOK, "application/json; charset=utf-8", body)
+	}
+}
+
+// DocsHandler 返回内置的接口文档页面，页面从同目录下的openapi.json读取文档
+func DocsHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Data(http.StatusOK, "text/html; charset=utf-8", docsPage)
+	}
+}
@@ -0,0 +1,237 @@
+package openapi
+
+import (
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Version 生成的OpenAPI规范版本
+const Version = "3.0.3"
+
+// bearerScheme 认证方案名称
+const bearerScheme = "bearerAuth"
+
+// Document OpenAPI文档
+type Document struct {
+	OpenAPI    string               `json:"openapi"`
+	Info       Info                 `json:"info"`
+	Paths      map[string]*PathItem `json:"paths"`
+	Components Components           `json:"components"`
+
+	schemas    *generator
+	errorModel *Schema
+}
+
+// Info 文档基本信息
+type Info struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version"`
+}
+
+// PathItem 路径下各HTTP方法的操作，键为小写的方法名
+type PathItem map[string]*Operation
+
+// Operation 一个接口
+type Operation struct {
+	Tags        []string              `json:"tags,omitempty"`
+	Summary     string                `json:"summary,omitempty"`
+	OperationID string                `json:"operationId,omitempty"`
+	Parameters  []Parameter           `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]*Response  `json:"responses"`
+	Security    []map[string][]string `json:"security,omitempty"`
+}
+
+// Parameter 路径或查询参数
+type Parameter struct {
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required"`
+	Schema   *Schema `json:"schema"`
+}
+
+// RequestBody 请求体
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+// Response 响应
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+// MediaType 请求体或响应的内容
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Components 可复用的定义
+type Components struct {
+	Schemas         map[string]*Schema         `json:"schemas"`
+	SecuritySchemes map[string]*SecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+// SecurityScheme 认证方案
+type SecurityScheme struct {
+	Type         string `json:"type"`
+	Scheme       string `json:"scheme,omitempty"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+}
+
+// Route 一个路由的文档描述
+type Route struct {
+	Method  string
+	Path    string // gin格式的路径，例如 /api/users/:id
+	Summary string
+	Tags    []string
+	Auth    bool // 需要Bearer令牌
+
+	Request  interface{} // 请求体示例值，nil表示没有请求体
+	Response interface{} // 成功响应示例值，nil表示没有响应体
+	Status   int         // 成功响应的状态码，默认200
+
+	// ContentType 成功响应的内容类型，默认application/json
+	ContentType string
+	// Responses 除成功和错误外的其他响应，键为状态码
+	Responses map[int]interface{}
+}
+
+// New 创建文档，errorModel为所有接口共用的错误响应，在components中命名为Error
+func New(info Info, errorModel interface{}) *Document {
+	d := &Document{
+		OpenAPI: Version,
+		Info:    info,
+		Paths:   make(map[string]*PathItem),
+		Components: Components{
+			Schemas: make(map[string]*Schema),
+			SecuritySchemes: map[string]*SecurityScheme{
+				bearerScheme: {Type: "http", Scheme: "bearer", BearerFormat: "JWT"},
+			},
+		},
+	}
+	d.schemas = newGenerator(d.Components.Schemas)
+	if errorModel != nil {
+		d.errorModel = d.schemas.named(reflect.TypeOf(errorModel), "Error")
+	}
+	return d
+}
+
+// Add 添加一个路由
+func (d *Document) Add(r Route) {
+	path, params := convertPath(r.Path)
+
+	op := &Operation{
+		Tags:        r.Tags,
+		Summary:     r.Summary,
+		OperationID: operationID(r.Method, path),
+		Parameters:  params,
+		Responses:   make(map[string]*Response),
+	}
+
+	if r.Request != nil {
+		op.RequestBody = &RequestBody{
+			Required: true,
+			Content:  map[string]MediaType{"application/json": {Schema: d.schemaFor(r.Request)}},
+		}
+	}
+
+	status := r.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	op.Responses[strconv.Itoa(status)] = d.response(status, r.Response, r.ContentType)
+	for code, body := range r.Responses {
+		op.Responses[strconv.Itoa(code)] = d.response(code, body, "")
+	}
+	if d.errorModel != nil {
+		op.Responses["default"] = &Response{
+			Description: "错误",
+			Content:     map[string]MediaType{"application/json": {Schema: d.errorModel}},
+		}
+	}
+
+	if r.Auth {
+		op.Security = []map[string][]string{{bearerScheme: {}}}
+	}
+
+	item, ok := d.Paths[path]
+	if !ok {
+		item = &PathItem{}
+		d.Paths[path] = item
+	}
+	(*item)[strings.ToLower(r.Method)] = op
+}
+
+// Has 判断文档中是否包含gin格式路径对应的操作
+func (d *Document) Has(method, ginPath string) bool {
+	path, _ := convertPath(ginPath)
+	item, ok := d.Paths[path]
+	if !ok {
+		return false
+	}
+	_, ok = (*item)[strings.ToLower(method)]
+	return ok
+}
+
+// schemaFor 返回示例值对应的Schema
+func (d *Document) schemaFor(v interface{}) *Schema {
+	return d.schemas.schemaOf(reflect.TypeOf(v))
+}
+
+// response 构造响应，body为nil时没有响应体
+func (d *Document) response(status int, body interface{}, contentType string) *Response {
+	resp := &Response{Description: http.StatusText(status)}
+	if contentType == "" {
+		contentType = "application/json"
+	}
+	switch {
+	case body != nil:
+		resp.Content = map[string]MediaType{contentType: {Schema: d.schemaFor(body)}}
+	case contentType != "application/json":
+		resp.Content = map[string]MediaType{contentType: {Schema: &Schema{Type: "string"}}}
+	}
+	return resp
+}
+
+// convertPath 将gin的 :param 和 *param 转换为OpenAPI的 {param}，并返回路径参数
+func convertPath(ginPath string) (string, []Parameter) {
+	segments := strings.Split(ginPath, "/")
+	var params []Parameter
+	for i, seg := range segments {
+		if seg == "" || (seg[0] != ':' && seg[0] != '*') {
+			continue
+		}
+		name := seg[1:]
+		segments[i] = "{" + name + "}"
+		params = append(params, Parameter{
+			Name:     name,
+			In:       "path",
+			Required: true,
+			Schema:   paramSchema(name),
+		})
+	}
+	return strings.Join(segments, "/"), params
+}
+
+// paramSchema ID类参数为整数，其他为字符串
+func paramSchema(name string) *Schema {
+	if name == "id" || strings.HasSuffix(name, "_id") {
+		return &Schema{Type: "integer", Format: "int64", Minimum: ptr(1)}
+	}
+	return &Schema{Type: "string"}
+}
+
+// operationID 由方法和路径生成唯一的操作ID，例如 get_api_users_id
+func operationID(method, path string) string {
+	replacer := strings.NewReplacer("/", "_", "{", "", "}", "", "-", "_", ".", "_")
+	return strings.ToLower(method) + replacer.Replace(strings.TrimSuffix(path, "/"))
+}
+
+func ptr(v float64) *float64 {
+	return &v
+}
@@ -0,0 +1,68 @@
+// Package rehash 检查和升级密码哈希
+//
+// 登录成功时会自动把较弱的哈希升级为当前算法，这里用于查看还有多少账号没有升级，
+// 以及一次性处理早期直接保存的明文密码。
+package rehash
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+	"xzyq/service"
+)
+
+// Usage rehash子命令的帮助信息
+const Usage = `usage: rehash <command>
+
+commands:
+  status     show how many accounts use each password hash scheme
+  plaintext  hash passwords that are still stored as plaintext`
+
+// Run 执行rehash子命令
+func Run(ctx context.Context, passwords *service.PasswordService, args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return fmt.Errorf("missing rehash command\n%s", Usage)
+	}
+
+	switch args[0] {
+	case "status":
+		counts, err := passwords.SchemeCounts(ctx)
+		if err != nil {
+			return err
+		}
+		schemes := make([]string, 0, len(counts))
+		legacy := 0
+		for scheme, count := range counts {
+			schemes = append(schemes, scheme)
+			if scheme != passwords.PreferredScheme() {
+				legacy += count
+			}
+		}
+		sort.Strings(schemes)
+
+		w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "SCHEME\tACCOUNTS")
+		for _, scheme := range schemes {
+			name := scheme
+			if name == "" {
+				name = "unknown"
+			}
+			fmt.Fprintf(w, "%s\t%d\n", name, counts[scheme])
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "%d accounts remain on legacy schemes\n", legacy)
+		return nil
+
+	case "plaintext":
+		hashed, err := passwords.HashPlaintext(ctx)
+		fmt.Fprintf(out, "hashed %d plaintext passwords with %s\n", hashed, passwords.PreferredScheme())
+		return err
+
+	default:
+		return fmt.Errorf("unknown rehash command %q\n%s", args[0], Usage)
+	}
+}
@@ -0,0 +1,215 @@
+package routes
+
+import (
+	"net/http"
+	"xzyq/apperr"
+	"xzyq/handlers"
+	"xzyq/health"
+	"xzyq/models"
+	"xzyq/openapi"
+	"xzyq/service"
+	"xzyq/utils"
+)
+
+// Spec 返回所有路由的OpenAPI文档，新增路由时需要同步在这里登记
+func Spec() *openapi.Document {
+	doc := openapi.New(openapi.Info{
+		Title:       "xzyq API",
+		Description: "用户、组织和对象类管理接口。错误响应统一为 {code, message, details, request_id}。",
+		Version:     health.Version,
+	}, apperr.Response{})
+
+	for _, r := range specRoutes() {
+		doc.Add(r)
+	}
+	return doc
+}
+
+// specRoutes 各路由的文档描述
+func specRoutes() []openapi.Route {
+	var (
+		user    = []string{"用户"}
+		profile = []string{"个人资料"}
+		org     = []string{"组织"}
+		class   = []string{"对象类"}
+		mfa     = []string{"两步验证"}
+		sso     = []string{"单点登录"}
+		apiKey  = []string{"API密钥"}
+		invite  = []string{"邀请"}
+		system  = []string{"系统"}
+	)
+
+	return []openapi.Route{
+		// 系统
+		{Method: http.MethodGet, Path: "/metrics", Summary: "Prometheus指标", Tags: system, ContentType: "text/plain"},
+		{Method: http.MethodGet, Path: "/healthz", Summary: "存活检查", Tags: system, Response: handlers.LivenessResponse{}},
+		{Method: http.MethodGet, Path: "/readyz", Summary: "就绪检查", Tags: system, Response: health.Report{},
+			Responses: map[int]interface{}{http.StatusServiceUnavailable: health.Report{}}},
+		{Method: http.MethodGet, Path: "/.well-known/jwks.json", Summary: "验证访问令牌的公钥（JWKS），使用HS256时为空", Tags: system,
+			Response: utils.JWKSet{}},
+		{Method: http.MethodGet, Path: "/api/openapi.json", Summary: "OpenAPI文档", Tags: system, Response: map[string]interface{}{}},
+		{Method: http.MethodGet, Path: "/api/docs", Summary: "接口文档页面", Tags: system, ContentType: "text/html"},
+
+		// 用户
+		{Method: http.MethodPost, Path: "/api/register", Summary: "自助注册为组织的用户，组织需要开放自助注册，角色由组织设置；密码需要符合密码策略", Tags: user,
+			Request: handlers.RegisterRequest{}, Response: handlers.RegisterResponse{}, Status: http.StatusCreated},
+		{Method: http.MethodPost, Path: "/api/login", Summary: "登录，连续失败多次后需要等待或被临时锁定；需要两步验证时只返回mfa；密码过期时返回PASSWORD_EXPIRED；目录用户首次登录需提供org_id", Tags: user,
+			Request: handlers.LoginRequest{}, Response: handlers.LoginResponse{}},
+		{Method: http.MethodPost, Path: "/api/login/mfa", Summary: "登录第二步，提交TOTP验证码或恢复码", Tags: mfa,
+			Request: handlers.LoginMFARequest{}, Response: handlers.LoginResponse{}},
+		{Method: http.MethodPost, Path: "/api/login/mfa/enroll", Summary: "登录过程中设置两步验证（组织要求启用时）", Tags: mfa,
+			Request: handlers.LoginMFAEnrollRequest{}, Response: service.MFAEnrollment{}},
+		{Method: http.MethodGet, Path: "/api/login/oidc/providers", Summary: "登录页面可用的身份提供方", Tags: sso,
+			Response: []service.OIDCProviderInfo{}},
+		{Method: http.MethodPost, Path: "/api/login/oidc/providers/:name/authorize", Summary: "开始单点登录，返回跳转到身份提供方的地址（授权码+PKCE）", Tags: sso,
+			Response: handlers.OIDCAuthorizeResponse{}},
+		{Method: http.MethodPost, Path: "/api/login/oidc/callback", Summary: "提交身份提供方返回的state和code完成登录，响应与密码登录相同", Tags: sso,
+			Request: handlers.OIDCCallbackRequest{}, Response: handlers.LoginResponse{}},
+		{Method: http.MethodPost, Path: "/api/token/refresh", Summary: "刷新令牌，旧的刷新令牌随即失效", Tags: user,
+			Request: handlers.RefreshRequest{}, Response: service.TokenPair{}},
+		{Method: http.MethodPost, Path: "/api/password/forgot", Summary: "忘记密码，向邮箱发送重置链接；邮箱不存在时同样返回成功", Tags: user,
+			Request: handlers.ForgotPasswordRequest{}, Response: handlers.MessageResponse{}},
+		{Method: http.MethodPost, Path: "/api/password/reset", Summary: "使用邮件中的令牌重置密码，成功后吊销该用户的所有令牌", Tags: user,
+			Request: handlers.ResetPasswordRequest{}, Response: handlers.MessageResponse{}},
+		{Method: http.MethodPost, Path: "/api/password/expired", Summary: "登录时密码已过期，使用原密码设置新密码后重新登录", Tags: user,
+			Request: handlers.ChangeExpiredPasswordRequest{}, Response: handlers.MessageResponse{}},
+		{Method: http.MethodPost, Path: "/api/logout", Summary: "退出登录", Tags: user, Auth: true,
+			Request: handlers.LogoutRequest{}, Response: handlers.MessageResponse{}},
+		{Method: http.MethodGet, Path: "/api/users", Summary: "用户列表", Tags: user, Auth: true,
+			Response: []models.User{}},
+		{Method: http.MethodPost, Path: "/api/users", Summary: "创建用户（管理员），用户首次登录时必须修改密码", Tags: user, Auth: true,
+			Request: handlers.CreateUserRequest{}, Response: models.User{}, Status: http.StatusCreated},
+		{Method: http.MethodGet, Path: "/api/users/:id", Summary: "用户详情", Tags: user, Auth: true,
+			Response: models.User{}},
+		{Method: http.MethodPut, Path: "/api/users/:id", Summary: "更新用户（管理员），角色或组织变化时用户需要重新登录", Tags: user, Auth: true,
+			Request: handlers.UpdateUserRequest{}, Response: models.User{}},
+		{Method: http.MethodDelete, Path: "/api/users/:id", Summary: "删除用户（管理员）", Tags: user, Auth: true,
+			Response: handlers.MessageResponse{}},
+		{Method: http.MethodPost, Path: "/api/admin/users/:id/sign-out", Summary: "强制用户在所有设备上退出登录（管理员）", Tags: user, Auth: true,
+			Response: handlers.MessageResponse{}},
+		{Method: http.MethodPost, Path: "/api/admin/users/:id/unlock", Summary: "解锁因连续登录失败被锁定的用户（管理员）", Tags: user, Auth: true,
+			Response: handlers.MessageResponse{}},
+		{Method: http.MethodPost, Path: "/api/admin/users/:id/disable", Summary: "禁用用户，已签发的令牌和API密钥立即失效（管理员）", Tags: user, Auth: true,
+			Request: handlers.DisableUserRequest{}, Response: models.User{}},
+		{Method: http.MethodPost, Path: "/api/admin/users/:id/enable", Summary: "重新启用被禁用的用户（管理员）", Tags: user, Auth: true,
+			Response: models.User{}},
+		{Method: http.MethodGet, Path: "/api/admin/users/:id/sessions", Summary: "用户的有效登录会话（管理员）", Tags: user, Auth: true,
+			Response: []models.Session{}},
+		{Method: http.MethodDelete, Path: "/api/admin/users/:id/sessions/:session_id", Summary: "吊销用户的登录会话，该设备需要重新登录（管理员）", Tags: user, Auth: true,
+			Response: handlers.MessageResponse{}},
+		{Method: http.MethodPost, Path: "/api/admin/users/:id/mfa/reset", Summary: "重置用户的两步验证（管理员）", Tags: mfa, Auth: true,
+			Response: handlers.MessageResponse{}},
+
+		// 个人资料
+		{Method: http.MethodGet, Path: "/api/user/profile", Summary: "当前用户资料", Tags: profile, Auth: true,
+			Response: models.User{}},
+		{Method: http.MethodPut, Path: "/api/user/profile", Summary: "更新个人资料，修改密码或邮箱时需要提供old_password；修改了密码时原有令牌失效，返回新令牌", Tags: profile, Auth: true,
+			Request: handlers.ProfileUpdateRequest{}, Response: handlers.ProfileUpdateResponse{}},
+		{Method: http.MethodGet, Path: "/api/user/sessions", Summary: "当前用户的有效登录会话，current标记发起请求的会话", Tags: profile, Auth: true,
+			Response: []models.Session{}},
+		{Method: http.MethodDelete, Path: "/api/user/sessions/:session_id", Summary: "吊销登录会话，该设备需要重新登录", Tags: profile, Auth: true,
+			Response: handlers.MessageResponse{}},
+		{Method: http.MethodPut, Path: "/api/user/change-password", Summary: "修改密码", Tags: profile, Auth: true,
+			Request: handlers.ChangePasswordRequest{}, Response: handlers.ChangePasswordResponse{}},
+
+		// 两步验证
+		{Method: http.MethodGet, Path: "/api/user/mfa", Summary: "两步验证状态", Tags: mfa, Auth: true,
+			Response: service.MFAStatus{}},
+		{Method: http.MethodPost, Path: "/api/user/mfa/enroll", Summary: "生成TOTP密钥，验证第一个验证码后启用", Tags: mfa, Auth: true,
+			Response: service.MFAEnrollment{}},
+		{Method: http.MethodPost, Path: "/api/user/mfa/activate", Summary: "启用两步验证，返回恢复码", Tags: mfa, Auth: true,
+			Request: handlers.MFACodeRequest{}, Response: handlers.RecoveryCodesResponse{}},
+		{Method: http.MethodPost, Path: "/api/user/mfa/recovery-codes", Summary: "重新生成恢复码", Tags: mfa, Auth: true,
+			Request: handlers.MFACodeRequest{}, Response: handlers.RecoveryCodesResponse{}},
+		{Method: http.MethodPost, Path: "/api/user/mfa/disable", Summary: "关闭两步验证", Tags: mfa, Auth: true,
+			Request: handlers.MFADisableRequest{}, Response: handlers.MessageResponse{}},
+
+		// API密钥
+		{Method: http.MethodGet, Path: "/api/user/api-keys", Summary: "当前用户的API密钥，包括已吊销和已过期的", Tags: apiKey, Auth: true,
+			Response: []models.APIKey{}},
+		{Method: http.MethodPost, Path: "/api/user/api-keys", Summary: "创建API密钥，密钥只在响应中返回一次；请求头 Authorization: Bearer <key>", Tags: apiKey, Auth: true,
+			Request: handlers.CreateAPIKeyRequest{}, Response: service.CreatedAPIKey{}, Status: http.StatusCreated},
+		{Method: http.MethodDelete, Path: "/api/user/api-keys/:key_id", Summary: "吊销API密钥", Tags: apiKey, Auth: true,
+			Response: handlers.MessageResponse{}},
+		{Method: http.MethodGet, Path: "/api/admin/service-accounts", Summary: "服务账号列表（管理员）", Tags: apiKey, Auth: true,
+			Response: []models.User{}},
+		{Method: http.MethodPost, Path: "/api/admin/service-accounts", Summary: "创建没有密码、只能使用API密钥的服务账号（管理员）", Tags: apiKey, Auth: true,
+			Request: handlers.CreateServiceAccountRequest{}, Response: models.User{}, Status: http.StatusCreated},
+		{Method: http.MethodGet, Path: "/api/admin/service-accounts/:id/api-keys", Summary: "服务账号的API密钥（管理员）", Tags: apiKey, Auth: true,
+			Response: []models.APIKey{}},
+		{Method: http.MethodPost, Path: "/api/admin/service-accounts/:id/api-keys", Summary: "为服务账号创建API密钥，密钥只在响应中返回一次（管理员）", Tags: apiKey, Auth: true,
+			Request: handlers.CreateAPIKeyRequest{}, Response: service.CreatedAPIKey{}, Status: http.StatusCreated},
+		{Method: http.MethodDelete, Path: "/api/admin/service-accounts/:id/api-keys/:key_id", Summary: "吊销服务账号的API密钥（管理员）", Tags: apiKey, Auth: true,
+			Response: handlers.MessageResponse{}},
+
+		// 邀请
+		{Method: http.MethodPost, Path: "/api/invitations/accept", Summary: "使用邮件中的令牌接受邀请，设置用户名和密码后加入组织", Tags: invite,
+			Request: handlers.AcceptInvitationRequest{}, Response: handlers.RegisterResponse{}, Status: http.StatusCreated},
+		{Method: http.MethodGet, Path: "/api/admin/invitations", Summary: "组织的邀请（管理员）", Tags: invite, Auth: true,
+			Response: []models.Invitation{}},
+		{Method: http.MethodPost, Path: "/api/admin/invitations", Summary: "邀请邮箱加入组织并发送邀请邮件（管理员）", Tags: invite, Auth: true,
+			Request: handlers.CreateInvitationRequest{}, Response: models.Invitation{}, Status: http.StatusCreated},
+		{Method: http.MethodPost, Path: "/api/admin/invitations/:id/resend", Summary: "重新发送邀请，之前的链接失效，有效期重新计算（管理员）", Tags: invite, Auth: true,
+			Response: models.Invitation{}},
+		{Method: http.MethodDelete, Path: "/api/admin/invitations/:id", Summary: "撤销尚未接受的邀请（管理员）", Tags: invite, Auth: true,
+			Response: handlers.MessageResponse{}},
+
+		// 组织
+		{Method: http.MethodGet, Path: "/api/organizations", Summary: "当前用户创建的组织", Tags: org, Auth: true,
+			Response: []service.OrganizationDetail{}},
+		{Method: http.MethodGet, Path: "/api/organizations/all", Summary: "所有组织", Tags: org, Auth: true,
+			Response: []models.Organization{}},
+		{Method: http.MethodGet, Path: "/api/organizations/:id", Summary: "组织详情", Tags: org, Auth: true,
+			Response: models.Organization{}},
+		{Method: http.MethodGet, Path: "/api/organizations/:id/users", Summary: "组织下的用户", Tags: org, Auth: true,
+			Response: []models.User{}},
+		{Method: http.MethodPost, Path: "/api/organizations", Summary: "创建顶级组织及其管理员（平台管理员）", Tags: org, Auth: true,
+			Request: handlers.CreateOrganizationRequest{}, Response: handlers.CreateOrganizationResponse{}, Status: http.StatusCreated},
+		{Method: http.MethodPut, Path: "/api/organizations/:id", Summary: "更新组织（管理员），只有平台管理员可以修改父组织", Tags: org, Auth: true,
+			Request: handlers.UpdateOrganizationRequest{}, Response: models.Organization{}},
+		{Method: http.MethodDelete, Path: "/api/organizations/:id", Summary: "删除组织（管理员）", Tags: org, Auth: true,
+			Response: handlers.MessageResponse{}},
+		{Method: http.MethodGet, Path: "/api/admin/organizations/:id/password-policy", Summary: "组织的密码策略及实际生效的策略（管理员）", Tags: org, Auth: true,
+			Response: service.OrgPasswordPolicyDetail{}},
+		{Method: http.MethodPut, Path: "/api/admin/organizations/:id/password-policy", Summary: "设置组织的密码策略，只能比全局策略更严格（管理员）", Tags: org, Auth: true,
+			Request: handlers.OrgPasswordPolicyRequest{}, Response: service.OrgPasswordPolicyDetail{}},
+		{Method: http.MethodGet, Path: "/api/admin/organizations/:id/mfa-policy", Summary: "组织的两步验证要求（管理员）", Tags: mfa, Auth: true,
+			Response: service.OrgMFAPolicy{}},
+		{Method: http.MethodPut, Path: "/api/admin/organizations/:id/mfa-policy", Summary: "设置组织管理员是否必须启用两步验证，不能使用API密钥（管理员）", Tags: mfa, Auth: true,
+			Request: handlers.OrgMFAPolicyRequest{}, Response: service.OrgMFAPolicy{}},
+		{Method: http.MethodGet, Path: "/api/admin/organizations/:id/registration", Summary: "组织的自助注册设置（管理员）", Tags: org, Auth: true,
+			Response: models.OrgRegistration{}},
+		{Method: http.MethodPut, Path: "/api/admin/organizations/:id/registration", Summary: "设置组织是否开放自助注册及自助注册的用户的角色（管理员）", Tags: org, Auth: true,
+			Request: handlers.OrgRegistrationRequest{}, Response: models.OrgRegistration{}},
+		{Method: http.MethodGet, Path: "/api/admin/organizations/:id/oidc-providers", Summary: "组织的身份提供方（管理员）", Tags: sso, Auth: true,
+			Response: []models.OIDCProvider{}},
+		{Method: http.MethodPost, Path: "/api/admin/organizations/:id/oidc-providers", Summary: "为组织添加身份提供方（管理员）", Tags: sso, Auth: true,
+			Request: handlers.OIDCProviderRequest{}, Response: models.OIDCProvider{}, Status: http.StatusCreated},
+		{Method: http.MethodPut, Path: "/api/admin/organizations/:id/oidc-providers/:provider_id", Summary: "修改组织的身份提供方，不提交client_secret时保留原值（管理员）", Tags: sso, Auth: true,
+			Request: handlers.OIDCProviderRequest{}, Response: models.OIDCProvider{}},
+		{Method: http.MethodDelete, Path: "/api/admin/organizations/:id/oidc-providers/:provider_id", Summary: "删除组织的身份提供方及用户关联（管理员）", Tags: sso, Auth: true,
+			Response: handlers.MessageResponse{}},
+		{Method: http.MethodGet, Path: "/api/admin/organizations/:id/directory", Summary: "组织的LDAP目录服务配置（管理员）", Tags: sso, Auth: true,
+			Response: models.OrgDirectory{}},
+		{Method: http.MethodPut, Path: "/api/admin/organizations/:id/directory", Summary: "保存组织的LDAP目录服务配置，启用时先测试连接，不提交bind_password时保留原值（管理员）", Tags: sso, Auth: true,
+			Request: handlers.OrgDirectoryRequest{}, Response: models.OrgDirectory{}},
+		{Method: http.MethodDelete, Path: "/api/admin/organizations/:id/directory", Summary: "删除组织的LDAP目录服务配置，使用目录认证的用户将不能登录（管理员）", Tags: sso, Auth: true,
+			Response: handlers.MessageResponse{}},
+
+		// 对象类
+		{Method: http.MethodGet, Path: "/api/object-classes", Summary: "对象类列表", Tags: class, Auth: true,
+			Response: []models.ObjectClass{}},
+		{Method: http.MethodGet, Path: "/api/object-classes/:id", Summary: "对象类详情", Tags: class, Auth: true,
+			Response: models.ObjectClass{}},
+		{Method: http.MethodPost, Path: "/api/object-classes", Summary: "创建对象类", Tags: class, Auth: true,
+			Request: models.ObjectClass{}, Response: models.ObjectClass{}, Status: http.StatusCreated},
+		{Method: http.MethodPut, Path: "/api/object-classes/:id", Summary: "更新对象类", Tags: class, Auth: true,
+			Request: handlers.ObjectClassUpdateRequest{}, Response: models.ObjectClass{}},
+		{Method: http.MethodDelete, Path: "/api/object-classes/:id", Summary: "删除对象类", Tags: class, Auth: true,
+			Response: handlers.MessageResponse{}},
+		{Method: http.MethodGet, Path: "/api/object-classes/:id/children", Summary: "子对象类列表", Tags: class, Auth: true,
+			Response: []models.ObjectClass{}},
+		{Method: http.MethodPost, Path: "/api/object-classes/:id/children", Summary: "创建子对象类", Tags: class, Auth: true,
+			Request: models.ObjectClass{}, Response: models.ObjectClass{}, Status: http.StatusCreated},
+	}
+}
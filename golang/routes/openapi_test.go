@@ -0,0 +1,86 @@
+package routes
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"xzyq/handlers"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newTestEngine 注册所有路由，处理器不会被调用所以不需要依赖
+func newTestEngine() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	Setup(r, Handlers{
+		User:           &handlers.UserHandler{},
+		MFA:            &handlers.MFAHandler{},
+		PasswordReset:  &handlers.PasswordResetHandler{},
+		PasswordPolicy: &handlers.PasswordPolicyHandler{},
+		OIDC:           &handlers.OIDCHandler{},
+		Directory:      &handlers.DirectoryHandler{},
+		APIKey:         &handlers.APIKeyHandler{},
+		Session:        &handlers.SessionHandler{},
+		Invitation:     &handlers.InvitationHandler{},
+		Organization:   &handlers.OrganizationHandler{},
+		ObjectClass:    &handlers.ObjectClassHandler{},
+		Health:         &handlers.HealthHandler{},
+	})
+	return r
+}
+
+func TestSpecCoversAllRoutes(t *testing.T) {
+	doc := Spec()
+	for _, route := range newTestEngine().Routes() {
+		if !doc.Has(route.Method, route.Path) {
+			t.Errorf("route %s %s is not documented in routes.Spec", route.Method, route.Path)
+		}
+	}
+}
+
+func TestSpecHasNoUnregisteredRoutes(t *testing.T) {
+	registered := make(map[string]bool)
+	for _, route := range newTestEngine().Routes() {
+		registered[route.Method+" "+route.Path] = true
+	}
+	for _, r := range specRoutes() {
+		if !registered[r.Method+" "+r.Path] {
+			t.Errorf("documented route %s %s is not registered", r.Method, r.Path)
+		}
+	}
+}
+
+func TestSpecReferencesResolve(t *testing.T) {
+	doc := Spec()
+	body, err := json.Marshal(doc)
+	if err != nil {
+		t.Fatalf("marshal spec: %v", err)
+	}
+
+	var raw interface{}
+	if err := json.Unmarshal(body, &raw); err != nil {
+		t.Fatalf("unmarshal spec: %v", err)
+	}
+
+	var walk func(v interface{})
+	walk = func(v interface{}) {
+		switch v := v.(type) {
+		case map[string]interface{}:
+			if ref, ok := v["$ref"].(string); ok {
+				name := strings.TrimPrefix(ref, "#/components/schemas/")
+				if _, ok := doc.Components.Schemas[name]; !ok {
+					t.Errorf("unresolved reference %s", ref)
+				}
+			}
+			for _, child := range v {
+				walk(child)
+			}
+		case []interface{}:
+			for _, child := range v {
+				walk(child)
+			}
+		}
+	}
+	walk(raw)
+}
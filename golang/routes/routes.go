@@ -1,10 +1,171 @@
-// 对象类管理路由
-objectClassRoutes := router.Group("/api/object-classes")
-objectClassRoutes.Use(middleware.AuthMiddleware())
-{
-	objectClassRoutes.GET("", handlers.GetObjectClasses)
-	objectClassRoutes.GET("/:id", handlers.GetObjectClass)
-	objectClassRoutes.POST("", handlers.CreateObjectClass)
-	objectClassRoutes.PUT("/:id", handlers.UpdateObjectClass)
-	objectClassRoutes.DELETE("/:id", handlers.DeleteObjectClass)
-} 
\ No newline at end of file
+package routes
+
+import (
+	"fmt"
+	"xzyq/apperr"
+	"xzyq/handlers"
+	"xzyq/logging"
+	"xzyq/metrics"
+	"xzyq/middleware"
+	"xzyq/openapi"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Handlers 路由依赖的处理器
+type Handlers struct {
+	User           *handlers.UserHandler
+	MFA            *handlers.MFAHandler
+	PasswordReset  *handlers.PasswordResetHandler
+	PasswordPolicy *handlers.PasswordPolicyHandler
+	OIDC           *handlers.OIDCHandler
+	Directory      *handlers.DirectoryHandler
+	APIKey         *handlers.APIKeyHandler
+	Session        *handlers.SessionHandler
+	Invitation     *handlers.InvitationHandler
+	Organization   *handlers.OrganizationHandler
+	ObjectClass    *handlers.ObjectClassHandler
+	Health         *handlers.HealthHandler
+
+	// Revocations 认证中间件查询令牌是否已被吊销
+	Revocations middleware.RevocationChecker
+	// Users 认证中间件查询令牌的用户是否已被禁用
+	Users middleware.UserStatusChecker
+	// APIKeys 认证中间件校验API密钥
+	APIKeys middleware.APIKeyVerifier
+}
+
+// Setup 注册所有路由
+func Setup(r *gin.Engine, h Handlers) {
+	// 请求ID、访问日志、异常恢复、请求指标和跨域
+	r.Use(middleware.RequestIDMiddleware())
+	r.Use(logging.AccessLog())
+	r.Use(gin.CustomRecovery(func(c *gin.Context, recovered interface{}) {
+		apperr.Respond(c, apperr.Internal(fmt.Errorf("panic: %v", recovered)))
+	}))
+	r.Use(metrics.Middleware())
+	r.Use(middleware.CORSMiddleware())
+
+	// 未注册的路由
+	r.NoRoute(func(c *gin.Context) {
+		apperr.Respond(c, apperr.ErrNotFound)
+	})
+
+	// Prometheus指标
+	r.GET("/metrics", metrics.Handler())
+
+	// 健康检查
+	r.GET("/healthz", h.Health.Liveness)
+	r.GET("/readyz", h.Health.Readiness)
+
+	// 验证令牌的公钥
+	r.GET("/.well-known/jwks.json", handlers.JWKS)
+
+	// 公开路由
+	public := r.Group("/api")
+	{
+		public.POST("/register", h.User.RegisterUser)
+		public.POST("/login", h.User.Login)
+		public.POST("/login/mfa", h.User.LoginMFA)
+		public.POST("/login/mfa/enroll", h.User.LoginMFAEnroll)
+		public.GET("/login/oidc/providers", h.OIDC.Providers)
+		public.POST("/login/oidc/providers/:name/authorize", h.OIDC.Authorize)
+		public.POST("/login/oidc/callback", h.OIDC.Callback)
+		public.POST("/token/refresh", h.User.RefreshToken)
+		public.POST("/password/forgot", h.PasswordReset.Forgot)
+		public.POST("/password/reset", h.PasswordReset.Reset)
+		public.POST("/password/expired", h.User.ChangeExpiredPassword)
+		public.POST("/invitations/accept", h.Invitation.Accept)
+
+		// 接口文档
+		public.GET("/openapi.json", openapi.Handler(Spec()))
+		public.GET("/docs", openapi.DocsHandler())
+	}
+
+	// 需要认证的路由
+	protected := r.Group("/api")
+	protected.Use(middleware.AuthMiddleware(h.Revocations, h.Users, h.APIKeys))
+	{
+		// 用户相关路由，退出登录、修改密码、两步验证和管理API密钥不能使用API密钥
+		protected.POST("/logout", middleware.SessionOnly(), h.User.Logout)
+		protected.GET("/users", h.User.GetUsers)
+		protected.POST("/users", middleware.AdminAuthMiddleware(), h.User.CreateUser)
+		protected.GET("/users/:id", h.User.GetUser)
+		protected.PUT("/users/:id", middleware.AdminAuthMiddleware(), h.User.UpdateUser)
+		protected.DELETE("/users/:id", middleware.AdminAuthMiddleware(), h.User.DeleteUser)
+
+		// 个人资料相关路由
+		protected.GET("/user/profile", h.User.GetProfile)
+		protected.PUT("/user/profile", middleware.SessionOnly(), h.User.UpdateProfile)
+		protected.PUT("/user/change-password", middleware.SessionOnly(), h.User.ChangePassword)
+
+		// 两步验证
+		protected.GET("/user/mfa", middleware.SessionOnly(), h.MFA.Status)
+		protected.POST("/user/mfa/enroll", middleware.SessionOnly(), h.MFA.Enroll)
+		protected.POST("/user/mfa/activate", middleware.SessionOnly(), h.MFA.Activate)
+		protected.POST("/user/mfa/recovery-codes", middleware.SessionOnly(), h.MFA.RegenerateRecoveryCodes)
+		protected.POST("/user/mfa/disable", middleware.SessionOnly(), h.MFA.Disable)
+
+		// 登录会话
+		protected.GET("/user/sessions", middleware.SessionOnly(), h.Session.List)
+		protected.DELETE("/user/sessions/:session_id", middleware.SessionOnly(), h.Session.Revoke)
+
+		// API密钥
+		protected.GET("/user/api-keys", middleware.SessionOnly(), h.APIKey.ListMine)
+		protected.POST("/user/api-keys", middleware.SessionOnly(), h.APIKey.CreateMine)
+		protected.DELETE("/user/api-keys/:key_id", middleware.SessionOnly(), h.APIKey.RevokeMine)
+
+		// 组织管理路由
+		protected.GET("/organizations", h.Organization.GetOrganizations)
+		protected.GET("/organizations/all", h.Organization.GetAllOrganizations)
+		protected.GET("/organizations/:id", h.Organization.GetOrganization)
+		protected.GET("/organizations/:id/users", h.Organization.GetOrganizationUsers)
+		protected.POST("/organizations", middleware.AdminAuthMiddleware(), h.Organization.CreateOrganization)
+		protected.PUT("/organizations/:id", middleware.AdminAuthMiddleware(), h.Organization.UpdateOrganization)
+		protected.DELETE("/organizations/:id", middleware.AdminAuthMiddleware(), h.Organization.DeleteOrganization)
+
+		// 对象类管理路由
+		protected.GET("/object-classes", h.ObjectClass.GetObjectClasses)
+		protected.GET("/object-classes/:id", h.ObjectClass.GetObjectClass)
+		protected.POST("/object-classes", h.ObjectClass.CreateObjectClass)
+		protected.PUT("/object-classes/:id", h.ObjectClass.UpdateObjectClass)
+		protected.DELETE("/object-classes/:id", h.ObjectClass.DeleteObjectClass)
+		protected.GET("/object-classes/:id/children", h.ObjectClass.GetObjectClassChildren)
+		protected.POST("/object-classes/:id/children", h.ObjectClass.CreateChildObjectClass)
+	}
+
+	// 管理员路由
+	admin := protected.Group("/admin")
+	admin.Use(middleware.AdminAuthMiddleware())
+	{
+		admin.POST("/users/:id/sign-out", h.User.ForceSignOut)
+		admin.POST("/users/:id/unlock", h.User.Unlock)
+		admin.POST("/users/:id/disable", h.User.DisableUser)
+		admin.POST("/users/:id/enable", h.User.EnableUser)
+		admin.GET("/users/:id/sessions", h.Session.UserSessions)
+		admin.DELETE("/users/:id/sessions/:session_id", h.Session.RevokeUserSession)
+		admin.POST("/users/:id/mfa/reset", h.MFA.Reset)
+		admin.GET("/organizations/:id/password-policy", h.PasswordPolicy.Get)
+		admin.PUT("/organizations/:id/password-policy", h.PasswordPolicy.Update)
+		admin.GET("/organizations/:id/mfa-policy", h.Organization.GetMFAPolicy)
+		admin.PUT("/organizations/:id/mfa-policy", middleware.SessionOnly(), h.Organization.UpdateMFAPolicy)
+		admin.GET("/organizations/:id/registration", h.Organization.GetRegistration)
+		admin.PUT("/organizations/:id/registration", h.Organization.UpdateRegistration)
+		admin.GET("/organizations/:id/oidc-providers", h.OIDC.ListProviders)
+		admin.POST("/organizations/:id/oidc-providers", h.OIDC.CreateProvider)
+		admin.PUT("/organizations/:id/oidc-providers/:provider_id", h.OIDC.UpdateProvider)
+		admin.DELETE("/organizations/:id/oidc-providers/:provider_id", h.OIDC.DeleteProvider)
+		admin.GET("/organizations/:id/directory", h.Directory.Get)
+		admin.PUT("/organizations/:id/directory", h.Directory.Save)
+		admin.DELETE("/organizations/:id/directory", h.Directory.Delete)
+		admin.GET("/service-accounts", h.APIKey.ListServiceAccounts)
+		admin.POST("/service-accounts", middleware.SessionOnly(), h.APIKey.CreateServiceAccount)
+		admin.GET("/service-accounts/:id/api-keys", h.APIKey.ListServiceAccountKeys)
+		admin.POST("/service-accounts/:id/api-keys", middleware.SessionOnly(), h.APIKey.CreateServiceAccountKey)
+		admin.DELETE("/service-accounts/:id/api-keys/:key_id", middleware.SessionOnly(), h.APIKey.RevokeServiceAccountKey)
+		admin.GET("/invitations", h.Invitation.List)
+		admin.POST("/invitations", h.Invitation.Create)
+		admin.POST("/invitations/:id/resend", h.Invitation.Resend)
+		admin.DELETE("/invitations/:id", h.Invitation.Revoke)
+	}
+}
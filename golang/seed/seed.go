@@ -0,0 +1,54 @@
+package seed
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"xzyq/models"
+	"xzyq/service"
+)
+
+const (
+	// AdminUsername 平台管理员用户名
+	AdminUsername = "admin"
+	// DemoOrganization 示例组织名称
+	DemoOrganization = "demo"
+)
+
+// Run 写入初始数据：一个不属于任何组织的平台管理员，以及由其创建的示例组织
+//
+// 平台管理员已存在时跳过，因此可以重复执行。生成的密码只输出一次，示例组织的管理员首次登录时必须修改密码。
+func Run(ctx context.Context, users *service.UserService, orgs *service.OrganizationService, passwords *service.PasswordService, out io.Writer) error {
+	password, err := passwords.Generate(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("generate platform admin password: %w", err)
+	}
+
+	admin := models.User{
+		Username: AdminUsername,
+		Password: password,
+		IsActive: true,
+		Role:     "admin",
+	}
+	if err := users.Bootstrap(ctx, &admin); err != nil {
+		if errors.Is(err, service.ErrUsernameTaken) {
+			fmt.Fprintf(out, "seed: user %q already exists, skipped\n", AdminUsername)
+			return nil
+		}
+		return fmt.Errorf("seed platform admin: %w", err)
+	}
+	fmt.Fprintf(out, "seed: created platform admin %q with password %s\n", AdminUsername, password)
+
+	org := models.Organization{
+		Name:        DemoOrganization,
+		Description: "示例组织",
+	}
+	orgAdmin, err := orgs.Create(ctx, &org, admin.ID)
+	if err != nil {
+		return fmt.Errorf("seed demo organization: %w", err)
+	}
+	fmt.Fprintf(out, "seed: created organization %q with admin %q and password %s\n", org.Name, orgAdmin.Username, orgAdmin.Password)
+
+	return nil
+}
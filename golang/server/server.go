@@ -0,0 +1,106 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/http"
+	"xzyq/config"
+	"xzyq/logging"
+)
+
+// Server HTTP服务，负责超时设置、TLS和优雅停机
+type Server struct {
+	cfg        config.ServerConfig
+	http       *http.Server
+	certs      *certReloader
+	onShutdown []func(ctx context.Context) error
+}
+
+// New 创建HTTP服务
+func New(cfg config.ServerConfig, handler http.Handler) (*Server, error) {
+	s := &Server{
+		cfg: cfg,
+		http: &http.Server{
+			Addr:              cfg.Addr,
+			Handler:           handler,
+			ReadTimeout:       cfg.ReadTimeout,
+			ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+			WriteTimeout:      cfg.WriteTimeout,
+			IdleTimeout:       cfg.IdleTimeout,
+		},
+	}
+
+	if cfg.TLS.Enabled() {
+		certs, err := newCertReloader(cfg.TLS.CertFile, cfg.TLS.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		s.certs = certs
+		s.http.TLSConfig = &tls.Config{
+			MinVersion:     tls.VersionTLS12,
+			GetCertificate: certs.GetCertificate,
+		}
+	}
+
+	return s, nil
+}
+
+// OnShutdown 注册在处理中的请求全部完成后执行的清理函数，按注册顺序执行
+func (s *Server) OnShutdown(fn func(ctx context.Context) error) {
+	s.onShutdown = append(s.onShutdown, fn)
+}
+
+// Run 启动服务并阻塞，直到ctx被取消或服务出错
+//
+// ctx被取消后服务停止接受新连接，等待处理中的请求完成（最长ShutdownTimeout），
+// 然后执行OnShutdown注册的清理函数。
+func (s *Server) Run(ctx context.Context) error {
+	ln, err := net.Listen("tcp", s.cfg.Addr)
+	if err != nil {
+		return err
+	}
+
+	logger := logging.FromContext(ctx)
+	errCh := make(chan error, 1)
+	go func() {
+		logger.Info("server listening", "addr", ln.Addr().String(), "tls", s.certs != nil)
+		if s.certs != nil {
+			errCh <- s.http.ServeTLS(ln, "", "")
+		} else {
+			errCh <- s.http.Serve(ln)
+		}
+	}()
+
+	select {
+	case err := <-errCh:
+		if !errors.Is(err, http.ErrServerClosed) {
+			s.cleanup(context.Background())
+			return err
+		}
+	case <-ctx.Done():
+		logger.Info("shutting down server", "timeout", s.cfg.ShutdownTimeout.String())
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), s.cfg.ShutdownTimeout)
+	defer cancel()
+
+	err = s.http.Shutdown(shutdownCtx)
+	if err != nil {
+		logger.Error("graceful shutdown did not complete", "error", err)
+	}
+	s.cleanup(shutdownCtx)
+
+	logger.Info("server stopped")
+	return err
+}
+
+// cleanup 执行清理函数
+func (s *Server) cleanup(ctx context.Context) {
+	for _, fn := range s.onShutdown {
+		if err := fn(ctx); err != nil {
+			logging.FromContext(ctx).Error("shutdown hook failed", "error", err)
+		}
+	}
+}
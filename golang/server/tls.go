@@ -0,0 +1,120 @@
+package server
+
+import (
+	"crypto/tls"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+)
+
+// certCheckInterval 检查证书文件修改时间的最小间隔，避免每次TLS握手都访问文件系统
+const certCheckInterval = 5 * time.Second
+
+// certReloader 在证书或私钥文件修改后重新加载证书，证书轮换时无需重启服务
+type certReloader struct {
+	certFile string
+	keyFile  string
+
+	mu            sync.RWMutex
+	cert          *tls.Certificate
+	modTime       time.Time
+	lastCheck     time.Time
+	failedModTime time.Time // 加载失败的文件修改时间，文件再次修改前不重试，错误只记录一次
+}
+
+// newCertReloader 加载证书，加载失败时返回错误
+func newCertReloader(certFile, keyFile string) (*certReloader, error) {
+	r := &certReloader{certFile: certFile, keyFile: keyFile}
+	if err := r.reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// GetCertificate 实现tls.Config.GetCertificate，文件有更新时先重新加载
+//
+// 每certCheckInterval最多检查一次文件修改时间。
+func (r *certReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	if r.checkDue(time.Now()) {
+		r.checkFiles()
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.cert, nil
+}
+
+// checkDue 距上次检查超过certCheckInterval时返回true并记录本次检查时间，并发的握手中只有一个执行检查
+func (r *certReloader) checkDue(now time.Time) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if now.Sub(r.lastCheck) < certCheckInterval {
+		return false
+	}
+	r.lastCheck = now
+	return true
+}
+
+// checkFiles 文件的修改时间比已加载的证书新、且不是已经加载失败过的版本时重新加载
+func (r *certReloader) checkFiles() {
+	modTime, err := r.latestModTime()
+	if err != nil {
+		return
+	}
+
+	r.mu.RLock()
+	changed := modTime.After(r.modTime) && !modTime.Equal(r.failedModTime)
+	r.mu.RUnlock()
+	if !changed {
+		return
+	}
+
+	// 新证书加载失败时继续使用旧证书
+	if err := r.reload(); err != nil {
+		r.mu.Lock()
+		r.failedModTime = modTime
+		r.mu.Unlock()
+		slog.Error("reload TLS certificate failed", "cert_file", r.certFile, "error", err)
+	}
+}
+
+// reload 从文件加载证书
+func (r *certReloader) reload() error {
+	modTime, err := r.latestModTime()
+	if err != nil {
+		return err
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("load TLS certificate: %w", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cert = &cert
+	r.modTime = modTime
+
+	slog.Info("TLS certificate loaded", "cert_file", r.certFile, "mod_time", modTime)
+	return nil
+}
+
+// latestModTime 返回证书和私钥文件中较新的修改时间
+func (r *certReloader) latestModTime() (time.Time, error) {
+	certInfo, err := os.Stat(r.certFile)
+	if err != nil {
+		return time.Time{}, err
+	}
+	keyInfo, err := os.Stat(r.keyFile)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	if keyInfo.ModTime().After(certInfo.ModTime()) {
+		return keyInfo.ModTime(), nil
+	}
+	return certInfo.ModTime(), nil
+}
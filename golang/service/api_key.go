@@ -0,0 +1,244 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"xzyq/apperr"
+	"xzyq/config"
+	"xzyq/logging"
+	"xzyq/models"
+	"xzyq/store"
+	"xzyq/tenant"
+	"xzyq/utils"
+)
+
+const (
+	// APIKeyPrefix API密钥的固定开头，认证中间件据此区分API密钥和JWT
+	APIKeyPrefix = "xzyq_"
+	// apiKeyTouchInterval 记录最近使用时间的最小间隔
+	apiKeyTouchInterval = time.Minute
+)
+
+// APIKeyCreate 创建API密钥的内容，ExpiresIn为0时使用默认有效期
+type APIKeyCreate struct {
+	Name      string
+	Scopes    []string
+	ExpiresIn time.Duration
+}
+
+// CreatedAPIKey 新创建的密钥，Key只在创建时返回一次
+type CreatedAPIKey struct {
+	*models.APIKey
+	Key string `json:"key"`
+}
+
+// APIKeyService 用户和服务账号的API密钥
+//
+// 密钥格式为 xzyq_<8位标识>_<随机串>，只保存SHA-256哈希和用于辨认的前缀。
+// 权限范围read只允许GET/HEAD请求，write允许所有请求，admin允许访问管理员接口（用户本身必须是管理员）。
+type APIKeyService struct {
+	store store.Store
+	cfg   config.APIKeyConfig
+}
+
+// NewAPIKeyService 创建APIKeyService
+func NewAPIKeyService(st store.Store, cfg config.APIKeyConfig) *APIKeyService {
+	return &APIKeyService{store: st, cfg: cfg}
+}
+
+// Verify 校验请求携带的API密钥，返回密钥所属用户的令牌信息
+//
+// 密钥不存在、已吊销、已过期，或所属用户已被删除、禁用时返回ErrAPIKeyInvalid。
+func (s *APIKeyService) Verify(ctx context.Context, key, ip string) (*utils.Claims, *models.APIKey, error) {
+	// 密钥对所有组织的数据查询都有效，认证前还没有组织范围
+	ctx = tenant.Unscoped(ctx)
+
+	apiKey, err := s.store.APIKeys().GetByHash(ctx, utils.HashToken(key))
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return nil, nil, ErrAPIKeyInvalid
+		}
+		return nil, nil, err
+	}
+	now := time.Now()
+	if apiKey.RevokedAt != nil || !now.Before(apiKey.ExpiresAt) {
+		return nil, nil, ErrAPIKeyInvalid
+	}
+
+	user, err := s.store.Users().Get(ctx, apiKey.UserID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return nil, nil, ErrAPIKeyInvalid
+		}
+		return nil, nil, err
+	}
+	if !user.IsActive {
+		return nil, nil, ErrAPIKeyInvalid
+	}
+
+	// 记录最近使用时间失败时不影响请求
+	if err := s.store.APIKeys().Touch(ctx, apiKey.ID, now, ip, apiKeyTouchInterval); err != nil {
+		logging.FromContext(ctx).Error("touch api key failed", "api_key_id", apiKey.ID, "error", err)
+	}
+
+	claims := &utils.Claims{
+		UserID:   user.ID,
+		Username: user.Username,
+		Role:     user.Role,
+	}
+	if user.OrgID != nil {
+		claims.OrgID = *user.OrgID
+	}
+	return claims, apiKey, nil
+}
+
+// RecordUsage 在日志中记录使用API密钥的请求，detail为请求的方法、路径和状态码
+func (s *APIKeyService) RecordUsage(ctx context.Context, key *models.APIKey, claims *utils.Claims, ip, detail string) {
+	if len(detail) > 255 {
+		detail = detail[:255]
+	}
+	if err := s.writeLog(ctx, key, claims.UserID, claims.Username, "api_key_used", ip, detail); err != nil {
+		logging.FromContext(ctx).Error("write api key usage log failed", "api_key_id", key.ID, "error", err)
+	}
+}
+
+// List 返回用户的所有密钥，包括已吊销和已过期的
+func (s *APIKeyService) List(ctx context.Context, owner *models.User) ([]models.APIKey, error) {
+	return s.store.APIKeys().ListByUser(tenant.Unscoped(ctx), owner.ID)
+}
+
+// Create 为用户创建密钥，creatorID为操作者，为服务账号创建时是管理员
+func (s *APIKeyService) Create(ctx context.Context, owner *models.User, creatorID uint, create APIKeyCreate, ip string) (*CreatedAPIKey, error) {
+	ctx = tenant.Unscoped(ctx)
+
+	scopes, err := normalizeScopes(create.Scopes)
+	if err != nil {
+		return nil, err
+	}
+	if strings.Contains(scopes, models.APIKeyScopeAdmin) && owner.Role != "admin" {
+		return nil, apperr.ErrBadRequest.Wrap(errors.New("admin scope requires an admin user"))
+	}
+
+	expiresIn := create.ExpiresIn
+	if expiresIn == 0 {
+		expiresIn = s.cfg.DefaultExpire
+	}
+	if expiresIn < 0 || expiresIn > s.cfg.MaxExpire {
+		return nil, apperr.ErrBadRequest.Wrap(fmt.Errorf("api key lifetime must be positive and at most %s", s.cfg.MaxExpire))
+	}
+
+	now := time.Now()
+	count, err := s.store.APIKeys().CountActive(ctx, owner.ID, now)
+	if err != nil {
+		return nil, err
+	}
+	if count >= int64(s.cfg.MaxPerUser) {
+		return nil, ErrAPIKeyLimitReached.WithDetails(map[string]interface{}{"max_per_user": s.cfg.MaxPerUser})
+	}
+
+	id, err := utils.RandomCode(5)
+	if err != nil {
+		return nil, fmt.Errorf("generate api key: %w", err)
+	}
+	secret, err := utils.RandomToken(32)
+	if err != nil {
+		return nil, fmt.Errorf("generate api key: %w", err)
+	}
+	key := APIKeyPrefix + id + "_" + secret
+
+	apiKey := &models.APIKey{
+		UserID:    owner.ID,
+		Name:      strings.TrimSpace(create.Name),
+		Prefix:    APIKeyPrefix + id,
+		KeyHash:   utils.HashToken(key),
+		Scopes:    scopes,
+		CreatedBy: creatorID,
+		ExpiresAt: now.Add(expiresIn),
+	}
+	if err := s.store.APIKeys().Create(ctx, apiKey); err != nil {
+		return nil, err
+	}
+
+	logging.FromContext(ctx).Info("api key created", "api_key_id", apiKey.ID, "user_id", owner.ID, "scopes", scopes, "created_by", creatorID)
+	if err := s.writeLog(ctx, apiKey, owner.ID, owner.Username, "api_key_created", ip, apiKey.Name); err != nil {
+		logging.FromContext(ctx).Error("write api key log failed", "error", err)
+	}
+	return &CreatedAPIKey{APIKey: apiKey, Key: key}, nil
+}
+
+// Revoke 吊销用户的密钥，已吊销的密钥再次吊销时直接返回
+func (s *APIKeyService) Revoke(ctx context.Context, owner *models.User, keyID uint, ip string) error {
+	ctx = tenant.Unscoped(ctx)
+
+	apiKey, err := s.store.APIKeys().Get(ctx, owner.ID, keyID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return ErrAPIKeyNotFound
+		}
+		return err
+	}
+	revoked, err := s.store.APIKeys().Revoke(ctx, apiKey.ID, time.Now())
+	if err != nil || !revoked {
+		return err
+	}
+
+	logging.FromContext(ctx).Info("api key revoked", "api_key_id", apiKey.ID, "user_id", owner.ID)
+	if err := s.writeLog(ctx, apiKey, owner.ID, owner.Username, "api_key_revoked", ip, apiKey.Name); err != nil {
+		logging.FromContext(ctx).Error("write api key log failed", "error", err)
+	}
+	return nil
+}
+
+// ServiceAccount 查询管理员组织范围内的服务账号
+func (s *APIKeyService) ServiceAccount(ctx context.Context, id uint) (*models.User, error) {
+	user, err := s.store.Users().Get(ctx, id)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return nil, ErrServiceAccountNotFound
+		}
+		return nil, err
+	}
+	if !user.ServiceAccount {
+		return nil, ErrServiceAccountNotFound
+	}
+	return user, nil
+}
+
+// writeLog 写入与密钥相关的日志
+func (s *APIKeyService) writeLog(ctx context.Context, key *models.APIKey, userID uint, username, action, ip, detail string) error {
+	return s.store.Logs().Create(ctx, &models.Log{
+		UserID:    userID,
+		Username:  username,
+		Action:    action,
+		IP:        ip,
+		APIKeyID:  &key.ID,
+		Detail:    detail,
+		Timestamp: time.Now(),
+	})
+}
+
+// normalizeScopes 校验并去重权限范围，按read、write、admin的顺序以空格连接；为空时只有read
+func normalizeScopes(scopes []string) (string, error) {
+	if len(scopes) == 0 {
+		return models.APIKeyScopeRead, nil
+	}
+	requested := make(map[string]bool, len(scopes))
+	for _, scope := range scopes {
+		switch scope {
+		case models.APIKeyScopeRead, models.APIKeyScopeWrite, models.APIKeyScopeAdmin:
+			requested[scope] = true
+		default:
+			return "", apperr.ErrBadRequest.Wrap(fmt.Errorf("unknown api key scope %q", scope))
+		}
+	}
+	var normalized []string
+	for _, scope := range []string{models.APIKeyScopeRead, models.APIKeyScopeWrite, models.APIKeyScopeAdmin} {
+		if requested[scope] {
+			normalized = append(normalized, scope)
+		}
+	}
+	return strings.Join(normalized, " "), nil
+}
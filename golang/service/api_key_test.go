@@ -0,0 +1,113 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+	"xzyq/apperr"
+	"xzyq/models"
+	"xzyq/tenant"
+)
+
+func TestAPIKeyVerify(t *testing.T) {
+	e := newTestEnv(t)
+	keys := NewAPIKeyService(e.store, e.cfg.Auth.APIKeys)
+	ctx := context.Background()
+	owner := e.createUser(t, "alice", "Xq7#pass-word", "user", nil)
+
+	create := func(scopes []string, expiresIn time.Duration) *CreatedAPIKey {
+		t.Helper()
+		created, err := keys.Create(ctx, owner, owner.ID, APIKeyCreate{Name: "ci", Scopes: scopes, ExpiresIn: expiresIn}, "127.0.0.1")
+		if err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+		return created
+	}
+
+	valid := create(nil, 0)
+	claims, apiKey, err := keys.Verify(ctx, valid.Key, "127.0.0.1")
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if claims.UserID != owner.ID || apiKey.ID != valid.ID {
+		t.Errorf("Verify = user %d key %d, want user %d key %d", claims.UserID, apiKey.ID, owner.ID, valid.ID)
+	}
+	if _, _, err := keys.Verify(ctx, valid.Key+"x", "127.0.0.1"); !errors.Is(err, ErrAPIKeyInvalid) {
+		t.Errorf("Verify with unknown key: err = %v, want ErrAPIKeyInvalid", err)
+	}
+
+	expired := create(nil, time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+	if _, _, err := keys.Verify(ctx, expired.Key, "127.0.0.1"); !errors.Is(err, ErrAPIKeyInvalid) {
+		t.Errorf("Verify with expired key: err = %v, want ErrAPIKeyInvalid", err)
+	}
+
+	revoked := create(nil, 0)
+	if err := keys.Revoke(ctx, owner, revoked.ID, "127.0.0.1"); err != nil {
+		t.Fatalf("Revoke: %v", err)
+	}
+	if _, _, err := keys.Verify(ctx, revoked.Key, "127.0.0.1"); !errors.Is(err, ErrAPIKeyInvalid) {
+		t.Errorf("Verify with revoked key: err = %v, want ErrAPIKeyInvalid", err)
+	}
+
+	// 所属用户被禁用后密钥不能使用
+	if _, err := e.store.Users().Disable(tenant.Unscoped(ctx), owner.ID, time.Now(), owner.ID, "test", nil); err != nil {
+		t.Fatalf("disable owner: %v", err)
+	}
+	if _, _, err := keys.Verify(ctx, valid.Key, "127.0.0.1"); !errors.Is(err, ErrAPIKeyInvalid) {
+		t.Errorf("Verify with key of disabled owner: err = %v, want ErrAPIKeyInvalid", err)
+	}
+}
+
+func TestAPIKeyScopes(t *testing.T) {
+	e := newTestEnv(t)
+	keys := NewAPIKeyService(e.store, e.cfg.Auth.APIKeys)
+	ctx := context.Background()
+	user := e.createUser(t, "alice", "Xq7#pass-word", "user", nil)
+	admin := e.createUser(t, "root", "Xq7#pass-word", "admin", nil)
+
+	tests := []struct {
+		name   string
+		scopes []string
+		get    bool
+		post   bool
+		admin  bool
+	}{
+		{"default", nil, true, false, false},
+		{"read", []string{models.APIKeyScopeRead}, true, false, false},
+		{"write", []string{models.APIKeyScopeWrite}, true, true, false},
+		{"admin", []string{models.APIKeyScopeAdmin}, false, false, true},
+		{"read admin", []string{models.APIKeyScopeRead, models.APIKeyScopeAdmin, models.APIKeyScopeRead}, true, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			created, err := keys.Create(ctx, admin, admin.ID, APIKeyCreate{Scopes: tt.scopes}, "127.0.0.1")
+			if err != nil {
+				t.Fatalf("Create: %v", err)
+			}
+			_, key, err := keys.Verify(ctx, created.Key, "127.0.0.1")
+			if err != nil {
+				t.Fatalf("Verify: %v", err)
+			}
+			if got := key.AllowsMethod(http.MethodGet); got != tt.get {
+				t.Errorf("AllowsMethod(GET) = %v, want %v", got, tt.get)
+			}
+			if got := key.AllowsMethod(http.MethodPost); got != tt.post {
+				t.Errorf("AllowsMethod(POST) = %v, want %v", got, tt.post)
+			}
+			if got := key.HasScope(models.APIKeyScopeAdmin); got != tt.admin {
+				t.Errorf("HasScope(admin) = %v, want %v", got, tt.admin)
+			}
+		})
+	}
+
+	// 普通用户不能创建admin权限的密钥
+	if _, err := keys.Create(ctx, user, user.ID, APIKeyCreate{Scopes: []string{models.APIKeyScopeAdmin}}, "127.0.0.1"); !errors.Is(err, apperr.ErrBadRequest) {
+		t.Errorf("Create admin key for user: err = %v, want ErrBadRequest", err)
+	}
+	if _, err := keys.Create(ctx, user, user.ID, APIKeyCreate{Scopes: []string{"delete"}}, "127.0.0.1"); !errors.Is(err, apperr.ErrBadRequest) {
+		t.Errorf("Create with unknown scope: err = %v, want ErrBadRequest", err)
+	}
+}
@@ -0,0 +1,48 @@
+package service
+
+import (
+	"context"
+	"xzyq/models"
+)
+
+// Authenticator 登录时校验用户名和密码的一种认证方式
+//
+// 本地已有的用户按其auth_source选择认证方式；本地没有的用户依次尝试各认证方式，
+// 认证通过且结果中带有组织时在该组织中创建用户。
+type Authenticator interface {
+	// Source 认证方式的名称，与users.auth_source对应
+	Source() string
+	// Authenticate 校验密码，user为nil表示本地没有该用户，此时orgID为登录时指定的组织，可以为nil。
+	// 密码错误或用户不存在时返回ErrInvalidCredentials
+	Authenticate(ctx context.Context, user *models.User, orgID *uint, username, password string) (*AuthResult, error)
+}
+
+// AuthResult 认证通过的结果，外部认证方式可以带回用户在外部系统中的信息
+type AuthResult struct {
+	OrgID *uint  // 新用户所属的组织，为nil时不创建用户
+	Role  string // 非空时同步为用户的角色
+	Email string // 非空时同步为用户的邮箱
+}
+
+// PasswordAuthenticator 用本地保存的密码哈希认证
+type PasswordAuthenticator struct {
+	passwords *PasswordService
+}
+
+// NewPasswordAuthenticator 创建PasswordAuthenticator
+func NewPasswordAuthenticator(passwords *PasswordService) *PasswordAuthenticator {
+	return &PasswordAuthenticator{passwords: passwords}
+}
+
+// Source 本地密码
+func (a *PasswordAuthenticator) Source() string {
+	return models.AuthSourceLocal
+}
+
+// Authenticate 校验密码哈希，bcrypt或明文等较弱的哈希在校验通过后升级；单点登录创建的用户没有密码
+func (a *PasswordAuthenticator) Authenticate(ctx context.Context, user *models.User, orgID *uint, username, password string) (*AuthResult, error) {
+	if user == nil || !a.passwords.Verify(ctx, user, password) {
+		return nil, ErrInvalidCredentials
+	}
+	return &AuthResult{}, nil
+}
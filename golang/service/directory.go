@@ -0,0 +1,232 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+	"xzyq/apperr"
+	"xzyq/config"
+	"xzyq/directory"
+	"xzyq/logging"
+	"xzyq/models"
+	"xzyq/store"
+	"xzyq/tenant"
+)
+
+// OrgDirectoryUpdate 保存组织目录服务的内容，BindPassword为nil表示不修改
+type OrgDirectoryUpdate struct {
+	Enabled            bool
+	URL                string
+	StartTLS           bool
+	InsecureSkipVerify bool
+	BindDN             string
+	BindPassword       *string
+	UserBaseDN         string
+	UserFilter         string
+	UsernameAttribute  string
+	EmailAttribute     string
+	GroupBaseDN        string
+	GroupFilter        string
+	AutoProvision      bool
+	DefaultRole        string
+	RoleMappings       []models.DirectoryRoleMapping
+}
+
+// DirectoryService 组织的LDAP/Active Directory目录服务，同时是"ldap"认证方式
+//
+// 每个组织可以配置一个目录服务，auth_source为ldap的用户用所属组织的目录服务校验密码，
+// 每次登录时按目录中所属的组同步角色。允许自动创建用户的目录服务中的用户首次登录时指定组织，在该组织中创建。
+type DirectoryService struct {
+	store   store.Store
+	timeout time.Duration
+}
+
+// NewDirectoryService 创建DirectoryService
+func NewDirectoryService(st store.Store, cfg config.LDAPConfig) *DirectoryService {
+	return &DirectoryService{store: st, timeout: cfg.Timeout}
+}
+
+// Source 目录认证
+func (s *DirectoryService) Source() string {
+	return models.AuthSourceLDAP
+}
+
+// Authenticate 用目录服务校验密码并按所属的组确定角色
+//
+// 本地已有的用户只查询所属组织的目录服务。本地没有的用户只查询登录时指定的组织的目录服务，
+// 且该目录服务需要允许自动创建用户；密码不会发送给其他组织的目录服务。
+func (s *DirectoryService) Authenticate(ctx context.Context, user *models.User, orgID *uint, username, password string) (*AuthResult, error) {
+	ctx = tenant.Unscoped(ctx)
+
+	provisioning := user == nil
+	if !provisioning {
+		orgID = user.OrgID
+	}
+	if orgID == nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	dir, err := s.store.OrgDirectories().Get(ctx, *orgID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, err
+	}
+	if !dir.Enabled || (provisioning && !dir.AutoProvision) {
+		return nil, ErrInvalidCredentials
+	}
+	result, err := s.authenticate(ctx, dir, username, password)
+	if errors.Is(err, directory.ErrUserNotFound) {
+		return nil, ErrInvalidCredentials
+	}
+	if err != nil {
+		return nil, err
+	}
+	if provisioning {
+		result.OrgID = &dir.OrgID
+	}
+	return result, nil
+}
+
+// authenticate 在目录服务中校验密码，目录中没有该用户时返回directory.ErrUserNotFound
+func (s *DirectoryService) authenticate(ctx context.Context, dir *models.OrgDirectory, username, password string) (*AuthResult, error) {
+	entry, err := directory.Authenticate(ctx, s.clientConfig(dir), username, password)
+	if err != nil {
+		switch {
+		case errors.Is(err, directory.ErrInvalidCredentials):
+			return nil, ErrInvalidCredentials
+		case errors.Is(err, directory.ErrUserNotFound):
+			return nil, err
+		default:
+			return nil, ErrDirectoryUnavailable.Wrap(err)
+		}
+	}
+
+	role := dir.RoleForGroups(entry.Groups)
+	if role == "" {
+		logging.FromContext(ctx).Warn("directory user has no role", "org_id", dir.OrgID, "dn", entry.DN, "groups", entry.Groups)
+		return nil, ErrDirectoryNoRole
+	}
+	return &AuthResult{Role: role, Email: entry.Email}, nil
+}
+
+// Get 返回组织的目录服务配置
+func (s *DirectoryService) Get(ctx context.Context, orgID uint) (*models.OrgDirectory, error) {
+	if err := s.checkOrg(ctx, orgID); err != nil {
+		return nil, err
+	}
+	dir, err := s.store.OrgDirectories().Get(ctx, orgID)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, ErrDirectoryNotConfigured
+	}
+	return dir, err
+}
+
+// Save 保存组织的目录服务配置，启用时先连接目录服务确认配置正确
+func (s *DirectoryService) Save(ctx context.Context, orgID uint, update OrgDirectoryUpdate) (*models.OrgDirectory, error) {
+	if err := s.checkOrg(ctx, orgID); err != nil {
+		return nil, err
+	}
+
+	dir, err := s.store.OrgDirectories().Get(ctx, orgID)
+	if err != nil {
+		if !errors.Is(err, store.ErrNotFound) {
+			return nil, err
+		}
+		dir = &models.OrgDirectory{OrgID: orgID}
+	}
+	if err := applyDirectoryUpdate(dir, update); err != nil {
+		return nil, err
+	}
+
+	if dir.Enabled {
+		if err := directory.Check(ctx, s.clientConfig(dir)); err != nil {
+			return nil, ErrDirectoryUnavailable.Wrap(err)
+		}
+	}
+	if err := s.store.OrgDirectories().Save(ctx, dir); err != nil {
+		return nil, err
+	}
+	return dir, nil
+}
+
+// Delete 删除组织的目录服务配置，使用目录认证的用户将不能登录
+func (s *DirectoryService) Delete(ctx context.Context, orgID uint) error {
+	if _, err := s.Get(ctx, orgID); err != nil {
+		return err
+	}
+	return s.store.OrgDirectories().Delete(ctx, orgID)
+}
+
+// applyDirectoryUpdate 校验并写入目录服务配置，未填写的查询条件使用OpenLDAP的默认值
+func applyDirectoryUpdate(dir *models.OrgDirectory, update OrgDirectoryUpdate) error {
+	u, err := url.Parse(strings.TrimSpace(update.URL))
+	if err != nil || (u.Scheme != "ldap" && u.Scheme != "ldaps") || u.Host == "" {
+		return apperr.ErrBadRequest.Wrap(fmt.Errorf("invalid directory url %q", update.URL))
+	}
+	if update.StartTLS && u.Scheme == "ldaps" {
+		return apperr.ErrBadRequest.Wrap(errors.New("start_tls cannot be used with ldaps"))
+	}
+
+	dir.Enabled = update.Enabled
+	dir.URL = u.String()
+	dir.StartTLS = update.StartTLS
+	dir.InsecureSkipVerify = update.InsecureSkipVerify
+	dir.BindDN = strings.TrimSpace(update.BindDN)
+	if update.BindPassword != nil {
+		dir.BindPassword = *update.BindPassword
+	}
+	dir.UserBaseDN = strings.TrimSpace(update.UserBaseDN)
+	dir.UserFilter = valueOr(update.UserFilter, "(uid={username})")
+	dir.UsernameAttribute = valueOr(update.UsernameAttribute, "uid")
+	dir.EmailAttribute = valueOr(update.EmailAttribute, "mail")
+	dir.GroupBaseDN = strings.TrimSpace(update.GroupBaseDN)
+	dir.GroupFilter = valueOr(update.GroupFilter, "(member={dn})")
+	dir.AutoProvision = update.AutoProvision
+	dir.DefaultRole = update.DefaultRole
+	dir.RoleMappings = update.RoleMappings
+
+	if !strings.Contains(dir.UserFilter, "{username}") {
+		return apperr.ErrBadRequest.Wrap(errors.New("user_filter must contain {username}"))
+	}
+	return nil
+}
+
+// clientConfig 返回目录服务的连接配置
+func (s *DirectoryService) clientConfig(dir *models.OrgDirectory) directory.Config {
+	return directory.Config{
+		URL:                dir.URL,
+		StartTLS:           dir.StartTLS,
+		InsecureSkipVerify: dir.InsecureSkipVerify,
+		BindDN:             dir.BindDN,
+		BindPassword:       dir.BindPassword,
+		UserBaseDN:         dir.UserBaseDN,
+		UserFilter:         dir.UserFilter,
+		UsernameAttribute:  dir.UsernameAttribute,
+		EmailAttribute:     dir.EmailAttribute,
+		GroupBaseDN:        dir.GroupBaseDN,
+		GroupFilter:        dir.GroupFilter,
+		Timeout:            s.timeout,
+	}
+}
+
+// checkOrg 检查组织是否存在且在调用者的组织范围内
+func (s *DirectoryService) checkOrg(ctx context.Context, orgID uint) error {
+	_, err := s.store.Organizations().Get(ctx, orgID)
+	if errors.Is(err, store.ErrNotFound) {
+		return ErrOrgNotFound
+	}
+	return err
+}
+
+// valueOr 去掉首尾空白后为空时返回默认值
+func valueOr(value, fallback string) string {
+	if value = strings.TrimSpace(value); value == "" {
+		return fallback
+	}
+	return value
+}
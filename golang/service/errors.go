@@ -0,0 +1,121 @@
+package service
+
+import (
+	"net/http"
+	"xzyq/apperr"
+)
+
+var (
+	// ErrUserNotFound 用户不存在
+	ErrUserNotFound = apperr.New(apperr.CodeUserNotFound, http.StatusNotFound)
+	// ErrUsernameTaken 用户名已被使用
+	ErrUsernameTaken = apperr.New(apperr.CodeUsernameTaken, http.StatusConflict)
+	// ErrInvalidCredentials 用户名或密码错误
+	ErrInvalidCredentials = apperr.New(apperr.CodeInvalidCredentials, http.StatusUnauthorized)
+	// ErrAccountDisabled 账号已被禁用
+	ErrAccountDisabled = apperr.New(apperr.CodeAccountDisabled, http.StatusForbidden)
+	// ErrUserAlreadyDisabled 要禁用的用户已被禁用
+	ErrUserAlreadyDisabled = apperr.New(apperr.CodeUserAlreadyDisabled, http.StatusConflict)
+	// ErrUserNotDisabled 要启用的用户未被禁用
+	ErrUserNotDisabled = apperr.New(apperr.CodeUserNotDisabled, http.StatusConflict)
+	// ErrCannotDisableSelf 管理员不能禁用自己
+	ErrCannotDisableSelf = apperr.New(apperr.CodeCannotDisableSelf, http.StatusBadRequest)
+	// ErrRegistrationClosed 组织不存在或未开放自助注册
+	ErrRegistrationClosed = apperr.New(apperr.CodeRegistrationClosed, http.StatusForbidden)
+	// ErrInvitationNotFound 邀请不存在或不在管理员的组织内
+	ErrInvitationNotFound = apperr.New(apperr.CodeInvitationNotFound, http.StatusNotFound)
+	// ErrInvitationInvalid 邀请令牌不存在、已过期、已被接受或已被撤销
+	ErrInvitationInvalid = apperr.New(apperr.CodeInvitationInvalid, http.StatusBadRequest)
+	// ErrInvitationNotPending 已被接受或撤销的邀请不能重新发送或撤销
+	ErrInvitationNotPending = apperr.New(apperr.CodeInvitationNotPending, http.StatusConflict)
+	// ErrInvitationExists 组织已有发给该邮箱的有效邀请
+	ErrInvitationExists = apperr.New(apperr.CodeInvitationExists, http.StatusConflict)
+	// ErrAccountLocked 连续登录失败次数过多，账号或IP已被锁定
+	ErrAccountLocked = apperr.New(apperr.CodeAccountLocked, http.StatusLocked)
+	// ErrLoginThrottled 登录失败后需要等待一段时间才能重试
+	ErrLoginThrottled = apperr.New(apperr.CodeLoginThrottled, http.StatusTooManyRequests)
+	// ErrRefreshTokenInvalid 刷新令牌不存在、已过期或已被吊销
+	ErrRefreshTokenInvalid = apperr.New(apperr.CodeRefreshTokenInvalid, http.StatusUnauthorized)
+	// ErrRefreshTokenReused 刷新令牌被重复使用，整个令牌族已被吊销
+	ErrRefreshTokenReused = apperr.New(apperr.CodeRefreshTokenReused, http.StatusUnauthorized)
+	// ErrMFAChallengeInvalid 两步验证登录挑战不存在、已过期或已被使用
+	ErrMFAChallengeInvalid = apperr.New(apperr.CodeMFAChallengeInvalid, http.StatusUnauthorized)
+	// ErrMFACodeInvalid TOTP验证码或恢复码错误
+	ErrMFACodeInvalid = apperr.New(apperr.CodeMFACodeInvalid, http.StatusBadRequest)
+	// ErrMFAAlreadyEnabled 已启用两步验证
+	ErrMFAAlreadyEnabled = apperr.New(apperr.CodeMFAAlreadyEnabled, http.StatusConflict)
+	// ErrMFANotEnrolled 尚未生成TOTP密钥或尚未启用两步验证
+	ErrMFANotEnrolled = apperr.New(apperr.CodeMFANotEnrolled, http.StatusBadRequest)
+	// ErrMFARequired 组织要求管理员启用两步验证
+	ErrMFARequired = apperr.New(apperr.CodeMFARequired, http.StatusForbidden)
+	// ErrOIDCProviderNotFound 身份提供方不存在或未启用
+	ErrOIDCProviderNotFound = apperr.New(apperr.CodeOIDCProviderNotFound, http.StatusNotFound)
+	// ErrOIDCProviderNameTaken 身份提供方标识已被使用
+	ErrOIDCProviderNameTaken = apperr.New(apperr.CodeOIDCProviderNameTaken, http.StatusConflict)
+	// ErrOIDCProviderUnavailable 读取发现文档失败
+	ErrOIDCProviderUnavailable = apperr.New(apperr.CodeOIDCProviderUnavailable, http.StatusBadGateway)
+	// ErrOIDCStateInvalid 单点登录状态不存在、已过期或已被使用
+	ErrOIDCStateInvalid = apperr.New(apperr.CodeOIDCStateInvalid, http.StatusBadRequest)
+	// ErrOIDCLoginFailed 换取令牌失败或ID令牌无效
+	ErrOIDCLoginFailed = apperr.New(apperr.CodeOIDCLoginFailed, http.StatusUnauthorized)
+	// ErrOIDCUserNotLinked 身份提供方的账号没有关联用户，且不允许自动创建
+	ErrOIDCUserNotLinked = apperr.New(apperr.CodeOIDCUserNotLinked, http.StatusForbidden)
+	// ErrSessionNotFound 会话不存在或不属于该用户
+	ErrSessionNotFound = apperr.New(apperr.CodeSessionNotFound, http.StatusNotFound)
+	// ErrAPIKeyInvalid API密钥不存在、已过期、已被吊销或所属用户已被删除
+	ErrAPIKeyInvalid = apperr.New(apperr.CodeAPIKeyInvalid, http.StatusUnauthorized)
+	// ErrAPIKeyNotFound 要吊销的密钥不存在或不属于该用户
+	ErrAPIKeyNotFound = apperr.New(apperr.CodeAPIKeyNotFound, http.StatusNotFound)
+	// ErrAPIKeyLimitReached 用户有效的密钥数量已达上限
+	ErrAPIKeyLimitReached = apperr.New(apperr.CodeAPIKeyLimitReached, http.StatusBadRequest)
+	// ErrServiceAccountNotFound 服务账号不存在或不在管理员的组织内
+	ErrServiceAccountNotFound = apperr.New(apperr.CodeServiceAccountNotFound, http.StatusNotFound)
+	// ErrServiceAccountNoPassword 服务账号不能设置密码
+	ErrServiceAccountNoPassword = apperr.New(apperr.CodeServiceAccountNoPassword, http.StatusBadRequest)
+	// ErrDirectoryNotConfigured 组织没有配置目录服务
+	ErrDirectoryNotConfigured = apperr.New(apperr.CodeDirectoryNotConfigured, http.StatusNotFound)
+	// ErrDirectoryUnavailable 连接目录服务或查询失败
+	ErrDirectoryUnavailable = apperr.New(apperr.CodeDirectoryUnavailable, http.StatusBadGateway)
+	// ErrDirectoryNoRole 目录中的账号不属于任何映射了角色的组，且没有默认角色
+	ErrDirectoryNoRole = apperr.New(apperr.CodeDirectoryNoRole, http.StatusForbidden)
+	// ErrInvalidOldPassword 原密码错误
+	ErrInvalidOldPassword = apperr.New(apperr.CodeInvalidOldPassword, http.StatusBadRequest)
+	// ErrPasswordResetInvalid 重置密码令牌不存在、已过期或已被使用
+	ErrPasswordResetInvalid = apperr.New(apperr.CodePasswordResetInvalid, http.StatusBadRequest)
+	// ErrPasswordTooShort 密码过短
+	ErrPasswordTooShort = apperr.New(apperr.CodePasswordTooShort, http.StatusBadRequest)
+	// ErrPasswordTooLong 密码过长
+	ErrPasswordTooLong = apperr.New(apperr.CodePasswordTooLong, http.StatusBadRequest)
+	// ErrPasswordNeedsUppercase 密码缺少大写字母
+	ErrPasswordNeedsUppercase = apperr.New(apperr.CodePasswordNeedsUppercase, http.StatusBadRequest)
+	// ErrPasswordNeedsLowercase 密码缺少小写字母
+	ErrPasswordNeedsLowercase = apperr.New(apperr.CodePasswordNeedsLowercase, http.StatusBadRequest)
+	// ErrPasswordNeedsDigit 密码缺少数字
+	ErrPasswordNeedsDigit = apperr.New(apperr.CodePasswordNeedsDigit, http.StatusBadRequest)
+	// ErrPasswordNeedsSymbol 密码缺少符号
+	ErrPasswordNeedsSymbol = apperr.New(apperr.CodePasswordNeedsSymbol, http.StatusBadRequest)
+	// ErrPasswordBanned 密码在禁用列表中
+	ErrPasswordBanned = apperr.New(apperr.CodePasswordBanned, http.StatusBadRequest)
+	// ErrPasswordContainsUsername 密码包含用户名
+	ErrPasswordContainsUsername = apperr.New(apperr.CodePasswordContainsUsername, http.StatusBadRequest)
+	// ErrPasswordReused 密码与最近使用过的密码相同
+	ErrPasswordReused = apperr.New(apperr.CodePasswordReused, http.StatusBadRequest)
+	// ErrPasswordExpired 密码已过期，需要修改后才能登录
+	ErrPasswordExpired = apperr.New(apperr.CodePasswordExpired, http.StatusForbidden)
+	// ErrPasswordManagedExternally 使用目录认证的用户不能在本系统中设置密码
+	ErrPasswordManagedExternally = apperr.New(apperr.CodePasswordManagedExternally, http.StatusBadRequest)
+	// ErrUserHasNoOrg 用户不属于任何组织
+	ErrUserHasNoOrg = apperr.New(apperr.CodeUserHasNoOrg, http.StatusBadRequest)
+
+	// ErrOrgNotFound 组织不存在
+	ErrOrgNotFound = apperr.New(apperr.CodeOrgNotFound, http.StatusNotFound)
+	// ErrOrgHasUsers 组织下还有用户，不能删除
+	ErrOrgHasUsers = apperr.New(apperr.CodeOrgHasUsers, http.StatusConflict)
+	// ErrAdminUsernameTaken 组织管理员用户名已被使用
+	ErrAdminUsernameTaken = apperr.New(apperr.CodeAdminUsernameTaken, http.StatusConflict)
+
+	// ErrObjectClassNotFound 对象类不存在
+	ErrObjectClassNotFound = apperr.New(apperr.CodeObjectClassNotFound, http.StatusNotFound)
+	// ErrParentObjectClassNotFound 父对象类不存在
+	ErrParentObjectClassNotFound = apperr.New(apperr.CodeParentObjectClassNotFound, http.StatusNotFound)
+)
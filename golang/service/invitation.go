@@ -0,0 +1,297 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+	"xzyq/apperr"
+	"xzyq/config"
+	"xzyq/logging"
+	"xzyq/mailer"
+	"xzyq/models"
+	"xzyq/store"
+	"xzyq/tenant"
+	"xzyq/utils"
+)
+
+// invitationTokenSize 邀请令牌的随机字节数
+const invitationTokenSize = 32
+
+// InvitationService 邀请用户加入组织
+//
+// 组织默认不开放自助注册，由管理员向邮箱发出邀请，被邀请人通过邮件中的链接设置用户名和密码。
+// 令牌只保存哈希，只能使用一次；重新发送时更换令牌，之前的链接随之失效。
+type InvitationService struct {
+	store     store.Store
+	passwords *PasswordService
+	mailer    mailer.Mailer
+	expire    time.Duration
+	url       string
+}
+
+// NewInvitationService 创建InvitationService
+func NewInvitationService(st store.Store, passwords *PasswordService, m mailer.Mailer, cfg config.InvitationConfig) *InvitationService {
+	return &InvitationService{
+		store:     st,
+		passwords: passwords,
+		mailer:    m,
+		expire:    cfg.Expire,
+		url:       cfg.URL,
+	}
+}
+
+// List 返回管理员组织范围内的邀请，最近创建的在前
+func (s *InvitationService) List(ctx context.Context) ([]models.Invitation, error) {
+	invitations, err := s.store.Invitations().List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	for i := range invitations {
+		invitations[i].Status = invitations[i].StatusAt(now)
+	}
+	return invitations, nil
+}
+
+// Create 邀请邮箱加入组织并发送邀请邮件
+//
+// 组织管理员只能邀请用户加入自己的组织，平台管理员需要指定组织。
+func (s *InvitationService) Create(ctx context.Context, inviterID uint, orgID *uint, email, role, ip string) (*models.Invitation, error) {
+	inviter, err := s.store.Users().GetUnscoped(tenant.Unscoped(ctx), inviterID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+	if inviter.OrgID != nil {
+		orgID = inviter.OrgID
+	} else if orgID == nil {
+		return nil, apperr.ErrBadRequest.Wrap(errors.New("org_id is required"))
+	}
+	org, err := s.store.Organizations().Get(ctx, *orgID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return nil, ErrOrgNotFound
+		}
+		return nil, err
+	}
+
+	email = strings.TrimSpace(email)
+	now := time.Now()
+	exists, err := s.store.Invitations().PendingExists(ctx, org.ID, email, now)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		return nil, ErrInvitationExists
+	}
+
+	token, err := utils.RandomToken(invitationTokenSize)
+	if err != nil {
+		return nil, fmt.Errorf("generate invitation token: %w", err)
+	}
+	invitation := &models.Invitation{
+		OrgID:     org.ID,
+		Email:     email,
+		Role:      role,
+		TokenHash: utils.HashToken(token),
+		InvitedBy: inviter.ID,
+		SentAt:    now,
+		ExpiresAt: now.Add(s.expire),
+	}
+	if err := s.store.Invitations().Create(ctx, invitation); err != nil {
+		return nil, fmt.Errorf("store invitation: %w", err)
+	}
+	if err := s.send(ctx, inviter, org, invitation, token); err != nil {
+		return nil, err
+	}
+
+	logging.FromContext(ctx).Info("invitation created", "invitation_id", invitation.ID, "org_id", org.ID, "invited_by", inviter.ID)
+	s.writeLog(ctx, inviter, "invitation_created", ip, email)
+	invitation.Status = invitation.StatusAt(now)
+	return invitation, nil
+}
+
+// Resend 更换邀请令牌并重新发送邮件，有效期重新计算，已过期的邀请也可以重新发送
+func (s *InvitationService) Resend(ctx context.Context, adminID, id uint, ip string) (*models.Invitation, error) {
+	invitation, err := s.get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	admin, err := s.store.Users().GetUnscoped(tenant.Unscoped(ctx), adminID)
+	if err != nil {
+		return nil, err
+	}
+	org, err := s.store.Organizations().Get(tenant.Unscoped(ctx), invitation.OrgID)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := utils.RandomToken(invitationTokenSize)
+	if err != nil {
+		return nil, fmt.Errorf("generate invitation token: %w", err)
+	}
+	now := time.Now()
+	resent, err := s.store.Invitations().Resend(ctx, invitation.ID, utils.HashToken(token), now, now.Add(s.expire))
+	if err != nil {
+		return nil, err
+	}
+	if !resent {
+		return nil, ErrInvitationNotPending
+	}
+	invitation.SentAt, invitation.ExpiresAt = now, now.Add(s.expire)
+	if err := s.send(ctx, admin, org, invitation, token); err != nil {
+		return nil, err
+	}
+
+	logging.FromContext(ctx).Info("invitation resent", "invitation_id", invitation.ID, "org_id", invitation.OrgID)
+	s.writeLog(ctx, admin, "invitation_resent", ip, invitation.Email)
+	invitation.Status = invitation.StatusAt(now)
+	return invitation, nil
+}
+
+// Revoke 撤销尚未接受的邀请，邀请链接立即失效
+func (s *InvitationService) Revoke(ctx context.Context, adminID, id uint, ip string) error {
+	invitation, err := s.get(ctx, id)
+	if err != nil {
+		return err
+	}
+	revoked, err := s.store.Invitations().Revoke(ctx, invitation.ID, time.Now())
+	if err != nil {
+		return err
+	}
+	if !revoked {
+		return ErrInvitationNotPending
+	}
+
+	admin, err := s.store.Users().GetUnscoped(tenant.Unscoped(ctx), adminID)
+	if err != nil {
+		return err
+	}
+	logging.FromContext(ctx).Info("invitation revoked", "invitation_id", invitation.ID, "org_id", invitation.OrgID)
+	s.writeLog(ctx, admin, "invitation_revoked", ip, invitation.Email)
+	return nil
+}
+
+// Accept 接受邀请，以邀请的邮箱和角色在组织中创建用户
+func (s *InvitationService) Accept(ctx context.Context, token, username, password, phone, ip string) (*models.User, error) {
+	ctx = tenant.Unscoped(ctx)
+
+	invitation, err := s.store.Invitations().GetByHash(ctx, utils.HashToken(token))
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return nil, ErrInvitationInvalid
+		}
+		return nil, err
+	}
+	now := time.Now()
+	if invitation.StatusAt(now) != models.InvitationPending {
+		return nil, ErrInvitationInvalid
+	}
+
+	user := &models.User{
+		Username:          username,
+		Email:             invitation.Email,
+		Phone:             phone,
+		Role:              invitation.Role,
+		OrgID:             &invitation.OrgID,
+		IsActive:          true,
+		CreatedBy:         invitation.InvitedBy,
+		PasswordChangedAt: &now,
+	}
+	// 按组织的密码策略校验，不符合要求时邀请仍然可以使用
+	if user.Password, err = s.passwords.Hash(ctx, user, password); err != nil {
+		return nil, err
+	}
+
+	err = s.store.Transaction(ctx, func(tx store.Store) error {
+		exists, err := tx.Users().UsernameExists(ctx, username)
+		if err != nil {
+			return err
+		}
+		if exists {
+			return ErrUsernameTaken
+		}
+		if err := tx.Users().Create(ctx, user); err != nil {
+			return err
+		}
+		// 并发接受同一个邀请时只有一个成功
+		accepted, err := tx.Invitations().Accept(ctx, invitation.ID, now, user.ID)
+		if err != nil {
+			return err
+		}
+		if !accepted {
+			return ErrInvitationInvalid
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logging.FromContext(ctx).Info("invitation accepted", "invitation_id", invitation.ID, "org_id", invitation.OrgID, "user_id", user.ID)
+	s.writeLog(ctx, user, "invitation_accepted", ip, invitation.Email)
+	return user, nil
+}
+
+// get 查询管理员组织范围内的邀请
+func (s *InvitationService) get(ctx context.Context, id uint) (*models.Invitation, error) {
+	invitation, err := s.store.Invitations().Get(ctx, id)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return nil, ErrInvitationNotFound
+		}
+		return nil, err
+	}
+	return invitation, nil
+}
+
+// send 生成邀请邮件并在后台发送
+func (s *InvitationService) send(ctx context.Context, inviter *models.User, org *models.Organization, invitation *models.Invitation, token string) error {
+	link, err := url.Parse(s.url)
+	if err != nil {
+		return fmt.Errorf("parse invitation url: %w", err)
+	}
+	query := link.Query()
+	query.Set("token", token)
+	link.RawQuery = query.Encode()
+
+	body := fmt.Sprintf(`您好：
+
+%s 邀请您加入组织[%s]。请在 %d 小时内打开下面的链接设置用户名和密码：
+
+%s
+
+链接只能使用一次。如果您不认识邀请人，请忽略此邮件。
+`, inviter.Username, org.Name, int(s.expire/time.Hour), link.String())
+	msg := mailer.Message{To: invitation.Email, Subject: "邀请您加入" + org.Name, Body: body}
+
+	go func(ctx context.Context) {
+		ctx, cancel := context.WithTimeout(ctx, mailSendTimeout)
+		defer cancel()
+
+		if err := s.mailer.Send(ctx, msg); err != nil {
+			logging.FromContext(ctx).Error("send invitation mail failed", "invitation_id", invitation.ID, "error", err)
+		}
+	}(context.WithoutCancel(ctx))
+	return nil
+}
+
+// writeLog 写入邀请日志，detail为被邀请的邮箱，失败时只记录日志
+func (s *InvitationService) writeLog(ctx context.Context, user *models.User, action, ip, detail string) {
+	err := s.store.Logs().Create(ctx, &models.Log{
+		UserID:    user.ID,
+		Username:  user.Username,
+		Action:    action,
+		IP:        ip,
+		Detail:    detail,
+		Timestamp: time.Now(),
+	})
+	if err != nil {
+		logging.FromContext(ctx).Error("write invitation log failed", "action", action, "error", err)
+	}
+}
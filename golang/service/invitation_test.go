@@ -0,0 +1,136 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sync"
+	"testing"
+	"time"
+	"xzyq/mailer"
+	"xzyq/models"
+	"xzyq/tenant"
+)
+
+// fakeMailer 把发送的邮件放入channel，邀请邮件在后台发送
+type fakeMailer struct {
+	sent chan mailer.Message
+}
+
+func (m *fakeMailer) Send(_ context.Context, msg mailer.Message) error {
+	m.sent <- msg
+	return nil
+}
+
+// invitationTokenPattern 邀请邮件中链接的令牌参数
+var invitationTokenPattern = regexp.MustCompile(`[?&]token=([^&\s]+)`)
+
+// invitationToken 等待发往email的邀请邮件并取出其中的令牌
+func (m *fakeMailer) invitationToken(t *testing.T, email string) string {
+	t.Helper()
+
+	select {
+	case msg := <-m.sent:
+		if msg.To != email {
+			t.Fatalf("invitation sent to %s, want %s", msg.To, email)
+		}
+		match := invitationTokenPattern.FindStringSubmatch(msg.Body)
+		if match == nil {
+			t.Fatalf("invitation mail has no token: %s", msg.Body)
+		}
+		token, err := url.QueryUnescape(match[1])
+		if err != nil {
+			t.Fatalf("unescape invitation token: %v", err)
+		}
+		return token
+	case <-time.After(5 * time.Second):
+		t.Fatal("invitation mail not sent")
+		return ""
+	}
+}
+
+// newTestInvitation 创建组织和组织管理员，邀请email加入组织并返回邀请令牌
+func newTestInvitation(t *testing.T, e *testEnv, email string) (*InvitationService, *models.Organization, string) {
+	t.Helper()
+
+	ctx := tenant.Unscoped(context.Background())
+	org := &models.Organization{Name: "acme"}
+	if err := e.store.Organizations().Create(ctx, org); err != nil {
+		t.Fatalf("create organization: %v", err)
+	}
+	admin := e.createUser(t, "acme_admin", "Xq7#pass-word", "admin", &org.ID)
+
+	m := &fakeMailer{sent: make(chan mailer.Message, 1)}
+	invitations := NewInvitationService(e.store, e.passwords, m, e.cfg.Auth.Invitation)
+	if _, err := invitations.Create(ctx, admin.ID, nil, email, "user", "127.0.0.1"); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	return invitations, org, m.invitationToken(t, email)
+}
+
+func TestInvitationAcceptOnce(t *testing.T) {
+	e := newTestEnv(t)
+	ctx := context.Background()
+	invitations, org, token := newTestInvitation(t, e, "alice@example.com")
+
+	user, err := invitations.Accept(ctx, token, "alice", "Xq7#pass-word", "", "127.0.0.1")
+	if err != nil {
+		t.Fatalf("Accept: %v", err)
+	}
+	if user.OrgID == nil || *user.OrgID != org.ID || user.Email != "alice@example.com" || user.Role != "user" {
+		t.Errorf("accepted user = %+v, want user alice@example.com in organization %d", user, org.ID)
+	}
+	e.login(t, "alice", "Xq7#pass-word")
+
+	if _, err := invitations.Accept(ctx, token, "alice2", "Xq7#pass-word", "", "127.0.0.1"); !errors.Is(err, ErrInvitationInvalid) {
+		t.Errorf("Accept twice: err = %v, want ErrInvitationInvalid", err)
+	}
+	if _, err := invitations.Accept(ctx, "unknown", "bob", "Xq7#pass-word", "", "127.0.0.1"); !errors.Is(err, ErrInvitationInvalid) {
+		t.Errorf("Accept with unknown token: err = %v, want ErrInvitationInvalid", err)
+	}
+}
+
+// 并发接受同一个邀请时只创建一个用户
+func TestInvitationConcurrentAccept(t *testing.T) {
+	e := newTestEnv(t)
+	ctx := context.Background()
+	invitations, org, token := newTestInvitation(t, e, "alice@example.com")
+
+	const n = 5
+	var wg sync.WaitGroup
+	errs := make(chan error, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, err := invitations.Accept(ctx, token, fmt.Sprintf("alice%d", i), "Xq7#pass-word", "", "127.0.0.1")
+			errs <- err
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+
+	accepted := 0
+	for err := range errs {
+		switch {
+		case err == nil:
+			accepted++
+		case !errors.Is(err, ErrInvitationInvalid):
+			t.Errorf("Accept: err = %v, want nil or ErrInvitationInvalid", err)
+		}
+	}
+	if accepted != 1 {
+		t.Errorf("accepted %d times, want 1", accepted)
+	}
+
+	users, err := e.store.Users().ListByOrg(tenant.Unscoped(ctx), org.ID)
+	if err != nil {
+		t.Fatalf("ListByOrg: %v", err)
+	}
+	// 组织管理员和接受邀请创建的用户
+	if len(users) != 2 {
+		t.Errorf("organization has %d users, want 2", len(users))
+	}
+}
@@ -0,0 +1,146 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"math"
+	"time"
+	"xzyq/config"
+	"xzyq/logging"
+	"xzyq/models"
+	"xzyq/store"
+)
+
+// 登录失败原因，写入日志表的reason字段
+const (
+	LoginFailUnknownUser = "unknown_user"
+	LoginFailBadPassword = "bad_password"
+	LoginFailLocked      = "locked"
+	LoginFailThrottled   = "throttled"
+	LoginFailDisabled    = "disabled"
+	LoginFailDeleted     = "deleted"
+	LoginFailBadMFACode  = "bad_mfa_code"
+	LoginFailExpired     = "password_expired"
+	LoginFailOIDC        = "oidc_failed"
+	LoginFailNotLinked   = "not_linked"
+	LoginFailNoRole      = "no_role"
+)
+
+// LoginGuard 按用户名和IP统计连续登录失败次数，实现逐次增加的等待时间和临时锁定
+//
+// 失败记录保存在数据库中，多实例部署时共享。
+type LoginGuard struct {
+	store store.Store
+	cfg   config.LockoutConfig
+}
+
+// NewLoginGuard 创建LoginGuard
+func NewLoginGuard(st store.Store, cfg config.LockoutConfig) *LoginGuard {
+	return &LoginGuard{store: st, cfg: cfg}
+}
+
+// Check 在校验密码之前调用，用户名或IP被锁定、或仍处于等待时间内时返回错误和失败原因
+func (g *LoginGuard) Check(ctx context.Context, username, ip string) (reason string, err error) {
+	now := time.Now()
+	for _, s := range []struct {
+		scope, subject string
+		delayAfter     int
+	}{
+		{models.LoginScopeUser, username, g.cfg.DelayAfter},
+		{models.LoginScopeIP, ip, g.cfg.IPDelayAfter},
+	} {
+		attempt, err := g.store.LoginAttempts().Get(ctx, s.scope, s.subject)
+		if err != nil {
+			if errors.Is(err, store.ErrNotFound) {
+				continue
+			}
+			return "", err
+		}
+		if !g.active(attempt, now) {
+			continue
+		}
+
+		if attempt.LockedUntil != nil {
+			return LoginFailLocked, ErrAccountLocked.WithDetails(retryAfter(attempt.LockedUntil.Sub(now)))
+		}
+		if next := attempt.LastFailedAt.Add(g.delay(attempt.Failures, s.delayAfter)); now.Before(next) {
+			return LoginFailThrottled, ErrLoginThrottled.WithDetails(retryAfter(next.Sub(now)))
+		}
+	}
+	return "", nil
+}
+
+// Fail 记录一次登录失败，达到上限时锁定
+func (g *LoginGuard) Fail(ctx context.Context, username, ip string) error {
+	now := time.Now()
+	logger := logging.FromContext(ctx)
+
+	err := g.store.Transaction(ctx, func(tx store.Store) error {
+		if err := g.fail(ctx, tx, models.LoginScopeUser, username, g.cfg.MaxFailures, now); err != nil {
+			return err
+		}
+		return g.fail(ctx, tx, models.LoginScopeIP, ip, g.cfg.IPMaxFailures, now)
+	})
+	if err != nil {
+		return err
+	}
+
+	// 顺便清理长时间没有失败的记录
+	if err := g.store.LoginAttempts().DeleteStale(ctx, now.Add(-g.cfg.Window)); err != nil {
+		logger.Error("delete stale login attempts failed", "error", err)
+	}
+	return nil
+}
+
+// Succeed 登录成功后清除用户名的失败计数，IP的计数不清除，避免攻击者用自己的账号重置
+func (g *LoginGuard) Succeed(ctx context.Context, username string) error {
+	return g.store.LoginAttempts().Delete(ctx, models.LoginScopeUser, username)
+}
+
+// Unlock 管理员解锁用户
+func (g *LoginGuard) Unlock(ctx context.Context, username string) error {
+	return g.store.LoginAttempts().Delete(ctx, models.LoginScopeUser, username)
+}
+
+// fail 增加失败计数，达到上限时锁定
+func (g *LoginGuard) fail(ctx context.Context, tx store.Store, scope, subject string, limit int, now time.Time) error {
+	attempt, err := tx.LoginAttempts().Fail(ctx, scope, subject, now, now.Add(-g.cfg.Window), limit, now.Add(g.cfg.Duration))
+	if err != nil {
+		return err
+	}
+	if attempt.Failures >= limit {
+		logging.FromContext(ctx).Warn("login locked", "scope", scope, "subject", subject,
+			"failures", attempt.Failures, "locked_until", attempt.LockedUntil)
+	}
+	return nil
+}
+
+// active 失败记录是否仍然有效：处于锁定中，或者未锁定过且在Window内有失败
+func (g *LoginGuard) active(attempt *models.LoginAttempt, now time.Time) bool {
+	if attempt.LockedUntil != nil {
+		// 锁定到期后重新开始计数
+		return now.Before(*attempt.LockedUntil)
+	}
+	return now.Sub(attempt.LastFailedAt) <= g.cfg.Window
+}
+
+// delay 连续失败failures次后下一次登录前需要等待的时间，失败delayAfter次后开始等待
+func (g *LoginGuard) delay(failures, delayAfter int) time.Duration {
+	if failures < delayAfter || g.cfg.BaseDelay <= 0 {
+		return 0
+	}
+	d := float64(g.cfg.BaseDelay) * math.Pow(2, float64(failures-delayAfter))
+	if d > float64(g.cfg.Duration) {
+		return g.cfg.Duration
+	}
+	return time.Duration(d)
+}
+
+// retryAfter 返回错误详情中的等待秒数，不足一秒按一秒计算
+func retryAfter(d time.Duration) map[string]interface{} {
+	seconds := int64(math.Ceil(d.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	return map[string]interface{}{"retry_after": seconds}
+}
@@ -0,0 +1,122 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+	"xzyq/config"
+	"xzyq/models"
+)
+
+// 并发的登录失败都被计数，不会互相覆盖
+func TestLoginGuardConcurrentFailures(t *testing.T) {
+	e := newTestEnv(t, func(cfg *config.Config) {
+		cfg.Auth.Lockout.MaxFailures = 100
+		cfg.Auth.Lockout.IPMaxFailures = 100
+	})
+	ctx := context.Background()
+
+	const n = 20
+	var wg sync.WaitGroup
+	errs := make(chan error, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs <- e.guard.Fail(ctx, "alice", fmt.Sprintf("10.0.0.%d", i))
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("Fail: %v", err)
+		}
+	}
+
+	attempt, err := e.store.LoginAttempts().Get(ctx, models.LoginScopeUser, "alice")
+	if err != nil {
+		t.Fatalf("get login attempt: %v", err)
+	}
+	if attempt.Failures != n {
+		t.Errorf("failures = %d, want %d", attempt.Failures, n)
+	}
+}
+
+func TestLoginGuardLocksAtLimit(t *testing.T) {
+	e := newTestEnv(t, func(cfg *config.Config) {
+		cfg.Auth.Lockout.MaxFailures = 3
+		// 只检查锁定，不检查等待时间
+		cfg.Auth.Lockout.BaseDelay = 0
+	})
+	ctx := context.Background()
+	e.createUser(t, "alice", "Xq7#pass-word", "user", nil)
+
+	for i := 0; i < 2; i++ {
+		if _, err := e.users.Login(ctx, "alice", "wrong", nil, "127.0.0.1", "test"); !errors.Is(err, ErrInvalidCredentials) {
+			t.Fatalf("Login with wrong password: err = %v, want ErrInvalidCredentials", err)
+		}
+	}
+	if _, err := e.guard.Check(ctx, "alice", "127.0.0.1"); err != nil {
+		t.Fatalf("Check below limit: %v", err)
+	}
+
+	if _, err := e.users.Login(ctx, "alice", "wrong", nil, "127.0.0.1", "test"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("Login with wrong password: err = %v, want ErrInvalidCredentials", err)
+	}
+	if reason, err := e.guard.Check(ctx, "alice", "10.0.0.1"); !errors.Is(err, ErrAccountLocked) || reason != LoginFailLocked {
+		t.Fatalf("Check at limit: reason = %q, err = %v, want ErrAccountLocked", reason, err)
+	}
+	// 锁定期间正确的密码也不能登录
+	if _, err := e.users.Login(ctx, "alice", "Xq7#pass-word", nil, "10.0.0.1", "test"); !errors.Is(err, ErrAccountLocked) {
+		t.Errorf("Login while locked: err = %v, want ErrAccountLocked", err)
+	}
+
+	if err := e.guard.Unlock(ctx, "alice"); err != nil {
+		t.Fatalf("Unlock: %v", err)
+	}
+	e.login(t, "alice", "Xq7#pass-word")
+}
+
+// 锁定到期或超出统计窗口后从1重新计数
+func TestLoginAttemptFailRestartsCount(t *testing.T) {
+	st := newTestStore(t)
+	ctx := context.Background()
+	const window, lock = 15 * time.Minute, time.Minute
+	now := time.Now()
+
+	fail := func(at time.Time) *models.LoginAttempt {
+		t.Helper()
+		attempt, err := st.LoginAttempts().Fail(ctx, models.LoginScopeUser, "alice", at, at.Add(-window), 3, at.Add(lock))
+		if err != nil {
+			t.Fatalf("Fail: %v", err)
+		}
+		return attempt
+	}
+
+	for i := 1; i <= 3; i++ {
+		attempt := fail(now)
+		if attempt.Failures != i {
+			t.Fatalf("failures = %d, want %d", attempt.Failures, i)
+		}
+		if locked := attempt.LockedUntil != nil; locked != (i == 3) {
+			t.Fatalf("after %d failures locked = %v", i, locked)
+		}
+	}
+	// 锁定期间的失败继续计数并延长锁定
+	later := now.Add(time.Second)
+	if attempt := fail(later); attempt.Failures != 4 || attempt.LockedUntil == nil || !attempt.LockedUntil.Equal(later.Add(lock)) {
+		t.Errorf("failure while locked = %+v, want 4 failures locked until %v", attempt, later.Add(lock))
+	}
+
+	expired := later.Add(2 * lock)
+	if attempt := fail(expired); attempt.Failures != 1 || attempt.LockedUntil != nil {
+		t.Errorf("failure after lock expired = %+v, want 1 failure and no lock", attempt)
+	}
+	if attempt := fail(expired.Add(window + time.Second)); attempt.Failures != 1 {
+		t.Errorf("failure after window = %d failures, want 1", attempt.Failures)
+	}
+}
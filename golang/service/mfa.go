@@ -0,0 +1,301 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"xzyq/config"
+	"xzyq/logging"
+	"xzyq/models"
+	"xzyq/store"
+	"xzyq/tenant"
+	"xzyq/utils"
+)
+
+const (
+	// recoveryCodeCount 每次生成的恢复码数量
+	recoveryCodeCount = 10
+	// mfaChallengeSize 登录挑战令牌的随机字节数
+	mfaChallengeSize = 32
+)
+
+// MFAStatus 当前用户的两步验证状态
+type MFAStatus struct {
+	Enabled                bool  `json:"enabled"`
+	Required               bool  `json:"required"` // 组织要求该用户启用两步验证
+	RecoveryCodesRemaining int64 `json:"recovery_codes_remaining"`
+}
+
+// MFAEnrollment 新生成的TOTP密钥，验证第一个验证码后才会启用
+type MFAEnrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"otpauth_uri"`
+}
+
+// MFAChallenge 密码验证通过后返回的挑战，客户端凭此提交第二步验证
+type MFAChallenge struct {
+	Token              string `json:"mfa_token"`
+	ExpiresIn          int64  `json:"expires_in"`
+	EnrollmentRequired bool   `json:"enrollment_required"` // 组织要求启用但用户尚未设置，需要先完成设置
+}
+
+// MFAService TOTP两步验证和恢复码
+//
+// 各方法接收调用方已经按权限加载的用户，更新该用户自己的记录时不再受组织范围限制。
+type MFAService struct {
+	store           store.Store
+	issuer          string
+	challengeExpire time.Duration
+}
+
+// NewMFAService 创建MFAService
+func NewMFAService(st store.Store, cfg config.MFAConfig) *MFAService {
+	return &MFAService{store: st, issuer: cfg.Issuer, challengeExpire: cfg.ChallengeExpire}
+}
+
+// Status 返回用户的两步验证状态
+func (s *MFAService) Status(ctx context.Context, user *models.User) (*MFAStatus, error) {
+	required, err := s.Required(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+	remaining, err := s.store.RecoveryCodes().CountUnused(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &MFAStatus{Enabled: user.MFAEnabled, Required: required, RecoveryCodesRemaining: remaining}, nil
+}
+
+// Required 组织开启了管理员必须使用两步验证时，该组织的管理员必须启用
+func (s *MFAService) Required(ctx context.Context, user *models.User) (bool, error) {
+	if user.Role != "admin" || user.OrgID == nil {
+		return false, nil
+	}
+	org, err := s.store.Organizations().Get(ctx, *user.OrgID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	return org.RequireAdminMFA, nil
+}
+
+// Enroll 生成新的TOTP密钥，替换尚未启用的旧密钥
+func (s *MFAService) Enroll(ctx context.Context, user *models.User) (*MFAEnrollment, error) {
+	if user.MFAEnabled {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	secret, err := utils.NewTOTPSecret()
+	if err != nil {
+		return nil, fmt.Errorf("generate totp secret: %w", err)
+	}
+	if err := s.store.Users().UpdateMFA(tenant.Unscoped(ctx), user.ID, secret, false); err != nil {
+		return nil, err
+	}
+	user.MFASecret = secret
+
+	return &MFAEnrollment{Secret: secret, URI: utils.TOTPURI(s.issuer, user.Username, secret)}, nil
+}
+
+// Activate 验证第一个验证码后启用两步验证，返回新生成的恢复码
+func (s *MFAService) Activate(ctx context.Context, user *models.User, code string) ([]string, error) {
+	if user.MFAEnabled {
+		return nil, ErrMFAAlreadyEnabled
+	}
+	if user.MFASecret == "" {
+		return nil, ErrMFANotEnrolled
+	}
+	step, ok := utils.ValidateTOTP(user.MFASecret, code, time.Now())
+	if !ok {
+		return nil, ErrMFACodeInvalid
+	}
+
+	var codes []string
+	err := s.store.Transaction(ctx, func(tx store.Store) error {
+		if err := tx.Users().UpdateMFA(tenant.Unscoped(ctx), user.ID, user.MFASecret, true); err != nil {
+			return err
+		}
+		if _, err := tx.Users().UseMFAStep(tenant.Unscoped(ctx), user.ID, step); err != nil {
+			return err
+		}
+		var err error
+		codes, err = s.replaceRecoveryCodes(ctx, tx, user.ID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	user.MFAEnabled = true
+
+	logging.FromContext(ctx).Info("mfa enabled", "user_id", user.ID)
+	return codes, nil
+}
+
+// Verify 校验TOTP验证码或恢复码，二者提供其一即可
+func (s *MFAService) Verify(ctx context.Context, user *models.User, code, recoveryCode string) error {
+	if !user.MFAEnabled {
+		return ErrMFANotEnrolled
+	}
+
+	if recoveryCode != "" {
+		used, err := s.store.RecoveryCodes().Use(ctx, user.ID, hashRecoveryCode(recoveryCode), time.Now())
+		if err != nil {
+			return err
+		}
+		if !used {
+			return ErrMFACodeInvalid
+		}
+		logging.FromContext(ctx).Info("mfa recovery code used", "user_id", user.ID)
+		return nil
+	}
+
+	step, ok := utils.ValidateTOTP(user.MFASecret, code, time.Now())
+	if !ok {
+		return ErrMFACodeInvalid
+	}
+	// 同一个验证码只能使用一次
+	fresh, err := s.store.Users().UseMFAStep(tenant.Unscoped(ctx), user.ID, step)
+	if err != nil {
+		return err
+	}
+	if !fresh {
+		return ErrMFACodeInvalid
+	}
+	return nil
+}
+
+// RegenerateRecoveryCodes 验证TOTP验证码后重新生成恢复码，旧的恢复码全部失效
+func (s *MFAService) RegenerateRecoveryCodes(ctx context.Context, user *models.User, code string) ([]string, error) {
+	if err := s.Verify(ctx, user, code, ""); err != nil {
+		return nil, err
+	}
+	return s.replaceRecoveryCodes(ctx, s.store, user.ID)
+}
+
+// Disable 验证后关闭两步验证，组织要求启用时不允许关闭
+func (s *MFAService) Disable(ctx context.Context, user *models.User, code, recoveryCode string) error {
+	required, err := s.Required(ctx, user)
+	if err != nil {
+		return err
+	}
+	if required {
+		return ErrMFARequired
+	}
+	if err := s.Verify(ctx, user, code, recoveryCode); err != nil {
+		return err
+	}
+	if err := s.Reset(ctx, user); err != nil {
+		return err
+	}
+
+	logging.FromContext(ctx).Info("mfa disabled", "user_id", user.ID)
+	return nil
+}
+
+// Reset 清除用户的TOTP密钥和恢复码，用于用户关闭两步验证或管理员为丢失设备的用户重置
+func (s *MFAService) Reset(ctx context.Context, user *models.User) error {
+	err := s.store.Transaction(ctx, func(tx store.Store) error {
+		if err := tx.Users().UpdateMFA(tenant.Unscoped(ctx), user.ID, "", false); err != nil {
+			return err
+		}
+		return tx.RecoveryCodes().DeleteByUser(ctx, user.ID)
+	})
+	if err != nil {
+		return err
+	}
+	user.MFASecret = ""
+	user.MFAEnabled = false
+	return nil
+}
+
+// NewChallenge 为通过密码验证的用户创建登录挑战
+func (s *MFAService) NewChallenge(ctx context.Context, user *models.User, ip string) (*MFAChallenge, error) {
+	token, err := utils.RandomToken(mfaChallengeSize)
+	if err != nil {
+		return nil, fmt.Errorf("generate mfa challenge: %w", err)
+	}
+
+	now := time.Now()
+	// 顺便清理已过期的挑战
+	if err := s.store.MFAChallenges().DeleteExpired(ctx, now); err != nil {
+		logging.FromContext(ctx).Error("delete expired mfa challenges failed", "error", err)
+	}
+
+	err = s.store.MFAChallenges().Create(ctx, &models.MFAChallenge{
+		UserID:    user.ID,
+		TokenHash: utils.HashToken(token),
+		IP:        ip,
+		ExpiresAt: now.Add(s.challengeExpire),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("store mfa challenge: %w", err)
+	}
+
+	return &MFAChallenge{
+		Token:              token,
+		ExpiresIn:          int64(s.challengeExpire / time.Second),
+		EnrollmentRequired: !user.MFAEnabled,
+	}, nil
+}
+
+// Challenge 返回未使用且未过期的登录挑战
+func (s *MFAService) Challenge(ctx context.Context, token string) (*models.MFAChallenge, error) {
+	challenge, err := s.store.MFAChallenges().GetByHash(ctx, utils.HashToken(token))
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return nil, ErrMFAChallengeInvalid
+		}
+		return nil, err
+	}
+	if challenge.UsedAt != nil || !time.Now().Before(challenge.ExpiresAt) {
+		return nil, ErrMFAChallengeInvalid
+	}
+	return challenge, nil
+}
+
+// CompleteChallenge 第二步验证通过后将挑战标记为已使用，并发提交时只有一个成功
+func (s *MFAService) CompleteChallenge(ctx context.Context, challenge *models.MFAChallenge) error {
+	marked, err := s.store.MFAChallenges().MarkUsed(ctx, challenge.ID, time.Now())
+	if err != nil {
+		return err
+	}
+	if !marked {
+		return ErrMFAChallengeInvalid
+	}
+	return nil
+}
+
+// replaceRecoveryCodes 生成新的恢复码并替换旧的恢复码
+func (s *MFAService) replaceRecoveryCodes(ctx context.Context, st store.Store, userID uint) ([]string, error) {
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		code, err := utils.RandomCode(5)
+		if err != nil {
+			return nil, fmt.Errorf("generate recovery code: %w", err)
+		}
+		// 8个字符分两组，便于抄写
+		codes[i] = code[:4] + "-" + code[4:]
+		hashes[i] = hashRecoveryCode(codes[i])
+	}
+
+	if err := st.RecoveryCodes().Replace(ctx, userID, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// hashRecoveryCode 忽略分隔符、空白和大小写后计算哈希
+func hashRecoveryCode(code string) string {
+	normalized := strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' {
+			return -1
+		}
+		return r
+	}, strings.ToLower(strings.TrimSpace(code)))
+	return utils.HashToken(normalized)
+}
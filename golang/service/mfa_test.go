@@ -0,0 +1,131 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+	"xzyq/models"
+)
+
+// totpCode 按RFC 6238计算secret在step时间步的验证码
+func totpCode(t *testing.T, secret string, step int64) string {
+	t.Helper()
+
+	key, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(secret)
+	if err != nil {
+		t.Fatalf("decode totp secret: %v", err)
+	}
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(step))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+	offset := sum[len(sum)-1] & 0x0f
+	return fmt.Sprintf("%06d", (binary.BigEndian.Uint32(sum[offset:offset+4])&0x7fffffff)%1000000)
+}
+
+// enableMFA 为用户启用两步验证，返回启用时使用的时间步和恢复码
+func enableMFA(t *testing.T, e *testEnv, user *models.User) (int64, []string) {
+	t.Helper()
+
+	ctx := context.Background()
+	enrollment, err := e.mfa.Enroll(ctx, user)
+	if err != nil {
+		t.Fatalf("Enroll: %v", err)
+	}
+	step := time.Now().Unix() / 30
+	codes, err := e.mfa.Activate(ctx, user, totpCode(t, enrollment.Secret, step))
+	if err != nil {
+		t.Fatalf("Activate: %v", err)
+	}
+	return step, codes
+}
+
+// 同一个时间步的验证码只能使用一次，已用过的时间步之前的验证码也不能使用
+func TestMFATOTPStepUsedOnce(t *testing.T) {
+	e := newTestEnv(t)
+	ctx := context.Background()
+	user := e.createUser(t, "alice", "Xq7#pass-word", "user", nil)
+	step, _ := enableMFA(t, e, user)
+
+	if err := e.mfa.Verify(ctx, user, totpCode(t, user.MFASecret, step), ""); !errors.Is(err, ErrMFACodeInvalid) {
+		t.Fatalf("Verify with code used by Activate: err = %v, want ErrMFACodeInvalid", err)
+	}
+	if err := e.mfa.Verify(ctx, user, totpCode(t, user.MFASecret, step+1), ""); err != nil {
+		t.Fatalf("Verify with next code: %v", err)
+	}
+	if err := e.mfa.Verify(ctx, user, totpCode(t, user.MFASecret, step+1), ""); !errors.Is(err, ErrMFACodeInvalid) {
+		t.Errorf("Verify with the same code twice: err = %v, want ErrMFACodeInvalid", err)
+	}
+	if err := e.mfa.Verify(ctx, user, totpCode(t, user.MFASecret, step-1), ""); !errors.Is(err, ErrMFACodeInvalid) {
+		t.Errorf("Verify with an earlier code: err = %v, want ErrMFACodeInvalid", err)
+	}
+}
+
+func TestMFARecoveryCodeUsedOnce(t *testing.T) {
+	e := newTestEnv(t)
+	ctx := context.Background()
+	user := e.createUser(t, "alice", "Xq7#pass-word", "user", nil)
+	_, codes := enableMFA(t, e, user)
+	if len(codes) == 0 {
+		t.Fatal("Activate returned no recovery codes")
+	}
+
+	if err := e.mfa.Verify(ctx, user, "", codes[0]); err != nil {
+		t.Fatalf("Verify with recovery code: %v", err)
+	}
+	if err := e.mfa.Verify(ctx, user, "", codes[0]); !errors.Is(err, ErrMFACodeInvalid) {
+		t.Errorf("Verify with used recovery code: err = %v, want ErrMFACodeInvalid", err)
+	}
+	if err := e.mfa.Verify(ctx, user, "", codes[1]); err != nil {
+		t.Errorf("Verify with another recovery code: %v", err)
+	}
+}
+
+// 登录挑战只能完成一次
+func TestMFAChallengeUsedOnce(t *testing.T) {
+	e := newTestEnv(t)
+	ctx := context.Background()
+	user := e.createUser(t, "alice", "Xq7#pass-word", "user", nil)
+	_, codes := enableMFA(t, e, user)
+
+	result, err := e.users.Login(ctx, "alice", "Xq7#pass-word", nil, "127.0.0.1", "test")
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	if result.Tokens != nil || result.MFA == nil {
+		t.Fatalf("Login with mfa enabled = %+v, want an mfa challenge without tokens", result)
+	}
+
+	if _, err := e.users.LoginMFA(ctx, result.MFA.Token, "", codes[0], "127.0.0.1", "test"); err != nil {
+		t.Fatalf("LoginMFA: %v", err)
+	}
+	if _, err := e.users.LoginMFA(ctx, result.MFA.Token, "", codes[1], "127.0.0.1", "test"); !errors.Is(err, ErrMFAChallengeInvalid) {
+		t.Errorf("LoginMFA with completed challenge: err = %v, want ErrMFAChallengeInvalid", err)
+	}
+
+	// 并发提交时已经读取到的挑战也只能完成一次
+	challenge, err := e.mfa.NewChallenge(ctx, user, "127.0.0.1")
+	if err != nil {
+		t.Fatalf("NewChallenge: %v", err)
+	}
+	loaded, err := e.mfa.Challenge(ctx, challenge.Token)
+	if err != nil {
+		t.Fatalf("Challenge: %v", err)
+	}
+	if err := e.mfa.CompleteChallenge(ctx, loaded); err != nil {
+		t.Fatalf("CompleteChallenge: %v", err)
+	}
+	if err := e.mfa.CompleteChallenge(ctx, loaded); !errors.Is(err, ErrMFAChallengeInvalid) {
+		t.Errorf("CompleteChallenge twice: err = %v, want ErrMFAChallengeInvalid", err)
+	}
+	if _, err := e.mfa.Challenge(ctx, challenge.Token); !errors.Is(err, ErrMFAChallengeInvalid) {
+		t.Errorf("Challenge after completion: err = %v, want ErrMFAChallengeInvalid", err)
+	}
+}
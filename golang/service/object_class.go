@@ -0,0 +1,121 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+	"xzyq/models"
+	"xzyq/store"
+	"xzyq/tenant"
+)
+
+// ObjectClassService 对象类相关业务逻辑
+type ObjectClassService struct {
+	store store.Store
+}
+
+// NewObjectClassService 创建ObjectClassService
+func NewObjectClassService(st store.Store) *ObjectClassService {
+	return &ObjectClassService{store: st}
+}
+
+// List 获取对象类列表
+func (s *ObjectClassService) List(ctx context.Context) ([]models.ObjectClass, error) {
+	return s.store.ObjectClasses().List(ctx)
+}
+
+// Get 获取单个对象类及其关联数据
+func (s *ObjectClassService) Get(ctx context.Context, id uint) (*models.ObjectClass, error) {
+	class, err := s.store.ObjectClasses().GetDetail(ctx, id)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, ErrObjectClassNotFound
+	}
+	return class, err
+}
+
+// ListChildren 获取对象类的子对象类列表
+func (s *ObjectClassService) ListChildren(ctx context.Context, id uint) ([]models.ObjectClass, error) {
+	return s.store.ObjectClasses().ListChildren(ctx, id)
+}
+
+// Create 创建对象类，对象类属于创建者所在的组织
+func (s *ObjectClassService) Create(ctx context.Context, class *models.ObjectClass, userID uint) (*models.ObjectClass, error) {
+	// 获取当前用户信息以获取其组织ID
+	user, err := s.store.Users().Get(tenant.Unscoped(ctx), userID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+	if user.OrgID == nil {
+		return nil, ErrUserHasNoOrg
+	}
+
+	class.ID = 0
+	class.ParentID = nil
+	class.CreatedBy = userID
+	class.OrgID = *user.OrgID
+	class.UpdatedAt = time.Now()
+
+	if err := s.store.ObjectClasses().Create(ctx, class); err != nil {
+		return nil, fmt.Errorf("create object class: %w", err)
+	}
+
+	// 重新获取完整的对象类信息
+	return s.store.ObjectClasses().GetDetail(ctx, class.ID)
+}
+
+// CreateChild 创建子对象类，子对象类继承父对象类的组织
+func (s *ObjectClassService) CreateChild(ctx context.Context, parentID uint, class *models.ObjectClass, userID uint) (*models.ObjectClass, error) {
+	parent, err := s.store.ObjectClasses().Get(ctx, parentID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return nil, ErrParentObjectClassNotFound
+		}
+		return nil, err
+	}
+
+	class.ID = 0
+	class.CreatedBy = userID
+	// 使用父对象类的组织ID
+	class.OrgID = parent.OrgID
+	class.ParentID = &parent.ID
+	class.UpdatedAt = time.Now()
+
+	if err := s.store.ObjectClasses().Create(ctx, class); err != nil {
+		return nil, fmt.Errorf("create child object class: %w", err)
+	}
+
+	// 重新获取完整的对象类信息
+	return s.store.ObjectClasses().GetDetail(ctx, class.ID)
+}
+
+// Update 更新对象类的名称和描述
+func (s *ObjectClassService) Update(ctx context.Context, id uint, name, description string) (*models.ObjectClass, error) {
+	if _, err := s.store.ObjectClasses().Get(ctx, id); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return nil, ErrObjectClassNotFound
+		}
+		return nil, err
+	}
+
+	if err := s.store.ObjectClasses().Update(ctx, id, name, description); err != nil {
+		return nil, fmt.Errorf("update object class: %w", err)
+	}
+
+	// 重新获取完整的对象类信息
+	return s.store.ObjectClasses().GetDetail(ctx, id)
+}
+
+// Delete 删除对象类
+func (s *ObjectClassService) Delete(ctx context.Context, id uint) error {
+	if _, err := s.store.ObjectClasses().Get(ctx, id); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return ErrObjectClassNotFound
+		}
+		return err
+	}
+	return s.store.ObjectClasses().Delete(ctx, id)
+}
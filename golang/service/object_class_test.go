@@ -0,0 +1,89 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"xzyq/models"
+	"xzyq/store"
+	"xzyq/tenant"
+)
+
+// newTestObjectClassService 创建两个组织，每个组织有一个用户和一个对象类
+func newTestObjectClassService(t *testing.T) (*ObjectClassService, store.Store, [2]*models.User, [2]*models.ObjectClass) {
+	t.Helper()
+
+	st := newTestStore(t)
+	classes := NewObjectClassService(st)
+	var users [2]*models.User
+	var roots [2]*models.ObjectClass
+	for i, name := range []string{"acme", "beta"} {
+		ctx := tenant.Unscoped(context.Background())
+		org := &models.Organization{Name: name}
+		if err := st.Organizations().Create(ctx, org); err != nil {
+			t.Fatalf("create organization %s: %v", name, err)
+		}
+		users[i] = &models.User{Username: name + "_user", Role: "user", OrgID: &org.ID, IsActive: true}
+		if err := st.Users().Create(ctx, users[i]); err != nil {
+			t.Fatalf("create user of %s: %v", name, err)
+		}
+
+		root, err := classes.Create(tenant.WithOrg(context.Background(), org.ID), &models.ObjectClass{Name: name + " root"}, users[i].ID)
+		if err != nil {
+			t.Fatalf("Create in %s: %v", name, err)
+		}
+		roots[i] = root
+	}
+	return classes, st, users, roots
+}
+
+// 子对象类继承父对象类的组织，而不是使用请求中的组织ID
+func TestObjectClassCreateChildInheritsOrg(t *testing.T) {
+	classes, _, users, roots := newTestObjectClassService(t)
+	ctx := tenant.WithOrg(context.Background(), *users[0].OrgID)
+
+	child, err := classes.CreateChild(ctx, roots[0].ID, &models.ObjectClass{Name: "child", OrgID: *users[1].OrgID}, users[0].ID)
+	if err != nil {
+		t.Fatalf("CreateChild: %v", err)
+	}
+	if child.OrgID != roots[0].OrgID {
+		t.Errorf("child org_id = %d, want parent org_id %d", child.OrgID, roots[0].OrgID)
+	}
+	if child.ParentID == nil || *child.ParentID != roots[0].ID || child.CreatedBy != users[0].ID {
+		t.Errorf("child parent_id = %v, created_by = %d, want %d and %d", child.ParentID, child.CreatedBy, roots[0].ID, users[0].ID)
+	}
+
+	children, err := classes.ListChildren(ctx, roots[0].ID)
+	if err != nil {
+		t.Fatalf("ListChildren: %v", err)
+	}
+	if len(children) != 1 || children[0].ID != child.ID {
+		t.Errorf("children = %+v, want the created child", children)
+	}
+}
+
+func TestObjectClassCreateChildMissingParent(t *testing.T) {
+	classes, _, users, roots := newTestObjectClassService(t)
+	ctx := tenant.WithOrg(context.Background(), *users[0].OrgID)
+
+	if _, err := classes.CreateChild(ctx, roots[1].ID+100, &models.ObjectClass{Name: "child"}, users[0].ID); !errors.Is(err, ErrParentObjectClassNotFound) {
+		t.Errorf("CreateChild with missing parent: err = %v, want ErrParentObjectClassNotFound", err)
+	}
+}
+
+// 其他组织的父对象类不可见，不能在其下创建子对象类
+func TestObjectClassCreateChildOtherTenant(t *testing.T) {
+	classes, st, users, roots := newTestObjectClassService(t)
+	ctx := tenant.WithOrg(context.Background(), *users[0].OrgID)
+
+	if _, err := classes.CreateChild(ctx, roots[1].ID, &models.ObjectClass{Name: "child"}, users[0].ID); !errors.Is(err, ErrParentObjectClassNotFound) {
+		t.Fatalf("CreateChild under another organization: err = %v, want ErrParentObjectClassNotFound", err)
+	}
+	children, err := st.ObjectClasses().ListChildren(tenant.Unscoped(context.Background()), roots[1].ID)
+	if err != nil {
+		t.Fatalf("ListChildren: %v", err)
+	}
+	if len(children) != 0 {
+		t.Errorf("parent in another organization has %d children, want 0", len(children))
+	}
+}
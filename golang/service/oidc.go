@@ -0,0 +1,472 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+	"xzyq/apperr"
+	"xzyq/config"
+	"xzyq/logging"
+	"xzyq/models"
+	"xzyq/oidc"
+	"xzyq/store"
+	"xzyq/tenant"
+	"xzyq/utils"
+)
+
+// providerNamePattern 身份提供方标识只能包含小写字母、数字、-和_
+var providerNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// OIDCProviderInfo 登录页面显示的身份提供方
+type OIDCProviderInfo struct {
+	Name        string `json:"name"`
+	DisplayName string `json:"display_name"`
+}
+
+// OIDCProviderUpdate 创建或修改身份提供方的内容，ClientSecret为nil表示不修改
+type OIDCProviderUpdate struct {
+	Name          string
+	DisplayName   string
+	Issuer        string
+	ClientID      string
+	ClientSecret  *string
+	Scopes        string
+	Enabled       bool
+	LinkByEmail   bool
+	AutoProvision bool
+	DefaultRole   string
+}
+
+// OIDCService OpenID Connect单点登录
+//
+// 每个组织可以配置自己的身份提供方。用户首次登录时按sub关联用户：
+// 可以按已验证的邮箱关联组织内已有的用户，或者自动在组织中创建用户，两者都是可选的。
+// 登录成功后与密码登录一样签发本系统的令牌，已启用两步验证的用户仍需完成第二步验证。
+type OIDCService struct {
+	store       store.Store
+	users       *UserService
+	client      *oidc.Client
+	redirectURL string
+	stateExpire time.Duration
+}
+
+// NewOIDCService 创建OIDCService
+func NewOIDCService(st store.Store, users *UserService, cfg config.OIDCConfig) *OIDCService {
+	return &OIDCService{
+		store:       st,
+		users:       users,
+		client:      oidc.NewClient(cfg.HTTPTimeout),
+		redirectURL: cfg.RedirectURL,
+		stateExpire: cfg.StateExpire,
+	}
+}
+
+// Providers 返回所有已启用的身份提供方
+func (s *OIDCService) Providers(ctx context.Context) ([]OIDCProviderInfo, error) {
+	providers, err := s.store.OIDCProviders().ListEnabled(tenant.Unscoped(ctx))
+	if err != nil {
+		return nil, err
+	}
+	infos := make([]OIDCProviderInfo, len(providers))
+	for i, p := range providers {
+		infos[i] = OIDCProviderInfo{Name: p.Name, DisplayName: p.DisplayName}
+	}
+	return infos, nil
+}
+
+// Authorize 开始单点登录，返回跳转到身份提供方的地址
+func (s *OIDCService) Authorize(ctx context.Context, name, ip string) (string, error) {
+	ctx = tenant.Unscoped(ctx)
+
+	provider, err := enabledProvider(s.store.OIDCProviders().GetByName(ctx, name))
+	if err != nil {
+		return "", err
+	}
+
+	state, err := oidc.NewState()
+	if err != nil {
+		return "", fmt.Errorf("generate oidc state: %w", err)
+	}
+	nonce, err := oidc.NewState()
+	if err != nil {
+		return "", fmt.Errorf("generate oidc nonce: %w", err)
+	}
+	verifier, err := oidc.NewVerifier()
+	if err != nil {
+		return "", fmt.Errorf("generate pkce verifier: %w", err)
+	}
+
+	authURL, err := s.client.AuthCodeURL(ctx, s.clientConfig(provider), state, nonce, oidc.S256Challenge(verifier))
+	if err != nil {
+		return "", ErrOIDCProviderUnavailable.Wrap(err)
+	}
+
+	now := time.Now()
+	// 顺便清理已过期的登录状态
+	if err := s.store.OIDCLoginStates().DeleteExpired(ctx, now); err != nil {
+		logging.FromContext(ctx).Error("delete expired oidc login states failed", "error", err)
+	}
+	err = s.store.OIDCLoginStates().Create(ctx, &models.OIDCLoginState{
+		ProviderID:   provider.ID,
+		StateHash:    utils.HashToken(state),
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		IP:           ip,
+		ExpiresAt:    now.Add(s.stateExpire),
+	})
+	if err != nil {
+		return "", fmt.Errorf("store oidc login state: %w", err)
+	}
+	return authURL, nil
+}
+
+// Callback 身份提供方跳转回来后，用授权码换取ID令牌并登录对应的用户
+func (s *OIDCService) Callback(ctx context.Context, state, code, ip, userAgent string) (*LoginResult, error) {
+	ctx = tenant.Unscoped(ctx)
+	logger := logging.FromContext(ctx).With("ip", ip)
+
+	loginState, err := s.store.OIDCLoginStates().GetByHash(ctx, utils.HashToken(state))
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return nil, ErrOIDCStateInvalid
+		}
+		return nil, err
+	}
+	if loginState.UsedAt != nil || !time.Now().Before(loginState.ExpiresAt) {
+		return nil, ErrOIDCStateInvalid
+	}
+	// 授权码和state都只能使用一次，并发提交时只有一个成功
+	marked, err := s.store.OIDCLoginStates().MarkUsed(ctx, loginState.ID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if !marked {
+		return nil, ErrOIDCStateInvalid
+	}
+
+	provider, err := enabledProvider(s.store.OIDCProviders().Get(ctx, loginState.ProviderID))
+	if err != nil {
+		return nil, err
+	}
+	logger = logger.With("provider", provider.Name)
+
+	cfg := s.clientConfig(provider)
+	token, err := s.client.Exchange(ctx, cfg, code, loginState.CodeVerifier)
+	if err != nil {
+		logger.Warn("oidc login failed", "reason", "exchange code", "error", err)
+		s.users.loginFailed(ctx, nil, "", ip, LoginFailOIDC)
+		return nil, ErrOIDCLoginFailed.Wrap(err)
+	}
+	claims, err := s.client.VerifyIDToken(ctx, cfg, token.IDToken, loginState.Nonce)
+	if err != nil {
+		logger.Warn("oidc login failed", "reason", "verify id token", "error", err)
+		s.users.loginFailed(ctx, nil, "", ip, LoginFailOIDC)
+		return nil, ErrOIDCLoginFailed.Wrap(err)
+	}
+	logger = logger.With("subject", claims.Subject)
+
+	user, err := s.resolveUser(ctx, provider, claims, ip)
+	if err != nil {
+		if errors.Is(err, ErrOIDCUserNotLinked) {
+			s.users.loginFailed(ctx, nil, claims.Email, ip, LoginFailNotLinked)
+		}
+		return nil, err
+	}
+	if err := s.users.checkActive(ctx, user); err != nil {
+		if errors.Is(err, ErrAccountDisabled) {
+			s.users.loginFailed(ctx, user, user.Username, ip, LoginFailDisabled)
+		}
+		return nil, err
+	}
+
+	logger.Info("oidc login verified", "user_id", user.ID)
+	return s.users.authenticated(ctx, user, ip, userAgent)
+}
+
+// resolveUser 返回身份提供方账号对应的用户，首次登录时按配置关联或创建用户
+func (s *OIDCService) resolveUser(ctx context.Context, provider *models.OIDCProvider, claims *oidc.Claims, ip string) (*models.User, error) {
+	identity, err := s.store.UserIdentities().Get(ctx, provider.ID, claims.Subject)
+	if err == nil {
+		user, err := s.store.Users().Get(ctx, identity.UserID)
+		if errors.Is(err, store.ErrNotFound) {
+			// 关联的用户已被删除，按未关联处理，不能让对方以为账号还在只是被禁用
+			logging.FromContext(ctx).Warn("oidc identity links to a deleted user", "user_id", identity.UserID, "provider", provider.Name)
+			return nil, ErrOIDCUserNotLinked
+		}
+		return user, err
+	}
+	if !errors.Is(err, store.ErrNotFound) {
+		return nil, err
+	}
+
+	if provider.LinkByEmail && claims.EmailVerified && claims.Email != "" {
+		user, err := s.userByEmail(ctx, provider.OrgID, claims.Email)
+		if err != nil {
+			return nil, err
+		}
+		if user != nil {
+			err := s.store.UserIdentities().Create(ctx, &models.UserIdentity{
+				UserID:     user.ID,
+				ProviderID: provider.ID,
+				Subject:    claims.Subject,
+				Email:      claims.Email,
+			})
+			if err != nil {
+				return nil, fmt.Errorf("link oidc identity: %w", err)
+			}
+			logging.FromContext(ctx).Info("oidc identity linked", "user_id", user.ID, "provider", provider.Name)
+			s.writeLog(ctx, user, "oidc_linked", ip)
+			return user, nil
+		}
+	}
+
+	if provider.AutoProvision {
+		return s.provision(ctx, provider, claims, ip)
+	}
+	return nil, ErrOIDCUserNotLinked
+}
+
+// userByEmail 返回组织内邮箱唯一对应的用户，没有或有多个时返回nil
+func (s *OIDCService) userByEmail(ctx context.Context, orgID uint, email string) (*models.User, error) {
+	users, err := s.store.Users().ListByEmail(ctx, email)
+	if err != nil {
+		return nil, err
+	}
+	var matched *models.User
+	for i := range users {
+		if users[i].OrgID == nil || *users[i].OrgID != orgID {
+			continue
+		}
+		// 同一邮箱有多个用户时无法确定是哪一个
+		if matched != nil {
+			return nil, nil
+		}
+		matched = &users[i]
+	}
+	return matched, nil
+}
+
+// provision 在身份提供方所属的组织中创建用户，用户没有密码，只能通过单点登录或重置密码登录
+func (s *OIDCService) provision(ctx context.Context, provider *models.OIDCProvider, claims *oidc.Claims, ip string) (*models.User, error) {
+	username, err := s.availableUsername(ctx, claims)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	orgID := provider.OrgID
+	user := &models.User{
+		Username:          username,
+		IsActive:          true,
+		Role:              provider.DefaultRole,
+		OrgID:             &orgID,
+		PasswordChangedAt: &now,
+	}
+	if claims.EmailVerified {
+		user.Email = claims.Email
+	}
+
+	err = s.store.Transaction(ctx, func(tx store.Store) error {
+		if err := tx.Users().Create(ctx, user); err != nil {
+			return err
+		}
+		return tx.UserIdentities().Create(ctx, &models.UserIdentity{
+			UserID:     user.ID,
+			ProviderID: provider.ID,
+			Subject:    claims.Subject,
+			Email:      claims.Email,
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("provision oidc user: %w", err)
+	}
+
+	logging.FromContext(ctx).Info("oidc user provisioned", "user_id", user.ID, "provider", provider.Name, "org_id", orgID)
+	s.writeLog(ctx, user, "oidc_provisioned", ip)
+	return user, nil
+}
+
+// availableUsername 按preferred_username、邮箱前缀的顺序选择用户名，已被使用时加数字后缀
+func (s *OIDCService) availableUsername(ctx context.Context, claims *oidc.Claims) (string, error) {
+	base := strings.TrimSpace(claims.PreferredUsername)
+	if base == "" {
+		base, _, _ = strings.Cut(claims.Email, "@")
+	}
+	if base == "" {
+		base = "sso"
+	}
+	// 用户名最多50个字符，留出后缀的位置
+	if runes := []rune(base); len(runes) > 45 {
+		base = string(runes[:45])
+	}
+
+	for i := 1; i <= 100; i++ {
+		username := base
+		if i > 1 {
+			username = fmt.Sprintf("%s%d", base, i)
+		}
+		exists, err := s.store.Users().UsernameExists(ctx, username)
+		if err != nil {
+			return "", err
+		}
+		if !exists {
+			return username, nil
+		}
+	}
+
+	suffix, err := utils.RandomCode(3)
+	if err != nil {
+		return "", err
+	}
+	return base + "_" + suffix, nil
+}
+
+// ListProviders 返回组织的身份提供方
+func (s *OIDCService) ListProviders(ctx context.Context, orgID uint) ([]models.OIDCProvider, error) {
+	if err := s.users.checkOrgExists(ctx, orgID); err != nil {
+		return nil, err
+	}
+	return s.store.OIDCProviders().List(ctx, orgID)
+}
+
+// CreateProvider 为组织添加身份提供方，保存前读取发现文档确认签发者地址正确
+func (s *OIDCService) CreateProvider(ctx context.Context, orgID uint, update OIDCProviderUpdate) (*models.OIDCProvider, error) {
+	if err := s.users.checkOrgExists(ctx, orgID); err != nil {
+		return nil, err
+	}
+
+	provider := &models.OIDCProvider{OrgID: orgID}
+	if err := s.applyUpdate(ctx, provider, update); err != nil {
+		return nil, err
+	}
+	if err := s.store.OIDCProviders().Create(ctx, provider); err != nil {
+		return nil, err
+	}
+	return provider, nil
+}
+
+// UpdateProvider 修改组织的身份提供方
+func (s *OIDCService) UpdateProvider(ctx context.Context, orgID, id uint, update OIDCProviderUpdate) (*models.OIDCProvider, error) {
+	provider, err := s.orgProvider(ctx, orgID, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.applyUpdate(ctx, provider, update); err != nil {
+		return nil, err
+	}
+	if err := s.store.OIDCProviders().Save(ctx, provider); err != nil {
+		return nil, err
+	}
+	return provider, nil
+}
+
+// DeleteProvider 删除组织的身份提供方，用户与其账号的关联一并删除
+func (s *OIDCService) DeleteProvider(ctx context.Context, orgID, id uint) error {
+	if _, err := s.orgProvider(ctx, orgID, id); err != nil {
+		return err
+	}
+	return s.store.OIDCProviders().Delete(ctx, id)
+}
+
+// applyUpdate 校验并写入身份提供方的配置
+func (s *OIDCService) applyUpdate(ctx context.Context, provider *models.OIDCProvider, update OIDCProviderUpdate) error {
+	if !providerNamePattern.MatchString(update.Name) {
+		return apperr.ErrBadRequest.Wrap(fmt.Errorf("invalid provider name %q", update.Name))
+	}
+	// 标识全局唯一，不受组织范围限制
+	taken, err := s.store.OIDCProviders().NameExists(tenant.Unscoped(ctx), update.Name, provider.ID)
+	if err != nil {
+		return err
+	}
+	if taken {
+		return ErrOIDCProviderNameTaken
+	}
+
+	scopes := strings.Fields(update.Scopes)
+	if len(scopes) == 0 {
+		scopes = []string{"openid", "profile", "email"}
+	} else if !contains(scopes, "openid") {
+		scopes = append([]string{"openid"}, scopes...)
+	}
+	role := update.DefaultRole
+	if role == "" {
+		role = "user"
+	}
+
+	provider.Name = update.Name
+	provider.DisplayName = update.DisplayName
+	provider.Issuer = strings.TrimSpace(update.Issuer)
+	provider.ClientID = update.ClientID
+	if update.ClientSecret != nil {
+		provider.ClientSecret = *update.ClientSecret
+	}
+	provider.Scopes = strings.Join(scopes, " ")
+	provider.Enabled = update.Enabled
+	provider.LinkByEmail = update.LinkByEmail
+	provider.AutoProvision = update.AutoProvision
+	provider.DefaultRole = role
+
+	if _, err := s.client.Discover(ctx, provider.Issuer); err != nil {
+		return ErrOIDCProviderUnavailable.Wrap(err)
+	}
+	return nil
+}
+
+// orgProvider 返回组织的身份提供方
+func (s *OIDCService) orgProvider(ctx context.Context, orgID, id uint) (*models.OIDCProvider, error) {
+	provider, err := s.store.OIDCProviders().Get(ctx, id)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return nil, ErrOIDCProviderNotFound
+		}
+		return nil, err
+	}
+	if provider.OrgID != orgID {
+		return nil, ErrOIDCProviderNotFound
+	}
+	return provider, nil
+}
+
+// enabledProvider 检查查询到的身份提供方已启用
+func enabledProvider(provider *models.OIDCProvider, err error) (*models.OIDCProvider, error) {
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return nil, ErrOIDCProviderNotFound
+		}
+		return nil, err
+	}
+	if !provider.Enabled {
+		return nil, ErrOIDCProviderNotFound
+	}
+	return provider, nil
+}
+
+// clientConfig 返回身份提供方的客户端配置
+func (s *OIDCService) clientConfig(provider *models.OIDCProvider) oidc.Provider {
+	return oidc.Provider{
+		Issuer:       provider.Issuer,
+		ClientID:     provider.ClientID,
+		ClientSecret: provider.ClientSecret,
+		RedirectURL:  s.redirectURL,
+		Scopes:       provider.ScopeList(),
+	}
+}
+
+// writeLog 写入单点登录日志，失败时只记录日志
+func (s *OIDCService) writeLog(ctx context.Context, user *models.User, action, ip string) {
+	if err := s.users.writeLog(ctx, user.ID, user.Username, action, ip); err != nil {
+		logging.FromContext(ctx).Error("write oidc log failed", "action", action, "error", err)
+	}
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
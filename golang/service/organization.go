@@ -0,0 +1,323 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"xzyq/apperr"
+	"xzyq/logging"
+	"xzyq/models"
+	"xzyq/store"
+	"xzyq/tenant"
+)
+
+// OrganizationService 组织相关业务逻辑
+type OrganizationService struct {
+	store     store.Store
+	passwords *PasswordService
+}
+
+// NewOrganizationService 创建OrganizationService
+func NewOrganizationService(st store.Store, passwords *PasswordService) *OrganizationService {
+	return &OrganizationService{store: st, passwords: passwords}
+}
+
+// OrganizationDetail 组织及其用户数量、父组织信息
+type OrganizationDetail struct {
+	models.Organization
+	UserCount int64                `json:"user_count"`
+	ParentOrg *models.Organization `json:"parent_org,omitempty"`
+}
+
+// OrganizationUpdate 更新组织的内容，注册设置和两步验证要求有单独的管理员接口
+type OrganizationUpdate struct {
+	Name        string
+	Description string
+	ParentID    *uint // 为nil表示顶级组织
+}
+
+// OrganizationAdmin 创建组织时自动创建的管理员账号，密码随机生成，首次登录时必须修改
+type OrganizationAdmin struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// ListCreatedBy 获取用户创建的组织及其详细信息
+func (s *OrganizationService) ListCreatedBy(ctx context.Context, userID uint) ([]OrganizationDetail, error) {
+	organizations, err := s.store.Organizations().ListByCreator(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	details := make([]OrganizationDetail, 0, len(organizations))
+	for _, org := range organizations {
+		// 统计用户数量，包括软删除的用户
+		count, err := s.store.Users().CountByOrg(ctx, org.ID, true)
+		if err != nil {
+			return nil, fmt.Errorf("count users of organization %d: %w", org.ID, err)
+		}
+
+		detail := OrganizationDetail{
+			Organization: org,
+			UserCount:    count,
+		}
+
+		// 如果有父组织ID，查询父组织信息
+		if org.ParentID != nil {
+			if parent, err := s.store.Organizations().Get(ctx, *org.ParentID); err == nil {
+				detail.ParentOrg = parent
+			}
+		}
+
+		details = append(details, detail)
+	}
+	return details, nil
+}
+
+// List 获取所有组织
+func (s *OrganizationService) List(ctx context.Context) ([]models.Organization, error) {
+	return s.store.Organizations().List(ctx)
+}
+
+// Get 获取单个组织
+func (s *OrganizationService) Get(ctx context.Context, id uint) (*models.Organization, error) {
+	org, err := s.store.Organizations().Get(ctx, id)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, ErrOrgNotFound
+	}
+	return org, err
+}
+
+// Create 创建顶级组织，并同时创建该组织的管理员账号，只有平台管理员可以创建
+func (s *OrganizationService) Create(ctx context.Context, org *models.Organization, creatorID uint) (*OrganizationAdmin, error) {
+	if err := s.checkPlatformAdmin(ctx, creatorID); err != nil {
+		return nil, err
+	}
+
+	// 设置创建者ID
+	org.CreatedBy = creatorID
+	// 设置父组织ID为null，因为这是一个新的顶级组织
+	org.ParentID = nil
+	// 新组织只能通过邀请加入，管理员之后再按需开放自助注册
+	org.Registration = models.OrgRegistration{SelfRegistrationRole: "user"}
+
+	// 新组织还没有自己的密码策略，按全局策略生成初始密码
+	adminUsername := "admin_" + org.Name
+	password, err := s.passwords.Generate(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("generate admin password: %w", err)
+	}
+	hashedPassword, err := s.passwords.Hash(ctx, &models.User{Username: adminUsername}, password)
+	if err != nil {
+		return nil, err
+	}
+
+	var admin *OrganizationAdmin
+	err = s.store.Transaction(ctx, func(tx store.Store) error {
+		if err := tx.Organizations().Create(ctx, org); err != nil {
+			return fmt.Errorf("create organization: %w", err)
+		}
+
+		// 使用组织名称创建唯一的管理员用户名
+		adminUser := models.User{
+			Username:  adminUsername,
+			Password:  hashedPassword,
+			IsActive:  true,
+			Role:      "admin",
+			OrgID:     &org.ID,
+			CreatedBy: creatorID,
+		}
+
+		// 检查用户名是否已存在
+		exists, err := tx.Users().UsernameExists(ctx, adminUser.Username)
+		if err != nil {
+			return err
+		}
+		if exists {
+			return ErrAdminUsernameTaken
+		}
+
+		if err := tx.Users().Create(ctx, &adminUser); err != nil {
+			return fmt.Errorf("create organization admin: %w", err)
+		}
+
+		admin = &OrganizationAdmin{
+			Username: adminUser.Username,
+			Password: password,
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return admin, nil
+}
+
+// Update 更新组织的名称、描述和父组织，只有平台管理员可以修改父组织
+func (s *OrganizationService) Update(ctx context.Context, actorID, id uint, update OrganizationUpdate) (*models.Organization, error) {
+	org, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if !sameID(org.ParentID, update.ParentID) {
+		if err := s.checkPlatformAdmin(ctx, actorID); err != nil {
+			return nil, err
+		}
+		if update.ParentID != nil {
+			if *update.ParentID == org.ID {
+				return nil, apperr.ErrBadRequest.Wrap(errors.New("organization cannot be its own parent"))
+			}
+			if _, err := s.Get(ctx, *update.ParentID); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	org.Name = update.Name
+	org.Description = update.Description
+	org.ParentID = update.ParentID
+	if err := s.store.Organizations().Save(ctx, org); err != nil {
+		return nil, err
+	}
+	return org, nil
+}
+
+// Delete 删除组织，组织下还有用户时不允许删除
+func (s *OrganizationService) Delete(ctx context.Context, id uint) (*models.Organization, error) {
+	var org *models.Organization
+	err := s.store.Transaction(ctx, func(tx store.Store) error {
+		var err error
+		org, err = tx.Organizations().Get(ctx, id)
+		if err != nil {
+			if errors.Is(err, store.ErrNotFound) {
+				return ErrOrgNotFound
+			}
+			return err
+		}
+
+		// 查找该组织下的用户数量
+		userCount, err := tx.Users().CountByOrg(ctx, org.ID, false)
+		if err != nil {
+			return fmt.Errorf("count users of organization %d: %w", org.ID, err)
+		}
+
+		// 如果组织下还有用户，则不允许删除
+		if userCount > 0 {
+			return ErrOrgHasUsers.WithDetails(map[string]interface{}{
+				"organization": org.Name,
+				"user_count":   userCount,
+			})
+		}
+
+		// 删除组织下的用户（虽然已经确认数量为0，但为了保险起见）
+		if err := tx.Users().DeleteByOrg(ctx, org.ID); err != nil {
+			return fmt.Errorf("delete users of organization %d: %w", org.ID, err)
+		}
+
+		// 删除组织本身
+		if err := tx.Organizations().Delete(ctx, org.ID); err != nil {
+			return fmt.Errorf("delete organization %d: %w", org.ID, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return org, nil
+}
+
+// Registration 获取组织的自助注册设置
+func (s *OrganizationService) Registration(ctx context.Context, id uint) (*models.OrgRegistration, error) {
+	org, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return &org.Registration, nil
+}
+
+// SaveRegistration 保存组织的自助注册设置
+func (s *OrganizationService) SaveRegistration(ctx context.Context, id uint, registration models.OrgRegistration) (*models.OrgRegistration, error) {
+	if registration.SelfRegistrationRole != "admin" && registration.SelfRegistrationRole != "user" {
+		return nil, apperr.ErrBadRequest.Wrap(fmt.Errorf("unknown self registration role %q", registration.SelfRegistrationRole))
+	}
+	org, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	org.Registration = registration
+	if err := s.store.Organizations().Save(ctx, org); err != nil {
+		return nil, err
+	}
+	return &org.Registration, nil
+}
+
+// OrgMFAPolicy 组织的两步验证要求
+type OrgMFAPolicy struct {
+	RequireAdminMFA bool `json:"require_admin_mfa"` // 组织管理员必须启用两步验证
+}
+
+// MFAPolicy 获取组织的两步验证要求
+func (s *OrganizationService) MFAPolicy(ctx context.Context, id uint) (*OrgMFAPolicy, error) {
+	org, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return &OrgMFAPolicy{RequireAdminMFA: org.RequireAdminMFA}, nil
+}
+
+// SaveMFAPolicy 设置组织管理员是否必须启用两步验证，开启后尚未启用的管理员在下次登录时设置
+func (s *OrganizationService) SaveMFAPolicy(ctx context.Context, id uint, policy OrgMFAPolicy) (*OrgMFAPolicy, error) {
+	org, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	org.RequireAdminMFA = policy.RequireAdminMFA
+	if err := s.store.Organizations().Save(ctx, org); err != nil {
+		return nil, err
+	}
+
+	logging.FromContext(ctx).Info("organization mfa policy changed", "org_id", org.ID, "require_admin_mfa", org.RequireAdminMFA)
+	return &OrgMFAPolicy{RequireAdminMFA: org.RequireAdminMFA}, nil
+}
+
+// ListUsers 获取组织下的用户列表，包括软删除的用户
+func (s *OrganizationService) ListUsers(ctx context.Context, id uint) ([]models.User, error) {
+	if _, err := s.Get(ctx, id); err != nil {
+		return nil, err
+	}
+
+	users, err := s.store.Users().ListByOrg(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	// 如果没有找到用户，返回空数组而不是 null
+	if users == nil {
+		users = make([]models.User, 0)
+	}
+	return users, nil
+}
+
+// checkPlatformAdmin 检查用户是否为平台管理员，组织管理员不能创建顶级组织或调整组织层级
+func (s *OrganizationService) checkPlatformAdmin(ctx context.Context, userID uint) error {
+	user, err := s.store.Users().Get(tenant.Unscoped(ctx), userID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return ErrUserNotFound
+		}
+		return err
+	}
+	if !user.IsPlatformAdmin() {
+		return apperr.ErrForbidden.Wrap(errors.New("platform admin required"))
+	}
+	return nil
+}
+
+// sameID 两个可以为空的ID是否相同
+func sameID(a, b *uint) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
@@ -0,0 +1,157 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"xzyq/apperr"
+	"xzyq/config"
+	"xzyq/models"
+	"xzyq/store"
+	"xzyq/tenant"
+)
+
+// newTestOrganizationService 创建OrganizationService和一个平台管理员
+func newTestOrganizationService(t *testing.T) (*OrganizationService, store.Store, *models.User) {
+	t.Helper()
+
+	st := newTestStore(t)
+	cfg := config.Default()
+	// 测试中不需要默认的哈希强度
+	cfg.Auth.PasswordHash = config.PasswordHashConfig{Memory: 1024, Iterations: 1, Parallelism: 1}
+	passwords, err := NewPasswordService(st, cfg.Auth.Password, cfg.Auth.PasswordHash)
+	if err != nil {
+		t.Fatalf("new password service: %v", err)
+	}
+
+	admin := &models.User{Username: "root", Role: "admin", IsActive: true}
+	if err := st.Users().Create(context.Background(), admin); err != nil {
+		t.Fatalf("create platform admin: %v", err)
+	}
+	return NewOrganizationService(st, passwords), st, admin
+}
+
+func TestOrganizationCreate(t *testing.T) {
+	orgs, st, admin := newTestOrganizationService(t)
+	ctx := tenant.Unscoped(context.Background())
+
+	org := &models.Organization{Name: "acme", RequireAdminMFA: true,
+		Registration: models.OrgRegistration{SelfRegistration: true, SelfRegistrationRole: "admin"}}
+	created, err := orgs.Create(ctx, org, admin.ID)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if created.Username != "admin_acme" || created.Password == "" {
+		t.Errorf("organization admin = %+v, want admin_acme with a generated password", created)
+	}
+
+	saved, err := orgs.Get(ctx, org.ID)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if saved.CreatedBy != admin.ID || saved.ParentID != nil {
+		t.Errorf("created_by = %d, parent_id = %v, want %d and nil", saved.CreatedBy, saved.ParentID, admin.ID)
+	}
+	// 注册设置和两步验证要求只能通过单独的管理员接口修改
+	if saved.Registration.SelfRegistration || saved.Registration.SelfRegistrationRole != "user" {
+		t.Errorf("registration = %+v, want closed with role user", saved.Registration)
+	}
+
+	users, err := st.Users().ListByOrg(ctx, org.ID)
+	if err != nil {
+		t.Fatalf("ListByOrg: %v", err)
+	}
+	if len(users) != 1 || users[0].Username != "admin_acme" || users[0].Role != "admin" {
+		t.Fatalf("organization users = %+v, want the admin_acme admin", users)
+	}
+	// 初始密码由他人设置，首次登录时必须修改
+	if users[0].PasswordChangedAt != nil {
+		t.Error("organization admin password should be temporary")
+	}
+	if !orgs.passwords.Verify(ctx, &users[0], created.Password) {
+		t.Error("organization admin password does not verify")
+	}
+
+	// 管理员用户名已被使用时不创建组织
+	if err := st.Users().Create(ctx, &models.User{Username: "admin_beta", Role: "user", IsActive: true}); err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	if _, err := orgs.Create(ctx, &models.Organization{Name: "beta"}, admin.ID); !errors.Is(err, ErrAdminUsernameTaken) {
+		t.Errorf("Create with taken admin username: err = %v, want ErrAdminUsernameTaken", err)
+	}
+	list, err := orgs.ListCreatedBy(ctx, admin.ID)
+	if err != nil {
+		t.Fatalf("ListCreatedBy: %v", err)
+	}
+	if len(list) != 1 || list[0].UserCount != 1 {
+		t.Errorf("ListCreatedBy = %+v, want one organization with one user", list)
+	}
+}
+
+func TestOrganizationCreateRequiresPlatformAdmin(t *testing.T) {
+	orgs, st, admin := newTestOrganizationService(t)
+	ctx := tenant.Unscoped(context.Background())
+
+	org := &models.Organization{Name: "acme"}
+	if _, err := orgs.Create(ctx, org, admin.ID); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	orgAdmin := &models.User{Username: "acme_admin", Role: "admin", OrgID: &org.ID, IsActive: true}
+	if err := st.Users().Create(ctx, orgAdmin); err != nil {
+		t.Fatalf("create organization admin: %v", err)
+	}
+
+	if _, err := orgs.Create(ctx, &models.Organization{Name: "other"}, orgAdmin.ID); !errors.Is(err, apperr.ErrForbidden) {
+		t.Errorf("Create by organization admin: err = %v, want ErrForbidden", err)
+	}
+
+	list, err := orgs.ListCreatedBy(ctx, orgAdmin.ID)
+	if err != nil {
+		t.Fatalf("ListCreatedBy: %v", err)
+	}
+	// 没有组织时返回空数组而不是null
+	if list == nil || len(list) != 0 {
+		t.Errorf("ListCreatedBy = %#v, want an empty slice", list)
+	}
+}
+
+func TestOrganizationDelete(t *testing.T) {
+	orgs, st, admin := newTestOrganizationService(t)
+	ctx := tenant.Unscoped(context.Background())
+
+	org := &models.Organization{Name: "acme"}
+	if _, err := orgs.Create(ctx, org, admin.ID); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	// 组织下还有管理员
+	if _, err := orgs.Delete(ctx, org.ID); !errors.Is(err, ErrOrgHasUsers) {
+		t.Fatalf("Delete with users: err = %v, want ErrOrgHasUsers", err)
+	}
+	if _, err := orgs.Get(ctx, org.ID); err != nil {
+		t.Fatalf("organization deleted although it has users: %v", err)
+	}
+
+	// 删除管理员后组织可以删除
+	users, err := st.Users().ListByOrg(ctx, org.ID)
+	if err != nil {
+		t.Fatalf("ListByOrg: %v", err)
+	}
+	if err := st.Users().HardDelete(ctx, users[0].ID); err != nil {
+		t.Fatalf("delete organization admin: %v", err)
+	}
+	deleted, err := orgs.Delete(ctx, org.ID)
+	if err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if deleted.ID != org.ID {
+		t.Errorf("Delete returned organization %d, want %d", deleted.ID, org.ID)
+	}
+	if _, err := orgs.Get(ctx, org.ID); !errors.Is(err, ErrOrgNotFound) {
+		t.Errorf("Get after Delete: err = %v, want ErrOrgNotFound", err)
+	}
+
+	if _, err := orgs.Delete(ctx, org.ID); !errors.Is(err, ErrOrgNotFound) {
+		t.Errorf("Delete twice: err = %v, want ErrOrgNotFound", err)
+	}
+}
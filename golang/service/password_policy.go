@@ -0,0 +1,461 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+	"xzyq/config"
+	"xzyq/logging"
+	"xzyq/models"
+	"xzyq/store"
+	"xzyq/tenant"
+	"xzyq/utils"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// generatedPasswordLength 自动生成的初始密码的最少长度
+const generatedPasswordLength = 16
+
+// PasswordPolicy 合并全局策略和组织策略后实际生效的密码策略
+type PasswordPolicy struct {
+	MinLength        int  `json:"min_length"`
+	MaxLength        int  `json:"max_length"`
+	RequireUppercase bool `json:"require_uppercase"`
+	RequireLowercase bool `json:"require_lowercase"`
+	RequireDigit     bool `json:"require_digit"`
+	RequireSymbol    bool `json:"require_symbol"`
+	History          int  `json:"history"`
+	MaxAgeDays       int  `json:"max_age_days"` // 0表示不过期
+}
+
+// maxAge 密码有效期，0表示不过期
+func (p *PasswordPolicy) maxAge() time.Duration {
+	return time.Duration(p.MaxAgeDays) * 24 * time.Hour
+}
+
+// OrgPasswordPolicyDetail 组织自己的密码策略及实际生效的策略
+type OrgPasswordPolicyDetail struct {
+	Override  *models.OrgPasswordPolicy `json:"override"` // 组织未设置时为null
+	Effective PasswordPolicy            `json:"effective"`
+}
+
+// PasswordService 密码策略，所有设置和校验密码的入口都经过这里
+//
+// 组织可以设置更严格的要求，但不能放宽全局策略。
+// 新密码使用argon2id，bcrypt和明文只用于校验旧密码，校验通过后自动升级为argon2id。
+type PasswordService struct {
+	store   store.Store
+	global  PasswordPolicy
+	banned  map[string]struct{}
+	hashers *utils.PasswordHashers
+}
+
+// NewPasswordService 创建PasswordService，配置了禁用密码文件时读取该文件
+func NewPasswordService(st store.Store, cfg config.PasswordPolicyConfig, hashCfg config.PasswordHashConfig) (*PasswordService, error) {
+	s := &PasswordService{
+		store: st,
+		hashers: utils.NewPasswordHashers(
+			&utils.Argon2idHasher{
+				Memory:      uint32(hashCfg.Memory),
+				Iterations:  uint32(hashCfg.Iterations),
+				Parallelism: uint8(hashCfg.Parallelism),
+			},
+			&utils.BcryptHasher{Cost: bcrypt.DefaultCost},
+			utils.PlaintextHasher{},
+		),
+		global: PasswordPolicy{
+			MinLength:        cfg.MinLength,
+			MaxLength:        cfg.MaxLength,
+			RequireUppercase: cfg.RequireUppercase,
+			RequireLowercase: cfg.RequireLowercase,
+			RequireDigit:     cfg.RequireDigit,
+			RequireSymbol:    cfg.RequireSymbol,
+			History:          cfg.History,
+			MaxAgeDays:       int(cfg.MaxAge / (24 * time.Hour)),
+		},
+		banned: make(map[string]struct{}),
+	}
+	if cfg.BannedFile != "" {
+		if err := s.loadBanned(cfg.BannedFile); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// Policy 返回组织实际生效的密码策略，orgID为nil时返回全局策略
+func (s *PasswordService) Policy(ctx context.Context, orgID *uint) (*PasswordPolicy, error) {
+	policy := s.global
+	if orgID == nil {
+		return &policy, nil
+	}
+
+	override, err := s.store.OrgPasswordPolicies().Get(ctx, *orgID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return &policy, nil
+		}
+		return nil, err
+	}
+
+	// 组织策略只能让要求更严格
+	policy.MinLength = max(policy.MinLength, override.MinLength)
+	policy.RequireUppercase = policy.RequireUppercase || override.RequireUppercase
+	policy.RequireLowercase = policy.RequireLowercase || override.RequireLowercase
+	policy.RequireDigit = policy.RequireDigit || override.RequireDigit
+	policy.RequireSymbol = policy.RequireSymbol || override.RequireSymbol
+	policy.History = max(policy.History, override.History)
+	if override.MaxAgeDays > 0 && (policy.MaxAgeDays == 0 || override.MaxAgeDays < policy.MaxAgeDays) {
+		policy.MaxAgeDays = override.MaxAgeDays
+	}
+	return &policy, nil
+}
+
+// Check 校验密码是否符合用户所属组织的策略；已存在的用户还会检查最近使用过的密码
+func (s *PasswordService) Check(ctx context.Context, user *models.User, password string) error {
+	// 外部认证的用户在本系统中没有密码
+	if user.AuthSource != "" && user.AuthSource != models.AuthSourceLocal {
+		return ErrPasswordManagedExternally
+	}
+	if user.ServiceAccount {
+		return ErrServiceAccountNoPassword
+	}
+
+	policy, err := s.Policy(ctx, user.OrgID)
+	if err != nil {
+		return err
+	}
+
+	if utf8.RuneCountInString(password) < policy.MinLength {
+		return ErrPasswordTooShort.WithDetails(map[string]interface{}{"min_length": policy.MinLength})
+	}
+	if len(password) > policy.MaxLength {
+		return ErrPasswordTooLong.WithDetails(map[string]interface{}{"max_length": policy.MaxLength})
+	}
+
+	var upper, lower, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r) || r == ' ':
+			symbol = true
+		}
+	}
+	switch {
+	case policy.RequireUppercase && !upper:
+		return ErrPasswordNeedsUppercase
+	case policy.RequireLowercase && !lower:
+		return ErrPasswordNeedsLowercase
+	case policy.RequireDigit && !digit:
+		return ErrPasswordNeedsDigit
+	case policy.RequireSymbol && !symbol:
+		return ErrPasswordNeedsSymbol
+	}
+
+	lowered := strings.ToLower(password)
+	if _, ok := s.banned[lowered]; ok {
+		return ErrPasswordBanned
+	}
+	// 用户名太短时包含关系没有意义
+	if username := strings.ToLower(user.Username); utf8.RuneCountInString(username) >= 3 && strings.Contains(lowered, username) {
+		return ErrPasswordContainsUsername
+	}
+
+	if user.ID == 0 || policy.History == 0 {
+		return nil
+	}
+	// 当前密码加上历史记录中最近的History-1个
+	hashes := []string{user.Password}
+	if policy.History > 1 {
+		recent, err := s.store.PasswordHistory().Recent(ctx, user.ID, policy.History-1)
+		if err != nil {
+			return err
+		}
+		hashes = append(hashes, recent...)
+	}
+	for _, hash := range hashes {
+		if ok, _ := s.hashers.Verify(password, hash); ok {
+			return ErrPasswordReused.WithDetails(map[string]interface{}{"history": policy.History})
+		}
+	}
+	return nil
+}
+
+// Hash 校验新用户的密码并返回哈希
+func (s *PasswordService) Hash(ctx context.Context, user *models.User, password string) (string, error) {
+	if err := s.Check(ctx, user, password); err != nil {
+		return "", err
+	}
+	hashed, err := s.hashers.Hash(password)
+	if err != nil {
+		return "", fmt.Errorf("hash password: %w", err)
+	}
+	return hashed, nil
+}
+
+// Verify 校验用户的密码，通过后将较弱的哈希升级为当前算法和参数
+func (s *PasswordService) Verify(ctx context.Context, user *models.User, password string) bool {
+	switch s.hashers.Scheme(user.Password) {
+	case "":
+		if user.Password != "" {
+			logging.FromContext(ctx).Warn("unrecognized password hash", "user_id", user.ID)
+		}
+	case utils.PasswordSchemePlaintext:
+		logging.FromContext(ctx).Warn("verifying plaintext password", "user_id", user.ID)
+	}
+
+	ok, rehash := s.hashers.Verify(password, user.Password)
+	if ok && rehash {
+		s.upgrade(ctx, user, password)
+	}
+	return ok
+}
+
+// upgrade 重新生成密码哈希，不改变密码的设置时间，失败时只记录日志
+func (s *PasswordService) upgrade(ctx context.Context, user *models.User, password string) {
+	logger := logging.FromContext(ctx).With("user_id", user.ID, "from", s.hashers.Scheme(user.Password), "to", s.hashers.Preferred())
+
+	hashed, err := s.hashers.Hash(password)
+	if err == nil {
+		err = s.store.Users().UpdatePassword(ctx, user.ID, hashed)
+	}
+	if err != nil {
+		logger.Error("upgrade password hash failed", "error", err)
+		return
+	}
+	user.Password = hashed
+	logger.Info("password hash upgraded")
+}
+
+// SchemeCounts 统计各哈希算法的账号数量，没有密码的账号（服务账号、外部认证的用户）不计入
+func (s *PasswordService) SchemeCounts(ctx context.Context) (map[string]int, error) {
+	users, err := s.store.Users().ListPasswordHashes(tenant.Unscoped(ctx))
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[string]int)
+	for _, user := range users {
+		counts[s.hashers.Scheme(user.Password)]++
+	}
+	return counts, nil
+}
+
+// PreferredScheme 新密码使用的哈希算法
+func (s *PasswordService) PreferredScheme() string {
+	return s.hashers.Preferred()
+}
+
+// HashPlaintext 将仍以明文保存的密码改为哈希，不检查密码策略，返回处理的账号数量
+//
+// bcrypt哈希无法离线升级，只能在用户下次登录时升级。
+func (s *PasswordService) HashPlaintext(ctx context.Context) (int, error) {
+	ctx = tenant.Unscoped(ctx)
+	users, err := s.store.Users().ListPasswordHashes(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	hashed := 0
+	for i := range users {
+		user := &users[i]
+		if s.hashers.Scheme(user.Password) != utils.PasswordSchemePlaintext {
+			continue
+		}
+		encoded, err := s.hashers.Hash(user.Password)
+		if err != nil {
+			return hashed, fmt.Errorf("hash password of user %d: %w", user.ID, err)
+		}
+		if err := s.store.Users().UpdatePassword(ctx, user.ID, encoded); err != nil {
+			return hashed, fmt.Errorf("update password of user %d: %w", user.ID, err)
+		}
+		hashed++
+	}
+	return hashed, nil
+}
+
+// Change 校验并设置已存在用户的新密码，旧密码保存到历史记录中
+//
+// temporary为true表示密码由他人设置，用户下次登录时必须修改。
+func (s *PasswordService) Change(ctx context.Context, user *models.User, password string, temporary bool) error {
+	hashed, err := s.Hash(ctx, user, password)
+	if err != nil {
+		return err
+	}
+	policy, err := s.Policy(ctx, user.OrgID)
+	if err != nil {
+		return err
+	}
+
+	var changedAt *time.Time
+	if !temporary {
+		now := time.Now()
+		changedAt = &now
+	}
+	err = s.store.Transaction(ctx, func(tx store.Store) error {
+		if policy.History > 1 && user.Password != "" {
+			if err := tx.PasswordHistory().Add(ctx, user.ID, user.Password, policy.History-1); err != nil {
+				return err
+			}
+		}
+		return tx.Users().SetPassword(ctx, user.ID, hashed, changedAt)
+	})
+	if err != nil {
+		return err
+	}
+	user.Password = hashed
+	user.PasswordChangedAt = changedAt
+	return nil
+}
+
+// Expired 用户的密码是否已过期或者必须在下次登录时修改
+func (s *PasswordService) Expired(ctx context.Context, user *models.User) (bool, error) {
+	if user.PasswordChangedAt == nil {
+		return true, nil
+	}
+	policy, err := s.Policy(ctx, user.OrgID)
+	if err != nil {
+		return false, err
+	}
+	return policy.MaxAgeDays > 0 && time.Since(*user.PasswordChangedAt) > policy.maxAge(), nil
+}
+
+// Generate 生成符合组织策略的随机密码，用于自动创建的账号
+func (s *PasswordService) Generate(ctx context.Context, orgID *uint) (string, error) {
+	policy, err := s.Policy(ctx, orgID)
+	if err != nil {
+		return "", err
+	}
+
+	// 每类字符至少一个，避免易混淆的字符
+	classes := []string{
+		"ABCDEFGHJKLMNPQRSTUVWXYZ",
+		"abcdefghijkmnpqrstuvwxyz",
+		"23456789",
+		"!@#$%^&*-_=+",
+	}
+	length := max(policy.MinLength, generatedPasswordLength)
+	password := make([]byte, 0, length)
+	for _, class := range classes {
+		c, err := randomChar(class)
+		if err != nil {
+			return "", err
+		}
+		password = append(password, c)
+	}
+	all := strings.Join(classes, "")
+	for len(password) < length {
+		c, err := randomChar(all)
+		if err != nil {
+			return "", err
+		}
+		password = append(password, c)
+	}
+
+	// 打乱顺序，固定位置的字符类型会降低强度
+	for i := len(password) - 1; i > 0; i-- {
+		j, err := rand.Int(rand.Reader, big.NewInt(int64(i+1)))
+		if err != nil {
+			return "", err
+		}
+		password[i], password[j.Int64()] = password[j.Int64()], password[i]
+	}
+	return string(password), nil
+}
+
+// OrgPolicy 返回组织自己的密码策略和实际生效的策略
+func (s *PasswordService) OrgPolicy(ctx context.Context, orgID uint) (*OrgPasswordPolicyDetail, error) {
+	if err := s.checkOrg(ctx, orgID); err != nil {
+		return nil, err
+	}
+
+	override, err := s.store.OrgPasswordPolicies().Get(ctx, orgID)
+	if err != nil && !errors.Is(err, store.ErrNotFound) {
+		return nil, err
+	}
+	effective, err := s.Policy(ctx, &orgID)
+	if err != nil {
+		return nil, err
+	}
+	return &OrgPasswordPolicyDetail{Override: override, Effective: *effective}, nil
+}
+
+// SaveOrgPolicy 保存组织的密码策略，比全局策略宽松的设置不生效
+func (s *PasswordService) SaveOrgPolicy(ctx context.Context, orgID uint, update models.OrgPasswordPolicy) (*OrgPasswordPolicyDetail, error) {
+	if err := s.checkOrg(ctx, orgID); err != nil {
+		return nil, err
+	}
+
+	policy, err := s.store.OrgPasswordPolicies().Get(ctx, orgID)
+	if err != nil {
+		if !errors.Is(err, store.ErrNotFound) {
+			return nil, err
+		}
+		policy = &models.OrgPasswordPolicy{OrgID: orgID}
+	}
+	policy.MinLength = update.MinLength
+	policy.RequireUppercase = update.RequireUppercase
+	policy.RequireLowercase = update.RequireLowercase
+	policy.RequireDigit = update.RequireDigit
+	policy.RequireSymbol = update.RequireSymbol
+	policy.History = update.History
+	policy.MaxAgeDays = update.MaxAgeDays
+
+	if err := s.store.OrgPasswordPolicies().Save(ctx, policy); err != nil {
+		return nil, err
+	}
+	return s.OrgPolicy(ctx, orgID)
+}
+
+// checkOrg 检查组织是否存在且在调用者的组织范围内
+func (s *PasswordService) checkOrg(ctx context.Context, orgID uint) error {
+	_, err := s.store.Organizations().Get(ctx, orgID)
+	if errors.Is(err, store.ErrNotFound) {
+		return ErrOrgNotFound
+	}
+	return err
+}
+
+// loadBanned 读取禁用密码列表，忽略空行和以#开头的注释
+func (s *PasswordService) loadBanned(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("open banned password file: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		s.banned[strings.ToLower(line)] = struct{}{}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("read banned password file: %w", err)
+	}
+	return nil
+}
+
+// randomChar 从chars中随机取一个字符
+func randomChar(chars string) (byte, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(int64(len(chars))))
+	if err != nil {
+		return 0, err
+	}
+	return chars[n.Int64()], nil
+}
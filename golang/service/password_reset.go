@@ -0,0 +1,230 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+	"xzyq/config"
+	"xzyq/logging"
+	"xzyq/mailer"
+	"xzyq/models"
+	"xzyq/store"
+	"xzyq/tenant"
+	"xzyq/utils"
+)
+
+const (
+	// passwordResetTokenSize 重置密码令牌的随机字节数
+	passwordResetTokenSize = 32
+	// passwordResetInterval 同一个用户两次申请重置之间的最短间隔
+	passwordResetInterval = time.Minute
+	// mailSendTimeout 发送一封邮件的超时时间
+	mailSendTimeout = 30 * time.Second
+)
+
+// PasswordResetService 通过邮件自助重置密码
+//
+// 令牌只保存哈希，只能使用一次，重置成功后吊销该用户的所有令牌。
+type PasswordResetService struct {
+	store       store.Store
+	revocations *RevocationService
+	guard       *LoginGuard
+	passwords   *PasswordService
+	mailer      mailer.Mailer
+	expire      time.Duration
+	url         string
+}
+
+// NewPasswordResetService 创建PasswordResetService
+func NewPasswordResetService(st store.Store, revocations *RevocationService, guard *LoginGuard, passwords *PasswordService, m mailer.Mailer, cfg config.PasswordResetConfig) *PasswordResetService {
+	return &PasswordResetService{
+		store:       st,
+		revocations: revocations,
+		guard:       guard,
+		passwords:   passwords,
+		mailer:      m,
+		expire:      cfg.Expire,
+		url:         cfg.URL,
+	}
+}
+
+// Forgot 向邮箱对应的用户发送重置密码邮件
+//
+// 无论邮箱是否存在都返回成功，避免泄露哪些邮箱已注册；邮件在后台发送，响应时间不受邮件服务器影响。
+func (s *PasswordResetService) Forgot(ctx context.Context, email, ip string) error {
+	ctx = tenant.Unscoped(ctx)
+	logger := logging.FromContext(ctx).With("ip", ip)
+
+	users, err := s.store.Users().ListByEmail(ctx, strings.TrimSpace(email))
+	if err != nil {
+		return err
+	}
+	if len(users) == 0 {
+		logger.Info("password reset requested for unknown email")
+		return nil
+	}
+
+	now := time.Now()
+	// 顺便清理已过期的令牌
+	if err := s.store.PasswordResetTokens().DeleteExpired(ctx, now); err != nil {
+		logger.Error("delete expired password reset tokens failed", "error", err)
+	}
+
+	for i := range users {
+		user := &users[i]
+		if !user.IsActive {
+			logger.Info("password reset requested for disabled user", "user_id", user.ID)
+			continue
+		}
+		if user.AuthSource != models.AuthSourceLocal {
+			logger.Info("password reset requested for externally authenticated user", "user_id", user.ID)
+			continue
+		}
+		if user.ServiceAccount {
+			logger.Info("password reset requested for service account", "user_id", user.ID)
+			continue
+		}
+
+		latest, err := s.store.PasswordResetTokens().LatestCreatedAt(ctx, user.ID)
+		if err != nil && !errors.Is(err, store.ErrNotFound) {
+			return err
+		}
+		if err == nil && now.Sub(latest) < passwordResetInterval {
+			logger.Info("password reset throttled", "user_id", user.ID)
+			continue
+		}
+
+		token, err := utils.RandomToken(passwordResetTokenSize)
+		if err != nil {
+			return fmt.Errorf("generate password reset token: %w", err)
+		}
+		err = s.store.PasswordResetTokens().Create(ctx, &models.PasswordResetToken{
+			UserID:    user.ID,
+			TokenHash: utils.HashToken(token),
+			IP:        ip,
+			ExpiresAt: now.Add(s.expire),
+		})
+		if err != nil {
+			return fmt.Errorf("store password reset token: %w", err)
+		}
+
+		msg, err := s.message(user, token)
+		if err != nil {
+			return err
+		}
+		go s.send(context.WithoutCancel(ctx), user.ID, msg)
+
+		logger.Info("password reset requested", "user_id", user.ID)
+		s.writeLog(ctx, user, "password_reset_requested", ip)
+	}
+	return nil
+}
+
+// Reset 校验重置令牌并设置新密码
+func (s *PasswordResetService) Reset(ctx context.Context, token, newPassword, ip string) error {
+	ctx = tenant.Unscoped(ctx)
+
+	resetToken, err := s.store.PasswordResetTokens().GetByHash(ctx, utils.HashToken(token))
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return ErrPasswordResetInvalid
+		}
+		return err
+	}
+	if resetToken.UsedAt != nil || !time.Now().Before(resetToken.ExpiresAt) {
+		return ErrPasswordResetInvalid
+	}
+
+	user, err := s.store.Users().Get(ctx, resetToken.UserID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return ErrPasswordResetInvalid
+		}
+		return err
+	}
+	if !user.IsActive {
+		return ErrAccountDisabled
+	}
+
+	// 先按密码策略校验，不符合要求时令牌仍然可以使用
+	if err := s.passwords.Check(ctx, user, newPassword); err != nil {
+		return err
+	}
+
+	// 并发提交同一个令牌时只有一个成功
+	marked, err := s.store.PasswordResetTokens().MarkUsed(ctx, resetToken.ID, time.Now())
+	if err != nil {
+		return err
+	}
+	if !marked {
+		return ErrPasswordResetInvalid
+	}
+	if err := s.passwords.Change(ctx, user, newPassword, false); err != nil {
+		return err
+	}
+	// 其他尚未使用的重置链接一并失效
+	if err := s.store.PasswordResetTokens().DeleteByUser(ctx, user.ID); err != nil {
+		logging.FromContext(ctx).Error("delete password reset tokens failed", "user_id", user.ID, "error", err)
+	}
+
+	if err := s.revocations.RevokeUser(ctx, user.ID, RevokePasswordReset); err != nil {
+		return err
+	}
+	// 能收到邮件说明是用户本人，解除因登录失败造成的锁定
+	if err := s.guard.Unlock(ctx, user.Username); err != nil {
+		logging.FromContext(ctx).Error("unlock user after password reset failed", "user_id", user.ID, "error", err)
+	}
+
+	logging.FromContext(ctx).Info("password reset", "user_id", user.ID, "ip", ip)
+	s.writeLog(ctx, user, "password_reset", ip)
+	return nil
+}
+
+// message 生成重置密码邮件
+func (s *PasswordResetService) message(user *models.User, token string) (mailer.Message, error) {
+	link, err := url.Parse(s.url)
+	if err != nil {
+		return mailer.Message{}, fmt.Errorf("parse password reset url: %w", err)
+	}
+	query := link.Query()
+	query.Set("token", token)
+	link.RawQuery = query.Encode()
+
+	body := fmt.Sprintf(`%s，您好：
+
+我们收到了重置您账号密码的申请。请在 %d 分钟内打开下面的链接设置新密码：
+
+%s
+
+链接只能使用一次。如果这不是您本人的操作，请忽略此邮件，您的密码不会被修改。
+`, user.Username, int(s.expire/time.Minute), link.String())
+
+	return mailer.Message{To: user.Email, Subject: "重置密码", Body: body}, nil
+}
+
+// send 在后台发送邮件，失败时只记录日志
+func (s *PasswordResetService) send(ctx context.Context, userID uint, msg mailer.Message) {
+	ctx, cancel := context.WithTimeout(ctx, mailSendTimeout)
+	defer cancel()
+
+	if err := s.mailer.Send(ctx, msg); err != nil {
+		logging.FromContext(ctx).Error("send password reset mail failed", "user_id", userID, "error", err)
+	}
+}
+
+// writeLog 写入重置密码日志，失败时只记录日志
+func (s *PasswordResetService) writeLog(ctx context.Context, user *models.User, action, ip string) {
+	err := s.store.Logs().Create(ctx, &models.Log{
+		UserID:    user.ID,
+		Username:  user.Username,
+		Action:    action,
+		IP:        ip,
+		Timestamp: time.Now(),
+	})
+	if err != nil {
+		logging.FromContext(ctx).Error("write password reset log failed", "action", action, "error", err)
+	}
+}
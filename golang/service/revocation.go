@@ -0,0 +1,214 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+	"xzyq/config"
+	"xzyq/logging"
+	"xzyq/models"
+	"xzyq/store"
+	"xzyq/utils"
+)
+
+// 令牌吊销原因
+const (
+	RevokeLogout         = "logout"
+	RevokePasswordChange = "password_change"
+	RevokePasswordReset  = "password_reset"
+	RevokeUserDeleted    = "user_deleted"
+	RevokeForceSignOut   = "force_sign_out"
+	RevokeUserDisabled   = "user_disabled"
+	RevokeRoleChange     = "role_change"
+	RevokeSessionRevoked = "session_revoked"
+	RevokeSessionLimit   = "session_limit"
+	RevokeRefreshReuse   = "refresh_token_reuse"
+)
+
+// RevocationService 访问令牌吊销
+//
+// 吊销记录保存在数据库中，并在内存中缓存所有未过期的记录，认证中间件只查询内存。
+// 多实例部署时通过Run定期从数据库同步其他实例写入的记录。
+type RevocationService struct {
+	store        store.Store
+	accessExpire time.Duration
+
+	mu       sync.RWMutex
+	tokens   map[string]time.Time // jti -> 过期时间
+	sessions map[string]time.Time // 会话ID -> 过期时间
+	users    map[uint]userRevocation
+}
+
+// userRevocation 用户级别的吊销，notBefore之前签发的令牌无效
+type userRevocation struct {
+	notBefore time.Time
+	expiresAt time.Time
+}
+
+// NewRevocationService 创建RevocationService，使用前需要调用Sync加载已有记录
+func NewRevocationService(st store.Store, cfg config.JWTConfig) *RevocationService {
+	return &RevocationService{
+		store:        st,
+		accessExpire: cfg.Expire,
+		tokens:       make(map[string]time.Time),
+		sessions:     make(map[string]time.Time),
+		users:        make(map[uint]userRevocation),
+	}
+}
+
+// IsRevoked 判断令牌是否已被吊销，调用方需保证令牌带有jti
+func (s *RevocationService) IsRevoked(claims *utils.Claims) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if _, ok := s.tokens[claims.ID]; ok {
+		return true
+	}
+	if _, ok := s.sessions[claims.SessionID]; ok && claims.SessionID != "" {
+		return true
+	}
+	if r, ok := s.users[claims.UserID]; ok {
+		if claims.IssuedAt == nil || claims.IssuedAt.Time.Before(r.notBefore) {
+			return true
+		}
+	}
+	return false
+}
+
+// RevokeToken 吊销单个访问令牌，直到其过期
+func (s *RevocationService) RevokeToken(ctx context.Context, claims *utils.Claims, reason string) error {
+	expiresAt := time.Now().Add(s.accessExpire)
+	if claims.ExpiresAt != nil {
+		expiresAt = claims.ExpiresAt.Time
+	}
+
+	return s.create(ctx, &models.TokenRevocation{
+		JTI:       claims.ID,
+		UserID:    claims.UserID,
+		ExpiresAt: expiresAt,
+		Reason:    reason,
+	})
+}
+
+// RevokeSession 吊销会话已签发的所有访问令牌，刷新令牌由调用方吊销
+func (s *RevocationService) RevokeSession(ctx context.Context, userID uint, sessionID, reason string) error {
+	return s.create(ctx, &models.TokenRevocation{
+		SessionID: sessionID,
+		UserID:    userID,
+		ExpiresAt: time.Now().Add(s.accessExpire),
+		Reason:    reason,
+	})
+}
+
+// RevokeUser 吊销用户当前持有的所有访问令牌和刷新令牌，并结束其所有会话
+func (s *RevocationService) RevokeUser(ctx context.Context, userID uint, reason string) error {
+	// 与令牌签发时间的精度一致，吊销后立即签发的令牌不受影响
+	now := time.Now().Truncate(time.Millisecond)
+	if err := s.store.RefreshTokens().RevokeByUser(ctx, userID, now); err != nil {
+		return err
+	}
+	if err := s.store.Sessions().RevokeByUser(ctx, userID, now, reason); err != nil {
+		return err
+	}
+	err := s.create(ctx, &models.TokenRevocation{
+		UserID:    userID,
+		NotBefore: &now,
+		ExpiresAt: now.Add(s.accessExpire),
+		Reason:    reason,
+	})
+	if err != nil {
+		return err
+	}
+
+	logging.FromContext(ctx).Info("user tokens revoked", "user_id", userID, "reason", reason)
+	return nil
+}
+
+// Sync 从数据库加载所有未过期的吊销记录，并清理已过期的记录
+//
+// 记录的ID在插入时分配、在事务提交后才可见，较小的ID可能晚于较大的ID出现，
+// 不能只加载上次同步之后的ID；未过期的记录最多保留访问令牌有效期，每次全部重新读取。
+func (s *RevocationService) Sync(ctx context.Context) error {
+	now := time.Now()
+
+	revocations, err := s.store.TokenRevocations().ListActive(ctx, now)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	for i := range revocations {
+		s.apply(&revocations[i])
+	}
+	for jti, expiresAt := range s.tokens {
+		if !expiresAt.After(now) {
+			delete(s.tokens, jti)
+		}
+	}
+	for sessionID, expiresAt := range s.sessions {
+		if !expiresAt.After(now) {
+			delete(s.sessions, sessionID)
+		}
+	}
+	for userID, r := range s.users {
+		if !r.expiresAt.After(now) {
+			delete(s.users, userID)
+		}
+	}
+	s.mu.Unlock()
+
+	return s.store.TokenRevocations().DeleteExpired(ctx, now)
+}
+
+// Run 每隔interval同步一次，直到ctx结束
+func (s *RevocationService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.Sync(ctx); err != nil && ctx.Err() == nil {
+				logging.FromContext(ctx).Error("sync token revocations failed", "error", err)
+			}
+		}
+	}
+}
+
+// create 写入数据库并立即生效于本实例
+func (s *RevocationService) create(ctx context.Context, revocation *models.TokenRevocation) error {
+	if err := s.store.TokenRevocations().Create(ctx, revocation); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.apply(revocation)
+	s.mu.Unlock()
+	return nil
+}
+
+// apply 将吊销记录加入缓存，调用方需持有写锁
+func (s *RevocationService) apply(r *models.TokenRevocation) {
+	if r.JTI != "" {
+		s.tokens[r.JTI] = r.ExpiresAt
+		return
+	}
+	if r.SessionID != "" {
+		s.sessions[r.SessionID] = r.ExpiresAt
+		return
+	}
+	if r.NotBefore == nil {
+		return
+	}
+	// 同一用户的多次吊销取最晚的时间
+	current, ok := s.users[r.UserID]
+	if !ok || r.NotBefore.After(current.notBefore) {
+		current.notBefore = *r.NotBefore
+	}
+	if r.ExpiresAt.After(current.expiresAt) {
+		current.expiresAt = r.ExpiresAt
+	}
+	s.users[r.UserID] = current
+}
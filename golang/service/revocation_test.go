@@ -0,0 +1,162 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+	"xzyq/config"
+	"xzyq/models"
+	"xzyq/utils"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// testClaims 在issuedAt签发的访问令牌
+func testClaims(userID uint, jti, sessionID string, issuedAt time.Time) *utils.Claims {
+	return &utils.Claims{
+		UserID:    userID,
+		SessionID: sessionID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        jti,
+			IssuedAt:  jwt.NewNumericDate(issuedAt),
+			ExpiresAt: jwt.NewNumericDate(issuedAt.Add(15 * time.Minute)),
+		},
+	}
+}
+
+// 其他实例的吊销记录即使ID小于已同步的记录（事务较晚提交）也要加载
+func TestRevocationSyncLoadsLateCommittedRecords(t *testing.T) {
+	st := newTestStore(t)
+	ctx := context.Background()
+	cfg := config.JWTConfig{Expire: 15 * time.Minute}
+	expiresAt := time.Now().Add(time.Minute)
+
+	if err := st.TokenRevocations().Create(ctx, &models.TokenRevocation{ID: 5, JTI: "later", UserID: 1, ExpiresAt: expiresAt}); err != nil {
+		t.Fatalf("create revocation: %v", err)
+	}
+	other := NewRevocationService(st, cfg)
+	if err := other.Sync(ctx); err != nil {
+		t.Fatalf("Sync: %v", err)
+	}
+
+	if err := st.TokenRevocations().Create(ctx, &models.TokenRevocation{ID: 3, JTI: "earlier", UserID: 1, ExpiresAt: expiresAt}); err != nil {
+		t.Fatalf("create revocation: %v", err)
+	}
+	if err := other.Sync(ctx); err != nil {
+		t.Fatalf("Sync: %v", err)
+	}
+
+	now := time.Now()
+	for _, jti := range []string{"later", "earlier"} {
+		if !other.IsRevoked(testClaims(1, jti, "", now)) {
+			t.Errorf("token %s not revoked after Sync", jti)
+		}
+	}
+	if other.IsRevoked(testClaims(1, "fresh", "", now)) {
+		t.Error("unrevoked token reported as revoked")
+	}
+}
+
+func TestRevocationRevokeUser(t *testing.T) {
+	st := newTestStore(t)
+	ctx := context.Background()
+	cfg := config.JWTConfig{Expire: 15 * time.Minute}
+	revocations := NewRevocationService(st, cfg)
+
+	issued := time.Now().Add(-time.Second)
+	if err := revocations.RevokeUser(ctx, 1, RevokePasswordChange); err != nil {
+		t.Fatalf("RevokeUser: %v", err)
+	}
+
+	// 吊销之前签发的令牌失效，之后签发的令牌和其他用户不受影响
+	if !revocations.IsRevoked(testClaims(1, "old", "s1", issued)) {
+		t.Error("token issued before RevokeUser is not revoked")
+	}
+	if revocations.IsRevoked(testClaims(1, "new", "s2", time.Now().Add(2*time.Second))) {
+		t.Error("token issued after RevokeUser is revoked")
+	}
+	if revocations.IsRevoked(testClaims(2, "other", "s3", issued)) {
+		t.Error("token of another user is revoked")
+	}
+
+	// 其他实例同步后同样生效
+	other := NewRevocationService(st, cfg)
+	if err := other.Sync(ctx); err != nil {
+		t.Fatalf("Sync: %v", err)
+	}
+	if !other.IsRevoked(testClaims(1, "old", "s1", issued)) {
+		t.Error("token issued before RevokeUser is not revoked on another instance")
+	}
+}
+
+// 退出登录后当前访问令牌和刷新令牌失效，其他会话不受影响
+func TestRevocationAfterLogout(t *testing.T) {
+	e := newTestEnv(t)
+	ctx := context.Background()
+	e.createUser(t, "alice", "Xq7#pass-word", "user", nil)
+	tokens := e.login(t, "alice", "Xq7#pass-word")
+	other := e.login(t, "alice", "Xq7#pass-word")
+
+	claims, err := utils.ParseToken(tokens.AccessToken)
+	if err != nil {
+		t.Fatalf("parse access token: %v", err)
+	}
+	if err := e.users.Logout(ctx, claims, tokens.RefreshToken, "127.0.0.1"); err != nil {
+		t.Fatalf("Logout: %v", err)
+	}
+
+	if !e.revoked(t, tokens.AccessToken) {
+		t.Error("access token is not revoked after Logout")
+	}
+	if _, err := e.tokens.Refresh(ctx, tokens.RefreshToken, "127.0.0.1"); !errors.Is(err, ErrRefreshTokenInvalid) {
+		t.Errorf("Refresh after Logout: err = %v, want ErrRefreshTokenInvalid", err)
+	}
+	if e.revoked(t, other.AccessToken) {
+		t.Error("access token of another session is revoked after Logout")
+	}
+}
+
+// 修改密码后之前签发的所有令牌失效，修改密码时签发的新令牌可以使用
+func TestRevocationAfterPasswordChange(t *testing.T) {
+	e := newTestEnv(t)
+	ctx := context.Background()
+	user := e.createUser(t, "alice", "Xq7#pass-word", "user", nil)
+	first := e.login(t, "alice", "Xq7#pass-word")
+	second := e.login(t, "alice", "Xq7#pass-word")
+	// 令牌签发时间精确到毫秒
+	time.Sleep(2 * time.Millisecond)
+
+	changed, err := e.users.ChangePassword(ctx, user.ID, "Xq7#pass-word", "Zr9!other-secret", "127.0.0.1", "test")
+	if err != nil {
+		t.Fatalf("ChangePassword: %v", err)
+	}
+
+	for name, tokens := range map[string]*TokenPair{"first": first, "second": second} {
+		if !e.revoked(t, tokens.AccessToken) {
+			t.Errorf("%s access token is not revoked after ChangePassword", name)
+		}
+		if _, err := e.tokens.Refresh(ctx, tokens.RefreshToken, "127.0.0.1"); err == nil {
+			t.Errorf("%s refresh token still works after ChangePassword", name)
+		}
+	}
+	if e.revoked(t, changed.AccessToken) {
+		t.Error("access token issued by ChangePassword is revoked")
+	}
+	if _, err := e.tokens.Refresh(ctx, changed.RefreshToken, "127.0.0.1"); err != nil {
+		t.Errorf("Refresh with token issued by ChangePassword: %v", err)
+	}
+
+	// 其他实例同步后同样生效
+	other := NewRevocationService(e.store, e.cfg.JWT)
+	if err := other.Sync(ctx); err != nil {
+		t.Fatalf("Sync: %v", err)
+	}
+	claims, err := utils.ParseToken(first.AccessToken)
+	if err != nil {
+		t.Fatalf("parse access token: %v", err)
+	}
+	if !other.IsRevoked(claims) {
+		t.Error("access token is not revoked on another instance after ChangePassword")
+	}
+}
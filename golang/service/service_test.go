@@ -0,0 +1,133 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+	"xzyq/config"
+	"xzyq/migrate"
+	"xzyq/models"
+	"xzyq/store"
+	"xzyq/tenant"
+	"xzyq/utils"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// newTestStore 创建执行过全部迁移的SQLite内存数据库
+func newTestStore(t *testing.T) store.Store {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open(":memory:?_pragma=foreign_keys(1)"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("get sql.DB: %v", err)
+	}
+	// 内存数据库的每个连接都是独立的数据库
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+
+	if err := tenant.RegisterCallbacks(db); err != nil {
+		t.Fatalf("register tenant callbacks: %v", err)
+	}
+	migrator, err := migrate.New(db)
+	if err != nil {
+		t.Fatalf("load migrations: %v", err)
+	}
+	if _, err := migrator.Up(context.Background()); err != nil {
+		t.Fatalf("migrate up: %v", err)
+	}
+	return store.NewGormStore(db)
+}
+
+// testEnv 按main.go的方式组装的服务，使用SQLite内存数据库
+type testEnv struct {
+	store       store.Store
+	cfg         *config.Config
+	revocations *RevocationService
+	sessions    *SessionService
+	tokens      *TokenService
+	guard       *LoginGuard
+	mfa         *MFAService
+	passwords   *PasswordService
+	users       *UserService
+}
+
+// newTestEnv 使用默认配置创建服务，configure可以在创建前修改配置
+func newTestEnv(t *testing.T, configure ...func(cfg *config.Config)) *testEnv {
+	t.Helper()
+
+	cfg := config.Default()
+	cfg.JWT.Secret = "test-secret"
+	// 测试中不需要默认的哈希强度
+	cfg.Auth.PasswordHash = config.PasswordHashConfig{Memory: 1024, Iterations: 1, Parallelism: 1}
+	for _, fn := range configure {
+		fn(cfg)
+	}
+	if err := utils.InitJWT(cfg.JWT); err != nil {
+		t.Fatalf("init jwt: %v", err)
+	}
+
+	e := &testEnv{store: newTestStore(t), cfg: cfg}
+	e.revocations = NewRevocationService(e.store, cfg.JWT)
+	e.sessions = NewSessionService(e.store, e.revocations, cfg.Auth.Sessions)
+	e.tokens = NewTokenService(e.store, e.sessions, cfg.JWT)
+	e.guard = NewLoginGuard(e.store, cfg.Auth.Lockout)
+	e.mfa = NewMFAService(e.store, cfg.Auth.MFA)
+	passwords, err := NewPasswordService(e.store, cfg.Auth.Password, cfg.Auth.PasswordHash)
+	if err != nil {
+		t.Fatalf("new password service: %v", err)
+	}
+	e.passwords = passwords
+	e.users = NewUserService(e.store, e.tokens, e.revocations, e.guard, e.mfa, passwords, NewPasswordAuthenticator(passwords))
+	return e
+}
+
+// createUser 直接在数据库中创建密码未过期的本地用户
+func (e *testEnv) createUser(t *testing.T, username, password, role string, orgID *uint) *models.User {
+	t.Helper()
+
+	ctx := tenant.Unscoped(context.Background())
+	user := &models.User{Username: username, Role: role, OrgID: orgID, IsActive: true, AuthSource: models.AuthSourceLocal}
+	hashed, err := e.passwords.Hash(ctx, user, password)
+	if err != nil {
+		t.Fatalf("hash password of %s: %v", username, err)
+	}
+	now := time.Now()
+	user.Password = hashed
+	user.PasswordChangedAt = &now
+	if err := e.store.Users().Create(ctx, user); err != nil {
+		t.Fatalf("create user %s: %v", username, err)
+	}
+	return user
+}
+
+// login 登录并返回令牌，失败时结束测试
+func (e *testEnv) login(t *testing.T, username, password string) *TokenPair {
+	t.Helper()
+
+	result, err := e.users.Login(context.Background(), username, password, nil, "127.0.0.1", "test")
+	if err != nil {
+		t.Fatalf("login %s: %v", username, err)
+	}
+	if result.Tokens == nil {
+		t.Fatalf("login %s returned no tokens", username)
+	}
+	return result.Tokens
+}
+
+// revoked 访问令牌是否已被吊销
+func (e *testEnv) revoked(t *testing.T, accessToken string) bool {
+	t.Helper()
+
+	claims, err := utils.ParseToken(accessToken)
+	if err != nil {
+		t.Fatalf("parse access token: %v", err)
+	}
+	return e.revocations.IsRevoked(claims)
+}
@@ -0,0 +1,166 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+	"xzyq/config"
+	"xzyq/logging"
+	"xzyq/models"
+	"xzyq/store"
+	"xzyq/tenant"
+	"xzyq/utils"
+)
+
+// SessionService 登录会话
+//
+// 每次登录创建一个会话，对应一个刷新令牌族，访问令牌通过sid关联到会话。
+// 最近活动时间在登录和刷新令牌时更新。用户同时登录的会话超过上限时吊销最久未活动的会话。
+type SessionService struct {
+	store       store.Store
+	revocations *RevocationService
+	maxPerUser  int
+}
+
+// NewSessionService 创建SessionService
+func NewSessionService(st store.Store, revocations *RevocationService, cfg config.SessionConfig) *SessionService {
+	return &SessionService{store: st, revocations: revocations, maxPerUser: cfg.MaxPerUser}
+}
+
+// Start 为新的令牌族创建会话，超过同时登录的会话数上限时先吊销最久未活动的会话
+func (s *SessionService) Start(ctx context.Context, user *models.User, familyID, ip, userAgent string, expiresAt time.Time) error {
+	now := time.Now()
+	// 顺便清理该用户已过期的会话
+	if err := s.store.Sessions().DeleteExpiredByUser(ctx, user.ID, now); err != nil {
+		logging.FromContext(ctx).Error("delete expired sessions failed", "user_id", user.ID, "error", err)
+	}
+
+	if s.maxPerUser > 0 {
+		active, err := s.store.Sessions().ListActive(ctx, user.ID, now)
+		if err != nil {
+			return err
+		}
+		// 最近活动的在前，保留前maxPerUser-1个
+		for i := s.maxPerUser - 1; i < len(active); i++ {
+			logging.FromContext(ctx).Info("session limit reached, revoking oldest session",
+				"user_id", user.ID, "session_id", active[i].ID, "max_per_user", s.maxPerUser)
+			if err := s.revoke(ctx, &active[i], RevokeSessionLimit); err != nil {
+				return err
+			}
+		}
+	}
+
+	if len(userAgent) > 255 {
+		userAgent = userAgent[:255]
+	}
+	return s.store.Sessions().Create(ctx, &models.Session{
+		UserID:     user.ID,
+		FamilyID:   familyID,
+		IP:         ip,
+		UserAgent:  userAgent,
+		LastSeenAt: now,
+		ExpiresAt:  expiresAt,
+	})
+}
+
+// End 吊销令牌族对应的会话，会话不存在时忽略
+func (s *SessionService) End(ctx context.Context, familyID, reason string) error {
+	session, err := s.store.Sessions().GetByFamily(ctx, familyID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return nil
+		}
+		return err
+	}
+	return s.revoke(ctx, session, reason)
+}
+
+// List 返回用户当前有效的会话，current为发起请求的令牌，用于标记当前会话
+func (s *SessionService) List(ctx context.Context, userID uint, current *utils.Claims) ([]models.Session, error) {
+	sessions, err := s.store.Sessions().ListActive(tenant.Unscoped(ctx), userID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	for i := range sessions {
+		sessions[i].Current = current != nil && current.SessionID != "" && sessions[i].FamilyID == current.SessionID
+	}
+	return sessions, nil
+}
+
+// Revoke 吊销用户的会话，已吊销的会话再次吊销时直接返回
+func (s *SessionService) Revoke(ctx context.Context, userID, id uint, ip string) error {
+	ctx = tenant.Unscoped(ctx)
+
+	session, err := s.store.Sessions().Get(ctx, userID, id)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return ErrSessionNotFound
+		}
+		return err
+	}
+	if session.RevokedAt != nil {
+		return nil
+	}
+	if err := s.revoke(ctx, session, RevokeSessionRevoked); err != nil {
+		return err
+	}
+
+	user, err := s.store.Users().GetUnscoped(ctx, userID)
+	if err != nil {
+		return err
+	}
+	err = s.store.Logs().Create(ctx, &models.Log{
+		UserID:    user.ID,
+		Username:  user.Username,
+		Action:    "session_revoked",
+		IP:        ip,
+		Timestamp: time.Now(),
+	})
+	if err != nil {
+		logging.FromContext(ctx).Error("write session log failed", "error", err)
+	}
+	return nil
+}
+
+// UserSessions 返回管理员组织范围内用户的有效会话
+func (s *SessionService) UserSessions(ctx context.Context, userID uint, current *utils.Claims) ([]models.Session, error) {
+	if err := s.checkUser(ctx, userID); err != nil {
+		return nil, err
+	}
+	return s.List(ctx, userID, current)
+}
+
+// RevokeUserSession 管理员吊销组织范围内用户的会话
+func (s *SessionService) RevokeUserSession(ctx context.Context, userID, id uint, ip string) error {
+	if err := s.checkUser(ctx, userID); err != nil {
+		return err
+	}
+	return s.Revoke(ctx, userID, id, ip)
+}
+
+// revoke 吊销会话及其刷新令牌族，并使该会话已签发的访问令牌失效
+func (s *SessionService) revoke(ctx context.Context, session *models.Session, reason string) error {
+	now := time.Now()
+	revoked, err := s.store.Sessions().Revoke(ctx, session.ID, now, reason)
+	if err != nil || !revoked {
+		return err
+	}
+	if err := s.store.RefreshTokens().RevokeFamily(ctx, session.FamilyID, now); err != nil {
+		return err
+	}
+	if err := s.revocations.RevokeSession(ctx, session.UserID, session.FamilyID, reason); err != nil {
+		return err
+	}
+
+	logging.FromContext(ctx).Info("session revoked", "user_id", session.UserID, "session_id", session.ID, "reason", reason)
+	return nil
+}
+
+// checkUser 检查用户是否存在且在调用者的组织范围内
+func (s *SessionService) checkUser(ctx context.Context, userID uint) error {
+	_, err := s.store.Users().Get(ctx, userID)
+	if errors.Is(err, store.ErrNotFound) {
+		return ErrUserNotFound
+	}
+	return err
+}
@@ -0,0 +1,49 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+	"xzyq/config"
+)
+
+// 超过同时登录的会话数上限时吊销最久未活动的会话
+func TestSessionLimitRevokesLeastRecentlyActive(t *testing.T) {
+	e := newTestEnv(t, func(cfg *config.Config) {
+		cfg.Auth.Sessions.MaxPerUser = 2
+	})
+	ctx := context.Background()
+	user := e.createUser(t, "alice", "Xq7#pass-word", "user", nil)
+
+	first := e.login(t, "alice", "Xq7#pass-word")
+	time.Sleep(5 * time.Millisecond)
+	second := e.login(t, "alice", "Xq7#pass-word")
+	time.Sleep(5 * time.Millisecond)
+	// 刷新后第一个会话成为最近活动的会话
+	first, err := e.tokens.Refresh(ctx, first.RefreshToken, "127.0.0.1")
+	if err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+	time.Sleep(5 * time.Millisecond)
+	third := e.login(t, "alice", "Xq7#pass-word")
+
+	if !e.revoked(t, second.AccessToken) {
+		t.Error("access token of the least recently active session is not revoked")
+	}
+	if _, err := e.tokens.Refresh(ctx, second.RefreshToken, "127.0.0.1"); err == nil {
+		t.Error("refresh token of the least recently active session still works")
+	}
+	for name, tokens := range map[string]*TokenPair{"first": first, "third": third} {
+		if e.revoked(t, tokens.AccessToken) {
+			t.Errorf("access token of the %s session is revoked", name)
+		}
+	}
+
+	sessions, err := e.sessions.List(ctx, user.ID, nil)
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(sessions) != 2 {
+		t.Errorf("active sessions = %d, want 2", len(sessions))
+	}
+}
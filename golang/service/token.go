@@ -0,0 +1,185 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+	"xzyq/config"
+	"xzyq/logging"
+	"xzyq/models"
+	"xzyq/store"
+	"xzyq/utils"
+)
+
+// refreshTokenSize 刷新令牌的随机字节数
+const refreshTokenSize = 32
+
+// TokenPair 登录或刷新后签发的令牌
+type TokenPair struct {
+	AccessToken  string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int64  `json:"expires_in"` // 访问令牌的有效秒数
+}
+
+// TokenService 签发访问令牌和刷新令牌
+type TokenService struct {
+	store         store.Store
+	sessions      *SessionService
+	accessExpire  time.Duration
+	refreshExpire time.Duration
+}
+
+// NewTokenService 创建TokenService
+func NewTokenService(st store.Store, sessions *SessionService, cfg config.JWTConfig) *TokenService {
+	return &TokenService{store: st, sessions: sessions, accessExpire: cfg.Expire, refreshExpire: cfg.RefreshExpire}
+}
+
+// Issue 为用户签发新的令牌族，并创建对应的登录会话
+func (s *TokenService) Issue(ctx context.Context, user *models.User, ip, userAgent string) (*TokenPair, error) {
+	familyID, err := utils.RandomToken(16)
+	if err != nil {
+		return nil, fmt.Errorf("generate token family: %w", err)
+	}
+
+	// 顺便清理该用户已过期的刷新令牌
+	if err := s.store.RefreshTokens().DeleteExpiredByUser(ctx, user.ID, time.Now()); err != nil {
+		logging.FromContext(ctx).Error("delete expired refresh tokens failed", "user_id", user.ID, "error", err)
+	}
+	if err := s.sessions.Start(ctx, user, familyID, ip, userAgent, time.Now().Add(s.refreshExpire)); err != nil {
+		return nil, fmt.Errorf("start session: %w", err)
+	}
+
+	return s.issue(ctx, s.store, user, familyID, ip)
+}
+
+// Refresh 使用刷新令牌换取新的令牌，旧的刷新令牌随即失效
+//
+// 已使用过的刷新令牌再次出现时吊销整个令牌族，持有该族任意令牌的客户端都需要重新登录。
+func (s *TokenService) Refresh(ctx context.Context, refreshToken, ip string) (*TokenPair, error) {
+	logger := logging.FromContext(ctx).With("ip", ip)
+	now := time.Now()
+
+	var (
+		pair   *TokenPair
+		reused *models.RefreshToken
+	)
+	err := s.store.Transaction(ctx, func(tx store.Store) error {
+		token, err := tx.RefreshTokens().GetByHash(ctx, utils.HashToken(refreshToken))
+		if err != nil {
+			if errors.Is(err, store.ErrNotFound) {
+				return ErrRefreshTokenInvalid
+			}
+			return err
+		}
+		if token.RevokedAt != nil || !now.Before(token.ExpiresAt) {
+			return ErrRefreshTokenInvalid
+		}
+		if token.UsedAt != nil {
+			reused = token
+			return nil
+		}
+
+		marked, err := tx.RefreshTokens().MarkUsed(ctx, token.ID, now)
+		if err != nil {
+			return err
+		}
+		if !marked {
+			// 并发请求已经使用了该令牌
+			reused = token
+			return nil
+		}
+
+		user, err := tx.Users().Get(ctx, token.UserID)
+		if err != nil {
+			if errors.Is(err, store.ErrNotFound) {
+				return ErrRefreshTokenInvalid
+			}
+			return err
+		}
+		// 禁用时已吊销所有刷新令牌，这里再确认一次
+		if !user.IsActive {
+			return ErrRefreshTokenInvalid
+		}
+
+		pair, err = s.issue(ctx, tx, user, token.FamilyID, ip)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if reused != nil {
+		logger.Warn("refresh token reuse detected, revoking token family",
+			"user_id", reused.UserID, "family_id", reused.FamilyID)
+		if err := s.store.RefreshTokens().RevokeFamily(ctx, reused.FamilyID, now); err != nil {
+			return nil, err
+		}
+		if err := s.sessions.End(ctx, reused.FamilyID, RevokeRefreshReuse); err != nil {
+			return nil, err
+		}
+		return nil, ErrRefreshTokenReused
+	}
+	return pair, nil
+}
+
+// Revoke 吊销刷新令牌所在的令牌族，令牌不存在时忽略
+func (s *TokenService) Revoke(ctx context.Context, userID uint, refreshToken string) error {
+	token, err := s.store.RefreshTokens().GetByHash(ctx, utils.HashToken(refreshToken))
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return nil
+		}
+		return err
+	}
+	if token.UserID != userID {
+		return nil
+	}
+	return s.store.RefreshTokens().RevokeFamily(ctx, token.FamilyID, time.Now())
+}
+
+// EndSession 结束访问令牌所属的会话，没有会话的旧令牌忽略
+func (s *TokenService) EndSession(ctx context.Context, claims *utils.Claims, reason string) error {
+	if claims.SessionID == "" {
+		return nil
+	}
+	return s.sessions.End(ctx, claims.SessionID, reason)
+}
+
+// issue 在令牌族中签发新的访问令牌和刷新令牌，并更新会话的最近活动时间
+func (s *TokenService) issue(ctx context.Context, st store.Store, user *models.User, familyID, ip string) (*TokenPair, error) {
+	var orgID uint
+	if user.OrgID != nil {
+		orgID = *user.OrgID
+	}
+	accessToken, jti, err := utils.GenerateToken(user.ID, orgID, user.Username, user.Role, familyID)
+	if err != nil {
+		return nil, fmt.Errorf("generate access token: %w", err)
+	}
+
+	refreshToken, err := utils.RandomToken(refreshTokenSize)
+	if err != nil {
+		return nil, fmt.Errorf("generate refresh token: %w", err)
+	}
+	now := time.Now()
+	expiresAt := now.Add(s.refreshExpire)
+	err = st.RefreshTokens().Create(ctx, &models.RefreshToken{
+		UserID:    user.ID,
+		FamilyID:  familyID,
+		TokenHash: utils.HashToken(refreshToken),
+		IP:        ip,
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("store refresh token: %w", err)
+	}
+	if err := st.Sessions().Rotate(ctx, familyID, jti, ip, now, expiresAt); err != nil {
+		return nil, fmt.Errorf("update session: %w", err)
+	}
+
+	return &TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(s.accessExpire / time.Second),
+	}, nil
+}
@@ -0,0 +1,68 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestTokenRefreshRotates(t *testing.T) {
+	e := newTestEnv(t)
+	ctx := context.Background()
+	e.createUser(t, "alice", "Xq7#pass-word", "user", nil)
+	first := e.login(t, "alice", "Xq7#pass-word")
+
+	second, err := e.tokens.Refresh(ctx, first.RefreshToken, "127.0.0.1")
+	if err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+	if second.RefreshToken == first.RefreshToken || second.AccessToken == first.AccessToken {
+		t.Error("Refresh did not issue new tokens")
+	}
+
+	// 新的刷新令牌可以继续使用
+	third, err := e.tokens.Refresh(ctx, second.RefreshToken, "127.0.0.1")
+	if err != nil {
+		t.Fatalf("Refresh with rotated token: %v", err)
+	}
+	if third.RefreshToken == second.RefreshToken {
+		t.Error("Refresh did not rotate the refresh token")
+	}
+
+	if _, err := e.tokens.Refresh(ctx, "unknown", "127.0.0.1"); !errors.Is(err, ErrRefreshTokenInvalid) {
+		t.Errorf("Refresh with unknown token: err = %v, want ErrRefreshTokenInvalid", err)
+	}
+}
+
+// 已使用过的刷新令牌再次出现时吊销整个令牌族
+func TestTokenRefreshReuseRevokesFamily(t *testing.T) {
+	e := newTestEnv(t)
+	ctx := context.Background()
+	e.createUser(t, "alice", "Xq7#pass-word", "user", nil)
+	first := e.login(t, "alice", "Xq7#pass-word")
+	other := e.login(t, "alice", "Xq7#pass-word")
+
+	second, err := e.tokens.Refresh(ctx, first.RefreshToken, "127.0.0.1")
+	if err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+
+	if _, err := e.tokens.Refresh(ctx, first.RefreshToken, "10.0.0.1"); !errors.Is(err, ErrRefreshTokenReused) {
+		t.Fatalf("Refresh with used token: err = %v, want ErrRefreshTokenReused", err)
+	}
+	// 同一族中后签发的令牌同样失效
+	if _, err := e.tokens.Refresh(ctx, second.RefreshToken, "127.0.0.1"); !errors.Is(err, ErrRefreshTokenInvalid) {
+		t.Errorf("Refresh with rotated token after reuse: err = %v, want ErrRefreshTokenInvalid", err)
+	}
+	if !e.revoked(t, second.AccessToken) {
+		t.Error("access token of the reused family is not revoked")
+	}
+
+	// 其他登录会话不受影响
+	if e.revoked(t, other.AccessToken) {
+		t.Error("access token of another session is revoked")
+	}
+	if _, err := e.tokens.Refresh(ctx, other.RefreshToken, "127.0.0.1"); err != nil {
+		t.Errorf("Refresh of another session: %v", err)
+	}
+}
@@ -0,0 +1,998 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"xzyq/apperr"
+	"xzyq/logging"
+	"xzyq/models"
+	"xzyq/store"
+	"xzyq/tenant"
+	"xzyq/utils"
+)
+
+// UserService 用户相关业务逻辑
+type UserService struct {
+	store       store.Store
+	tokens      *TokenService
+	revocations *RevocationService
+	guard       *LoginGuard
+	mfa         *MFAService
+	passwords   *PasswordService
+
+	authenticators map[string]Authenticator
+	sources        []string // 本地没有的用户按此顺序尝试各认证方式
+}
+
+// NewUserService 创建UserService，authenticators为支持的认证方式
+func NewUserService(st store.Store, tokens *TokenService, revocations *RevocationService, guard *LoginGuard, mfa *MFAService, passwords *PasswordService, authenticators ...Authenticator) *UserService {
+	s := &UserService{
+		store:          st,
+		tokens:         tokens,
+		revocations:    revocations,
+		guard:          guard,
+		mfa:            mfa,
+		passwords:      passwords,
+		authenticators: make(map[string]Authenticator, len(authenticators)),
+	}
+	for _, a := range authenticators {
+		s.authenticators[a.Source()] = a
+		s.sources = append(s.sources, a.Source())
+	}
+	return s
+}
+
+// LoginResult 登录结果，需要两步验证时只返回MFA挑战
+type LoginResult struct {
+	Tokens        *TokenPair
+	User          *models.User
+	MFA           *MFAChallenge
+	RecoveryCodes []string // 登录时完成两步验证设置后生成的恢复码
+}
+
+// UserUpdate 管理员更新用户的内容，空字段表示不修改
+//
+// 启用和禁用、密码、两步验证都有单独的接口，不能通过这里修改。
+type UserUpdate struct {
+	Username   string
+	Email      string
+	Phone      string
+	Role       string
+	OrgID      *uint
+	AuthSource string
+}
+
+// ProfileUpdate 个人资料更新内容，空字段表示不修改
+type ProfileUpdate struct {
+	Username string
+	Password string
+	Email    string
+	Phone    string
+	// OldPassword 修改密码或邮箱时需要校验当前密码，邮箱用于找回密码
+	OldPassword string
+}
+
+// Register 自助注册为组织的用户
+//
+// 组织需要开放自助注册，用户的角色由组织设置，不能由客户端指定；组织不存在时同样视为未开放。
+func (s *UserService) Register(ctx context.Context, orgID uint, user *models.User) error {
+	org, err := s.store.Organizations().Get(tenant.Unscoped(ctx), orgID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return ErrRegistrationClosed
+		}
+		return err
+	}
+	if !org.Registration.SelfRegistration {
+		return ErrRegistrationClosed
+	}
+
+	user.OrgID = &org.ID
+	switch role := org.Registration.SelfRegistrationRole; role {
+	case "admin", "user":
+		user.Role = role
+	case "":
+		user.Role = "user"
+	default:
+		// 只能是SaveRegistration之前写入的无效设置
+		logging.FromContext(ctx).Warn("invalid self registration role", "org_id", org.ID, "role", role)
+		return ErrRegistrationClosed
+	}
+	user.IsActive = true
+	return s.register(ctx, user)
+}
+
+// Bootstrap 直接创建设置了密码的用户，不检查注册设置，只用于写入初始数据
+func (s *UserService) Bootstrap(ctx context.Context, user *models.User) error {
+	// 如果指定了组织ID，检查组织是否存在
+	if user.OrgID != nil {
+		if err := s.checkOrgExists(ctx, *user.OrgID); err != nil {
+			return err
+		}
+	}
+	return s.register(ctx, user)
+}
+
+// register 检查用户名后按密码策略加密密码并创建用户
+func (s *UserService) register(ctx context.Context, user *models.User) error {
+	// 检查用户名是否已存在
+	exists, err := s.store.Users().UsernameExists(ctx, user.Username)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return ErrUsernameTaken
+	}
+
+	// 按密码策略校验后加密
+	hashedPassword, err := s.passwords.Hash(ctx, user, user.Password)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	user.Password = hashedPassword
+	user.PasswordChangedAt = &now
+
+	return s.store.Users().Create(ctx, user)
+}
+
+// Create 管理员创建用户，组织管理员只能在自己的组织中创建
+//
+// 密码由管理员设置，用户首次登录时必须修改。服务账号没有密码，只能使用API密钥。
+func (s *UserService) Create(ctx context.Context, creatorID uint, user *models.User) error {
+	creator, err := s.Get(tenant.Unscoped(ctx), creatorID)
+	if err != nil {
+		return err
+	}
+	if creator.OrgID != nil {
+		user.OrgID = creator.OrgID
+	} else if user.OrgID != nil {
+		if err := s.checkOrgExists(ctx, *user.OrgID); err != nil {
+			return err
+		}
+	}
+
+	exists, err := s.store.Users().UsernameExists(ctx, user.Username)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return ErrUsernameTaken
+	}
+
+	// 创建时默认启用，数据库默认值会覆盖false，创建后再禁用
+	active := user.IsActive
+	if user.ServiceAccount {
+		now := time.Now()
+		user.Password = ""
+		user.PasswordChangedAt = &now
+	} else {
+		hashedPassword, err := s.passwords.Hash(ctx, user, user.Password)
+		if err != nil {
+			return err
+		}
+		user.Password = hashedPassword
+		user.PasswordChangedAt = nil
+	}
+	user.CreatedBy = creator.ID
+
+	if err := s.store.Users().Create(ctx, user); err != nil {
+		return err
+	}
+	if !active {
+		now := time.Now()
+		if _, err := s.store.Users().Disable(ctx, user.ID, now, creator.ID, "", nil); err != nil {
+			return err
+		}
+		user.IsActive = false
+		user.DisabledAt = &now
+		user.DisabledBy = &creator.ID
+	}
+	logging.FromContext(ctx).Info("user created", "user_id", user.ID, "username", user.Username, "created_by", creator.ID,
+		"service_account", user.ServiceAccount)
+	return nil
+}
+
+// Login 校验用户名和密码，成功后返回令牌和用户信息
+//
+// 已启用两步验证或组织要求启用两步验证的用户只返回MFA挑战，需要再调用LoginMFA完成登录。
+// orgID只用于本地没有的用户，外部认证方式只在该组织中查找并创建用户。
+func (s *UserService) Login(ctx context.Context, username, password string, orgID *uint, ip, userAgent string) (*LoginResult, error) {
+	// 查找用户（包括软删除的用户）
+	logger := logging.FromContext(ctx).With("username", username, "ip", ip)
+
+	user, err := s.store.Users().GetByUsername(ctx, username)
+	if err != nil && !errors.Is(err, store.ErrNotFound) {
+		logger.Error("login failed", "reason", "lookup user", "error", err)
+		return nil, err
+	}
+	if user != nil {
+		logger = logger.With("user_id", user.ID)
+	}
+
+	// 用户名或IP连续失败次数过多时拒绝，不论用户是否存在，避免泄露用户名是否存在
+	reason, err := s.guard.Check(ctx, username, ip)
+	if err != nil {
+		if reason != "" {
+			s.loginFailed(ctx, user, username, ip, reason)
+		}
+		return nil, err
+	}
+
+	// 已删除的用户视为不存在
+	if user != nil && user.DeletedAt.Valid {
+		s.loginFailed(ctx, user, username, ip, LoginFailDeleted)
+		return nil, s.countFailure(ctx, username, ip, ErrInvalidCredentials)
+	}
+
+	source, result, err := s.authenticate(ctx, user, orgID, username, password)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrInvalidCredentials) && user == nil:
+			s.loginFailed(ctx, nil, username, ip, LoginFailUnknownUser)
+			return nil, s.countFailure(ctx, username, ip, err)
+		case errors.Is(err, ErrInvalidCredentials):
+			s.loginFailed(ctx, user, username, ip, LoginFailBadPassword)
+			return nil, s.countFailure(ctx, username, ip, err)
+		case errors.Is(err, ErrDirectoryNoRole):
+			s.loginFailed(ctx, user, username, ip, LoginFailNoRole)
+			return nil, err
+		}
+		logger.Error("login failed", "reason", "authenticate", "error", err)
+		return nil, err
+	}
+
+	if user == nil {
+		if user, err = s.provision(ctx, source, username, result, ip); err != nil {
+			logger.Error("login failed", "reason", "provision user", "error", err)
+			return nil, err
+		}
+	} else {
+		// 密码正确后才提示账号已被禁用，避免泄露账号状态
+		if err := s.checkActive(ctx, user); err != nil {
+			if errors.Is(err, ErrAccountDisabled) {
+				s.loginFailed(ctx, user, username, ip, LoginFailDisabled)
+			}
+			return nil, err
+		}
+		s.syncExternal(ctx, user, result)
+	}
+
+	// 本地密码过期时需要先调用ChangeExpiredPassword修改密码，外部认证方式的密码由外部系统管理
+	if user.AuthSource == models.AuthSourceLocal {
+		expired, err := s.passwords.Expired(ctx, user)
+		if err != nil {
+			return nil, err
+		}
+		if expired {
+			s.loginFailed(ctx, user, username, ip, LoginFailExpired)
+			return nil, ErrPasswordExpired
+		}
+	}
+
+	return s.authenticated(ctx, user, ip, userAgent)
+}
+
+// authenticate 用用户的认证方式校验密码；本地没有的用户依次尝试各认证方式，返回认证通过的认证方式
+func (s *UserService) authenticate(ctx context.Context, user *models.User, orgID *uint, username, password string) (string, *AuthResult, error) {
+	if user != nil {
+		authenticator, ok := s.authenticators[user.AuthSource]
+		if !ok {
+			return "", nil, fmt.Errorf("unknown auth source %q", user.AuthSource)
+		}
+		result, err := authenticator.Authenticate(ctx, user, nil, username, password)
+		return user.AuthSource, result, err
+	}
+
+	for _, source := range s.sources {
+		result, err := s.authenticators[source].Authenticate(ctx, nil, orgID, username, password)
+		if errors.Is(err, ErrInvalidCredentials) {
+			continue
+		}
+		return source, result, err
+	}
+	return "", nil, ErrInvalidCredentials
+}
+
+// provision 创建外部认证方式认证通过的新用户，用户没有本地密码
+func (s *UserService) provision(ctx context.Context, source, username string, result *AuthResult, ip string) (*models.User, error) {
+	if result.OrgID == nil {
+		return nil, fmt.Errorf("auth source %q authenticated unknown user without organization", source)
+	}
+
+	now := time.Now()
+	user := &models.User{
+		Username:          username,
+		Email:             result.Email,
+		IsActive:          true,
+		Role:              result.Role,
+		OrgID:             result.OrgID,
+		AuthSource:        source,
+		PasswordChangedAt: &now,
+	}
+	if err := s.store.Users().Create(ctx, user); err != nil {
+		return nil, fmt.Errorf("provision user: %w", err)
+	}
+
+	logging.FromContext(ctx).Info("user provisioned", "user_id", user.ID, "username", username, "auth_source", source, "org_id", *user.OrgID)
+	if err := s.writeLog(ctx, user.ID, user.Username, source+"_provisioned", ip); err != nil {
+		logging.FromContext(ctx).Error("write provision log failed", "error", err)
+	}
+	return user, nil
+}
+
+// syncExternal 用外部认证方式返回的角色和邮箱更新用户，失败时不影响登录
+func (s *UserService) syncExternal(ctx context.Context, user *models.User, result *AuthResult) {
+	changed := false
+	if result.Role != "" && result.Role != user.Role {
+		logging.FromContext(ctx).Info("user role synced", "user_id", user.ID, "from", user.Role, "to", result.Role)
+		user.Role = result.Role
+		changed = true
+	}
+	if result.Email != "" && result.Email != user.Email {
+		user.Email = result.Email
+		changed = true
+	}
+	if !changed {
+		return
+	}
+	if err := s.store.Users().Save(ctx, user); err != nil {
+		logging.FromContext(ctx).Error("sync external user failed", "user_id", user.ID, "error", err)
+	}
+}
+
+// authenticated 密码或单点登录验证通过后调用，需要两步验证时返回MFA挑战，否则完成登录
+func (s *UserService) authenticated(ctx context.Context, user *models.User, ip, userAgent string) (*LoginResult, error) {
+	// 需要两步验证时先不清除失败计数，验证码错误同样计入失败次数
+	required, err := s.mfa.Required(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+	if user.MFAEnabled || required {
+		challenge, err := s.mfa.NewChallenge(ctx, user, ip)
+		if err != nil {
+			return nil, err
+		}
+		logging.FromContext(ctx).Info("login requires mfa", "username", user.Username, "ip", ip,
+			"user_id", user.ID, "enrollment_required", challenge.EnrollmentRequired)
+		return &LoginResult{MFA: challenge}, nil
+	}
+
+	return s.completeLogin(ctx, user, ip, userAgent)
+}
+
+// LoginMFA 使用Login返回的MFA挑战和TOTP验证码或恢复码完成登录
+//
+// 尚未启用两步验证的用户（组织要求启用）需要先调用LoginMFAEnroll获取密钥，
+// 此时验证码用于确认设置，成功后同时返回新生成的恢复码。
+func (s *UserService) LoginMFA(ctx context.Context, token, code, recoveryCode, ip, userAgent string) (*LoginResult, error) {
+	challenge, user, err := s.mfaChallengeUser(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	// 第一步之后可能已被禁用
+	if err := s.checkActive(ctx, user); err != nil {
+		if errors.Is(err, ErrAccountDisabled) {
+			s.loginFailed(ctx, user, user.Username, ip, LoginFailDisabled)
+		}
+		return nil, err
+	}
+
+	reason, err := s.guard.Check(ctx, user.Username, ip)
+	if err != nil {
+		if reason != "" {
+			s.loginFailed(ctx, user, user.Username, ip, reason)
+		}
+		return nil, err
+	}
+
+	var recoveryCodes []string
+	if user.MFAEnabled {
+		err = s.mfa.Verify(ctx, user, code, recoveryCode)
+	} else {
+		recoveryCodes, err = s.mfa.Activate(ctx, user, code)
+	}
+	if err != nil {
+		if errors.Is(err, ErrMFACodeInvalid) {
+			s.loginFailed(ctx, user, user.Username, ip, LoginFailBadMFACode)
+			return nil, s.countFailure(ctx, user.Username, ip, err)
+		}
+		return nil, err
+	}
+
+	if err := s.mfa.CompleteChallenge(ctx, challenge); err != nil {
+		return nil, err
+	}
+
+	result, err := s.completeLogin(ctx, user, ip, userAgent)
+	if err != nil {
+		return nil, err
+	}
+	result.RecoveryCodes = recoveryCodes
+	return result, nil
+}
+
+// LoginMFAEnroll 组织要求启用两步验证的用户在登录过程中生成TOTP密钥
+func (s *UserService) LoginMFAEnroll(ctx context.Context, token string) (*MFAEnrollment, error) {
+	_, user, err := s.mfaChallengeUser(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	return s.mfa.Enroll(ctx, user)
+}
+
+// completeLogin 签发令牌并记录登录
+func (s *UserService) completeLogin(ctx context.Context, user *models.User, ip, userAgent string) (*LoginResult, error) {
+	logger := logging.FromContext(ctx).With("username", user.Username, "ip", ip, "user_id", user.ID)
+
+	if err := s.guard.Succeed(ctx, user.Username); err != nil {
+		logger.Error("reset login failures failed", "error", err)
+	}
+
+	// 签发访问令牌和刷新令牌
+	tokens, err := s.tokens.Issue(ctx, user, ip, userAgent)
+	if err != nil {
+		logger.Error("issue tokens failed", "error", err)
+		return nil, err
+	}
+
+	// 更新最后登录时间
+	user.LastLoginAt = time.Now()
+	if err := s.store.Users().Save(ctx, user); err != nil {
+		logger.Error("update last login time failed", "error", err)
+	}
+
+	// 记录登录日志
+	if err := s.writeLog(ctx, user.ID, user.Username, "login", ip); err != nil {
+		logger.Error("write login log failed", "error", err)
+	}
+
+	logger.Info("login succeeded")
+
+	return &LoginResult{Tokens: tokens, User: user}, nil
+}
+
+// mfaChallengeUser 返回MFA挑战及其用户
+func (s *UserService) mfaChallengeUser(ctx context.Context, token string) (*models.MFAChallenge, *models.User, error) {
+	challenge, err := s.mfa.Challenge(ctx, token)
+	if err != nil {
+		return nil, nil, err
+	}
+	user, err := s.store.Users().Get(ctx, challenge.UserID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return nil, nil, ErrMFAChallengeInvalid
+		}
+		return nil, nil, err
+	}
+	return challenge, user, nil
+}
+
+// ChangeExpiredPassword 密码过期的用户校验原密码后设置新密码，之后需要使用新密码重新登录
+//
+// 与登录共用失败计数，密码未过期时不允许通过这里修改，避免绕过两步验证。
+func (s *UserService) ChangeExpiredPassword(ctx context.Context, username, oldPassword, newPassword, ip string) error {
+	ctx = tenant.Unscoped(ctx)
+
+	reason, err := s.guard.Check(ctx, username, ip)
+	if err != nil {
+		if reason != "" {
+			s.loginFailed(ctx, nil, username, ip, reason)
+		}
+		return err
+	}
+
+	user, err := s.store.Users().GetByUsername(ctx, username)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			s.loginFailed(ctx, nil, username, ip, LoginFailUnknownUser)
+			return s.countFailure(ctx, username, ip, ErrInvalidCredentials)
+		}
+		return err
+	}
+	if user.DeletedAt.Valid {
+		s.loginFailed(ctx, user, username, ip, LoginFailDeleted)
+		return s.countFailure(ctx, username, ip, ErrInvalidCredentials)
+	}
+	if !s.passwords.Verify(ctx, user, oldPassword) {
+		s.loginFailed(ctx, user, username, ip, LoginFailBadPassword)
+		return s.countFailure(ctx, username, ip, ErrInvalidCredentials)
+	}
+	if err := s.checkActive(ctx, user); err != nil {
+		if errors.Is(err, ErrAccountDisabled) {
+			s.loginFailed(ctx, user, username, ip, LoginFailDisabled)
+		}
+		return err
+	}
+
+	expired, err := s.passwords.Expired(ctx, user)
+	if err != nil {
+		return err
+	}
+	if !expired {
+		return apperr.ErrForbidden
+	}
+
+	if err := s.passwords.Change(ctx, user, newPassword, false); err != nil {
+		return err
+	}
+	if err := s.revocations.RevokeUser(ctx, user.ID, RevokePasswordChange); err != nil {
+		return err
+	}
+
+	logging.FromContext(ctx).Info("expired password changed", "user_id", user.ID, "ip", ip)
+	if err := s.writeLog(ctx, user.ID, user.Username, "password_changed", ip); err != nil {
+		logging.FromContext(ctx).Error("write password change log failed", "error", err)
+	}
+	return nil
+}
+
+// Refresh 使用刷新令牌换取新的令牌
+func (s *UserService) Refresh(ctx context.Context, refreshToken, ip string) (*TokenPair, error) {
+	return s.tokens.Refresh(ctx, refreshToken, ip)
+}
+
+// Logout 结束当前会话，吊销当前访问令牌和客户端持有的刷新令牌，并记录退出日志
+func (s *UserService) Logout(ctx context.Context, claims *utils.Claims, refreshToken, ip string) error {
+	if err := s.revocations.RevokeToken(ctx, claims, RevokeLogout); err != nil {
+		return err
+	}
+	if err := s.tokens.EndSession(ctx, claims, RevokeLogout); err != nil {
+		return err
+	}
+	if refreshToken != "" {
+		if err := s.tokens.Revoke(ctx, claims.UserID, refreshToken); err != nil {
+			return err
+		}
+	}
+	return s.writeLog(ctx, claims.UserID, claims.Username, "logout", ip)
+}
+
+// ForceSignOut 吊销用户的所有令牌，使其在所有设备上退出登录
+func (s *UserService) ForceSignOut(ctx context.Context, id uint) error {
+	if _, err := s.Get(ctx, id); err != nil {
+		return err
+	}
+	return s.revocations.RevokeUser(ctx, id, RevokeForceSignOut)
+}
+
+// Unlock 管理员解锁因连续登录失败被锁定的用户
+func (s *UserService) Unlock(ctx context.Context, id uint, ip string) error {
+	user, err := s.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := s.guard.Unlock(ctx, user.Username); err != nil {
+		return err
+	}
+
+	logging.FromContext(ctx).Info("user unlocked", "user_id", user.ID, "username", user.Username)
+	if err := s.writeLog(ctx, user.ID, user.Username, "account_unlocked", ip); err != nil {
+		logging.FromContext(ctx).Error("write unlock log failed", "error", err)
+	}
+	return nil
+}
+
+// List 获取用户列表
+func (s *UserService) List(ctx context.Context) ([]models.User, error) {
+	return s.store.Users().List(ctx)
+}
+
+// ListServiceAccounts 获取服务账号列表
+func (s *UserService) ListServiceAccounts(ctx context.Context) ([]models.User, error) {
+	return s.store.Users().ListServiceAccounts(ctx)
+}
+
+// Get 获取单个用户
+func (s *UserService) Get(ctx context.Context, id uint) (*models.User, error) {
+	user, err := s.store.Users().Get(ctx, id)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, ErrUserNotFound
+	}
+	return user, err
+}
+
+// Profile 获取当前用户自己的资料，不受组织范围限制
+func (s *UserService) Profile(ctx context.Context, id uint) (*models.User, error) {
+	return s.Get(tenant.Unscoped(ctx), id)
+}
+
+// Active 用户是否存在且未被禁用，认证中间件对每个请求调用
+//
+// 禁用时吊销令牌的记录要等其他实例同步后才生效，这里直接查询数据库，禁用后所有实例立即拒绝该用户的令牌。
+func (s *UserService) Active(ctx context.Context, id uint) (bool, error) {
+	user, err := s.store.Users().Get(tenant.Unscoped(ctx), id)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	return user.IsActive, nil
+}
+
+// Update 管理员更新用户，只修改update中的非空字段
+//
+// 角色或组织变化时吊销用户的所有令牌，新的权限在重新登录后生效。
+// 服务账号的API密钥沿用账号的角色和组织，创建后只能修改用户名和电话，需要其他权限时另建服务账号。
+func (s *UserService) Update(ctx context.Context, id uint, update UserUpdate) (*models.User, error) {
+	user, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if user.ServiceAccount && serviceAccountLocked(user, update) {
+		return nil, apperr.ErrBadRequest.Wrap(errors.New("role, organization, email and auth source of service accounts cannot be changed"))
+	}
+
+	var updates models.User
+	if update.Username != "" && update.Username != user.Username {
+		// 目录认证按用户名查找目录中的用户
+		if user.AuthSource != models.AuthSourceLocal {
+			return nil, apperr.ErrBadRequest.Wrap(errors.New("username of externally authenticated users cannot be changed"))
+		}
+		exists, err := s.store.Users().UsernameExists(ctx, update.Username)
+		if err != nil {
+			return nil, err
+		}
+		if exists {
+			return nil, ErrUsernameTaken
+		}
+		updates.Username = update.Username
+	}
+	updates.Email = update.Email
+	updates.Phone = update.Phone
+
+	roleChanged := update.Role != "" && update.Role != user.Role
+	if roleChanged {
+		if update.Role != "admin" && update.Role != "user" {
+			return nil, apperr.ErrBadRequest.Wrap(fmt.Errorf("unknown role %q", update.Role))
+		}
+		updates.Role = update.Role
+	}
+	// 组织管理员只能看到自己的组织，不能把用户移到其他组织
+	orgChanged := update.OrgID != nil && (user.OrgID == nil || *user.OrgID != *update.OrgID)
+	if orgChanged {
+		if err := s.checkOrgExists(ctx, *update.OrgID); err != nil {
+			return nil, err
+		}
+		updates.OrgID = update.OrgID
+	}
+	// 管理员可以把已有用户改为使用目录认证
+	if update.AuthSource != "" {
+		if _, ok := s.authenticators[update.AuthSource]; !ok {
+			return nil, apperr.ErrBadRequest.Wrap(fmt.Errorf("unknown auth source %q", update.AuthSource))
+		}
+		updates.AuthSource = update.AuthSource
+	}
+
+	if err := s.store.Users().Updates(ctx, user, updates); err != nil {
+		return nil, err
+	}
+	// 令牌中带有角色和组织，需要重新签发
+	if roleChanged || orgChanged {
+		if err := s.revocations.RevokeUser(ctx, user.ID, RevokeRoleChange); err != nil {
+			return nil, err
+		}
+		logging.FromContext(ctx).Info("user role changed", "user_id", user.ID, "role", update.Role, "org_id", update.OrgID)
+	}
+
+	// 重新查询用户信息以获取关联的组织数据
+	return s.store.Users().GetWithOrg(ctx, id)
+}
+
+// serviceAccountLocked update是否修改了服务账号不允许修改的字段
+func serviceAccountLocked(user *models.User, update UserUpdate) bool {
+	return (update.Role != "" && update.Role != user.Role) ||
+		(update.OrgID != nil && !sameID(update.OrgID, user.OrgID)) ||
+		(update.Email != "" && update.Email != user.Email) ||
+		(update.AuthSource != "" && update.AuthSource != user.AuthSource)
+}
+
+// Disable 禁用用户并立即吊销其所有令牌，reenableAt不为nil时到期后自动重新启用
+//
+// 禁用的用户不能登录，已签发的访问令牌、刷新令牌和API密钥都失效；与删除不同，用户数据保留并可以重新启用。
+func (s *UserService) Disable(ctx context.Context, adminID, id uint, reason string, reenableAt *time.Time, ip string) (*models.User, error) {
+	if id == adminID {
+		return nil, ErrCannotDisableSelf
+	}
+	user, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if reenableAt != nil && !reenableAt.After(now) {
+		return nil, apperr.ErrBadRequest.Wrap(errors.New("reenable_at must be in the future"))
+	}
+	disabled, err := s.store.Users().Disable(ctx, user.ID, now, adminID, reason, reenableAt)
+	if err != nil {
+		return nil, err
+	}
+	if !disabled {
+		return nil, ErrUserAlreadyDisabled
+	}
+	if err := s.revocations.RevokeUser(ctx, user.ID, RevokeUserDisabled); err != nil {
+		return nil, err
+	}
+
+	logging.FromContext(ctx).Info("user disabled", "user_id", user.ID, "username", user.Username, "disabled_by", adminID, "reenable_at", reenableAt)
+	s.writeStatusLog(ctx, user, "user_disabled", ip, reason)
+	return s.store.Users().GetWithOrg(ctx, user.ID)
+}
+
+// Enable 重新启用被禁用的用户，用户需要重新登录
+func (s *UserService) Enable(ctx context.Context, id uint, ip string) (*models.User, error) {
+	user, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	enabled, err := s.store.Users().Enable(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !enabled {
+		return nil, ErrUserNotDisabled
+	}
+
+	logging.FromContext(ctx).Info("user enabled", "user_id", user.ID, "username", user.Username)
+	s.writeStatusLog(ctx, user, "user_enabled", ip, "")
+	return s.store.Users().GetWithOrg(ctx, user.ID)
+}
+
+// EnableDue 重新启用计划启用时间已到的用户
+func (s *UserService) EnableDue(ctx context.Context) error {
+	ctx = tenant.Unscoped(ctx)
+
+	users, err := s.store.Users().ListReenableDue(ctx, time.Now())
+	if err != nil {
+		return err
+	}
+	for i := range users {
+		if err := s.enableScheduled(ctx, &users[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RunScheduledEnable 每隔interval重新启用计划启用时间已到的用户，直到ctx结束
+func (s *UserService) RunScheduledEnable(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.EnableDue(ctx); err != nil && ctx.Err() == nil {
+				logging.FromContext(ctx).Error("enable scheduled users failed", "error", err)
+			}
+		}
+	}
+}
+
+// checkActive 检查用户是否已被禁用，计划重新启用的时间已到时先重新启用
+func (s *UserService) checkActive(ctx context.Context, user *models.User) error {
+	if user.IsActive {
+		return nil
+	}
+	if user.ReenableAt != nil && !time.Now().Before(*user.ReenableAt) {
+		return s.enableScheduled(ctx, user)
+	}
+	if user.ReenableAt != nil {
+		return ErrAccountDisabled.WithDetails(map[string]interface{}{"reenable_at": user.ReenableAt})
+	}
+	return ErrAccountDisabled
+}
+
+// enableScheduled 按计划重新启用用户，并同步更新user
+func (s *UserService) enableScheduled(ctx context.Context, user *models.User) error {
+	enabled, err := s.store.Users().Enable(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	user.IsActive = true
+	user.DisabledAt, user.DisabledBy, user.DisabledReason, user.ReenableAt = nil, nil, "", nil
+	if enabled {
+		logging.FromContext(ctx).Info("user enabled", "user_id", user.ID, "username", user.Username, "scheduled", true)
+		s.writeStatusLog(ctx, user, "user_enabled", "", "scheduled")
+	}
+	return nil
+}
+
+// Delete 彻底删除用户（包括已软删除的用户），并吊销其未过期的令牌
+func (s *UserService) Delete(ctx context.Context, id uint) error {
+	err := s.store.Transaction(ctx, func(tx store.Store) error {
+		if _, err := tx.Users().GetUnscoped(ctx, id); err != nil {
+			if errors.Is(err, store.ErrNotFound) {
+				return ErrUserNotFound
+			}
+			return err
+		}
+		return tx.Users().HardDelete(ctx, id)
+	})
+	if err != nil {
+		return err
+	}
+	return s.revocations.RevokeUser(ctx, id, RevokeUserDeleted)
+}
+
+// UpdateProfile 更新当前用户的个人资料
+//
+// 修改密码或邮箱前需要校验当前密码，避免拿到会话的人直接改掉密码，或改成自己的邮箱后重置密码；
+// 外部认证用户的密码和邮箱由外部系统管理，不能在这里修改。管理员可以通过Update直接修改邮箱。
+// 修改了密码时与ChangePassword一样吊销原有令牌，并返回当前会话的新令牌。
+func (s *UserService) UpdateProfile(ctx context.Context, id uint, update ProfileUpdate, ip, userAgent string) (*models.User, *TokenPair, error) {
+	// 用户总是可以修改自己的资料
+	ctx = tenant.Unscoped(ctx)
+
+	user, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// 如果要更新用户名，检查是否已存在
+	if update.Username != "" && update.Username != user.Username {
+		// 目录认证按用户名查找目录中的用户
+		if user.AuthSource != models.AuthSourceLocal {
+			return nil, nil, apperr.ErrBadRequest.Wrap(errors.New("username of externally authenticated users cannot be changed"))
+		}
+		exists, err := s.store.Users().UsernameExists(ctx, update.Username)
+		if err != nil {
+			return nil, nil, err
+		}
+		if exists {
+			return nil, nil, ErrUsernameTaken
+		}
+		user.Username = update.Username
+	}
+
+	if update.Password != "" && user.AuthSource != models.AuthSourceLocal {
+		return nil, nil, ErrPasswordManagedExternally
+	}
+	emailChanged := update.Email != "" && !strings.EqualFold(update.Email, user.Email)
+	if emailChanged && (user.AuthSource != models.AuthSourceLocal || user.ServiceAccount) {
+		return nil, nil, apperr.ErrBadRequest.Wrap(errors.New("email of externally authenticated users cannot be changed"))
+	}
+	// 修改密码或邮箱都需要先校验当前密码
+	if update.Password != "" || emailChanged {
+		if !s.passwords.Verify(ctx, user, update.OldPassword) {
+			return nil, nil, ErrInvalidOldPassword
+		}
+	}
+
+	if update.Email != "" {
+		user.Email = update.Email
+	}
+	// 更新其他信息
+	if update.Phone != "" {
+		user.Phone = update.Phone
+	}
+	// 保存资料前先按密码策略校验新密码，避免只保存了一部分
+	if update.Password != "" {
+		if err := s.passwords.Check(ctx, user, update.Password); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if err := s.store.Users().Save(ctx, user); err != nil {
+		return nil, nil, err
+	}
+	if emailChanged {
+		logging.FromContext(ctx).Info("user email changed", "user_id", user.ID, "ip", ip)
+		if err := s.writeLog(ctx, user.ID, user.Username, "email_changed", ip); err != nil {
+			logging.FromContext(ctx).Error("write email change log failed", "error", err)
+		}
+	}
+
+	// 与ChangePassword相同，修改密码后原有令牌全部失效
+	var tokens *TokenPair
+	if update.Password != "" {
+		tokens, err = s.setPassword(ctx, user, update.Password, ip, userAgent)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	return user, tokens, nil
+}
+
+// ChangePassword 校验原密码后修改密码
+//
+// 修改成功后吊销该用户的所有令牌，并为当前会话签发新的令牌。
+func (s *UserService) ChangePassword(ctx context.Context, id uint, oldPassword, newPassword, ip, userAgent string) (*TokenPair, error) {
+	// 用户总是可以修改自己的密码
+	ctx = tenant.Unscoped(ctx)
+
+	user, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.AuthSource != models.AuthSourceLocal {
+		return nil, ErrPasswordManagedExternally
+	}
+	// 验证原密码
+	if !s.passwords.Verify(ctx, user, oldPassword) {
+		return nil, ErrInvalidOldPassword
+	}
+
+	return s.setPassword(ctx, user, newPassword, ip, userAgent)
+}
+
+// setPassword 修改已验证过原密码的用户的密码，吊销该用户的所有令牌，并为当前会话签发新的令牌
+func (s *UserService) setPassword(ctx context.Context, user *models.User, newPassword, ip, userAgent string) (*TokenPair, error) {
+	if err := s.passwords.Change(ctx, user, newPassword, false); err != nil {
+		return nil, err
+	}
+	if err := s.revocations.RevokeUser(ctx, user.ID, RevokePasswordChange); err != nil {
+		return nil, err
+	}
+	return s.tokens.Issue(ctx, user, ip, userAgent)
+}
+
+// checkOrgExists 检查组织是否存在
+func (s *UserService) checkOrgExists(ctx context.Context, orgID uint) error {
+	_, err := s.store.Organizations().Get(ctx, orgID)
+	if errors.Is(err, store.ErrNotFound) {
+		return ErrOrgNotFound
+	}
+	return err
+}
+
+// loginFailed 记录登录失败，user为nil表示用户不存在
+func (s *UserService) loginFailed(ctx context.Context, user *models.User, username, ip, reason string) {
+	logger := logging.FromContext(ctx).With("username", username, "ip", ip)
+	logger.Warn("login failed", "reason", reason)
+
+	entry := &models.Log{
+		Username:  username,
+		Action:    "login_failed",
+		Reason:    reason,
+		IP:        ip,
+		Timestamp: time.Now(),
+	}
+	if user != nil {
+		entry.UserID = user.ID
+	}
+	if err := s.store.Logs().Create(ctx, entry); err != nil {
+		logger.Error("write login failure log failed", "error", err)
+	}
+}
+
+// countFailure 累加失败次数并返回err，记录失败出错时只写日志，不影响返回给客户端的错误
+func (s *UserService) countFailure(ctx context.Context, username, ip string, err error) error {
+	if failErr := s.guard.Fail(ctx, username, ip); failErr != nil {
+		logging.FromContext(ctx).Error("record login failure failed", "username", username, "ip", ip, "error", failErr)
+	}
+	return err
+}
+
+// writeStatusLog 记录禁用和启用，detail为禁用原因，失败时不影响操作
+func (s *UserService) writeStatusLog(ctx context.Context, user *models.User, action, ip, detail string) {
+	err := s.store.Logs().Create(ctx, &models.Log{
+		UserID:    user.ID,
+		Username:  user.Username,
+		Action:    action,
+		IP:        ip,
+		Detail:    detail,
+		Timestamp: time.Now(),
+	})
+	if err != nil {
+		logging.FromContext(ctx).Error("write user status log failed", "user_id", user.ID, "error", err)
+	}
+}
+
+// writeLog 写入登录/退出日志
+func (s *UserService) writeLog(ctx context.Context, userID uint, username, action, ip string) error {
+	return s.store.Logs().Create(ctx, &models.Log{
+		UserID:    userID,
+		Username:  username,
+		Action:    action,
+		IP:        ip,
+		Timestamp: time.Now(),
+	})
+}
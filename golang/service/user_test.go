@@ -0,0 +1,97 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+	"xzyq/apperr"
+	"xzyq/tenant"
+)
+
+func TestUserDisableEnable(t *testing.T) {
+	e := newTestEnv(t)
+	ctx := context.Background()
+	admin := e.createUser(t, "root", "Xq7#pass-word", "admin", nil)
+	user := e.createUser(t, "alice", "Xq7#pass-word", "user", nil)
+	tokens := e.login(t, "alice", "Xq7#pass-word")
+	time.Sleep(2 * time.Millisecond)
+
+	if _, err := e.users.Disable(ctx, admin.ID, admin.ID, "", nil, "127.0.0.1"); !errors.Is(err, ErrCannotDisableSelf) {
+		t.Errorf("Disable self: err = %v, want ErrCannotDisableSelf", err)
+	}
+
+	disabled, err := e.users.Disable(ctx, admin.ID, user.ID, "left the company", nil, "127.0.0.1")
+	if err != nil {
+		t.Fatalf("Disable: %v", err)
+	}
+	if disabled.IsActive || disabled.DisabledReason != "left the company" {
+		t.Errorf("disabled user = %+v, want inactive with reason", disabled)
+	}
+	if active, err := e.users.Active(ctx, user.ID); err != nil || active {
+		t.Errorf("Active after Disable = (%v, %v), want false", active, err)
+	}
+	// 已签发的令牌立即失效，也不能重新登录
+	if !e.revoked(t, tokens.AccessToken) {
+		t.Error("access token is not revoked after Disable")
+	}
+	if _, err := e.tokens.Refresh(ctx, tokens.RefreshToken, "127.0.0.1"); err == nil {
+		t.Error("refresh token still works after Disable")
+	}
+	if _, err := e.users.Login(ctx, "alice", "Xq7#pass-word", nil, "127.0.0.1", "test"); !errors.Is(err, ErrAccountDisabled) {
+		t.Errorf("Login while disabled: err = %v, want ErrAccountDisabled", err)
+	}
+	if _, err := e.users.Disable(ctx, admin.ID, user.ID, "", nil, "127.0.0.1"); !errors.Is(err, ErrUserAlreadyDisabled) {
+		t.Errorf("Disable twice: err = %v, want ErrUserAlreadyDisabled", err)
+	}
+
+	enabled, err := e.users.Enable(ctx, user.ID, "127.0.0.1")
+	if err != nil {
+		t.Fatalf("Enable: %v", err)
+	}
+	if !enabled.IsActive || enabled.DisabledAt != nil {
+		t.Errorf("enabled user = %+v, want active without disabled_at", enabled)
+	}
+	if _, err := e.users.Enable(ctx, user.ID, "127.0.0.1"); !errors.Is(err, ErrUserNotDisabled) {
+		t.Errorf("Enable twice: err = %v, want ErrUserNotDisabled", err)
+	}
+	// 重新启用后需要重新登录，之前的令牌仍然无效
+	if !e.revoked(t, tokens.AccessToken) {
+		t.Error("access token issued before Disable works again after Enable")
+	}
+	e.login(t, "alice", "Xq7#pass-word")
+}
+
+// 计划启用时间已到的用户由EnableDue重新启用，未到的保持禁用
+func TestUserEnableDue(t *testing.T) {
+	e := newTestEnv(t)
+	ctx := tenant.Unscoped(context.Background())
+	admin := e.createUser(t, "root", "Xq7#pass-word", "admin", nil)
+	due := e.createUser(t, "alice", "Xq7#pass-word", "user", nil)
+	later := e.createUser(t, "bob", "Xq7#pass-word", "user", nil)
+
+	now := time.Now()
+	past, future := now.Add(-time.Minute), now.Add(time.Hour)
+	if _, err := e.store.Users().Disable(ctx, due.ID, now.Add(-time.Hour), admin.ID, "", &past); err != nil {
+		t.Fatalf("disable user: %v", err)
+	}
+	if _, err := e.users.Disable(ctx, admin.ID, later.ID, "", &future, "127.0.0.1"); err != nil {
+		t.Fatalf("Disable: %v", err)
+	}
+	if _, err := e.users.Disable(ctx, admin.ID, later.ID, "", &past, "127.0.0.1"); !errors.Is(err, apperr.ErrBadRequest) {
+		t.Errorf("Disable with reenable_at in the past: err = %v, want ErrBadRequest", err)
+	}
+
+	if err := e.users.EnableDue(ctx); err != nil {
+		t.Fatalf("EnableDue: %v", err)
+	}
+	if active, err := e.users.Active(ctx, due.ID); err != nil || !active {
+		t.Errorf("Active of due user = (%v, %v), want true", active, err)
+	}
+	if active, err := e.users.Active(ctx, later.ID); err != nil || active {
+		t.Errorf("Active of user scheduled later = (%v, %v), want false", active, err)
+	}
+	if _, err := e.users.Login(ctx, "bob", "Xq7#pass-word", nil, "127.0.0.1", "test"); !errors.Is(err, ErrAccountDisabled) {
+		t.Errorf("Login of user scheduled later: err = %v, want ErrAccountDisabled", err)
+	}
+}
@@ -0,0 +1,77 @@
+package store
+
+import (
+	"context"
+	"time"
+	"xzyq/models"
+
+	"gorm.io/gorm"
+)
+
+// APIKeyStore API密钥存储
+type APIKeyStore interface {
+	Create(ctx context.Context, key *models.APIKey) error
+	// Get 按ID查询用户的密钥，不属于该用户时返回ErrNotFound
+	Get(ctx context.Context, userID, id uint) (*models.APIKey, error)
+	GetByHash(ctx context.Context, hash string) (*models.APIKey, error)
+	// ListByUser 查询用户的所有密钥，包括已吊销和已过期的
+	ListByUser(ctx context.Context, userID uint) ([]models.APIKey, error)
+	// CountActive 统计用户未吊销且未过期的密钥数量
+	CountActive(ctx context.Context, userID uint, now time.Time) (int64, error)
+	// Revoke 吊销未吊销的密钥，已被吊销时返回false
+	Revoke(ctx context.Context, id uint, at time.Time) (bool, error)
+	// Touch 记录最近使用时间，距上次记录不足interval时不更新，避免每个请求都写数据库
+	Touch(ctx context.Context, id uint, at time.Time, ip string, interval time.Duration) error
+}
+
+// gormAPIKeyStore 基于GORM的APIKeyStore实现
+type gormAPIKeyStore struct {
+	db *gorm.DB
+}
+
+func (s *gormAPIKeyStore) Create(ctx context.Context, key *models.APIKey) error {
+	return s.db.WithContext(ctx).Create(key).Error
+}
+
+func (s *gormAPIKeyStore) Get(ctx context.Context, userID, id uint) (*models.APIKey, error) {
+	var key models.APIKey
+	if err := s.db.WithContext(ctx).Where("id = ? AND user_id = ?", id, userID).First(&key).Error; err != nil {
+		return nil, translateError(err)
+	}
+	return &key, nil
+}
+
+func (s *gormAPIKeyStore) GetByHash(ctx context.Context, hash string) (*models.APIKey, error) {
+	var key models.APIKey
+	if err := s.db.WithContext(ctx).Where("key_hash = ?", hash).First(&key).Error; err != nil {
+		return nil, translateError(err)
+	}
+	return &key, nil
+}
+
+func (s *gormAPIKeyStore) ListByUser(ctx context.Context, userID uint) ([]models.APIKey, error) {
+	var keys []models.APIKey
+	err := s.db.WithContext(ctx).Where("user_id = ?", userID).Order("id DESC").Find(&keys).Error
+	return keys, err
+}
+
+func (s *gormAPIKeyStore) CountActive(ctx context.Context, userID uint, now time.Time) (int64, error) {
+	var count int64
+	err := s.db.WithContext(ctx).Model(&models.APIKey{}).
+		Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, now).
+		Count(&count).Error
+	return count, err
+}
+
+func (s *gormAPIKeyStore) Revoke(ctx context.Context, id uint, at time.Time) (bool, error) {
+	result := s.db.WithContext(ctx).Model(&models.APIKey{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", at)
+	return result.RowsAffected == 1, result.Error
+}
+
+func (s *gormAPIKeyStore) Touch(ctx context.Context, id uint, at time.Time, ip string, interval time.Duration) error {
+	return s.db.WithContext(ctx).Model(&models.APIKey{}).
+		Where("id = ? AND (last_used_at IS NULL OR last_used_at < ?)", id, at.Add(-interval)).
+		Updates(map[string]interface{}{"last_used_at": at, "last_used_ip": ip}).Error
+}
@@ -0,0 +1,61 @@
+package store
+
+import (
+	"context"
+	"xzyq/models"
+
+	"gorm.io/gorm"
+)
+
+// OrgDirectoryStore 组织目录服务配置存储，查询时一并加载组与角色的映射
+type OrgDirectoryStore interface {
+	// Get 查询组织的目录服务，未配置时返回ErrNotFound
+	Get(ctx context.Context, orgID uint) (*models.OrgDirectory, error)
+	// Save 保存目录服务，组与角色的映射整体替换
+	Save(ctx context.Context, directory *models.OrgDirectory) error
+	Delete(ctx context.Context, orgID uint) error
+}
+
+// gormOrgDirectoryStore 基于GORM的OrgDirectoryStore实现
+type gormOrgDirectoryStore struct {
+	db *gorm.DB
+}
+
+func (s *gormOrgDirectoryStore) Get(ctx context.Context, orgID uint) (*models.OrgDirectory, error) {
+	var directory models.OrgDirectory
+	err := s.db.WithContext(ctx).Preload("RoleMappings", func(db *gorm.DB) *gorm.DB {
+		return db.Order("id")
+	}).Where("org_id = ?", orgID).First(&directory).Error
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return &directory, nil
+}
+
+func (s *gormOrgDirectoryStore) Save(ctx context.Context, directory *models.OrgDirectory) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("RoleMappings").Save(directory).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("org_id = ?", directory.OrgID).Delete(&models.DirectoryRoleMapping{}).Error; err != nil {
+			return err
+		}
+		for i := range directory.RoleMappings {
+			directory.RoleMappings[i].ID = 0
+			directory.RoleMappings[i].OrgID = directory.OrgID
+		}
+		if len(directory.RoleMappings) == 0 {
+			return nil
+		}
+		return tx.Create(&directory.RoleMappings).Error
+	})
+}
+
+func (s *gormOrgDirectoryStore) Delete(ctx context.Context, orgID uint) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("org_id = ?", orgID).Delete(&models.DirectoryRoleMapping{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("org_id = ?", orgID).Delete(&models.OrgDirectory{}).Error
+	})
+}
@@ -0,0 +1,88 @@
+package store
+
+import (
+	"context"
+	"time"
+	"xzyq/models"
+
+	"gorm.io/gorm"
+)
+
+// InvitationStore 邀请存储
+type InvitationStore interface {
+	Create(ctx context.Context, invitation *models.Invitation) error
+	Get(ctx context.Context, id uint) (*models.Invitation, error)
+	GetByHash(ctx context.Context, hash string) (*models.Invitation, error)
+	// List 查询邀请，最近创建的在前
+	List(ctx context.Context) ([]models.Invitation, error)
+	// PendingExists 组织是否有发给该邮箱的、未接受未撤销且未过期的邀请
+	PendingExists(ctx context.Context, orgID uint, email string, now time.Time) (bool, error)
+	// Resend 为未接受未撤销的邀请更换令牌和有效期，邀请已被接受或撤销时返回false
+	Resend(ctx context.Context, id uint, hash string, sentAt, expiresAt time.Time) (bool, error)
+	// Revoke 撤销未接受的邀请，邀请已被接受或撤销时返回false
+	Revoke(ctx context.Context, id uint, at time.Time) (bool, error)
+	// Accept 将未接受未撤销的邀请标记为已被userID接受，已被使用时返回false
+	Accept(ctx context.Context, id uint, at time.Time, userID uint) (bool, error)
+}
+
+// gormInvitationStore 基于GORM的InvitationStore实现
+type gormInvitationStore struct {
+	db *gorm.DB
+}
+
+func (s *gormInvitationStore) Create(ctx context.Context, invitation *models.Invitation) error {
+	return s.db.WithContext(ctx).Create(invitation).Error
+}
+
+func (s *gormInvitationStore) Get(ctx context.Context, id uint) (*models.Invitation, error) {
+	var invitation models.Invitation
+	if err := s.db.WithContext(ctx).First(&invitation, id).Error; err != nil {
+		return nil, translateError(err)
+	}
+	return &invitation, nil
+}
+
+func (s *gormInvitationStore) GetByHash(ctx context.Context, hash string) (*models.Invitation, error) {
+	var invitation models.Invitation
+	if err := s.db.WithContext(ctx).Where("token_hash = ?", hash).First(&invitation).Error; err != nil {
+		return nil, translateError(err)
+	}
+	return &invitation, nil
+}
+
+func (s *gormInvitationStore) List(ctx context.Context) ([]models.Invitation, error) {
+	var invitations []models.Invitation
+	if err := s.db.WithContext(ctx).Order("created_at DESC").Find(&invitations).Error; err != nil {
+		return nil, err
+	}
+	return invitations, nil
+}
+
+func (s *gormInvitationStore) PendingExists(ctx context.Context, orgID uint, email string, now time.Time) (bool, error) {
+	var count int64
+	err := s.db.WithContext(ctx).Model(&models.Invitation{}).
+		Where("org_id = ? AND LOWER(email) = LOWER(?) AND accepted_at IS NULL AND revoked_at IS NULL AND expires_at > ?", orgID, email, now).
+		Count(&count).Error
+	return count > 0, err
+}
+
+func (s *gormInvitationStore) Resend(ctx context.Context, id uint, hash string, sentAt, expiresAt time.Time) (bool, error) {
+	result := s.db.WithContext(ctx).Model(&models.Invitation{}).
+		Where("id = ? AND accepted_at IS NULL AND revoked_at IS NULL", id).
+		Updates(map[string]interface{}{"token_hash": hash, "sent_at": sentAt, "expires_at": expiresAt})
+	return result.RowsAffected == 1, result.Error
+}
+
+func (s *gormInvitationStore) Revoke(ctx context.Context, id uint, at time.Time) (bool, error) {
+	result := s.db.WithContext(ctx).Model(&models.Invitation{}).
+		Where("id = ? AND accepted_at IS NULL AND revoked_at IS NULL", id).
+		Update("revoked_at", at)
+	return result.RowsAffected == 1, result.Error
+}
+
+func (s *gormInvitationStore) Accept(ctx context.Context, id uint, at time.Time, userID uint) (bool, error) {
+	result := s.db.WithContext(ctx).Model(&models.Invitation{}).
+		Where("id = ? AND accepted_at IS NULL AND revoked_at IS NULL", id).
+		Updates(map[string]interface{}{"accepted_at": at, "accepted_user_id": userID})
+	return result.RowsAffected == 1, result.Error
+}
@@ -0,0 +1,22 @@
+package store
+
+import (
+	"context"
+	"xzyq/models"
+
+	"gorm.io/gorm"
+)
+
+// LogStore 日志存储
+type LogStore interface {
+	Create(ctx context.Context, log *models.Log) error
+}
+
+// gormLogStore 基于GORM的LogStore实现
+type gormLogStore struct {
+	db *gorm.DB
+}
+
+func (s *gormLogStore) Create(ctx context.Context, log *models.Log) error {
+	return s.db.WithContext(ctx).Create(log).Error
+}
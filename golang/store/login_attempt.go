@@ -0,0 +1,71 @@
+package store
+
+import (
+	"context"
+	"time"
+	"xzyq/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// LoginAttemptStore 登录失败记录存储
+type LoginAttemptStore interface {
+	Get(ctx context.Context, scope, subject string) (*models.LoginAttempt, error)
+	// Fail 原子地增加一次失败计数并返回更新后的记录，并发的失败不会互相覆盖
+	//
+	// 记录已失效（锁定已到期，或未锁定且最后一次失败早于since）时从1重新计数；计数达到limit时锁定到lockUntil。
+	Fail(ctx context.Context, scope, subject string, now, since time.Time, limit int, lockUntil time.Time) (*models.LoginAttempt, error)
+	Delete(ctx context.Context, scope, subject string) error
+	// DeleteStale 删除before之前最后一次失败且未处于锁定中的记录
+	DeleteStale(ctx context.Context, before time.Time) error
+}
+
+// gormLoginAttemptStore 基于GORM的LoginAttemptStore实现
+type gormLoginAttemptStore struct {
+	db *gorm.DB
+}
+
+func (s *gormLoginAttemptStore) Get(ctx context.Context, scope, subject string) (*models.LoginAttempt, error) {
+	var attempt models.LoginAttempt
+	if err := s.db.WithContext(ctx).Where("scope = ? AND subject = ?", scope, subject).First(&attempt).Error; err != nil {
+		return nil, translateError(err)
+	}
+	return &attempt, nil
+}
+
+func (s *gormLoginAttemptStore) Fail(ctx context.Context, scope, subject string, now, since time.Time, limit int, lockUntil time.Time) (*models.LoginAttempt, error) {
+	db := s.db.WithContext(ctx)
+	// 第一次失败时先插入空记录，已存在时不做任何修改
+	err := db.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&models.LoginAttempt{Scope: scope, Subject: subject, LastFailedAt: now}).Error
+	if err != nil {
+		return nil, err
+	}
+
+	// SET中的列都取更新前的值，在一条语句中完成判断和计数
+	active := gorm.Expr("(locked_until IS NOT NULL AND locked_until > ?) OR (locked_until IS NULL AND last_failed_at >= ?)", now, since)
+	failures := gorm.Expr("CASE WHEN ? THEN failures + 1 ELSE 1 END", active)
+	var attempt models.LoginAttempt
+	err = db.Model(&attempt).Clauses(clause.Returning{}).
+		Where("scope = ? AND subject = ?", scope, subject).
+		Updates(map[string]interface{}{
+			"failures":       failures,
+			"last_failed_at": now,
+			"locked_until":   gorm.Expr("CASE WHEN ? >= ? THEN ? WHEN ? THEN locked_until ELSE NULL END", failures, limit, lockUntil, active),
+		}).Error
+	if err != nil {
+		return nil, err
+	}
+	return &attempt, nil
+}
+
+func (s *gormLoginAttemptStore) Delete(ctx context.Context, scope, subject string) error {
+	return s.db.WithContext(ctx).Where("scope = ? AND subject = ?", scope, subject).Delete(&models.LoginAttempt{}).Error
+}
+
+func (s *gormLoginAttemptStore) DeleteStale(ctx context.Context, before time.Time) error {
+	return s.db.WithContext(ctx).
+		Where("last_failed_at < ? AND (locked_until IS NULL OR locked_until < ?)", before, before).
+		Delete(&models.LoginAttempt{}).Error
+}
@@ -0,0 +1,94 @@
+package store
+
+import (
+	"context"
+	"time"
+	"xzyq/models"
+
+	"gorm.io/gorm"
+)
+
+// RecoveryCodeStore 两步验证恢复码存储
+type RecoveryCodeStore interface {
+	// Replace 删除用户已有的恢复码并保存新的恢复码
+	Replace(ctx context.Context, userID uint, hashes []string) error
+	// Use 将未使用的恢复码标记为已使用，恢复码不存在或已被使用时返回false
+	Use(ctx context.Context, userID uint, hash string, at time.Time) (bool, error)
+	CountUnused(ctx context.Context, userID uint) (int64, error)
+	DeleteByUser(ctx context.Context, userID uint) error
+}
+
+// gormRecoveryCodeStore 基于GORM的RecoveryCodeStore实现
+type gormRecoveryCodeStore struct {
+	db *gorm.DB
+}
+
+func (s *gormRecoveryCodeStore) Replace(ctx context.Context, userID uint, hashes []string) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		codes := make([]models.RecoveryCode, len(hashes))
+		for i, hash := range hashes {
+			codes[i] = models.RecoveryCode{UserID: userID, CodeHash: hash}
+		}
+		return tx.Create(&codes).Error
+	})
+}
+
+func (s *gormRecoveryCodeStore) Use(ctx context.Context, userID uint, hash string, at time.Time) (bool, error) {
+	// 条件更新保证同一个恢复码只能使用一次
+	result := s.db.WithContext(ctx).Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, hash).
+		Update("used_at", at)
+	return result.RowsAffected == 1, result.Error
+}
+
+func (s *gormRecoveryCodeStore) CountUnused(ctx context.Context, userID uint) (int64, error) {
+	var count int64
+	err := s.db.WithContext(ctx).Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND used_at IS NULL", userID).
+		Count(&count).Error
+	return count, err
+}
+
+func (s *gormRecoveryCodeStore) DeleteByUser(ctx context.Context, userID uint) error {
+	return s.db.WithContext(ctx).Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error
+}
+
+// MFAChallengeStore 两步验证登录挑战存储
+type MFAChallengeStore interface {
+	Create(ctx context.Context, challenge *models.MFAChallenge) error
+	GetByHash(ctx context.Context, hash string) (*models.MFAChallenge, error)
+	// MarkUsed 将未使用的挑战标记为已使用，已被使用时返回false
+	MarkUsed(ctx context.Context, id uint, at time.Time) (bool, error)
+	DeleteExpired(ctx context.Context, before time.Time) error
+}
+
+// gormMFAChallengeStore 基于GORM的MFAChallengeStore实现
+type gormMFAChallengeStore struct {
+	db *gorm.DB
+}
+
+func (s *gormMFAChallengeStore) Create(ctx context.Context, challenge *models.MFAChallenge) error {
+	return s.db.WithContext(ctx).Create(challenge).Error
+}
+
+func (s *gormMFAChallengeStore) GetByHash(ctx context.Context, hash string) (*models.MFAChallenge, error) {
+	var challenge models.MFAChallenge
+	if err := s.db.WithContext(ctx).Where("token_hash = ?", hash).First(&challenge).Error; err != nil {
+		return nil, translateError(err)
+	}
+	return &challenge, nil
+}
+
+func (s *gormMFAChallengeStore) MarkUsed(ctx context.Context, id uint, at time.Time) (bool, error) {
+	result := s.db.WithContext(ctx).Model(&models.MFAChallenge{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", at)
+	return result.RowsAffected == 1, result.Error
+}
+
+func (s *gormMFAChallengeStore) DeleteExpired(ctx context.Context, before time.Time) error {
+	return s.db.WithContext(ctx).Where("expires_at <= ?", before).Delete(&models.MFAChallenge{}).Error
+}
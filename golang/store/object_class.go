@@ -0,0 +1,86 @@
+package store
+
+import (
+	"context"
+	"time"
+	"xzyq/models"
+
+	"gorm.io/gorm"
+)
+
+// ObjectClassStore 对象类存储
+type ObjectClassStore interface {
+	// Get 按ID查询对象类，不加载关联数据
+	Get(ctx context.Context, id uint) (*models.ObjectClass, error)
+	// GetDetail 按ID查询对象类并加载组织、父对象类和创建者
+	GetDetail(ctx context.Context, id uint) (*models.ObjectClass, error)
+	// List 查询所有对象类并加载组织和创建者
+	List(ctx context.Context) ([]models.ObjectClass, error)
+	// ListChildren 查询对象类的直接子对象类
+	ListChildren(ctx context.Context, parentID uint) ([]models.ObjectClass, error)
+	Create(ctx context.Context, class *models.ObjectClass) error
+	// Update 只更新名称和描述
+	Update(ctx context.Context, id uint, name, description string) error
+	Delete(ctx context.Context, id uint) error
+}
+
+// gormObjectClassStore 基于GORM的ObjectClassStore实现
+type gormObjectClassStore struct {
+	db *gorm.DB
+}
+
+func (s *gormObjectClassStore) Get(ctx context.Context, id uint) (*models.ObjectClass, error) {
+	var class models.ObjectClass
+	if err := s.db.WithContext(ctx).First(&class, id).Error; err != nil {
+		return nil, translateError(err)
+	}
+	return &class, nil
+}
+
+func (s *gormObjectClassStore) GetDetail(ctx context.Context, id uint) (*models.ObjectClass, error) {
+	var class models.ObjectClass
+	if err := s.db.WithContext(ctx).Preload("Organization").
+		Preload("Parent").
+		Preload("CreatedByUser").
+		First(&class, id).Error; err != nil {
+		return nil, translateError(err)
+	}
+	return &class, nil
+}
+
+func (s *gormObjectClassStore) List(ctx context.Context) ([]models.ObjectClass, error) {
+	var classes []models.ObjectClass
+	if err := s.db.WithContext(ctx).Preload("Organization").
+		Preload("CreatedByUser").
+		Find(&classes).Error; err != nil {
+		return nil, err
+	}
+	return classes, nil
+}
+
+func (s *gormObjectClassStore) ListChildren(ctx context.Context, parentID uint) ([]models.ObjectClass, error) {
+	var children []models.ObjectClass
+	if err := s.db.WithContext(ctx).Preload("Organization").
+		Preload("CreatedByUser").
+		Where("parent_id = ?", parentID).
+		Find(&children).Error; err != nil {
+		return nil, err
+	}
+	return children, nil
+}
+
+func (s *gormObjectClassStore) Create(ctx context.Context, class *models.ObjectClass) error {
+	return s.db.WithContext(ctx).Create(class).Error
+}
+
+func (s *gormObjectClassStore) Update(ctx context.Context, id uint, name, description string) error {
+	return s.db.WithContext(ctx).Model(&models.ObjectClass{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"name":        name,
+		"description": description,
+		"updated_at":  time.Now(),
+	}).Error
+}
+
+func (s *gormObjectClassStore) Delete(ctx context.Context, id uint) error {
+	return s.db.WithContext(ctx).Delete(&models.ObjectClass{}, id).Error
+}
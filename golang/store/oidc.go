@@ -0,0 +1,136 @@
+package store
+
+import (
+	"context"
+	"time"
+	"xzyq/models"
+
+	"gorm.io/gorm"
+)
+
+// OIDCProviderStore 身份提供方存储
+type OIDCProviderStore interface {
+	List(ctx context.Context, orgID uint) ([]models.OIDCProvider, error)
+	// ListEnabled 返回所有已启用的身份提供方，用于登录页面
+	ListEnabled(ctx context.Context) ([]models.OIDCProvider, error)
+	Get(ctx context.Context, id uint) (*models.OIDCProvider, error)
+	GetByName(ctx context.Context, name string) (*models.OIDCProvider, error)
+	NameExists(ctx context.Context, name string, excludeID uint) (bool, error)
+	Create(ctx context.Context, provider *models.OIDCProvider) error
+	Save(ctx context.Context, provider *models.OIDCProvider) error
+	Delete(ctx context.Context, id uint) error
+}
+
+// UserIdentityStore 用户与身份提供方账号的关联存储
+type UserIdentityStore interface {
+	// Get 按身份提供方和sub查询，未关联时返回ErrNotFound
+	Get(ctx context.Context, providerID uint, subject string) (*models.UserIdentity, error)
+	Create(ctx context.Context, identity *models.UserIdentity) error
+}
+
+// OIDCLoginStateStore 单点登录状态存储
+type OIDCLoginStateStore interface {
+	Create(ctx context.Context, state *models.OIDCLoginState) error
+	GetByHash(ctx context.Context, hash string) (*models.OIDCLoginState, error)
+	// MarkUsed 将未使用的状态标记为已使用，已被使用时返回false
+	MarkUsed(ctx context.Context, id uint, at time.Time) (bool, error)
+	DeleteExpired(ctx context.Context, before time.Time) error
+}
+
+// gormOIDCProviderStore 基于GORM的OIDCProviderStore实现
+type gormOIDCProviderStore struct {
+	db *gorm.DB
+}
+
+func (s *gormOIDCProviderStore) List(ctx context.Context, orgID uint) ([]models.OIDCProvider, error) {
+	var providers []models.OIDCProvider
+	err := s.db.WithContext(ctx).Where("org_id = ?", orgID).Order("id").Find(&providers).Error
+	return providers, err
+}
+
+func (s *gormOIDCProviderStore) ListEnabled(ctx context.Context) ([]models.OIDCProvider, error) {
+	var providers []models.OIDCProvider
+	err := s.db.WithContext(ctx).Where("enabled = ?", true).Order("display_name").Find(&providers).Error
+	return providers, err
+}
+
+func (s *gormOIDCProviderStore) Get(ctx context.Context, id uint) (*models.OIDCProvider, error) {
+	var provider models.OIDCProvider
+	if err := s.db.WithContext(ctx).First(&provider, id).Error; err != nil {
+		return nil, translateError(err)
+	}
+	return &provider, nil
+}
+
+func (s *gormOIDCProviderStore) GetByName(ctx context.Context, name string) (*models.OIDCProvider, error) {
+	var provider models.OIDCProvider
+	if err := s.db.WithContext(ctx).Where("name = ?", name).First(&provider).Error; err != nil {
+		return nil, translateError(err)
+	}
+	return &provider, nil
+}
+
+func (s *gormOIDCProviderStore) NameExists(ctx context.Context, name string, excludeID uint) (bool, error) {
+	var count int64
+	err := s.db.WithContext(ctx).Model(&models.OIDCProvider{}).
+		Where("name = ? AND id <> ?", name, excludeID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+func (s *gormOIDCProviderStore) Create(ctx context.Context, provider *models.OIDCProvider) error {
+	return s.db.WithContext(ctx).Create(provider).Error
+}
+
+func (s *gormOIDCProviderStore) Save(ctx context.Context, provider *models.OIDCProvider) error {
+	return s.db.WithContext(ctx).Save(provider).Error
+}
+
+func (s *gormOIDCProviderStore) Delete(ctx context.Context, id uint) error {
+	return s.db.WithContext(ctx).Delete(&models.OIDCProvider{}, id).Error
+}
+
+// gormUserIdentityStore 基于GORM的UserIdentityStore实现
+type gormUserIdentityStore struct {
+	db *gorm.DB
+}
+
+func (s *gormUserIdentityStore) Get(ctx context.Context, providerID uint, subject string) (*models.UserIdentity, error) {
+	var identity models.UserIdentity
+	if err := s.db.WithContext(ctx).Where("provider_id = ? AND subject = ?", providerID, subject).First(&identity).Error; err != nil {
+		return nil, translateError(err)
+	}
+	return &identity, nil
+}
+
+func (s *gormUserIdentityStore) Create(ctx context.Context, identity *models.UserIdentity) error {
+	return s.db.WithContext(ctx).Create(identity).Error
+}
+
+// gormOIDCLoginStateStore 基于GORM的OIDCLoginStateStore实现
+type gormOIDCLoginStateStore struct {
+	db *gorm.DB
+}
+
+func (s *gormOIDCLoginStateStore) Create(ctx context.Context, state *models.OIDCLoginState) error {
+	return s.db.WithContext(ctx).Create(state).Error
+}
+
+func (s *gormOIDCLoginStateStore) GetByHash(ctx context.Context, hash string) (*models.OIDCLoginState, error) {
+	var state models.OIDCLoginState
+	if err := s.db.WithContext(ctx).Where("state_hash = ?", hash).First(&state).Error; err != nil {
+		return nil, translateError(err)
+	}
+	return &state, nil
+}
+
+func (s *gormOIDCLoginStateStore) MarkUsed(ctx context.Context, id uint, at time.Time) (bool, error) {
+	result := s.db.WithContext(ctx).Model(&models.OIDCLoginState{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", at)
+	return result.RowsAffected == 1, result.Error
+}
+
+func (s *gormOIDCLoginStateStore) DeleteExpired(ctx context.Context, before time.Time) error {
+	return s.db.WithContext(ctx).Where("expires_at <= ?", before).Delete(&models.OIDCLoginState{}).Error
+}
@@ -0,0 +1,73 @@
+package store
+
+import (
+	"context"
+	"xzyq/models"
+	"xzyq/tenant"
+
+	"gorm.io/gorm"
+)
+
+// OrganizationStore 组织存储
+type OrganizationStore interface {
+	Get(ctx context.Context, id uint) (*models.Organization, error)
+	// GetByName 按名称查询组织
+	GetByName(ctx context.Context, name string) (*models.Organization, error)
+	// List 查询所有组织
+	List(ctx context.Context) ([]models.Organization, error)
+	// ListByCreator 查询指定用户创建的组织
+	ListByCreator(ctx context.Context, userID uint) ([]models.Organization, error)
+	Create(ctx context.Context, org *models.Organization) error
+	Save(ctx context.Context, org *models.Organization) error
+	Delete(ctx context.Context, id uint) error
+}
+
+// gormOrganizationStore 基于GORM的OrganizationStore实现
+type gormOrganizationStore struct {
+	db *gorm.DB
+}
+
+func (s *gormOrganizationStore) Get(ctx context.Context, id uint) (*models.Organization, error) {
+	var org models.Organization
+	if err := s.db.WithContext(ctx).First(&org, id).Error; err != nil {
+		return nil, translateError(err)
+	}
+	return &org, nil
+}
+
+func (s *gormOrganizationStore) GetByName(ctx context.Context, name string) (*models.Organization, error) {
+	var org models.Organization
+	// 组织名称全局唯一，需要检查所有组织
+	if err := s.db.WithContext(tenant.Unscoped(ctx)).Where("name = ?", name).First(&org).Error; err != nil {
+		return nil, translateError(err)
+	}
+	return &org, nil
+}
+
+func (s *gormOrganizationStore) List(ctx context.Context) ([]models.Organization, error) {
+	var orgs []models.Organization
+	if err := s.db.WithContext(ctx).Find(&orgs).Error; err != nil {
+		return nil, err
+	}
+	return orgs, nil
+}
+
+func (s *gormOrganizationStore) ListByCreator(ctx context.Context, userID uint) ([]models.Organization, error) {
+	var orgs []models.Organization
+	if err := s.db.WithContext(ctx).Where("created_by = ?", userID).Find(&orgs).Error; err != nil {
+		return nil, err
+	}
+	return orgs, nil
+}
+
+func (s *gormOrganizationStore) Create(ctx context.Context, org *models.Organization) error {
+	return s.db.WithContext(ctx).Create(org).Error
+}
+
+func (s *gormOrganizationStore) Save(ctx context.Context, org *models.Organization) error {
+	return s.db.WithContext(ctx).Save(org).Error
+}
+
+func (s *gormOrganizationStore) Delete(ctx context.Context, id uint) error {
+	return s.db.WithContext(ctx).Delete(&models.Organization{}, id).Error
+}
@@ -0,0 +1,74 @@
+package store
+
+import (
+	"context"
+	"xzyq/models"
+
+	"gorm.io/gorm"
+)
+
+// PasswordHistoryStore 历史密码存储
+type PasswordHistoryStore interface {
+	// Recent 返回用户最近的limit个历史密码哈希，按时间倒序
+	Recent(ctx context.Context, userID uint, limit int) ([]string, error)
+	// Add 保存历史密码，只保留最近的keep个
+	Add(ctx context.Context, userID uint, hash string, keep int) error
+}
+
+// gormPasswordHistoryStore 基于GORM的PasswordHistoryStore实现
+type gormPasswordHistoryStore struct {
+	db *gorm.DB
+}
+
+func (s *gormPasswordHistoryStore) Recent(ctx context.Context, userID uint, limit int) ([]string, error) {
+	var hashes []string
+	err := s.db.WithContext(ctx).Model(&models.PasswordHistory{}).
+		Where("user_id = ?", userID).
+		Order("id DESC").
+		Limit(limit).
+		Pluck("password_hash", &hashes).Error
+	return hashes, err
+}
+
+func (s *gormPasswordHistoryStore) Add(ctx context.Context, userID uint, hash string, keep int) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&models.PasswordHistory{UserID: userID, PasswordHash: hash}).Error; err != nil {
+			return err
+		}
+		// 删除超出保留数量的旧记录
+		var ids []uint
+		err := tx.Model(&models.PasswordHistory{}).
+			Where("user_id = ?", userID).
+			Order("id DESC").
+			Offset(keep).
+			Pluck("id", &ids).Error
+		if err != nil || len(ids) == 0 {
+			return err
+		}
+		return tx.Delete(&models.PasswordHistory{}, ids).Error
+	})
+}
+
+// OrgPasswordPolicyStore 组织密码策略存储
+type OrgPasswordPolicyStore interface {
+	// Get 查询组织的密码策略，未设置时返回ErrNotFound
+	Get(ctx context.Context, orgID uint) (*models.OrgPasswordPolicy, error)
+	Save(ctx context.Context, policy *models.OrgPasswordPolicy) error
+}
+
+// gormOrgPasswordPolicyStore 基于GORM的OrgPasswordPolicyStore实现
+type gormOrgPasswordPolicyStore struct {
+	db *gorm.DB
+}
+
+func (s *gormOrgPasswordPolicyStore) Get(ctx context.Context, orgID uint) (*models.OrgPasswordPolicy, error) {
+	var policy models.OrgPasswordPolicy
+	if err := s.db.WithContext(ctx).Where("org_id = ?", orgID).First(&policy).Error; err != nil {
+		return nil, translateError(err)
+	}
+	return &policy, nil
+}
+
+func (s *gormOrgPasswordPolicyStore) Save(ctx context.Context, policy *models.OrgPasswordPolicy) error {
+	return s.db.WithContext(ctx).Save(policy).Error
+}
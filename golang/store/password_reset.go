@@ -0,0 +1,61 @@
+package store
+
+import (
+	"context"
+	"time"
+	"xzyq/models"
+
+	"gorm.io/gorm"
+)
+
+// PasswordResetTokenStore 重置密码令牌存储
+type PasswordResetTokenStore interface {
+	Create(ctx context.Context, token *models.PasswordResetToken) error
+	GetByHash(ctx context.Context, hash string) (*models.PasswordResetToken, error)
+	// LatestCreatedAt 返回用户最近一次申请重置的时间，没有申请过时返回ErrNotFound
+	LatestCreatedAt(ctx context.Context, userID uint) (time.Time, error)
+	// MarkUsed 将未使用的令牌标记为已使用，已被使用时返回false
+	MarkUsed(ctx context.Context, id uint, at time.Time) (bool, error)
+	DeleteByUser(ctx context.Context, userID uint) error
+	DeleteExpired(ctx context.Context, before time.Time) error
+}
+
+// gormPasswordResetTokenStore 基于GORM的PasswordResetTokenStore实现
+type gormPasswordResetTokenStore struct {
+	db *gorm.DB
+}
+
+func (s *gormPasswordResetTokenStore) Create(ctx context.Context, token *models.PasswordResetToken) error {
+	return s.db.WithContext(ctx).Create(token).Error
+}
+
+func (s *gormPasswordResetTokenStore) GetByHash(ctx context.Context, hash string) (*models.PasswordResetToken, error) {
+	var token models.PasswordResetToken
+	if err := s.db.WithContext(ctx).Where("token_hash = ?", hash).First(&token).Error; err != nil {
+		return nil, translateError(err)
+	}
+	return &token, nil
+}
+
+func (s *gormPasswordResetTokenStore) LatestCreatedAt(ctx context.Context, userID uint) (time.Time, error) {
+	var token models.PasswordResetToken
+	if err := s.db.WithContext(ctx).Where("user_id = ?", userID).Order("created_at DESC").First(&token).Error; err != nil {
+		return time.Time{}, translateError(err)
+	}
+	return token.CreatedAt, nil
+}
+
+func (s *gormPasswordResetTokenStore) MarkUsed(ctx context.Context, id uint, at time.Time) (bool, error) {
+	result := s.db.WithContext(ctx).Model(&models.PasswordResetToken{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", at)
+	return result.RowsAffected == 1, result.Error
+}
+
+func (s *gormPasswordResetTokenStore) DeleteByUser(ctx context.Context, userID uint) error {
+	return s.db.WithContext(ctx).Where("user_id = ?", userID).Delete(&models.PasswordResetToken{}).Error
+}
+
+func (s *gormPasswordResetTokenStore) DeleteExpired(ctx context.Context, before time.Time) error {
+	return s.db.WithContext(ctx).Where("expires_at <= ?", before).Delete(&models.PasswordResetToken{}).Error
+}
@@ -0,0 +1,63 @@
+package store
+
+import (
+	"context"
+	"time"
+	"xzyq/models"
+
+	"gorm.io/gorm"
+)
+
+// RefreshTokenStore 刷新令牌存储
+type RefreshTokenStore interface {
+	Create(ctx context.Context, token *models.RefreshToken) error
+	GetByHash(ctx context.Context, hash string) (*models.RefreshToken, error)
+	// MarkUsed 将未使用的令牌标记为已使用，令牌已被使用时返回false
+	MarkUsed(ctx context.Context, id uint, at time.Time) (bool, error)
+	RevokeFamily(ctx context.Context, familyID string, at time.Time) error
+	RevokeByUser(ctx context.Context, userID uint, at time.Time) error
+	DeleteExpiredByUser(ctx context.Context, userID uint, before time.Time) error
+}
+
+// gormRefreshTokenStore 基于GORM的RefreshTokenStore实现
+type gormRefreshTokenStore struct {
+	db *gorm.DB
+}
+
+func (s *gormRefreshTokenStore) Create(ctx context.Context, token *models.RefreshToken) error {
+	return s.db.WithContext(ctx).Create(token).Error
+}
+
+func (s *gormRefreshTokenStore) GetByHash(ctx context.Context, hash string) (*models.RefreshToken, error) {
+	var token models.RefreshToken
+	if err := s.db.WithContext(ctx).Where("token_hash = ?", hash).First(&token).Error; err != nil {
+		return nil, translateError(err)
+	}
+	return &token, nil
+}
+
+func (s *gormRefreshTokenStore) MarkUsed(ctx context.Context, id uint, at time.Time) (bool, error) {
+	// 条件更新保证并发刷新时只有一个请求成功
+	result := s.db.WithContext(ctx).Model(&models.RefreshToken{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", at)
+	return result.RowsAffected == 1, result.Error
+}
+
+func (s *gormRefreshTokenStore) RevokeFamily(ctx context.Context, familyID string, at time.Time) error {
+	return s.db.WithContext(ctx).Model(&models.RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", at).Error
+}
+
+func (s *gormRefreshTokenStore) RevokeByUser(ctx context.Context, userID uint, at time.Time) error {
+	return s.db.WithContext(ctx).Model(&models.RefreshToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", at).Error
+}
+
+func (s *gormRefreshTokenStore) DeleteExpiredByUser(ctx context.Context, userID uint, before time.Time) error {
+	return s.db.WithContext(ctx).
+		Where("user_id = ? AND expires_at < ?", userID, before).
+		Delete(&models.RefreshToken{}).Error
+}
@@ -0,0 +1,84 @@
+package store
+
+import (
+	"context"
+	"time"
+	"xzyq/models"
+
+	"gorm.io/gorm"
+)
+
+// SessionStore 登录会话存储
+type SessionStore interface {
+	Create(ctx context.Context, session *models.Session) error
+	// Get 按ID查询用户的会话，不属于该用户时返回ErrNotFound
+	Get(ctx context.Context, userID, id uint) (*models.Session, error)
+	GetByFamily(ctx context.Context, familyID string) (*models.Session, error)
+	// ListActive 查询用户未吊销且未过期的会话，最近活动的在前
+	ListActive(ctx context.Context, userID uint, now time.Time) ([]models.Session, error)
+	// Rotate 记录会话新签发的访问令牌，同时更新最近活动时间、IP和过期时间
+	Rotate(ctx context.Context, familyID, tokenID, ip string, at, expiresAt time.Time) error
+	// Revoke 吊销未吊销的会话，已被吊销时返回false
+	Revoke(ctx context.Context, id uint, at time.Time, reason string) (bool, error)
+	RevokeByUser(ctx context.Context, userID uint, at time.Time, reason string) error
+	DeleteExpiredByUser(ctx context.Context, userID uint, before time.Time) error
+}
+
+// gormSessionStore 基于GORM的SessionStore实现
+type gormSessionStore struct {
+	db *gorm.DB
+}
+
+func (s *gormSessionStore) Create(ctx context.Context, session *models.Session) error {
+	return s.db.WithContext(ctx).Create(session).Error
+}
+
+func (s *gormSessionStore) Get(ctx context.Context, userID, id uint) (*models.Session, error) {
+	var session models.Session
+	if err := s.db.WithContext(ctx).Where("id = ? AND user_id = ?", id, userID).First(&session).Error; err != nil {
+		return nil, translateError(err)
+	}
+	return &session, nil
+}
+
+func (s *gormSessionStore) GetByFamily(ctx context.Context, familyID string) (*models.Session, error) {
+	var session models.Session
+	if err := s.db.WithContext(ctx).Where("family_id = ?", familyID).First(&session).Error; err != nil {
+		return nil, translateError(err)
+	}
+	return &session, nil
+}
+
+func (s *gormSessionStore) ListActive(ctx context.Context, userID uint, now time.Time) ([]models.Session, error) {
+	var sessions []models.Session
+	err := s.db.WithContext(ctx).
+		Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, now).
+		Order("last_seen_at DESC, id DESC").
+		Find(&sessions).Error
+	return sessions, err
+}
+
+func (s *gormSessionStore) Rotate(ctx context.Context, familyID, tokenID, ip string, at, expiresAt time.Time) error {
+	return s.db.WithContext(ctx).Model(&models.Session{}).
+		Where("family_id = ?", familyID).
+		Updates(map[string]interface{}{"token_id": tokenID, "ip": ip, "last_seen_at": at, "expires_at": expiresAt}).Error
+}
+
+func (s *gormSessionStore) Revoke(ctx context.Context, id uint, at time.Time, reason string) (bool, error) {
+	result := s.db.WithContext(ctx).Model(&models.Session{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Updates(map[string]interface{}{"revoked_at": at, "revoke_reason": reason})
+	return result.RowsAffected == 1, result.Error
+}
+
+func (s *gormSessionStore) RevokeByUser(ctx context.Context, userID uint, at time.Time, reason string) error {
+	return s.db.WithContext(ctx).Model(&models.Session{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Updates(map[string]interface{}{"revoked_at": at, "revoke_reason": reason}).Error
+}
+
+func (s *gormSessionStore) DeleteExpiredByUser(ctx context.Context, userID uint, before time.Time) error {
+	return s.db.WithContext(ctx).
+		Where("user_id = ? AND expires_at < ?", userID, before).
+		Delete(&models.Session{}).Error
+}
@@ -0,0 +1,102 @@
+package store
+
+import (
+	"context"
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+// ErrNotFound 记录不存在
+var ErrNotFound = errors.New("record not found")
+
+// Store 数据访问入口，聚合各个模型的存储接口
+type Store interface {
+	Users() UserStore
+	Organizations() OrganizationStore
+	ObjectClasses() ObjectClassStore
+	Logs() LogStore
+	RefreshTokens() RefreshTokenStore
+	TokenRevocations() TokenRevocationStore
+	LoginAttempts() LoginAttemptStore
+	RecoveryCodes() RecoveryCodeStore
+	MFAChallenges() MFAChallengeStore
+	PasswordResetTokens() PasswordResetTokenStore
+	PasswordHistory() PasswordHistoryStore
+	OrgPasswordPolicies() OrgPasswordPolicyStore
+	OIDCProviders() OIDCProviderStore
+	UserIdentities() UserIdentityStore
+	OIDCLoginStates() OIDCLoginStateStore
+	OrgDirectories() OrgDirectoryStore
+	APIKeys() APIKeyStore
+	Sessions() SessionStore
+	Invitations() InvitationStore
+
+	// Transaction 在事务中执行fn，fn返回错误时回滚
+	Transaction(ctx context.Context, fn func(tx Store) error) error
+}
+
+// gormStore 基于GORM的Store实现
+type gormStore struct {
+	db *gorm.DB
+}
+
+// NewGormStore 创建基于GORM的Store
+func NewGormStore(db *gorm.DB) Store {
+	return &gormStore{db: db}
+}
+
+func (s *gormStore) Users() UserStore                 { return &gormUserStore{db: s.db} }
+func (s *gormStore) Organizations() OrganizationStore { return &gormOrganizationStore{db: s.db} }
+func (s *gormStore) ObjectClasses() ObjectClassStore  { return &gormObjectClassStore{db: s.db} }
+func (s *gormStore) Logs() LogStore                   { return &gormLogStore{db: s.db} }
+func (s *gormStore) RefreshTokens() RefreshTokenStore { return &gormRefreshTokenStore{db: s.db} }
+func (s *gormStore) TokenRevocations() TokenRevocationStore {
+	return &gormTokenRevocationStore{db: s.db}
+}
+func (s *gormStore) LoginAttempts() LoginAttemptStore { return &gormLoginAttemptStore{db: s.db} }
+func (s *gormStore) RecoveryCodes() RecoveryCodeStore { return &gormRecoveryCodeStore{db: s.db} }
+func (s *gormStore) MFAChallenges() MFAChallengeStore { return &gormMFAChallengeStore{db: s.db} }
+func (s *gormStore) PasswordResetTokens() PasswordResetTokenStore {
+	return &gormPasswordResetTokenStore{db: s.db}
+}
+func (s *gormStore) PasswordHistory() PasswordHistoryStore {
+	return &gormPasswordHistoryStore{db: s.db}
+}
+func (s *gormStore) OrgPasswordPolicies() OrgPasswordPolicyStore {
+	return &gormOrgPasswordPolicyStore{db: s.db}
+}
+func (s *gormStore) OIDCProviders() OIDCProviderStore { return &gormOIDCProviderStore{db: s.db} }
+func (s *gormStore) UserIdentities() UserIdentityStore {
+	return &gormUserIdentityStore{db: s.db}
+}
+func (s *gormStore) OIDCLoginStates() OIDCLoginStateStore {
+	return &gormOIDCLoginStateStore{db: s.db}
+}
+func (s *gormStore) OrgDirectories() OrgDirectoryStore {
+	return &gormOrgDirectoryStore{db: s.db}
+}
+func (s *gormStore) APIKeys() APIKeyStore {
+	return &gormAPIKeyStore{db: s.db}
+}
+func (s *gormStore) Sessions() SessionStore {
+	return &gormSessionStore{db: s.db}
+}
+func (s *gormStore) Invitations() InvitationStore {
+	return &gormInvitationStore{db: s.db}
+}
+
+// Transaction 在事务中执行fn
+func (s *gormStore) Transaction(ctx context.Context, fn func(tx Store) error) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(&gormStore{db: tx})
+	})
+}
+
+// translateError 将GORM错误转换为store错误
+func translateError(err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrNotFound
+	}
+	return err
+}
@@ -0,0 +1,39 @@
+package store
+
+import (
+	"context"
+	"time"
+	"xzyq/models"
+
+	"gorm.io/gorm"
+)
+
+// TokenRevocationStore 访问令牌吊销记录存储
+type TokenRevocationStore interface {
+	Create(ctx context.Context, revocation *models.TokenRevocation) error
+	// ListActive 返回所有未过期的记录
+	ListActive(ctx context.Context, now time.Time) ([]models.TokenRevocation, error)
+	DeleteExpired(ctx context.Context, before time.Time) error
+}
+
+// gormTokenRevocationStore 基于GORM的TokenRevocationStore实现
+type gormTokenRevocationStore struct {
+	db *gorm.DB
+}
+
+func (s *gormTokenRevocationStore) Create(ctx context.Context, revocation *models.TokenRevocation) error {
+	return s.db.WithContext(ctx).Create(revocation).Error
+}
+
+func (s *gormTokenRevocationStore) ListActive(ctx context.Context, now time.Time) ([]models.TokenRevocation, error) {
+	var revocations []models.TokenRevocation
+	err := s.db.WithContext(ctx).
+		Where("expires_at > ?", now).
+		Order("id").
+		Find(&revocations).Error
+	return revocations, err
+}
+
+func (s *gormTokenRevocationStore) DeleteExpired(ctx context.Context, before time.Time) error {
+	return s.db.WithContext(ctx).Where("expires_at <= ?", before).Delete(&models.TokenRevocation{}).Error
+}
@@ -0,0 +1,237 @@
+package store
+
+import (
+	"context"
+	"time"
+	"xzyq/models"
+	"xzyq/tenant"
+
+	"gorm.io/gorm"
+)
+
+// UserStore 用户存储
+type UserStore interface {
+	// Get 按ID查询用户，不包含已删除的用户
+	Get(ctx context.Context, id uint) (*models.User, error)
+	// GetUnscoped 按ID查询用户，包含已删除的用户
+	GetUnscoped(ctx context.Context, id uint) (*models.User, error)
+	// GetWithOrg 按ID查询用户并加载所属组织
+	GetWithOrg(ctx context.Context, id uint) (*models.User, error)
+	// GetByUsername 按用户名查询用户，包含已删除的用户
+	GetByUsername(ctx context.Context, username string) (*models.User, error)
+	// UsernameExists 检查用户名是否已被使用
+	UsernameExists(ctx context.Context, username string) (bool, error)
+	// ListByEmail 按邮箱查询用户，不区分大小写，不包含已删除的用户
+	ListByEmail(ctx context.Context, email string) ([]models.User, error)
+	// List 查询所有用户并加载所属组织
+	List(ctx context.Context) ([]models.User, error)
+	// ListServiceAccounts 查询服务账号并加载所属组织
+	ListServiceAccounts(ctx context.Context) ([]models.User, error)
+	// ListByOrg 查询组织下的用户，包含已删除的用户
+	ListByOrg(ctx context.Context, orgID uint) ([]models.User, error)
+	// CountByOrg 统计组织下的用户数量，includeDeleted为true时包含已删除的用户
+	CountByOrg(ctx context.Context, orgID uint, includeDeleted bool) (int64, error)
+	Create(ctx context.Context, user *models.User) error
+	Save(ctx context.Context, user *models.User) error
+	// Updates 使用updates中的非零值字段更新用户
+	Updates(ctx context.Context, user *models.User, updates models.User) error
+	// Disable 禁用启用中的用户，已被禁用时返回false
+	Disable(ctx context.Context, id uint, at time.Time, by uint, reason string, reenableAt *time.Time) (bool, error)
+	// Enable 重新启用被禁用的用户并清除禁用信息，未被禁用时返回false
+	Enable(ctx context.Context, id uint) (bool, error)
+	// ListReenableDue 查询计划重新启用时间已到的禁用用户
+	ListReenableDue(ctx context.Context, now time.Time) ([]models.User, error)
+	// ListPasswordHashes 查询所有设置了密码的用户的ID、用户名和密码哈希
+	ListPasswordHashes(ctx context.Context) ([]models.User, error)
+	// UpdatePassword 只替换密码哈希，用于升级哈希算法，不改变密码的设置时间
+	UpdatePassword(ctx context.Context, id uint, hashedPassword string) error
+	// SetPassword 设置新密码，changedAt为nil表示下次登录时必须修改
+	SetPassword(ctx context.Context, id uint, hashedPassword string, changedAt *time.Time) error
+	// UpdateMFA 设置TOTP密钥和启用状态，并重置最近使用的时间步
+	UpdateMFA(ctx context.Context, id uint, secret string, enabled bool) error
+	// UseMFAStep 记录使用过的TOTP时间步，step不大于已使用的时间步时返回false
+	UseMFAStep(ctx context.Context, id uint, step int64) (bool, error)
+	// HardDelete 彻底删除用户，包含已软删除的用户
+	HardDelete(ctx context.Context, id uint) error
+	// DeleteByOrg 删除组织下的所有用户
+	DeleteByOrg(ctx context.Context, orgID uint) error
+}
+
+// gormUserStore 基于GORM的UserStore实现
+type gormUserStore struct {
+	db *gorm.DB
+}
+
+func (s *gormUserStore) Get(ctx context.Context, id uint) (*models.User, error) {
+	var user models.User
+	if err := s.db.WithContext(ctx).First(&user, id).Error; err != nil {
+		return nil, translateError(err)
+	}
+	return &user, nil
+}
+
+func (s *gormUserStore) GetUnscoped(ctx context.Context, id uint) (*models.User, error) {
+	var user models.User
+	if err := s.db.WithContext(ctx).Unscoped().First(&user, id).Error; err != nil {
+		return nil, translateError(err)
+	}
+	return &user, nil
+}
+
+func (s *gormUserStore) GetWithOrg(ctx context.Context, id uint) (*models.User, error) {
+	var user models.User
+	if err := s.db.WithContext(ctx).Preload("Org").First(&user, id).Error; err != nil {
+		return nil, translateError(err)
+	}
+	return &user, nil
+}
+
+func (s *gormUserStore) GetByUsername(ctx context.Context, username string) (*models.User, error) {
+	var user models.User
+	// 用户名全局唯一，登录时还不知道用户所属的组织
+	if err := s.db.WithContext(tenant.Unscoped(ctx)).Unscoped().Where("username = ?", username).First(&user).Error; err != nil {
+		return nil, translateError(err)
+	}
+	return &user, nil
+}
+
+func (s *gormUserStore) UsernameExists(ctx context.Context, username string) (bool, error) {
+	var count int64
+	// 用户名全局唯一，需要检查所有组织
+	if err := s.db.WithContext(tenant.Unscoped(ctx)).Model(&models.User{}).Where("username = ?", username).Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+func (s *gormUserStore) ListByEmail(ctx context.Context, email string) ([]models.User, error) {
+	var users []models.User
+	// 邮箱在所有组织中查找
+	if err := s.db.WithContext(tenant.Unscoped(ctx)).Where("LOWER(email) = LOWER(?)", email).Find(&users).Error; err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+func (s *gormUserStore) List(ctx context.Context) ([]models.User, error) {
+	var users []models.User
+	if err := s.db.WithContext(ctx).Preload("Org").Find(&users).Error; err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+func (s *gormUserStore) ListServiceAccounts(ctx context.Context) ([]models.User, error) {
+	var users []models.User
+	if err := s.db.WithContext(ctx).Preload("Org").Where("service_account = ?", true).Order("id").Find(&users).Error; err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+func (s *gormUserStore) ListByOrg(ctx context.Context, orgID uint) ([]models.User, error) {
+	var users []models.User
+	if err := s.db.WithContext(ctx).Unscoped().Where("org_id = ?", orgID).Find(&users).Error; err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+func (s *gormUserStore) CountByOrg(ctx context.Context, orgID uint, includeDeleted bool) (int64, error) {
+	db := s.db.WithContext(ctx)
+	if includeDeleted {
+		db = db.Unscoped()
+	}
+	var count int64
+	if err := db.Model(&models.User{}).Where("org_id = ?", orgID).Count(&count).Error; err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+func (s *gormUserStore) Create(ctx context.Context, user *models.User) error {
+	return s.db.WithContext(ctx).Create(user).Error
+}
+
+func (s *gormUserStore) Save(ctx context.Context, user *models.User) error {
+	return s.db.WithContext(ctx).Save(user).Error
+}
+
+func (s *gormUserStore) Updates(ctx context.Context, user *models.User, updates models.User) error {
+	return s.db.WithContext(ctx).Model(user).Updates(updates).Error
+}
+
+func (s *gormUserStore) Disable(ctx context.Context, id uint, at time.Time, by uint, reason string, reenableAt *time.Time) (bool, error) {
+	result := s.db.WithContext(ctx).Model(&models.User{}).
+		Where("id = ? AND is_active = ?", id, true).
+		Updates(map[string]interface{}{
+			"is_active":       false,
+			"disabled_at":     at,
+			"disabled_by":     by,
+			"disabled_reason": reason,
+			"reenable_at":     reenableAt,
+		})
+	return result.RowsAffected == 1, result.Error
+}
+
+func (s *gormUserStore) Enable(ctx context.Context, id uint) (bool, error) {
+	result := s.db.WithContext(ctx).Model(&models.User{}).
+		Where("id = ? AND is_active = ?", id, false).
+		Updates(map[string]interface{}{
+			"is_active":       true,
+			"disabled_at":     nil,
+			"disabled_by":     nil,
+			"disabled_reason": "",
+			"reenable_at":     nil,
+		})
+	return result.RowsAffected == 1, result.Error
+}
+
+func (s *gormUserStore) ListReenableDue(ctx context.Context, now time.Time) ([]models.User, error) {
+	var users []models.User
+	err := s.db.WithContext(ctx).
+		Where("is_active = ? AND reenable_at IS NOT NULL AND reenable_at <= ?", false, now).
+		Find(&users).Error
+	return users, err
+}
+
+func (s *gormUserStore) ListPasswordHashes(ctx context.Context) ([]models.User, error) {
+	var users []models.User
+	err := s.db.WithContext(ctx).Select("id", "username", "password").
+		Where("password IS NOT NULL AND password <> ''").Order("id").Find(&users).Error
+	return users, err
+}
+
+func (s *gormUserStore) UpdatePassword(ctx context.Context, id uint, hashedPassword string) error {
+	return s.db.WithContext(ctx).Model(&models.User{}).Where("id = ?", id).Update("password", hashedPassword).Error
+}
+
+func (s *gormUserStore) SetPassword(ctx context.Context, id uint, hashedPassword string, changedAt *time.Time) error {
+	return s.db.WithContext(ctx).Model(&models.User{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"password":            hashedPassword,
+		"password_changed_at": changedAt,
+	}).Error
+}
+
+func (s *gormUserStore) UpdateMFA(ctx context.Context, id uint, secret string, enabled bool) error {
+	return s.db.WithContext(ctx).Model(&models.User{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"mfa_secret":    secret,
+		"mfa_enabled":   enabled,
+		"mfa_last_step": 0,
+	}).Error
+}
+
+func (s *gormUserStore) UseMFAStep(ctx context.Context, id uint, step int64) (bool, error) {
+	result := s.db.WithContext(ctx).Model(&models.User{}).
+		Where("id = ? AND mfa_last_step < ?", id, step).
+		Update("mfa_last_step", step)
+	return result.RowsAffected == 1, result.Error
+}
+
+func (s *gormUserStore) HardDelete(ctx context.Context, id uint) error {
+	return s.db.WithContext(ctx).Unscoped().Delete(&models.User{}, id).Error
+}
+
+func (s *gormUserStore) DeleteByOrg(ctx context.Context, orgID uint) error {
+	return s.db.WithContext(ctx).Where("org_id = ?", orgID).Delete(&models.User{}).Error
+}
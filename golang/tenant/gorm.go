@@ -0,0 +1,67 @@
+package tenant
+
+import (
+	"reflect"
+	"sync"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ownedColumns 模型类型 -> 组织列名，空字符串表示不是组织拥有的模型
+var ownedColumns sync.Map
+
+// RegisterCallbacks 注册GORM回调，为组织拥有的模型自动加上组织条件
+func RegisterCallbacks(db *gorm.DB) error {
+	cb := db.Callback()
+
+	hooks := []struct {
+		name     string
+		register func(name string, fn func(*gorm.DB)) error
+	}{
+		{"tenant:query", cb.Query().Before("gorm:query").Register},
+		{"tenant:update", cb.Update().Before("gorm:update").Register},
+		{"tenant:delete", cb.Delete().Before("gorm:delete").Register},
+		{"tenant:row", cb.Row().Before("gorm:row").Register},
+	}
+	for _, h := range hooks {
+		if err := h.register(h.name, scopeCallback); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// scopeCallback 按context中的组织过滤
+func scopeCallback(db *gorm.DB) {
+	if db.Error != nil || db.Statement.Schema == nil {
+		return
+	}
+	orgID, scoped := FromContext(db.Statement.Context)
+	if !scoped {
+		return
+	}
+
+	column := tenantColumn(db.Statement.Schema.ModelType)
+	if column == "" {
+		return
+	}
+
+	db.Statement.AddClause(clause.Where{Exprs: []clause.Expression{
+		clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: column}, Value: orgID},
+	}})
+}
+
+// tenantColumn 返回模型的组织列名
+func tenantColumn(modelType reflect.Type) string {
+	if column, ok := ownedColumns.Load(modelType); ok {
+		return column.(string)
+	}
+
+	column := ""
+	if owned, ok := reflect.New(modelType).Interface().(Owned); ok {
+		column = owned.TenantColumn()
+	}
+	ownedColumns.Store(modelType, column)
+	return column
+}
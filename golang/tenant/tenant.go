@@ -0,0 +1,46 @@
+// Package tenant 按组织隔离数据
+//
+// 认证中间件把调用者所属的组织写入请求的context，注册的GORM回调据此为
+// 组织拥有的模型(实现Owned接口)的查询、更新和删除自动加上组织条件。
+// 平台管理员以及需要跨组织查询的系统操作使用Unscoped显式跳过过滤。
+//
+// 创建记录和原生SQL不做检查，由业务层负责设置正确的组织ID。
+package tenant
+
+import "context"
+
+// Owned 由组织拥有的模型
+type Owned interface {
+	// TenantColumn 保存所属组织ID的列名
+	TenantColumn() string
+}
+
+type contextKey struct{}
+
+// scope 请求的组织范围
+type scope struct {
+	orgID    uint
+	unscoped bool
+}
+
+// WithOrg 将数据访问限制在orgID组织内，没有组织的用户传0，此时看不到任何组织的数据
+func WithOrg(ctx context.Context, orgID uint) context.Context {
+	return context.WithValue(ctx, contextKey{}, scope{orgID: orgID})
+}
+
+// Unscoped 跳过组织过滤，用于平台管理员和需要全局查询的操作（如用户名唯一性检查）
+func Unscoped(ctx context.Context) context.Context {
+	return context.WithValue(ctx, contextKey{}, scope{unscoped: true})
+}
+
+// FromContext 返回context中的组织，没有设置组织或已跳过过滤时scoped为false
+func FromContext(ctx context.Context) (orgID uint, scoped bool) {
+	if ctx == nil {
+		return 0, false
+	}
+	s, ok := ctx.Value(contextKey{}).(scope)
+	if !ok || s.unscoped {
+		return 0, false
+	}
+	return s.orgID, true
+}
@@ -1,13 +1,87 @@
 package utils
 
 import (
+	"crypto"
+	"fmt"
+	"sort"
 	"time"
+	"xzyq/config"
 
 	"github.com/golang-jwt/jwt/v5"
 )
 
-// 定义密钥
-var jwtSecret = []byte("xzyq_secret_key")
+var (
+	// HS256签名密钥，未配置私钥时使用
+	jwtSecret []byte
+	// token有效期
+	jwtExpire time.Duration
+
+	// 非对称签名私钥及其kid
+	signingKey    crypto.Signer
+	signingKid    string
+	signingMethod jwt.SigningMethod
+	// kid -> 验证公钥，包含当前私钥对应的公钥和轮换期间仍然接受的旧公钥
+	verificationKeys map[string]verificationKey
+)
+
+// InitJWT 使用配置初始化JWT签名参数
+//
+// 配置了signing_key时使用RS256或EdDSA签名（由密钥类型决定），否则使用secret进行HS256签名。
+func InitJWT(cfg config.JWTConfig) error {
+	jwtSecret = []byte(cfg.Secret)
+	jwtExpire = cfg.Expire
+	signingKey, signingKid, signingMethod = nil, "", jwt.SigningMethodHS256
+	verificationKeys = make(map[string]verificationKey)
+
+	// 签发时间精确到毫秒，便于判断令牌是否在吊销之前签发
+	jwt.TimePrecision = time.Millisecond
+
+	if cfg.SigningKey == "" {
+		return nil
+	}
+
+	signer, err := loadPrivateKey(cfg.SigningKey)
+	if err != nil {
+		return fmt.Errorf("load signing key: %w", err)
+	}
+	current, err := newVerificationKey(signer.Public())
+	if err != nil {
+		return fmt.Errorf("load signing key: %w", err)
+	}
+	signingKey, signingKid, signingMethod = signer, current.kid, current.method
+	verificationKeys[current.kid] = current
+
+	for _, path := range cfg.VerificationKeys {
+		public, err := loadPublicKey(path)
+		if err != nil {
+			return fmt.Errorf("load verification key: %w", err)
+		}
+		key, err := newVerificationKey(public)
+		if err != nil {
+			return fmt.Errorf("load verification key %s: %w", path, err)
+		}
+		verificationKeys[key.kid] = key
+	}
+	return nil
+}
+
+// JWKS 返回验证令牌所需的公钥集合，使用HS256时为空
+func JWKS() JWKSet {
+	set := JWKSet{Keys: []JWK{}}
+	for _, key := range verificationKeys {
+		// 密钥在InitJWT中已经校验过
+		jwk, _ := publicJWK(key.public)
+		set.Keys = append(set.Keys, jwk)
+	}
+	// 当前签名公钥排在最前，其余按kid排序，保证输出稳定
+	sort.Slice(set.Keys, func(i, j int) bool {
+		if (set.Keys[i].Kid == signingKid) != (set.Keys[j].Kid == signingKid) {
+			return set.Keys[i].Kid == signingKid
+		}
+		return set.Keys[i].Kid < set.Keys[j].Kid
+	})
+	return set
+}
 
 // Claims 自定义JWT claims结构
 type Claims struct {
@@ -15,36 +89,59 @@ type Claims struct {
 	OrgID    uint   `json:"org_id"`
 	Username string `json:"username"`
 	Role     string `json:"role"`
+	// SessionID 登录会话（刷新令牌族）的标识，吊销会话时该会话签发的所有访问令牌失效
+	SessionID string `json:"sid,omitempty"`
 	jwt.RegisteredClaims
 }
 
-// GenerateToken 生成JWT token
-func GenerateToken(userID uint, username string, role string) (string, error) {
+// IsPlatformAdmin 不属于任何组织的管理员，可以访问所有组织的数据
+func (c *Claims) IsPlatformAdmin() bool {
+	return c.Role == "admin" && c.OrgID == 0
+}
+
+// GenerateToken 生成JWT token，orgID为0表示用户不属于任何组织，同时返回令牌ID(jti)
+func GenerateToken(userID uint, orgID uint, username string, role string, sessionID string) (string, string, error) {
+	// 令牌ID，用于吊销单个令牌
+	jti, err := RandomToken(16)
+	if err != nil {
+		return "", "", err
+	}
+
 	// 设置token的claims
+	now := time.Now()
 	claims := Claims{
-		UserID:   userID,
-		Username: username,
-		Role:     role,
+		UserID:    userID,
+		OrgID:     orgID,
+		Username:  username,
+		Role:      role,
+		SessionID: sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(7 * 24 * time.Hour)), // token有效期7天
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			NotBefore: jwt.NewNumericDate(time.Now()),
+			ID:        jti,
+			ExpiresAt: jwt.NewNumericDate(now.Add(jwtExpire)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
 		},
 	}
 
 	// 生成token
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString(jwtSecret)
-
-	return tokenString, err
+	token := jwt.NewWithClaims(signingMethod, claims)
+	var signed string
+	if signingKey == nil {
+		signed, err = token.SignedString(jwtSecret)
+	} else {
+		token.Header["kid"] = signingKid
+		signed, err = token.SignedString(signingKey)
+	}
+	if err != nil {
+		return "", "", err
+	}
+	return signed, jti, nil
 }
 
 // ParseToken 解析JWT token
 func ParseToken(tokenString string) (*Claims, error) {
 	// 解析token
-	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		return jwtSecret, nil
-	})
+	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, lookupKey)
 
 	if err != nil {
 		return nil, err
@@ -57,3 +154,23 @@ func ParseToken(tokenString string) (*Claims, error) {
 
 	return nil, err
 }
+
+// lookupKey 按令牌头部的kid选择验证密钥，并确保签名算法与密钥匹配
+func lookupKey(token *jwt.Token) (interface{}, error) {
+	if signingKey == nil {
+		if token.Method != jwt.SigningMethodHS256 {
+			return nil, fmt.Errorf("unexpected signing method %q", token.Method.Alg())
+		}
+		return jwtSecret, nil
+	}
+
+	kid, _ := token.Header["kid"].(string)
+	key, ok := verificationKeys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown key id %q", kid)
+	}
+	if token.Method.Alg() != key.method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method %q", token.Method.Alg())
+	}
+	return key.public, nil
+}
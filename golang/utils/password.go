@@ -1,17 +1,247 @@
 package utils
 
 import (
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
 	"golang.org/x/crypto/bcrypt"
 )
 
-// HashPassword 使用 bcrypt 对密码进行加密
-func HashPassword(password string) (string, error) {
-	bytes, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-	return string(bytes), err
+// 密码哈希算法的标识
+const (
+	PasswordSchemeArgon2id  = "argon2id"
+	PasswordSchemeBcrypt    = "bcrypt"
+	PasswordSchemePlaintext = "plaintext"
+)
+
+// PasswordHasher 一种密码哈希算法，哈希值自带算法标识和参数
+type PasswordHasher interface {
+	// Scheme 算法标识
+	Scheme() string
+	// Identify 判断哈希值是否由该算法生成
+	Identify(encoded string) bool
+	Hash(password string) (string, error)
+	Verify(password, encoded string) bool
+	// NeedsRehash 哈希值使用的参数比当前设置弱时返回true
+	NeedsRehash(encoded string) bool
+}
+
+// PasswordHashers 密码哈希算法注册表
+//
+// 新密码使用首选算法，其他算法只用于校验旧密码；校验通过但使用的不是首选算法或参数较弱时，
+// 调用方应使用首选算法重新生成哈希。
+type PasswordHashers struct {
+	hashers []PasswordHasher // 第一个为首选算法，按顺序识别哈希值
+}
+
+// NewPasswordHashers 创建注册表，verifyOnly按顺序识别，明文需要放在最后
+func NewPasswordHashers(preferred PasswordHasher, verifyOnly ...PasswordHasher) *PasswordHashers {
+	return &PasswordHashers{hashers: append([]PasswordHasher{preferred}, verifyOnly...)}
+}
+
+// Hash 使用首选算法生成哈希
+func (h *PasswordHashers) Hash(password string) (string, error) {
+	return h.hashers[0].Hash(password)
+}
+
+// Verify 校验密码，ok为true且rehash为true时应使用首选算法重新生成哈希
+func (h *PasswordHashers) Verify(password, encoded string) (ok, rehash bool) {
+	hasher := h.identify(encoded)
+	if hasher == nil || !hasher.Verify(password, encoded) {
+		return false, false
+	}
+	return true, hasher != h.hashers[0] || hasher.NeedsRehash(encoded)
+}
+
+// Scheme 返回哈希值的算法标识，没有密码或无法识别时返回空字符串
+func (h *PasswordHashers) Scheme(encoded string) string {
+	if hasher := h.identify(encoded); hasher != nil {
+		return hasher.Scheme()
+	}
+	return ""
+}
+
+// Preferred 返回首选算法的标识
+func (h *PasswordHashers) Preferred() string {
+	return h.hashers[0].Scheme()
 }
 
-// CheckPassword 验证密码是否正确
-func CheckPassword(password, hash string) bool {
-	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+// identify 返回生成该哈希值的算法
+func (h *PasswordHashers) identify(encoded string) PasswordHasher {
+	if encoded == "" {
+		return nil
+	}
+	for _, hasher := range h.hashers {
+		if hasher.Identify(encoded) {
+			return hasher
+		}
+	}
+	return nil
+}
+
+// Argon2idHasher argon2id，哈希值为PHC格式 $argon2id$v=19$m=65536,t=3,p=2$salt$key
+type Argon2idHasher struct {
+	Memory      uint32 // 内存开销，单位KiB
+	Iterations  uint32
+	Parallelism uint8
+}
+
+const (
+	argon2SaltLength = 16
+	argon2KeyLength  = 32
+)
+
+// 已保存哈希中argon2id参数的上限，超过的哈希值视为无法识别，避免一次登录占用过多内存和CPU；
+// 配置中允许的参数不能超过这些上限
+const (
+	Argon2MaxMemory     = 1024 * 1024 // 单位KiB，即1GiB
+	Argon2MaxIterations = 64
+	argon2MaxKeyLength  = 64
+)
+
+// argon2Params 从哈希值中解析出的参数
+type argon2Params struct {
+	memory      uint32
+	iterations  uint32
+	parallelism uint8
+	salt        []byte
+	key         []byte
+}
+
+// Scheme argon2id
+func (a *Argon2idHasher) Scheme() string {
+	return PasswordSchemeArgon2id
+}
+
+// Identify 以$argon2id$开头且格式和参数有效，参数超出范围的哈希值视为无法识别
+func (a *Argon2idHasher) Identify(encoded string) bool {
+	_, err := decodeArgon2id(encoded)
 	return err == nil
 }
+
+// Hash 使用随机盐生成哈希
+func (a *Argon2idHasher) Hash(password string) (string, error) {
+	salt, err := randomBytes(argon2SaltLength)
+	if err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(password), salt, a.Iterations, a.Memory, a.Parallelism, argon2KeyLength)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version, a.Memory, a.Iterations, a.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// Verify 使用哈希值中的参数重新计算并比较
+func (a *Argon2idHasher) Verify(password, encoded string) bool {
+	params, err := decodeArgon2id(encoded)
+	if err != nil {
+		return false
+	}
+	key := argon2.IDKey([]byte(password), params.salt, params.iterations, params.memory, params.parallelism, uint32(len(params.key)))
+	return subtle.ConstantTimeCompare(key, params.key) == 1
+}
+
+// NeedsRehash 任一参数低于当前设置时需要重新生成
+func (a *Argon2idHasher) NeedsRehash(encoded string) bool {
+	params, err := decodeArgon2id(encoded)
+	if err != nil {
+		return true
+	}
+	return params.memory < a.Memory || params.iterations < a.Iterations || params.parallelism < a.Parallelism
+}
+
+// decodeArgon2id 解析PHC格式的argon2id哈希值
+func decodeArgon2id(encoded string) (*argon2Params, error) {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 || parts[1] != PasswordSchemeArgon2id {
+		return nil, errors.New("invalid argon2id hash")
+	}
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return nil, fmt.Errorf("unsupported argon2 version %q", parts[2])
+	}
+
+	var params argon2Params
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.memory, &params.iterations, &params.parallelism); err != nil {
+		return nil, fmt.Errorf("invalid argon2id parameters: %w", err)
+	}
+	// 参数为0时argon2.IDKey会panic，过大时会占用过多资源
+	if params.iterations < 1 || params.iterations > Argon2MaxIterations ||
+		params.parallelism < 1 ||
+		params.memory < 8*uint32(params.parallelism) || params.memory > Argon2MaxMemory {
+		return nil, fmt.Errorf("argon2id parameters out of range: %s", parts[3])
+	}
+	var err error
+	if params.salt, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil || len(params.salt) == 0 {
+		return nil, errors.New("invalid argon2id salt")
+	}
+	if params.key, err = base64.RawStdEncoding.DecodeString(parts[5]); err != nil || len(params.key) == 0 || len(params.key) > argon2MaxKeyLength {
+		return nil, errors.New("invalid argon2id key")
+	}
+	return &params, nil
+}
+
+// BcryptHasher bcrypt，哈希值以$2a$、$2b$或$2y$开头
+type BcryptHasher struct {
+	Cost int
+}
+
+// Scheme bcrypt
+func (b *BcryptHasher) Scheme() string {
+	return PasswordSchemeBcrypt
+}
+
+// Identify 以bcrypt的版本标识开头
+func (b *BcryptHasher) Identify(encoded string) bool {
+	return strings.HasPrefix(encoded, "$2a$") || strings.HasPrefix(encoded, "$2b$") || strings.HasPrefix(encoded, "$2y$")
+}
+
+// Hash 生成bcrypt哈希，密码只使用前72字节
+func (b *BcryptHasher) Hash(password string) (string, error) {
+	bytes, err := bcrypt.GenerateFromPassword([]byte(password), b.Cost)
+	return string(bytes), err
+}
+
+// Verify 校验bcrypt哈希
+func (b *BcryptHasher) Verify(password, encoded string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password)) == nil
+}
+
+// NeedsRehash 成本低于当前设置时需要重新生成
+func (b *BcryptHasher) NeedsRehash(encoded string) bool {
+	cost, err := bcrypt.Cost([]byte(encoded))
+	return err != nil || cost < b.Cost
+}
+
+// PlaintextHasher 早期版本直接保存的明文密码，只能用于校验，需要放在注册表的最后
+type PlaintextHasher struct{}
+
+// Scheme plaintext
+func (PlaintextHasher) Scheme() string {
+	return PasswordSchemePlaintext
+}
+
+// Identify 不像哈希值的非空值视为明文
+//
+// 各种哈希格式都以$开头，以$开头但其他算法无法识别的值可能是损坏或截断的哈希，不能当作明文比较。
+func (PlaintextHasher) Identify(encoded string) bool {
+	return encoded != "" && !strings.HasPrefix(encoded, "$")
+}
+
+// Hash 不允许生成明文密码
+func (PlaintextHasher) Hash(string) (string, error) {
+	return "", errors.New("plaintext passwords can only be verified")
+}
+
+// Verify 按常量时间比较
+func (PlaintextHasher) Verify(password, encoded string) bool {
+	return subtle.ConstantTimeCompare([]byte(password), []byte(encoded)) == 1
+}
+
+// NeedsRehash 明文总是需要重新生成
+func (PlaintextHasher) NeedsRehash(string) bool {
+	return true
+}
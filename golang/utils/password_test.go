@@ -0,0 +1,143 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+)
+
+// testHashers 使用较小的参数，避免测试过慢
+func testHashers() (*PasswordHashers, *Argon2idHasher) {
+	argon := &Argon2idHasher{Memory: 1024, Iterations: 1, Parallelism: 1}
+	return NewPasswordHashers(argon, &BcryptHasher{Cost: 4}, PlaintextHasher{}), argon
+}
+
+func TestPasswordHashersHashUsesPreferred(t *testing.T) {
+	hashers, _ := testHashers()
+
+	encoded, err := hashers.Hash("s3cret!")
+	if err != nil {
+		t.Fatalf("hash: %v", err)
+	}
+	if !strings.HasPrefix(encoded, "$argon2id$v=19$m=1024,t=1,p=1$") {
+		t.Fatalf("unexpected encoding %q", encoded)
+	}
+	if scheme := hashers.Scheme(encoded); scheme != PasswordSchemeArgon2id {
+		t.Errorf("scheme = %q, want %q", scheme, PasswordSchemeArgon2id)
+	}
+
+	if ok, rehash := hashers.Verify("s3cret!", encoded); !ok || rehash {
+		t.Errorf("Verify(correct) = %v, %v, want true, false", ok, rehash)
+	}
+	if ok, _ := hashers.Verify("wrong", encoded); ok {
+		t.Error("Verify(wrong) = true")
+	}
+
+	other, err := hashers.Hash("s3cret!")
+	if err != nil {
+		t.Fatalf("hash: %v", err)
+	}
+	if other == encoded {
+		t.Error("hashes of the same password must use different salts")
+	}
+}
+
+func TestPasswordHashersLegacySchemesNeedRehash(t *testing.T) {
+	hashers, _ := testHashers()
+	bcryptHash, err := (&BcryptHasher{Cost: 4}).Hash("s3cret!")
+	if err != nil {
+		t.Fatalf("bcrypt: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		encoded string
+		scheme  string
+	}{
+		{"bcrypt", bcryptHash, PasswordSchemeBcrypt},
+		{"plaintext", "s3cret!", PasswordSchemePlaintext},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if scheme := hashers.Scheme(tt.encoded); scheme != tt.scheme {
+				t.Errorf("scheme = %q, want %q", scheme, tt.scheme)
+			}
+			if ok, rehash := hashers.Verify("s3cret!", tt.encoded); !ok || !rehash {
+				t.Errorf("Verify(correct) = %v, %v, want true, true", ok, rehash)
+			}
+			if ok, rehash := hashers.Verify("wrong", tt.encoded); ok || rehash {
+				t.Errorf("Verify(wrong) = %v, %v, want false, false", ok, rehash)
+			}
+		})
+	}
+}
+
+func TestPasswordHashersWeakerArgon2idNeedsRehash(t *testing.T) {
+	weak, err := (&Argon2idHasher{Memory: 512, Iterations: 1, Parallelism: 1}).Hash("s3cret!")
+	if err != nil {
+		t.Fatalf("hash: %v", err)
+	}
+	hashers, _ := testHashers()
+	if ok, rehash := hashers.Verify("s3cret!", weak); !ok || !rehash {
+		t.Errorf("Verify(weaker params) = %v, %v, want true, true", ok, rehash)
+	}
+}
+
+func TestPasswordHashersEmptyAndMalformed(t *testing.T) {
+	hashers, argon := testHashers()
+
+	// 单点登录和目录认证的用户没有密码
+	if ok, _ := hashers.Verify("", ""); ok {
+		t.Error("empty hash must not verify")
+	}
+	if scheme := hashers.Scheme(""); scheme != "" {
+		t.Errorf("scheme of empty hash = %q, want empty", scheme)
+	}
+
+	for _, encoded := range []string{
+		"$argon2id$v=19$m=1024,t=1,p=1$c2FsdA",
+		"$argon2id$v=16$m=1024,t=1,p=1$c2FsdA$a2V5",
+		"$argon2id$v=19$m=x,t=1,p=1$c2FsdA$a2V5",
+	} {
+		if argon.Verify("s3cret!", encoded) {
+			t.Errorf("malformed hash %q verified", encoded)
+		}
+		if !argon.NeedsRehash(encoded) {
+			t.Errorf("malformed hash %q does not need rehash", encoded)
+		}
+	}
+
+	// 参数超出范围时不能调用argon2（为0时会panic，过大时占用过多内存），视为无法识别
+	for _, params := range []string{
+		"m=1024,t=0,p=1",
+		"m=1024,t=1,p=0",
+		"m=7,t=1,p=1",
+		"m=1024,t=1,p=255",
+		"m=4194304,t=1,p=1",
+		"m=1024,t=65,p=1",
+	} {
+		encoded := "$argon2id$v=19$" + params + "$c2FsdHNhbHRzYWx0$a2V5a2V5a2V5a2V5"
+		if scheme := hashers.Scheme(encoded); scheme != "" {
+			t.Errorf("hash with %s: scheme = %q, want unrecognized", params, scheme)
+		}
+		if ok, _ := hashers.Verify("s3cret!", encoded); ok {
+			t.Errorf("hash with %s verified", params)
+		}
+		if argon.Verify("s3cret!", encoded) {
+			t.Errorf("argon2id verified hash with %s", params)
+		}
+	}
+
+	// 无法识别或截断的哈希不能当作明文比较
+	for _, encoded := range []string{"$2b$1", "$5$rounds=5000$salt$hash", "$argon2"} {
+		if ok, _ := hashers.Verify(encoded, encoded); ok {
+			t.Errorf("unrecognized hash %q verified as plaintext", encoded)
+		}
+		if scheme := hashers.Scheme(encoded); scheme == PasswordSchemePlaintext {
+			t.Errorf("unrecognized hash %q identified as plaintext", encoded)
+		}
+	}
+
+	if _, err := (PlaintextHasher{}).Hash("s3cret!"); err == nil {
+		t.Error("plaintext hasher must not hash")
+	}
+}
@@ -0,0 +1,93 @@
+package utils
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"hash"
+	"testing"
+	"time"
+)
+
+// RFC 6238 附录B的测试向量，8位验证码，时间步长30秒
+func TestHOTPRFC6238Vectors(t *testing.T) {
+	keys := map[string]struct {
+		key []byte
+		h   func() hash.Hash
+	}{
+		"SHA1":   {[]byte("12345678901234567890"), sha1.New},
+		"SHA256": {[]byte("12345678901234567890123456789012"), sha256.New},
+		"SHA512": {[]byte("1234567890123456789012345678901234567890123456789012345678901234"), sha512.New},
+	}
+
+	vectors := []struct {
+		unix int64
+		alg  string
+		code string
+	}{
+		{59, "SHA1", "94287082"},
+		{59, "SHA256", "46119246"},
+		{59, "SHA512", "90693936"},
+		{1111111109, "SHA1", "07081804"},
+		{1111111109, "SHA256", "68084774"},
+		{1111111109, "SHA512", "25091201"},
+		{1111111111, "SHA1", "14050471"},
+		{1111111111, "SHA256", "67062674"},
+		{1111111111, "SHA512", "99943326"},
+		{1234567890, "SHA1", "89005924"},
+		{1234567890, "SHA256", "91819424"},
+		{1234567890, "SHA512", "93441116"},
+		{2000000000, "SHA1", "69279037"},
+		{2000000000, "SHA256", "90698825"},
+		{2000000000, "SHA512", "38618901"},
+		{20000000000, "SHA1", "65353130"},
+		{20000000000, "SHA256", "77737706"},
+		{20000000000, "SHA512", "47863826"},
+	}
+
+	for _, v := range vectors {
+		k := keys[v.alg]
+		got := hotp(k.key, uint64(v.unix/30), 8, k.h)
+		if got != v.code {
+			t.Errorf("T=%d %s: got %s, want %s", v.unix, v.alg, got, v.code)
+		}
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	// RFC 6238 的SHA1密钥，6位验证码取8位验证码的后6位
+	secret := totpEncoding.EncodeToString([]byte("12345678901234567890"))
+	now := time.Unix(1111111109, 0)
+
+	step, ok := ValidateTOTP(secret, "081804", now)
+	if !ok || step != 1111111109/30 {
+		t.Fatalf("ValidateTOTP current step = (%d, %v), want (%d, true)", step, ok, 1111111109/30)
+	}
+
+	// 允许一个时间步的时钟偏差
+	if _, ok := ValidateTOTP(secret, "081804", now.Add(30*time.Second)); !ok {
+		t.Error("code from the previous step should be accepted")
+	}
+	if _, ok := ValidateTOTP(secret, "081804", now.Add(90*time.Second)); ok {
+		t.Error("code from three steps ago should be rejected")
+	}
+	if _, ok := ValidateTOTP(secret, "000000", now); ok {
+		t.Error("wrong code should be rejected")
+	}
+	if _, ok := ValidateTOTP(secret, "81804", now); ok {
+		t.Error("short code should be rejected")
+	}
+
+	// 没有密钥时不能通过，即使验证码与空密钥算出的相同
+	if _, ok := ValidateTOTP("", hotp(nil, uint64(now.Unix()/30), totpDigits, sha1.New), now); ok {
+		t.Error("empty secret should be rejected")
+	}
+}
+
+func TestTOTPURI(t *testing.T) {
+	got := TOTPURI("xzyq", "alice", "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ")
+	want := "otpauth://totp/xzyq:alice?algorithm=SHA1&digits=6&issuer=xzyq&period=30&secret=GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+	if got != want {
+		t.Errorf("TOTPURI = %s, want %s", got, want)
+	}
+}
@@ -23,16 +23,51 @@ axios.interceptors.request.use(
   }
 )
 
+// 正在进行的刷新请求，多个请求同时过期时共用一次刷新
+let refreshing = null
+
+// 使用刷新令牌换取新的访问令牌，刷新令牌只能使用一次
+const refreshToken = () => {
+  if (!refreshing) {
+    refreshing = axios.post('/api/token/refresh', {
+      refresh_token: localStorage.getItem('refresh_token')
+    }).then(response => {
+      localStorage.setItem('token', response.data.token)
+      localStorage.setItem('refresh_token', response.data.refresh_token)
+    }).finally(() => {
+      refreshing = null
+    })
+  }
+  return refreshing
+}
+
 // 添加响应拦截器
 axios.interceptors.response.use(
   response => response,
-  error => {
+  async error => {
+    const config = error.config
     if (error.response?.status === 401) {
-      // 清除已过期的token
-      localStorage.removeItem('token')
-      // 跳转到登录页
-      router.push('/login')
-      ElMessage.error('登录已过期，请重新登录')
+      // 访问令牌过期时先尝试刷新，刷新成功后重试原请求
+      const isLoginRequest = config?.url?.startsWith('/api/login')
+      const isAuthRequest = isLoginRequest || config?.url === '/api/token/refresh'
+      if (config && !config._retried && !isAuthRequest && localStorage.getItem('refresh_token')) {
+        config._retried = true
+        try {
+          await refreshToken()
+          return axios(config)
+        } catch (refreshError) {
+          // 刷新失败，按登录过期处理
+        }
+      }
+
+      if (!isLoginRequest) {
+        // 清除已过期的令牌
+        localStorage.removeItem('token')
+        localStorage.removeItem('refresh_token')
+        // 跳转到登录页
+        router.push('/login')
+        ElMessage.error('登录已过期，请重新登录')
+      }
     }
     return Promise.reject(error)
   }
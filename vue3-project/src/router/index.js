@@ -9,6 +9,18 @@ const routes = [
     name: 'LoginPage',
     component: LoginPage
   },
+  {
+    // 单点登录回调，身份提供方带回code和state，由登录页完成登录
+    path: '/oidc/callback',
+    name: 'OIDCCallback',
+    component: LoginPage
+  },
+  {
+    // 不带token时申请重置邮件，带token时设置新密码
+    path: '/reset-password',
+    name: 'ResetPassword',
+    component: () => import('../views/ResetPasswordPage.vue')
+  },
   {
     path: '/',
     name: 'HomePage',